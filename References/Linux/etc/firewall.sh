@@ -46,6 +46,9 @@ OUT_IVPN_STAT_EXP=IVPN-OUT-STAT-EXP
 # chain for non-VPN depended exceptios: only for ICMP protocol (ping)
 IN_IVPN_ICMP_EXP=IVPN-IN-ICMP-EXP
 OUT_IVPN_ICMP_EXP=IVPN-OUT-ICMP-EXP
+# chain for DNS leak protection rules (blocks DNS requests to all destinations except the VPN DNS)
+# Note: the chain is not a part of the IVPN firewall chains (applicable even when firewall disabled)
+OUT_IVPN_DNS=IVPN-OUT-DNS
 
 # returns 0 if chain exists
 function chain_exists()
@@ -86,12 +89,16 @@ function enable_firewall {
       # IPv6: define chains
       create_chain ${IPv6BIN} ${IN_IVPN}
       create_chain ${IPv6BIN} ${OUT_IVPN}
+      create_chain ${IPv6BIN} ${IN_IVPN_IF}
+      create_chain ${IPv6BIN} ${OUT_IVPN_IF}
       # IPv6: allow  local (lo) interface
       ${IPv6BIN} -w ${LOCKWAITTIME} -A ${OUT_IVPN} -o lo -j ACCEPT
       ${IPv6BIN} -w ${LOCKWAITTIME} -A ${IN_IVPN} -i lo -j ACCEPT
       # IPv6: assign our chains to global (global -> IVPN_CHAIN -> IVPN_VPN_CHAIN)
       ${IPv6BIN} -w ${LOCKWAITTIME} -A OUTPUT -j ${OUT_IVPN}
       ${IPv6BIN} -w ${LOCKWAITTIME} -A INPUT -j ${IN_IVPN}
+      ${IPv6BIN} -w ${LOCKWAITTIME} -A ${OUT_IVPN} -j ${OUT_IVPN_IF}
+      ${IPv6BIN} -w ${LOCKWAITTIME} -A ${IN_IVPN} -j ${IN_IVPN_IF}
 
       # IPv6: block everything by default
       ${IPv6BIN} -w ${LOCKWAITTIME} -P INPUT DROP
@@ -190,8 +197,14 @@ function disable_firewall {
     ### IPv6 ###
     ${IPv6BIN} -w ${LOCKWAITTIME} -D OUTPUT -j ${OUT_IVPN}
     ${IPv6BIN} -w ${LOCKWAITTIME} -D INPUT -j ${IN_IVPN}
+    ${IPv6BIN} -w ${LOCKWAITTIME} -D ${OUT_IVPN} -j ${OUT_IVPN_IF}
+    ${IPv6BIN} -w ${LOCKWAITTIME} -D ${IN_IVPN} -j ${IN_IVPN_IF}
+    ${IPv6BIN} -w ${LOCKWAITTIME} -F ${OUT_IVPN_IF}
+    ${IPv6BIN} -w ${LOCKWAITTIME} -F ${IN_IVPN_IF}
     ${IPv6BIN} -w ${LOCKWAITTIME} -F ${OUT_IVPN}
     ${IPv6BIN} -w ${LOCKWAITTIME} -F ${IN_IVPN}
+    ${IPv6BIN} -w ${LOCKWAITTIME} -X ${OUT_IVPN_IF}
+    ${IPv6BIN} -w ${LOCKWAITTIME} -X ${IN_IVPN_IF}
     ${IPv6BIN} -w ${LOCKWAITTIME} -X ${OUT_IVPN}
     ${IPv6BIN} -w ${LOCKWAITTIME} -X ${IN_IVPN}
 
@@ -207,6 +220,11 @@ function client_connected {
 function client_disconnected {
   ${IPv4BIN} -w ${LOCKWAITTIME} -F ${OUT_IVPN_IF}
   ${IPv4BIN} -w ${LOCKWAITTIME} -F ${IN_IVPN_IF}
+
+  if [ -f /proc/net/if_inet6 ]; then
+    ${IPv6BIN} -w ${LOCKWAITTIME} -F ${OUT_IVPN_IF}
+    ${IPv6BIN} -w ${LOCKWAITTIME} -F ${IN_IVPN_IF}
+  fi
 }
 
 function add_exceptions {
@@ -224,6 +242,31 @@ function add_exceptions {
   ${IPv4BIN} -w ${LOCKWAITTIME} -C ${OUT_CH} -d $@ -j ACCEPT || ${IPv4BIN} -w ${LOCKWAITTIME} -A ${OUT_CH} -d $@ -j ACCEPT
 }
 
+function add_exceptions_ipv6 {
+  IN_CH=$1
+  OUT_CH=$2
+  shift 2
+  EXP=$@
+
+  create_chain ${IPv6BIN} ${IN_CH}
+  create_chain ${IPv6BIN} ${OUT_CH}
+
+  #add new rule
+  # '-C' option is checking if the rule already exists (needed to avoid duplicates)
+  ${IPv6BIN} -w ${LOCKWAITTIME} -C ${IN_CH} -s $@ -j ACCEPT || ${IPv6BIN} -w ${LOCKWAITTIME} -A ${IN_CH} -s $@ -j ACCEPT
+  ${IPv6BIN} -w ${LOCKWAITTIME} -C ${OUT_CH} -d $@ -j ACCEPT || ${IPv6BIN} -w ${LOCKWAITTIME} -A ${OUT_CH} -d $@ -j ACCEPT
+}
+
+function remove_exceptions_ipv6 {
+  IN_CH=$1
+  OUT_CH=$2
+  shift 2
+  EXP=$@
+
+  ${IPv6BIN} -w ${LOCKWAITTIME} -D ${IN_CH} -s $@ -j ACCEPT
+  ${IPv6BIN} -w ${LOCKWAITTIME} -D ${OUT_CH} -d $@ -j ACCEPT
+}
+
 function add_direction_exception {
   IN_CH=$1
   OUT_CH=$2
@@ -271,6 +314,65 @@ function add_exceptions_icmp {
   ${IPv4BIN} -w ${LOCKWAITTIME} -A ${OUT_CH} -p icmp --icmp-type 8 -d $@ -m state --state NEW,ESTABLISHED,RELATED -j ACCEPT
 }
 
+function dns_block_start {
+  # remove rules from a previous call (if exists)
+  dns_block_stop
+
+  ALLOWED_DNS=$1
+  shift
+  DOH_RESOLVERS=$@
+
+  create_chain ${IPv4BIN} ${OUT_IVPN_DNS}
+
+  # requests to the VPN DNS have to be processed by the rest of the rules (RETURN, not ACCEPT)
+  ${IPv4BIN} -w ${LOCKWAITTIME} -A ${OUT_IVPN_DNS} -d ${ALLOWED_DNS} -p udp --dport 53 -j RETURN
+  ${IPv4BIN} -w ${LOCKWAITTIME} -A ${OUT_IVPN_DNS} -d ${ALLOWED_DNS} -p tcp --dport 53 -j RETURN
+  ${IPv4BIN} -w ${LOCKWAITTIME} -A ${OUT_IVPN_DNS} -d ${ALLOWED_DNS} -p tcp --dport 853 -j RETURN
+
+  # block plain DNS and DNS-over-TLS
+  ${IPv4BIN} -w ${LOCKWAITTIME} -A ${OUT_IVPN_DNS} -p udp --dport 53 -j DROP
+  ${IPv4BIN} -w ${LOCKWAITTIME} -A ${OUT_IVPN_DNS} -p tcp --dport 53 -j DROP
+  ${IPv4BIN} -w ${LOCKWAITTIME} -A ${OUT_IVPN_DNS} -p udp --dport 853 -j DROP
+  ${IPv4BIN} -w ${LOCKWAITTIME} -A ${OUT_IVPN_DNS} -p tcp --dport 853 -j DROP
+
+  # block DNS-over-HTTPS (by IP addresses of well-known public DoH resolvers)
+  for resolver in ${DOH_RESOLVERS} ; do
+    ${IPv4BIN} -w ${LOCKWAITTIME} -A ${OUT_IVPN_DNS} -d ${resolver} -p tcp --dport 443 -j DROP
+  done
+
+  # insert at the top of OUTPUT:
+  # the rules have to be evaluated before the 'accept all on VPN interface' rule
+  ${IPv4BIN} -w ${LOCKWAITTIME} -I OUTPUT -j ${OUT_IVPN_DNS}
+
+  if [ -f /proc/net/if_inet6 ]; then
+    # IPv6: block all DNS requests (the VPN DNS is IPv4)
+    create_chain ${IPv6BIN} ${OUT_IVPN_DNS}
+    ${IPv6BIN} -w ${LOCKWAITTIME} -A ${OUT_IVPN_DNS} -p udp --dport 53 -j DROP
+    ${IPv6BIN} -w ${LOCKWAITTIME} -A ${OUT_IVPN_DNS} -p tcp --dport 53 -j DROP
+    ${IPv6BIN} -w ${LOCKWAITTIME} -A ${OUT_IVPN_DNS} -p udp --dport 853 -j DROP
+    ${IPv6BIN} -w ${LOCKWAITTIME} -A ${OUT_IVPN_DNS} -p tcp --dport 853 -j DROP
+    ${IPv6BIN} -w ${LOCKWAITTIME} -I OUTPUT -j ${OUT_IVPN_DNS}
+  fi
+
+  echo "IVPN DNS blocking enabled"
+}
+
+function dns_block_stop {
+  if chain_exists ${IPv4BIN} ${OUT_IVPN_DNS} ; then
+    ${IPv4BIN} -w ${LOCKWAITTIME} -D OUTPUT -j ${OUT_IVPN_DNS}
+    ${IPv4BIN} -w ${LOCKWAITTIME} -F ${OUT_IVPN_DNS}
+    ${IPv4BIN} -w ${LOCKWAITTIME} -X ${OUT_IVPN_DNS}
+  fi
+
+  if [ -f /proc/net/if_inet6 ]; then
+    if chain_exists ${IPv6BIN} ${OUT_IVPN_DNS} ; then
+      ${IPv6BIN} -w ${LOCKWAITTIME} -D OUTPUT -j ${OUT_IVPN_DNS}
+      ${IPv6BIN} -w ${LOCKWAITTIME} -F ${OUT_IVPN_DNS}
+      ${IPv6BIN} -w ${LOCKWAITTIME} -X ${OUT_IVPN_DNS}
+    fi
+  fi
+}
+
 function main {
 
     if [[ $1 = "-enable" ]] ; then
@@ -303,6 +405,16 @@ function main {
       shift
       remove_exceptions ${IN_IVPN_IF} ${OUT_IVPN_IF} $@
 
+    elif [[ $1 = "-add_exceptions_ipv6" ]]; then
+
+      shift
+      add_exceptions_ipv6 ${IN_IVPN_IF} ${OUT_IVPN_IF} $@
+
+    elif [[ $1 = "-remove_exceptions_ipv6" ]]; then
+
+      shift
+      remove_exceptions_ipv6 ${IN_IVPN_IF} ${OUT_IVPN_IF} $@
+
     elif [[ $1 = "-add_exceptions_static" ]]; then
       
       shift
@@ -318,6 +430,15 @@ function main {
       shift
       add_exceptions_icmp ${IN_IVPN_ICMP_EXP} ${OUT_IVPN_ICMP_EXP} $@
 
+    elif [[ $1 = "-dns_block_start" ]]; then
+
+      shift
+      dns_block_start $@
+
+    elif [[ $1 = "-dns_block_stop" ]]; then
+
+      dns_block_stop
+
     elif [[ $1 = "-connected" ]]; then
         IFACE=$2
         #SRC_ADDR=$3
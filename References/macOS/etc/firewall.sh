@@ -89,6 +89,7 @@ function enable_firewall {
       pass in proto udp from any to any port = 68
 
       anchor tunnel all
+      anchor dns all
 
 _EOF
 
@@ -149,6 +150,53 @@ function client_disconnected {
     pfctl -a ${ANCHOR_NAME}/tunnel -Fr
 }
 
+# Block DNS requests to all destinations except the allowed DNS server
+# (plain DNS, DNS-over-TLS; requests to well-known public DoH resolvers are blocked by their IPs)
+function dns_block_start {
+
+    ALLOWED_DNS=$1
+    shift
+    DOH_RESOLVERS=$@
+
+    add_anchor_if_required
+
+    # restore 'dns' sub-anchor link (it is erased, for example, by the '-disable' command)
+    if [[ -z `pfctl -a ${ANCHOR_NAME} -sr 2> /dev/null | grep "anchor \"dns\""` ]] ; then
+        cat \
+          <(echo "anchor dns all") \
+          <(pfctl -a ${ANCHOR_NAME} -sr 2> /dev/null) \
+           | pfctl -a ${ANCHOR_NAME} -f -
+    fi
+
+    # 'quick' rules take precedence over the rest of the ruleset (e.g. 'pass all' rules of the tunnel anchor)
+    pfctl -a ${ANCHOR_NAME}/dns -f - <<_EOF
+        table <doh_resolvers> { ${DOH_RESOLVERS} }
+        block drop out quick proto { udp, tcp } from any to ! ${ALLOWED_DNS} port { 53, 853 }
+        block drop out quick proto tcp from any to <doh_resolvers> port 443
+_EOF
+
+    local TOKEN=`pfctl -E 2>&1 | grep -i token | sed -e 's/.*oken.*://' | tr -d ' \n'`
+
+    scutil <<_EOF
+      d.init
+      d.add Token "${TOKEN}"
+      set State:/Network/IVPN/PacketFilterDNS
+
+      quit
+_EOF
+
+    echo "IVPN DNS blocking enabled"
+}
+
+function dns_block_stop {
+    pfctl -a ${ANCHOR_NAME}/dns -Fr
+
+    local TOKEN=`echo 'show State:/Network/IVPN/PacketFilterDNS' | scutil | grep Token | sed -e 's/.*: //' | tr -d ' \n'`
+    pfctl -X "${TOKEN}"
+
+    echo "IVPN DNS blocking disabled"
+}
+
 function main {
 
     if [[ $1 = "-enable" ]] ; then
@@ -182,7 +230,16 @@ function main {
       shift
       pfctl -a "${ANCHOR_NAME}" -t "${EXCEPTIONS_TABLE}" -T delete $@
 
-    elif [[ $1 = "-connected" ]]; then       
+    elif [[ $1 = "-dns_block_start" ]]; then
+
+      shift
+      dns_block_start $@
+
+    elif [[ $1 = "-dns_block_stop" ]]; then
+
+      dns_block_stop
+
+    elif [[ $1 = "-connected" ]]; then
         
         IFACE=$2  
 
@@ -25,7 +25,11 @@ package api
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net"
+	"net/http"
+	"strings"
 	"sync"
 	"time"
 
@@ -40,11 +44,17 @@ const (
 	_updateHost            = "repo.ivpn.net"
 	_apiPathPrefix         = "v4"
 	_serversPath           = _apiPathPrefix + "/servers.json"
+	_accountNewPath        = _apiPathPrefix + "/account/new"
 	_sessionNewPath        = _apiPathPrefix + "/session/new"
 	_sessionStatusPath     = _apiPathPrefix + "/session/status"
 	_sessionDeletePath     = _apiPathPrefix + "/session/delete"
 	_wgKeySetPath          = _apiPathPrefix + "/session/wg/set"
 	_geoLookupPath         = _apiPathPrefix + "/geo-lookup"
+	_portForwardAddPath    = _apiPathPrefix + "/port-forward/add"
+	_portForwardDeletePath = _apiPathPrefix + "/port-forward/delete"
+
+	// GeoIP dataset (distributed through the update host; see 'geoip' package)
+	_geoipDatasetPath = "/common/geoip/geoip.json"
 )
 
 // Alias - alias description of API request (can be requested by UI client)
@@ -88,6 +98,84 @@ type API struct {
 	mutex               sync.Mutex
 	alternateIPs        []net.IP
 	lastGoodAlternateIP net.IP
+
+	// user-defined proxy for all API traffic (useful when API servers are blocked)
+	proxyType     string // "socks5", "http" or "" (no proxy)
+	proxyAddress  string // host:port
+	proxyUsername string
+	proxyPassword string
+
+	// custom API\update hosts (for staging or self-hosted environments; empty - use default hosts)
+	customAPIHost          string
+	customUpdateHost       string
+	customAPIPinnedKeys    []string
+	customUpdatePinnedKeys []string
+
+	// duration of the last successful request (used by metrics functionality)
+	lastRequestDuration time.Duration
+	// time of the last successful request (used by daemon health-check functionality)
+	lastGoodRequestTime time.Time
+
+	// persistent transports with connection pooling (lazy-initialized; see 'getAPITransport()')
+	// must be re-created when hosts\pinned keys\proxy configuration changes (see 'resetTransports()')
+	apiTransport    *http.Transport
+	updateTransport *http.Transport
+
+	// injected custom transport (when defined - used instead of the default transports;
+	// intended for tests: e.g. mock\replay harness of the 'apitest' package)
+	customRoundTripper http.RoundTripper
+
+	// version of the last successfully applied pin manifest (see 'UpdatePinsFromManifest()')
+	appliedPinManifestVersion int
+
+	// local IP to bind all outgoing API connections to (nil - do not bind)
+	// (used to force API traffic through the VPN tunnel interface when connected;
+	// prevents requests from escaping via a secondary adapter)
+	bindLocalIP net.IP
+}
+
+// SetBindLocalIP - bind all outgoing API connections to a specific local IP address
+// (e.g. IP of the VPN tunnel interface when connected; nil - do not bind)
+func (a *API) SetBindLocalIP(localIP net.IP) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if localIP.Equal(a.bindLocalIP) {
+		return
+	}
+	a.bindLocalIP = localIP
+
+	// pooled connections are bound to the old local IP - they are not valid anymore
+	a.resetTransports()
+}
+
+func (a *API) getBindLocalIP() net.IP {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.bindLocalIP
+}
+
+// saveLastRequestDuration stores duration of the last successful request
+func (a *API) saveLastRequestDuration(d time.Duration) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.lastRequestDuration = d
+	a.lastGoodRequestTime = time.Now()
+}
+
+// LastSuccessfulRequestTime returns time of the last successful request
+// (zero time - no successful requests performed yet)
+func (a *API) LastSuccessfulRequestTime() time.Time {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.lastGoodRequestTime
+}
+
+// LastRequestDuration returns duration of the last successful request (0 - no requests performed yet)
+func (a *API) LastRequestDuration() time.Duration {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.lastRequestDuration
 }
 
 // CreateAPI creates new API object
@@ -95,6 +183,115 @@ func CreateAPI() (*API, error) {
 	return &API{}, nil
 }
 
+// SetCustomRoundTripper - inject custom HTTP transport to be used for all requests
+// (intended for tests: allows to mock\replay API responses without network access; nil - restore default behavior)
+func (a *API) SetCustomRoundTripper(rt http.RoundTripper) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.customRoundTripper = rt
+}
+
+// SetCustomHosts - override API\update hosts and their pinned key sets
+// (for testing against staging or self-hosted API mirrors; empty host value resets to default)
+func (a *API) SetCustomHosts(apiHost string, updateHost string, apiPinnedKeys []string, updatePinnedKeys []string) error {
+	for _, host := range []string{apiHost, updateHost} {
+		if strings.ContainsAny(host, "/\\ :") {
+			return fmt.Errorf("invalid host value: '%s'", host)
+		}
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.customAPIHost = apiHost
+	a.customUpdateHost = updateHost
+	a.customAPIPinnedKeys = apiPinnedKeys
+	a.customUpdatePinnedKeys = updatePinnedKeys
+
+	// hosts or pinned keys changed - pooled connections are not valid anymore
+	a.resetTransports()
+
+	if len(apiHost) == 0 && len(updateHost) == 0 {
+		log.Info("Custom API hosts erased (using default hosts)")
+	} else {
+		log.Info(fmt.Sprintf("Custom API hosts defined: api='%s' update='%s'", apiHost, updateHost))
+	}
+	return nil
+}
+
+func (a *API) getAPIHost() string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if len(a.customAPIHost) > 0 {
+		return a.customAPIHost
+	}
+	return _apiHost
+}
+
+func (a *API) getUpdateHost() string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if len(a.customUpdateHost) > 0 {
+		return a.customUpdateHost
+	}
+	return _updateHost
+}
+
+func (a *API) getAPIPinnedKeys() []string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if len(a.customAPIHost) > 0 {
+		return a.customAPIPinnedKeys
+	}
+	return APIIvpnHashes
+}
+
+func (a *API) getUpdatePinnedKeys() []string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if len(a.customUpdateHost) > 0 {
+		return a.customUpdatePinnedKeys
+	}
+	return UpdateIvpnHashes
+}
+
+// SetProxy - configure proxy for all API traffic ('proxyType'=="" - disable proxy)
+func (a *API) SetProxy(proxyType string, address string, port int, username string, password string) error {
+	if proxyType != "" && proxyType != "socks5" && proxyType != "http" {
+		return fmt.Errorf("unsupported proxy type: '%s'", proxyType)
+	}
+	if proxyType != "" {
+		if len(address) == 0 || port <= 0 || port > 65535 {
+			return fmt.Errorf("invalid proxy address or port")
+		}
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.proxyType = proxyType
+	a.proxyAddress = fmt.Sprintf("%s:%d", address, port)
+	a.proxyUsername = username
+	a.proxyPassword = password
+
+	// proxy configuration changed - pooled connections are not valid anymore
+	a.resetTransports()
+
+	if proxyType == "" {
+		a.proxyAddress = ""
+		log.Info("API proxy disabled")
+	} else {
+		log.Info(fmt.Sprintf("API proxy configured: %s://%s", proxyType, a.proxyAddress))
+	}
+	return nil
+}
+
+func (a *API) getProxySettings() (proxyType, address, username, password string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.proxyType, a.proxyAddress, a.proxyUsername, a.proxyPassword
+}
+
 // IsAlternateIPsInitialized - checks if the alternate IP initialized
 func (a *API) IsAlternateIPsInitialized() bool {
 	a.mutex.Lock()
@@ -150,6 +347,12 @@ func (a *API) DownloadServersList() (*types.ServersInfoResponse, error) {
 	return servers, nil
 }
 
+// DownloadGeoIPDataset - download GeoIP dataset from the update host
+// (see 'geoip' package; returns raw JSON data)
+func (a *API) DownloadGeoIPDataset() ([]byte, error) {
+	return a.requestRaw(_updateHost, _geoipDatasetPath, "", "", nil, 0)
+}
+
 // DoRequestByAlias do API request (by API endpoint alias). Returns raw data of response
 func (a *API) DoRequestByAlias(apiAlias string) (responseData []byte, err error) {
 	alias, ok := APIAliases[apiAlias]
@@ -160,6 +363,89 @@ func (a *API) DoRequestByAlias(apiAlias string) (responseData []byte, err error)
 	return retData, retErr
 }
 
+// DoRequestByAliasStream do API request (by API endpoint alias), streaming response body by chunks
+// (avoids keeping the whole response in memory: useful for large payloads e.g. update binaries or blocklists)
+//
+//	maxSizeBytes - maximum acceptable response size; 0 - no limit
+//	onChunk      - called for each received chunk; 'contentLength' is -1 when response size is not known;
+//	               non-nil error returned by the callback aborts the download
+func (a *API) DoRequestByAliasStream(apiAlias string, maxSizeBytes int64, onChunk func(chunk []byte, received int64, contentLength int64) error) error {
+	alias, ok := APIAliases[apiAlias]
+	if ok != true {
+		return fmt.Errorf("Unexpected request alias")
+	}
+
+	resp, err := a.doRequest(alias.host, alias.path, "", "", nil, 0)
+	if err != nil {
+		return fmt.Errorf("API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	contentLength := resp.ContentLength
+	if maxSizeBytes > 0 && contentLength > maxSizeBytes {
+		return fmt.Errorf("response size (%d bytes) exceeds acceptable limit (%d bytes)", contentLength, maxSizeBytes)
+	}
+
+	var received int64
+	buff := make([]byte, 64*1024)
+	for {
+		n, err := resp.Body.Read(buff)
+		if n > 0 {
+			received += int64(n)
+			if maxSizeBytes > 0 && received > maxSizeBytes {
+				return fmt.Errorf("response size exceeds acceptable limit (%d bytes)", maxSizeBytes)
+			}
+			if cbErr := onChunk(buff[:n], received, contentLength); cbErr != nil {
+				return fmt.Errorf("download aborted: %w", cbErr)
+			}
+		}
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("failed to read API response body: %w", err)
+		}
+	}
+}
+
+// AccountNew - register new account (trial)
+// Possible API errors: 'AccountAlreadyExists' (duplicate account), 'AccountPaymentRequired' (no trial available)
+func (a *API) AccountNew(productName string) (
+	*types.AccountNewResponse,
+	*types.APIErrorResponse,
+	string, // RAW response
+	error) {
+
+	var successResp types.AccountNewResponse
+	var apiErr types.APIErrorResponse
+
+	rawResponse := ""
+
+	request := &types.AccountNewRequest{Product: productName}
+
+	data, err := a.requestRawEx("", _accountNewPath, "POST", "application/json", request, 0, true)
+	if err != nil {
+		return nil, nil, rawResponse, err
+	}
+
+	rawResponse = string(data)
+
+	// Check is it API error
+	if err := json.Unmarshal(data, &apiErr); err != nil {
+		return nil, nil, rawResponse, fmt.Errorf("failed to deserialize API response: %w", err)
+	}
+
+	// success
+	if apiErr.Status == types.CodeSuccess {
+		if err := json.Unmarshal(data, &successResp); err != nil {
+			return nil, nil, rawResponse, fmt.Errorf("failed to deserialize API response: %w", err)
+		}
+		return &successResp, &apiErr, rawResponse, nil
+	}
+
+	return nil, &apiErr, rawResponse, types.CreateAPIError(apiErr.Status, apiErr.Message)
+}
+
 // SessionNew - try to register new session
 func (a *API) SessionNew(accountID string, wgPublicKey string, forceLogin bool, captchaID string, captcha string, confirmation2FA string) (
 	*types.SessionNewResponse,
@@ -182,7 +468,7 @@ func (a *API) SessionNew(accountID string, wgPublicKey string, forceLogin bool,
 		Captcha:         captcha,
 		Confirmation2FA: confirmation2FA}
 
-	data, err := a.requestRaw("", _sessionNewPath, "POST", "application/json", request, 0)
+	data, err := a.requestRawEx("", _sessionNewPath, "POST", "application/json", request, 0, true)
 	if err != nil {
 		return nil, nil, nil, rawResponse, err
 	}
@@ -210,6 +496,14 @@ func (a *API) SessionNew(accountID string, wgPublicKey string, forceLogin bool,
 		return nil, &errorLimitResp, &apiErr, rawResponse, types.CreateAPIError(apiErr.Status, apiErr.Message)
 	}
 
+	// captcha confirmation required (the response contains ID and image of the captcha to display)
+	if apiErr.Status == types.CodeCaptchaRequired || apiErr.Status == types.CodeCaptchaInvalid {
+		if err := json.Unmarshal(data, &successResp); err != nil {
+			return nil, nil, &apiErr, rawResponse, fmt.Errorf("failed to deserialize API response: %w", err)
+		}
+		return &successResp, nil, &apiErr, rawResponse, types.CreateAPIError(apiErr.Status, apiErr.Message)
+	}
+
 	return nil, nil, &apiErr, rawResponse, types.CreateAPIError(apiErr.Status, apiErr.Message)
 }
 
@@ -224,7 +518,7 @@ func (a *API) SessionStatus(session string) (
 
 	request := &types.SessionStatusRequest{Session: session}
 
-	data, err := a.requestRaw("", _sessionStatusPath, "POST", "application/json", request, 0)
+	data, err := a.requestRawEx("", _sessionStatusPath, "POST", "application/json", request, 0, true)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -283,6 +577,39 @@ func (a *API) WireGuardKeySet(session string, newPublicWgKey string, activePubli
 	return localIP, nil
 }
 
+// PortForwardAdd - allocate forwarded port on currently connected server
+// (same request prolongs expiration time of already allocated port)
+func (a *API) PortForwardAdd(session string) (*types.PortForwardResponse, error) {
+	request := &types.PortForwardAddRequest{Session: session}
+	resp := &types.PortForwardResponse{}
+
+	if err := a.request("", _portForwardAddPath, "POST", "application/json", request, resp); err != nil {
+		return nil, err
+	}
+
+	if resp.Status != types.CodeSuccess {
+		return nil, types.CreateAPIError(resp.Status, resp.Message)
+	}
+
+	return resp, nil
+}
+
+// PortForwardDelete - release forwarded port
+func (a *API) PortForwardDelete(session string, port int) error {
+	request := &types.PortForwardDeleteRequest{Session: session, Port: port}
+	resp := &types.APIErrorResponse{}
+
+	if err := a.request("", _portForwardDeletePath, "POST", "application/json", request, resp); err != nil {
+		return err
+	}
+
+	if resp.Status != types.CodeSuccess {
+		return types.CreateAPIError(resp.Status, resp.Message)
+	}
+
+	return nil
+}
+
 // GeoLookup get geolocation
 func (a *API) GeoLookup(timeoutMs int) (location *types.GeoLookupResponse, err error) {
 	resp := &types.GeoLookupResponse{}
@@ -293,3 +620,37 @@ func (a *API) GeoLookup(timeoutMs int) (location *types.GeoLookupResponse, err e
 
 	return resp, nil
 }
+
+// GeoLookupBound get geolocation, binding socket to specific local IP address
+// (used to force the request through a specific network interface: e.g. physical interface when VPN connected)
+func (a *API) GeoLookupBound(timeoutMs int, bindLocalIP net.IP) (location *types.GeoLookupResponse, err error) {
+	apiHost := a.getAPIHost()
+	transCfg := &http.Transport{
+		// using certificate key pinning
+		DialTLS: a.makeDialerEx(a.getAPIPinnedKeys(), false, apiHost, bindLocalIP),
+	}
+
+	timeout := _defaultRequestTimeout
+	if timeoutMs > 0 {
+		timeout = time.Millisecond * time.Duration(timeoutMs)
+	}
+	client := &http.Client{Transport: transCfg, Timeout: timeout}
+
+	httpResp, err := client.Get(getURL(apiHost, _geoLookupPath))
+	if err != nil {
+		return nil, fmt.Errorf("API request failed: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	body, err := ioutil.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API HTTP response body: %w", err)
+	}
+
+	resp := &types.GeoLookupResponse{}
+	if err := json.Unmarshal(body, resp); err != nil {
+		return nil, fmt.Errorf("failed to deserialize API response: %w", err)
+	}
+
+	return resp, nil
+}
@@ -23,19 +23,24 @@
 package api
 
 import (
+	"bufio"
 	"bytes"
 	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
 	"path"
+	"strings"
 	"time"
+
+	"golang.org/x/net/proxy"
 )
 
 func (a *API) getAlternateIPs() (lastGoodIP net.IP, ipList []net.IP) {
@@ -84,7 +89,100 @@ func findPinnedKey(certHashes []string, certBase64hash256 string) bool {
 
 type dialer func(network, addr string) (net.Conn, error)
 
-func makeDialer(certHashes []string, skipCAVerification bool, serverName string) dialer {
+// dialNoTLS establishes TCP connection (through the user-defined proxy, when configured)
+func (a *API) dialNoTLS(network, addr string) (net.Conn, error) {
+	return a.dialNoTLSEx(network, addr, nil)
+}
+
+// dialNoTLSEx establishes TCP connection
+// localIP (when not nil) - bind socket to specific local IP address
+// (used to force traffic through a specific network interface; ignored when proxy configured)
+func (a *API) dialNoTLSEx(network, addr string, localIP net.IP) (net.Conn, error) {
+	proxyType, proxyAddr, username, password := a.getProxySettings()
+
+	if localIP == nil {
+		// when no explicit local IP requested - use the common binding configuration (if defined)
+		// (see 'SetBindLocalIP()')
+		localIP = a.getBindLocalIP()
+	}
+
+	if localIP != nil && proxyType == "" {
+		d := net.Dialer{LocalAddr: &net.TCPAddr{IP: localIP}}
+		return d.Dial(network, addr)
+	}
+
+	switch proxyType {
+	case "":
+		// For API\update hosts - try to resolve hostname by DoH bootstrap resolver first
+		// (system DNS can be poisoned or blocked; wrong IP will be detected by certificate key pinning anyway)
+		if host, port, err := net.SplitHostPort(addr); err == nil && (host == _apiHost || host == _updateHost) {
+			if ips, err := resolveDoH(host); err == nil {
+				for _, ip := range ips {
+					if c, err := net.Dial(network, net.JoinHostPort(ip.String(), port)); err == nil {
+						return c, nil
+					}
+				}
+				log.Warning("Failed to connect to " + host + " by DoH-resolved IPs")
+			} else {
+				log.Warning("DoH resolution failed: ", err)
+			}
+		}
+
+		// fallback to system DNS resolution
+		// (when it fails too - the caller will retry by hardcoded alternate IPs)
+		return net.Dial(network, addr)
+
+	case "socks5":
+		var auth *proxy.Auth
+		if len(username) > 0 {
+			auth = &proxy.Auth{User: username, Password: password}
+		}
+		socksDialer, err := proxy.SOCKS5("tcp", proxyAddr, auth, proxy.Direct)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize SOCKS5 proxy dialer: %w", err)
+		}
+		return socksDialer.Dial(network, addr)
+
+	case "http":
+		conn, err := net.Dial("tcp", proxyAddr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to HTTP proxy: %w", err)
+		}
+
+		connectReq := fmt.Sprintf("CONNECT %s HTTP/1.1\r\nHost: %s\r\n", addr, addr)
+		if len(username) > 0 {
+			credentials := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+			connectReq += "Proxy-Authorization: Basic " + credentials + "\r\n"
+		}
+		connectReq += "\r\n"
+
+		if _, err := conn.Write([]byte(connectReq)); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to send CONNECT request to HTTP proxy: %w", err)
+		}
+
+		resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to read HTTP proxy response: %w", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			conn.Close()
+			return nil, fmt.Errorf("HTTP proxy refused connection: %s", resp.Status)
+		}
+
+		return conn, nil
+	}
+
+	return nil, fmt.Errorf("unsupported proxy type: '%s'", proxyType)
+}
+
+func (a *API) makeDialer(certHashes []string, skipCAVerification bool, serverName string) dialer {
+	return a.makeDialerEx(certHashes, skipCAVerification, serverName, nil)
+}
+
+func (a *API) makeDialerEx(certHashes []string, skipCAVerification bool, serverName string, localIP net.IP) dialer {
 	return func(network, addr string) (net.Conn, error) {
 		defer func() {
 			if r := recover(); r != nil {
@@ -98,11 +196,19 @@ func makeDialer(certHashes []string, skipCAVerification bool, serverName string)
 		tlsConfig := &tls.Config{
 			InsecureSkipVerify: skipCAVerification,
 			ServerName:         serverName, // only have sense when skipCAVerification == false
+			// offer HTTP/2 in ALPN (the transport upgrades to HTTP/2 when the server agrees)
+			NextProtos: []string{"h2", "http/1.1"},
 		}
 
-		c, err := tls.Dial(network, addr, tlsConfig)
+		rawConn, err := a.dialNoTLSEx(network, addr, localIP)
 		if err != nil {
-			return c, err
+			return nil, err
+		}
+
+		c := tls.Client(rawConn, tlsConfig)
+		if err := c.Handshake(); err != nil {
+			rawConn.Close()
+			return nil, err
 		}
 		connstate := c.ConnectionState()
 		var lastErr error = nil
@@ -126,13 +232,111 @@ func makeDialer(certHashes []string, skipCAVerification bool, serverName string)
 
 		}
 		if lastErr != nil {
-			return nil, fmt.Errorf("Certificate check error: pinned certificate key not found: %w", lastErr)
+			return nil, &ErrorTLSPinning{Err: fmt.Errorf("Certificate check error: pinned certificate key not found: %w", lastErr)}
+		}
+		return nil, &ErrorTLSPinning{Err: fmt.Errorf("Certificate check error: pinned certificate key not found")}
+	}
+}
+
+// connection pooling parameters of the persistent transports
+const (
+	_transportMaxIdleConns        = 8
+	_transportMaxIdleConnsPerHost = 4
+	_transportIdleConnTimeout     = time.Second * 90
+)
+
+// getAPITransport returns persistent transport for the API host requests (initializes it on first use)
+// Keeping one transport across requests allows to reuse already established TLS connections
+// (connection pooling + HTTP/2 multiplexing: no new TCP\TLS handshakes for each request)
+func (a *API) getAPITransport() http.RoundTripper {
+	apiHost := a.getAPIHost()
+	apiPinnedKeys := a.getAPIPinnedKeys()
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.customRoundTripper != nil {
+		return a.customRoundTripper
+	}
+	if a.apiTransport != nil {
+		return a.apiTransport
+	}
+
+	if len(apiPinnedKeys) > 0 {
+		a.apiTransport = &http.Transport{
+			// using certificate key pinning
+			DialTLS:           a.makeDialer(apiPinnedKeys, false, apiHost),
+			ForceAttemptHTTP2: true,
+		}
+	} else {
+		log.Warning("No pinned certificates for ", apiHost)
+		a.apiTransport = &http.Transport{
+			// NOTE: TLSClientConfig not in use in case of DialTLS defined
+			TLSClientConfig: &tls.Config{
+				ServerName: apiHost,
+			},
+			// TCP connection through the user-defined proxy (when configured)
+			Dial:              a.dialNoTLS,
+			ForceAttemptHTTP2: true,
 		}
-		return nil, fmt.Errorf("Certificate check error: pinned certificate key not found")
+	}
+
+	a.apiTransport.MaxIdleConns = _transportMaxIdleConns
+	a.apiTransport.MaxIdleConnsPerHost = _transportMaxIdleConnsPerHost
+	a.apiTransport.IdleConnTimeout = _transportIdleConnTimeout
+
+	return a.apiTransport
+}
+
+// getUpdateTransport returns persistent transport for the update host requests (initializes it on first use)
+func (a *API) getUpdateTransport() http.RoundTripper {
+	updateHost := a.getUpdateHost()
+	updatePinnedKeys := a.getUpdatePinnedKeys()
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.customRoundTripper != nil {
+		return a.customRoundTripper
+	}
+	if a.updateTransport != nil {
+		return a.updateTransport
+	}
+
+	a.updateTransport = &http.Transport{
+		// using certificate key pinning
+		DialTLS:             a.makeDialer(updatePinnedKeys, false, updateHost),
+		ForceAttemptHTTP2:   true,
+		MaxIdleConns:        _transportMaxIdleConns,
+		MaxIdleConnsPerHost: _transportMaxIdleConnsPerHost,
+		IdleConnTimeout:     _transportIdleConnTimeout,
+	}
+
+	return a.updateTransport
+}
+
+// resetTransports closes pooled connections and erases the persistent transports
+// (transports will be re-created with actual configuration on next request)
+// Expects 'a.mutex' to be locked by the caller
+func (a *API) resetTransports() {
+	if a.apiTransport != nil {
+		a.apiTransport.CloseIdleConnections()
+		a.apiTransport = nil
+	}
+	if a.updateTransport != nil {
+		a.updateTransport.CloseIdleConnections()
+		a.updateTransport = nil
 	}
 }
 
 func (a *API) doRequest(host string, urlPath string, method string, contentType string, request interface{}, timeoutMs int) (resp *http.Response, err error) {
+	started := time.Now()
+	defer func() {
+		if err == nil {
+			a.saveLastRequestDuration(time.Since(started))
+		}
+	}()
+
 	if len(host) == 0 || host == _apiHost {
 		return a.doRequestAPIHost(urlPath, method, contentType, request, timeoutMs)
 	} else if host == _updateHost {
@@ -142,17 +346,14 @@ func (a *API) doRequest(host string, urlPath string, method string, contentType
 }
 
 func (a *API) doRequestUpdateHost(urlPath string, method string, contentType string, request interface{}, timeoutMs int) (resp *http.Response, err error) {
-	transCfg := &http.Transport{
-		// using certificate key pinning
-		DialTLS: makeDialer(UpdateIvpnHashes, false, _updateHost),
-	}
+	updateHost := a.getUpdateHost()
 
-	// configure http-client with preconfigured TLS transport
+	// configure http-client with the persistent TLS transport (pooled connections are reused)
 	timeout := _defaultRequestTimeout
 	if timeoutMs > 0 {
 		timeout = time.Millisecond * time.Duration(timeoutMs)
 	}
-	client := &http.Client{Transport: transCfg, Timeout: timeout}
+	client := &http.Client{Transport: a.getUpdateTransport(), Timeout: timeout}
 
 	data := []byte{}
 	if request != nil {
@@ -165,14 +366,14 @@ func (a *API) doRequestUpdateHost(urlPath string, method string, contentType str
 	bodyBuffer := bytes.NewBuffer(data)
 
 	// try to access API server by host DNS
-	req, err := newRequest(getURL(_updateHost, urlPath), method, contentType, bodyBuffer)
+	req, err := newRequest(getURL(updateHost, urlPath), method, contentType, bodyBuffer)
 	if err != nil {
 		return nil, err
 	}
 
 	resp, e := client.Do(req)
 	if e != nil {
-		log.Warning("Failed to access " + _updateHost)
+		log.Warning("Failed to access " + updateHost)
 		return resp, fmt.Errorf("Unable to access IVPN repo server: %w", e)
 	}
 
@@ -184,32 +385,15 @@ func (a *API) doRequestAPIHost(urlPath string, method string, contentType string
 
 	// When trying to access API server by alternate IPs (not by DNS name)
 	// we need to configure TLS to use api.ivpn.net hostname
-	// (to avoid certificate errors)
-	transCfg := &http.Transport{
-		// NOTE: TLSClientConfig not in use in case of DialTLS defined
-		//TLSClientConfig: &tls.Config{
-		//	ServerName: _apiHost,
-		//},
-
-		// using certificate key pinning
-		DialTLS: makeDialer(APIIvpnHashes, false, _apiHost),
-	}
-	if len(APIIvpnHashes) == 0 {
-		log.Warning("No pinned certificates for ", _apiHost)
-		transCfg = &http.Transport{
-			// NOTE: TLSClientConfig not in use in case of DialTLS defined
-			TLSClientConfig: &tls.Config{
-				ServerName: _apiHost,
-			},
-		}
-	}
+	// (to avoid certificate errors; the transport dialer is already configured this way)
+	apiHost := a.getAPIHost()
 
-	// configure http-client with preconfigured TLS transport
+	// configure http-client with the persistent TLS transport (pooled connections are reused)
 	timeout := _defaultRequestTimeout
 	if timeoutMs > 0 {
 		timeout = time.Millisecond * time.Duration(timeoutMs)
 	}
-	client := &http.Client{Transport: transCfg, Timeout: timeout}
+	client := &http.Client{Transport: a.getAPITransport(), Timeout: timeout}
 
 	data := []byte{}
 	if request != nil {
@@ -235,7 +419,7 @@ func (a *API) doRequestAPIHost(urlPath string, method string, contentType string
 	}
 
 	// try to access API server by host DNS
-	req, err := newRequest(getURL(_apiHost, urlPath), method, contentType, bodyBuffer)
+	req, err := newRequest(getURL(apiHost, urlPath), method, contentType, bodyBuffer)
 	if err != nil {
 		return nil, err
 	}
@@ -245,7 +429,7 @@ func (a *API) doRequestAPIHost(urlPath string, method string, contentType string
 		a.saveLastGoodAlternateIP(nil)
 		return firstResp, firstErr
 	}
-	log.Warning("Failed to access " + _apiHost)
+	log.Warning("Failed to access " + apiHost)
 
 	// try to access API server by alternate IP
 	for i, ip := range ips {
@@ -272,15 +456,46 @@ func (a *API) doRequestAPIHost(urlPath string, method string, contentType string
 	return firstResp, fmt.Errorf("Unable to access IVPN API server: %w", firstErr)
 }
 
+// _maxResponseSize - maximum acceptable size of API response body
+// (defensive limit; large payloads have to be downloaded by 'DoRequestByAliasStream()')
+const _maxResponseSize = int64(1024 * 1024 * 32) // 32 MB
+
 func (a *API) requestRaw(host string, urlPath string, method string, contentType string, requestObject interface{}, timeoutMs int) (responseData []byte, err error) {
-	resp, err := a.doRequest(host, urlPath, method, contentType, requestObject, timeoutMs)
+	return a.requestRawEx(host, urlPath, method, contentType, requestObject, timeoutMs, false)
+}
+
+// requestRawEx performs API request and returns raw response body
+// isRequireJSONResponse - when true, response with a non-JSON content type is rejected
+func (a *API) requestRawEx(host string, urlPath string, method string, contentType string, requestObject interface{}, timeoutMs int, isRequireJSONResponse bool) (responseData []byte, err error) {
+	resp, err := a.doRequestWithRetries(host, urlPath, method, contentType, requestObject, timeoutMs)
 	if err != nil {
+		// TLS-pinning failures keep their type; everything else from the transport is a network failure
+		var pinningErr *ErrorTLSPinning
+		if errors.As(err, &pinningErr) == false {
+			err = &ErrorNetwork{Err: err}
+		}
 		return nil, fmt.Errorf("API request failed: %w", err)
 	}
+	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
+	// the retry layer already re-tried transient server errors; what is left is a hard failure
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return nil, fmt.Errorf("API request failed: %w", &ErrorHTTPStatus{StatusCode: resp.StatusCode, Status: resp.Status})
+	}
+
+	if isRequireJSONResponse {
+		respContentType := resp.Header.Get("Content-Type")
+		if len(respContentType) > 0 && strings.Contains(strings.ToLower(respContentType), "json") == false {
+			return nil, &ErrorDeserialize{Err: fmt.Errorf("unexpected response content type '%s' (JSON expected)", respContentType)}
+		}
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, _maxResponseSize+1))
 	if err != nil {
-		return nil, fmt.Errorf("failed to get API HTTP response body: %w", err)
+		return nil, &ErrorNetwork{Err: fmt.Errorf("failed to get API HTTP response body: %w", err)}
+	}
+	if int64(len(body)) > _maxResponseSize {
+		return nil, fmt.Errorf("API response size exceeds acceptable limit (%d bytes)", _maxResponseSize)
 	}
 
 	return body, nil
@@ -291,13 +506,13 @@ func (a *API) request(host string, urlPath string, method string, contentType st
 }
 
 func (a *API) requestEx(host string, urlPath string, method string, contentType string, requestObject interface{}, responseObject interface{}, timeoutMs int) error {
-	body, err := a.requestRaw(host, urlPath, method, contentType, requestObject, timeoutMs)
+	body, err := a.requestRawEx(host, urlPath, method, contentType, requestObject, timeoutMs, true)
 	if err != nil {
 		return err
 	}
 
 	if err := json.Unmarshal(body, responseObject); err != nil {
-		return fmt.Errorf("failed to deserialize API response: %w", err)
+		return &ErrorDeserialize{Err: err}
 	}
 
 	return nil
@@ -0,0 +1,102 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package api_test
+
+import (
+	"testing"
+
+	"github.com/ivpn/desktop-app-daemon/api"
+	"github.com/ivpn/desktop-app-daemon/api/apitest"
+)
+
+// offline integration tests of the API flows
+// (responses are served by the mock\replay harness; no network access)
+
+func newOfflineAPI(t *testing.T, harness *apitest.Harness) *api.API {
+	a, err := api.CreateAPI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.SetCustomRoundTripper(harness)
+	return a
+}
+
+func TestDownloadServersListOffline(t *testing.T) {
+	harness := apitest.NewHarness()
+	harness.Stub("GET", "/v4/servers.json", 200, "application/json",
+		`{"wireguard":[{"gateway":"test.wg.ivpn.net","country_code":"XX","country":"Test","city":"Test"}],
+		"openvpn":[],
+		"config":{"api":{"ips":["198.51.100.1"]}}}`)
+
+	a := newOfflineAPI(t, harness)
+
+	servers, err := a.DownloadServersList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(servers.WireguardServers) != 1 || servers.WireguardServers[0].Gateway != "test.wg.ivpn.net" {
+		t.Fatalf("unexpected servers list: %v", servers.WireguardServers)
+	}
+	if a.IsAlternateIPsInitialized() == false {
+		t.Fatal("alternate API IPs not initialized from servers configuration")
+	}
+}
+
+func TestSessionNewOffline(t *testing.T) {
+	harness := apitest.NewHarness()
+	harness.Stub("POST", "/v4/session/new", 200, "application/json",
+		`{"status":200,"token":"test-session-token","vpn_username":"vpnuser","vpn_password":"vpnpass",
+		"service_status":{"is_active":true}}`)
+
+	a := newOfflineAPI(t, harness)
+
+	successResp, _, apiErr, _, err := a.SessionNew("i-XXXX-XXXX-XXXX", "", false, "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if apiErr == nil || apiErr.Status != 200 {
+		t.Fatalf("unexpected API status: %v", apiErr)
+	}
+	if successResp == nil || successResp.Token != "test-session-token" {
+		t.Fatalf("unexpected session response: %v", successResp)
+	}
+	if successResp.ServiceStatus.Active == false {
+		t.Fatal("expected active service status")
+	}
+}
+
+func TestWireGuardKeySetOffline(t *testing.T) {
+	harness := apitest.NewHarness()
+	harness.Stub("POST", "/v4/session/wg/set", 200, "application/json",
+		`{"status":200,"ip_address":"172.16.0.2"}`)
+
+	a := newOfflineAPI(t, harness)
+
+	localIP, err := a.WireGuardKeySet("test-session-token", "wg-public-key", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if localIP.String() != "172.16.0.2" {
+		t.Fatalf("unexpected local IP: %v", localIP)
+	}
+}
@@ -0,0 +1,198 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+// Package apitest implements a mock\replay HTTP transport for offline API testing.
+//
+// The harness can be injected into an API object by 'api.SetCustomRoundTripper()':
+// in replay mode (default) requests are answered by stubbed\recorded responses without network access;
+// in record mode requests are forwarded to a real transport and responses are persisted for later replay.
+package apitest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// CannedResponse - a single stubbed or recorded API response
+type CannedResponse struct {
+	Method      string `json:"method"`
+	URLPath     string `json:"url_path"`
+	StatusCode  int    `json:"status_code"`
+	ContentType string `json:"content_type"`
+	Body        string `json:"body"`
+}
+
+// Harness - mock\replay HTTP transport (implements http.RoundTripper)
+type Harness struct {
+	mutex     sync.Mutex
+	responses map[string]*CannedResponse
+
+	// record mode (not active when 'realTransport' is nil)
+	recordDir     string
+	realTransport http.RoundTripper
+}
+
+// NewHarness creates new harness in replay mode (no responses registered)
+func NewHarness() *Harness {
+	return &Harness{responses: map[string]*CannedResponse{}}
+}
+
+func requestKey(method string, urlPath string) string {
+	if len(method) == 0 {
+		method = "GET"
+	}
+	return strings.ToUpper(method) + " " + urlPath
+}
+
+// Stub registers in-memory response for a request (overwrites previously registered response)
+func (h *Harness) Stub(method string, urlPath string, statusCode int, contentType string, body string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.responses[requestKey(method, urlPath)] = &CannedResponse{
+		Method:      strings.ToUpper(method),
+		URLPath:     urlPath,
+		StatusCode:  statusCode,
+		ContentType: contentType,
+		Body:        body}
+}
+
+// LoadRecordings registers all responses previously recorded into a directory
+func (h *Harness) LoadRecordings(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return fmt.Errorf("failed to read recordings directory: %w", err)
+	}
+
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read recording '%s': %w", file, err)
+		}
+
+		canned := &CannedResponse{}
+		if err := json.Unmarshal(data, canned); err != nil {
+			return fmt.Errorf("failed to parse recording '%s': %w", file, err)
+		}
+
+		h.mutex.Lock()
+		h.responses[requestKey(canned.Method, canned.URLPath)] = canned
+		h.mutex.Unlock()
+	}
+
+	return nil
+}
+
+// StartRecording enables record mode: requests without a registered response
+// are forwarded to 'realTransport' and their responses are saved into 'dir'
+func (h *Harness) StartRecording(dir string, realTransport http.RoundTripper) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.recordDir = dir
+	h.realTransport = realTransport
+}
+
+// RoundTrip implements http.RoundTripper
+func (h *Harness) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := requestKey(req.Method, req.URL.Path)
+
+	h.mutex.Lock()
+	canned, isFound := h.responses[key]
+	recordDir, realTransport := h.recordDir, h.realTransport
+	h.mutex.Unlock()
+
+	if isFound {
+		return makeResponse(req, canned), nil
+	}
+
+	if realTransport == nil {
+		return nil, fmt.Errorf("no recorded response for '%s' (replay mode)", key)
+	}
+
+	// record mode: forward request to the real transport and persist the response
+	resp, err := realTransport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body for recording: %w", err)
+	}
+
+	canned = &CannedResponse{
+		Method:      strings.ToUpper(req.Method),
+		URLPath:     req.URL.Path,
+		StatusCode:  resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Body:        string(body)}
+
+	if err := saveRecording(recordDir, canned); err != nil {
+		return nil, err
+	}
+
+	h.mutex.Lock()
+	h.responses[key] = canned
+	h.mutex.Unlock()
+
+	return makeResponse(req, canned), nil
+}
+
+func makeResponse(req *http.Request, canned *CannedResponse) *http.Response {
+	header := http.Header{}
+	if len(canned.ContentType) > 0 {
+		header.Set("Content-Type", canned.ContentType)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", canned.StatusCode, http.StatusText(canned.StatusCode)),
+		StatusCode:    canned.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(strings.NewReader(canned.Body)),
+		ContentLength: int64(len(canned.Body)),
+		Request:       req}
+}
+
+func saveRecording(dir string, canned *CannedResponse) error {
+	data, err := json.MarshalIndent(canned, "", "\t")
+	if err != nil {
+		return fmt.Errorf("failed to serialize recording: %w", err)
+	}
+
+	// e.g. 'POST /v4/session/new' -> 'POST_v4_session_new.json'
+	fname := strings.ReplaceAll(strings.Trim(canned.Method+"_"+canned.URLPath, "/"), "/", "_") + ".json"
+	if err := ioutil.WriteFile(filepath.Join(dir, fname), data, 0600); err != nil {
+		return fmt.Errorf("failed to save recording: %w", err)
+	}
+
+	return nil
+}
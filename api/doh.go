@@ -0,0 +1,139 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DNS-over-HTTPS bootstrap resolver
+// It is used ONLY to resolve API\update hosts when system DNS is poisoned or blocked.
+// DoH endpoints are defined by IP addresses: no DNS resolution required to access them
+// (TLS certificates of the endpoints are valid for their IP addresses)
+
+const (
+	_dohRequestTimeout = time.Second * 5
+	// do not perform DoH request for a host more often than this interval (results are cached)
+	_dohCacheTTL = time.Minute * 10
+)
+
+// DoH endpoints which support JSON API (RFC 8427 - style responses)
+var _dohEndpoints = []string{
+	"https://1.1.1.1/dns-query",
+	"https://8.8.8.8/resolve",
+}
+
+type dohCacheEntry struct {
+	ips     []net.IP
+	expires time.Time
+}
+
+var (
+	_dohCacheMutex sync.Mutex
+	_dohCache      = map[string]dohCacheEntry{}
+)
+
+type dohAnswer struct {
+	Type int    `json:"type"`
+	Data string `json:"data"`
+}
+
+type dohResponse struct {
+	Status int         `json:"Status"`
+	Answer []dohAnswer `json:"Answer"`
+}
+
+// resolveDoH resolves IPv4 addresses of a hostname by DNS-over-HTTPS
+func resolveDoH(hostname string) ([]net.IP, error) {
+	// check cached result
+	_dohCacheMutex.Lock()
+	if cached, ok := _dohCache[hostname]; ok && time.Now().Before(cached.expires) {
+		_dohCacheMutex.Unlock()
+		return cached.ips, nil
+	}
+	_dohCacheMutex.Unlock()
+
+	client := &http.Client{Timeout: _dohRequestTimeout}
+
+	var lastErr error
+	for _, endpoint := range _dohEndpoints {
+		req, err := http.NewRequest("GET", fmt.Sprintf("%s?name=%s&type=A", endpoint, hostname), nil)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		req.Header.Set("Accept", "application/dns-json")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		var dohResp dohResponse
+		if err := json.Unmarshal(body, &dohResp); err != nil {
+			lastErr = fmt.Errorf("failed to deserialize DoH response: %w", err)
+			continue
+		}
+		if dohResp.Status != 0 {
+			lastErr = fmt.Errorf("DoH request failed (status=%d)", dohResp.Status)
+			continue
+		}
+
+		var ips []net.IP
+		for _, answer := range dohResp.Answer {
+			if answer.Type != 1 { // A record
+				continue
+			}
+			if ip := net.ParseIP(answer.Data); ip != nil {
+				ips = append(ips, ip)
+			}
+		}
+		if len(ips) == 0 {
+			lastErr = fmt.Errorf("DoH response contains no A records")
+			continue
+		}
+
+		// save result to cache
+		_dohCacheMutex.Lock()
+		_dohCache[hostname] = dohCacheEntry{ips: ips, expires: time.Now().Add(_dohCacheTTL)}
+		_dohCacheMutex.Unlock()
+
+		return ips, nil
+	}
+
+	return nil, fmt.Errorf("DoH resolution failed: %w", lastErr)
+}
@@ -0,0 +1,141 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package api
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Certificate pin set hot-update
+// The daemon can fetch a signed pin manifest from the update host and update
+// APIIvpnHashes\UpdateIvpnHashes at runtime (pin rotation without shipping a new daemon build).
+// The manifest signature is verified against an embedded signing key.
+
+const (
+	_pinManifestPath     = "/pins/manifest.json"
+	_pinManifestSignPath = "/pins/manifest.json.sign.sha256.base64"
+)
+
+// PinManifestSigningKeyBase64 - base64-encoded DER (PKIX) RSA public key used to verify pin manifest signature
+// Value is defined at build time (e.g. '-ldflags -X'). When not defined - pin hot-update functionality is disabled
+var PinManifestSigningKeyBase64 = ""
+
+// pinManifest - format of the signed pin manifest
+type pinManifest struct {
+	Version    int      `json:"version"`
+	APIKeys    []string `json:"api_keys"`
+	UpdateKeys []string `json:"update_keys"`
+}
+
+func parsePinManifestSigningKey() (*rsa.PublicKey, error) {
+	if len(PinManifestSigningKeyBase64) == 0 {
+		return nil, fmt.Errorf("pin manifest signing key not configured")
+	}
+
+	der, err := base64.StdEncoding.DecodeString(PinManifestSigningKeyBase64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode pin manifest signing key: %w", err)
+	}
+
+	pub, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse pin manifest signing key: %w", err)
+	}
+
+	rsaPub, ok := pub.(*rsa.PublicKey)
+	if ok == false {
+		return nil, fmt.Errorf("unexpected type of pin manifest signing key")
+	}
+	return rsaPub, nil
+}
+
+// UpdatePinsFromManifest fetches signed pin manifest from the update host,
+// verifies its signature and applies new pin sets
+func (a *API) UpdatePinsFromManifest() error {
+	signingKey, err := parsePinManifestSigningKey()
+	if err != nil {
+		return err
+	}
+
+	manifestData, err := a.requestRaw(_updateHost, _pinManifestPath, "GET", "", nil, 0)
+	if err != nil {
+		return fmt.Errorf("failed to download pin manifest: %w", err)
+	}
+
+	signData, err := a.requestRaw(_updateHost, _pinManifestSignPath, "GET", "", nil, 0)
+	if err != nil {
+		return fmt.Errorf("failed to download pin manifest signature: %w", err)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signData)))
+	if err != nil {
+		return fmt.Errorf("failed to decode pin manifest signature: %w", err)
+	}
+
+	// verify signature
+	hashed := sha256.Sum256(manifestData)
+	if err := rsa.VerifyPKCS1v15(signingKey, crypto.SHA256, hashed[:], signature); err != nil {
+		return fmt.Errorf("pin manifest signature verification failed: %w", err)
+	}
+
+	var manifest pinManifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return fmt.Errorf("failed to deserialize pin manifest: %w", err)
+	}
+
+	if len(manifest.APIKeys) == 0 || len(manifest.UpdateKeys) == 0 {
+		return fmt.Errorf("pin manifest is incomplete")
+	}
+	for _, key := range append(append([]string{}, manifest.APIKeys...), manifest.UpdateKeys...) {
+		if _, err := base64.StdEncoding.DecodeString(key); err != nil {
+			return fmt.Errorf("pin manifest contains invalid key value: %w", err)
+		}
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if manifest.Version <= a.appliedPinManifestVersion {
+		log.Info(fmt.Sprintf("Pin manifest skipped: version %d already applied", a.appliedPinManifestVersion))
+		return nil
+	}
+
+	// atomically replace pin sets
+	// (all readers are accessing pin sets under the same mutex)
+	APIIvpnHashes = manifest.APIKeys
+	UpdateIvpnHashes = manifest.UpdateKeys
+	a.appliedPinManifestVersion = manifest.Version
+
+	// pooled transports captured the old pinned keys at creation time - they must be re-created
+	a.resetTransports()
+
+	log.Info(fmt.Sprintf("Pin sets updated from manifest (version %d)", manifest.Version))
+	return nil
+}
@@ -0,0 +1,67 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package api
+
+import "fmt"
+
+// Typed errors of the API request layer.
+// Upper layers can distinguish the failure reason by 'errors.As()'
+// (e.g. network failures are worth a retry later; TLS-pinning failures are not)
+
+// ErrorNetwork - transport-level request failure (DNS resolution, TCP connection, timeout ...)
+type ErrorNetwork struct {
+	Err error
+}
+
+func (e *ErrorNetwork) Error() string { return "network error: " + e.Err.Error() }
+func (e *ErrorNetwork) Unwrap() error { return e.Err }
+
+// ErrorTLSPinning - TLS certificate key pinning check failure
+// (possible MITM attempt or outdated pinned keys; retrying makes no sense)
+type ErrorTLSPinning struct {
+	Err error
+}
+
+func (e *ErrorTLSPinning) Error() string { return e.Err.Error() }
+func (e *ErrorTLSPinning) Unwrap() error { return e.Err }
+
+// ErrorHTTPStatus - server replied with an unexpected HTTP status
+type ErrorHTTPStatus struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *ErrorHTTPStatus) Error() string {
+	return fmt.Sprintf("unexpected HTTP status of API response: %s", e.Status)
+}
+
+// ErrorDeserialize - response payload cannot be interpreted
+// (unexpected content type or malformed JSON)
+type ErrorDeserialize struct {
+	Err error
+}
+
+func (e *ErrorDeserialize) Error() string {
+	return "failed to deserialize API response: " + e.Err.Error()
+}
+func (e *ErrorDeserialize) Unwrap() error { return e.Err }
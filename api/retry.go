@@ -0,0 +1,140 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package api
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// retry policy for idempotent API requests
+// (exponential backoff with jitter, starting from '_retryDelayInitial')
+const (
+	_retryMaxAttempts  = 3
+	_retryDelayInitial = time.Millisecond * 500
+	_retryDelayMax     = time.Second * 5
+)
+
+// retryBudget limits the total amount of retry attempts (across all requests)
+// to avoid flooding the API when the network is in a bad state for a long time
+// (classic token bucket: each retry consumes a token; tokens are slowly restored over time)
+type retryBudget struct {
+	mutex      sync.Mutex
+	tokens     float64
+	lastRefill time.Time
+}
+
+const (
+	_retryBudgetMaxTokens       float64 = 10
+	_retryBudgetTokensPerSecond float64 = 0.2 // 1 retry per 5 seconds restored
+)
+
+var _retryBudgetInstance = retryBudget{tokens: _retryBudgetMaxTokens, lastRefill: time.Now()}
+
+// takeToken consumes one retry token (returns 'false' when the budget is exhausted)
+func (b *retryBudget) takeToken() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	now := time.Now()
+	b.tokens += now.Sub(b.lastRefill).Seconds() * _retryBudgetTokensPerSecond
+	if b.tokens > _retryBudgetMaxTokens {
+		b.tokens = _retryBudgetMaxTokens
+	}
+	b.lastRefill = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// idempotent API endpoints: safe to re-try on transient failures
+// (POST requests which do not modify any state on the API side)
+var _retriableRequests = map[string]struct{}{
+	_serversPath:       {},
+	_sessionStatusPath: {},
+	_geoLookupPath:     {},
+}
+
+// isRetriableRequest returns 'true' for requests which are safe to re-try
+func isRetriableRequest(method string, urlPath string) bool {
+	if method == "GET" {
+		return true
+	}
+	_, ok := _retriableRequests[urlPath]
+	return ok
+}
+
+// isTransientHTTPStatus returns 'true' for HTTP statuses which are worth a re-try
+func isTransientHTTPStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// doRequestWithRetries performs an API request, re-trying transient failures of idempotent requests
+// (exponential backoff with jitter; the amount of retries is limited by the global retry budget)
+func (a *API) doRequestWithRetries(host string, urlPath string, method string, contentType string, request interface{}, timeoutMs int) (resp *http.Response, err error) {
+	if isRetriableRequest(method, urlPath) == false {
+		return a.doRequest(host, urlPath, method, contentType, request, timeoutMs)
+	}
+
+	delay := _retryDelayInitial
+	for attempt := 1; ; attempt++ {
+		resp, err = a.doRequest(host, urlPath, method, contentType, request, timeoutMs)
+		if err == nil && isTransientHTTPStatus(resp.StatusCode) == false {
+			return resp, nil
+		}
+
+		if attempt >= _retryMaxAttempts {
+			break
+		}
+		if _retryBudgetInstance.takeToken() == false {
+			log.Warning("API retry budget exhausted. Not re-trying.")
+			break
+		}
+
+		if err != nil {
+			log.Warning(fmt.Sprintf("API request '%s' failed (attempt %d of %d): %s", urlPath, attempt, _retryMaxAttempts, err))
+		} else {
+			log.Warning(fmt.Sprintf("API request '%s' failed (attempt %d of %d): HTTP status %d", urlPath, attempt, _retryMaxAttempts, resp.StatusCode))
+			resp.Body.Close()
+		}
+
+		// exponential backoff with jitter: [delay/2 ... delay*1.5)
+		time.Sleep(delay/2 + time.Duration(rand.Int63n(int64(delay))))
+		delay *= 2
+		if delay > _retryDelayMax {
+			delay = _retryDelayMax
+		}
+	}
+
+	return resp, err
+}
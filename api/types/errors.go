@@ -40,8 +40,23 @@ const (
 	// CodeSessionsLimitReached - You've reached the session limit, log out from other device
 	CodeSessionsLimitReached int = 602
 
+	// AccountPaymentRequired - account registration requires payment (no trial available)
+	AccountPaymentRequired int = 402
+
+	// AccountAlreadyExists - account with the requested parameters is already registered
+	AccountAlreadyExists int = 409
+
 	// AccountNotActive - account should be purchased
 	AccountNotActive int = 702
+
+	// CodeCaptchaRequired - captcha confirmation required to continue login
+	CodeCaptchaRequired int = 70001
+	// CodeCaptchaInvalid - captcha validation failed
+	CodeCaptchaInvalid int = 70002
+	// Code2FARequired - two-factor authentication token required to continue login
+	Code2FARequired int = 70011
+	// Code2FAInvalidToken - two-factor authentication token validation failed
+	Code2FAInvalidToken int = 70012
 )
 
 // APIError - error, user not logged in into account
@@ -33,6 +33,11 @@ type SessionNewRequest struct {
 	Confirmation2FA string `json:"confirmation,omitempty"`
 }
 
+// AccountNewRequest request to register new account
+type AccountNewRequest struct {
+	Product string `json:"product_name,omitempty"` // empty - default product (trial account)
+}
+
 // SessionDeleteRequest request to delete session
 type SessionDeleteRequest struct {
 	Session string `json:"session_token"`
@@ -43,6 +48,17 @@ type SessionStatusRequest struct {
 	Session string `json:"session_token"`
 }
 
+// PortForwardAddRequest request to allocate forwarded port (or to prolong expiration of already allocated port)
+type PortForwardAddRequest struct {
+	Session string `json:"session_token"`
+}
+
+// PortForwardDeleteRequest request to release forwarded port
+type PortForwardDeleteRequest struct {
+	Session string `json:"session_token"`
+	Port    int    `json:"port"`
+}
+
 // SessionWireGuardKeySetRequest request to set new WK key for a session
 type SessionWireGuardKeySetRequest struct {
 	Session            string `json:"session_token"`
@@ -68,6 +68,14 @@ type SessionNewResponse struct {
 	} `json:"wireguard"`
 }
 
+// AccountNewResponse information about created account
+type AccountNewResponse struct {
+	APIErrorResponse
+	AccountID string `json:"account_id"`
+
+	ServiceStatus ServiceStatusAPIResp `json:"service_status"`
+}
+
 // SessionNewErrorLimitResponse information about session limit error
 type SessionNewErrorLimitResponse struct {
 	APIErrorResponse
@@ -80,6 +88,13 @@ type SessionsWireGuardResponse struct {
 	IPAddress string `json:"ip_address,omitempty"`
 }
 
+// PortForwardResponse info about allocated forwarded port
+type PortForwardResponse struct {
+	APIErrorResponse
+	Port      int   `json:"port"`
+	ExpiresAt int64 `json:"expires_at"` // unix time (seconds)
+}
+
 // SessionStatusResponse session status response
 type SessionStatusResponse struct {
 	APIErrorResponse
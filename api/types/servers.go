@@ -22,6 +22,8 @@
 
 package types
 
+import "strings"
+
 // WireGuardServerHostInfo contains info about WG server host
 type WireGuardServerHostInfo struct {
 	Hostname  string `json:"hostname"`
@@ -40,6 +42,9 @@ type WireGuardServerInfo struct {
 	Latitude  float32 `json:"latitude"`
 	Longitude float32 `json:"longitude"`
 
+	// Load - current server load in percents [0-100] (0 - when not defined in servers.json)
+	Load float32 `json:"load"`
+
 	Hosts []WireGuardServerHostInfo `json:"hosts"`
 }
 
@@ -53,6 +58,9 @@ type OpenvpnServerInfo struct {
 	Latitude  float32 `json:"latitude"`
 	Longitude float32 `json:"longitude"`
 
+	// Load - current server load in percents [0-100] (0 - when not defined in servers.json)
+	Load float32 `json:"load"`
+
 	IPAddresses []string `json:"ip_addresses"`
 }
 
@@ -73,10 +81,41 @@ type InfoAPI struct {
 	IPAddresses []string `json:"ips"`
 }
 
+// PortRange - range of applicable ports (e.g. UDP 5500-19999)
+type PortRange struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+// PortInfo - port description from servers configuration
+// (either single port or ports range)
+type PortInfo struct {
+	Type  string    `json:"type"` // "TCP" or "UDP"
+	Port  int       `json:"port,omitempty"`
+	Range PortRange `json:"range,omitempty"`
+}
+
+// IsTCP returns true when port applicable for TCP protocol
+func (pi PortInfo) IsTCP() bool {
+	return strings.EqualFold(pi.Type, "TCP")
+}
+
+// IsRange returns true when object defines range of ports (not a single port)
+func (pi PortInfo) IsRange() bool {
+	return pi.Range.Min > 0 && pi.Range.Max >= pi.Range.Min
+}
+
+// PortsInfo - applicable ports of VPN protocols
+type PortsInfo struct {
+	OpenVPN   []PortInfo `json:"openvpn"`
+	WireGuard []PortInfo `json:"wireguard"`
+}
+
 // ConfigInfo contains different configuration info (Antitracker, API ...)
 type ConfigInfo struct {
 	Antitracker AntitrackerInfo `json:"antitracker"`
 	API         InfoAPI         `json:"api"`
+	Ports       PortsInfo       `json:"ports"`
 }
 
 // ServersInfoResponse all info from servers.json
@@ -85,3 +124,30 @@ type ServersInfoResponse struct {
 	OpenvpnServers   []OpenvpnServerInfo   `json:"openvpn"`
 	Config           ConfigInfo            `json:"config"`
 }
+
+// ServerLoadChange contains new load value of a server (is a part of 'ServersListDiff')
+type ServerLoadChange struct {
+	Gateway string  `json:"gateway"`
+	Load    float32 `json:"load"`
+}
+
+// ServersListDiff contains changes of the servers list since the previous update
+// (allows to notify clients only about changed servers instead of pushing the entire list)
+type ServersListDiff struct {
+	AddedWireguardServers []WireGuardServerInfo `json:"wireguard_added"`
+	AddedOpenvpnServers   []OpenvpnServerInfo   `json:"openvpn_added"`
+	// gateways of removed servers
+	RemovedWireguardServers []string `json:"wireguard_removed"`
+	RemovedOpenvpnServers   []string `json:"openvpn_removed"`
+	// new load values of servers which stayed in the list (gateways are unique between protocols)
+	LoadChanges []ServerLoadChange `json:"load_changes"`
+}
+
+// HasChanges returns false when the servers list stayed the same
+func (d ServersListDiff) HasChanges() bool {
+	return len(d.AddedWireguardServers) > 0 ||
+		len(d.AddedOpenvpnServers) > 0 ||
+		len(d.RemovedWireguardServers) > 0 ||
+		len(d.RemovedOpenvpnServers) > 0 ||
+		len(d.LoadChanges) > 0
+}
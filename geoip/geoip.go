@@ -0,0 +1,142 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+// Package geoip implements local lookup of country\region by IP address.
+// The dataset is small (networks of known VPN providers and major hosters; distributed
+// through the update host) and intended only to annotate ping results and custom imported
+// servers without extra geo-lookup API calls - it is not a full GeoIP database.
+package geoip
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"sync"
+
+	"github.com/ivpn/desktop-app-daemon/logger"
+)
+
+var log *logger.Logger
+
+func init() {
+	log = logger.NewLogger("geoip")
+}
+
+// Record - one entry of the GeoIP dataset (JSON)
+type Record struct {
+	// Network - IP range in CIDR notation (e.g. "145.255.0.0/16")
+	Network string
+	// CountryCode - ISO 3166-1 alpha-2 code (e.g. "DE")
+	CountryCode string
+	Country     string
+	Region      string
+}
+
+// Location - result of a lookup
+type Location struct {
+	CountryCode string
+	Country     string
+	Region      string
+}
+
+// parsed entry of the dataset
+type entry struct {
+	network  *net.IPNet
+	location Location
+}
+
+var (
+	mutex   sync.RWMutex
+	entries []entry
+)
+
+// Load parses the GeoIP dataset (JSON list of 'Record') and replaces the active dataset
+func Load(data []byte) error {
+	var records []Record
+	if err := json.Unmarshal(data, &records); err != nil {
+		return fmt.Errorf("failed to parse GeoIP dataset: %w", err)
+	}
+
+	newEntries := make([]entry, 0, len(records))
+	for _, record := range records {
+		_, network, err := net.ParseCIDR(record.Network)
+		if err != nil {
+			log.Warning(fmt.Sprintf("Skipping GeoIP record '%s': %s", record.Network, err))
+			continue
+		}
+		newEntries = append(newEntries, entry{
+			network: network,
+			location: Location{
+				CountryCode: record.CountryCode,
+				Country:     record.Country,
+				Region:      record.Region}})
+	}
+
+	mutex.Lock()
+	entries = newEntries
+	mutex.Unlock()
+
+	log.Info(fmt.Sprintf("GeoIP dataset loaded (%d networks)", len(newEntries)))
+	return nil
+}
+
+// LoadFromFile parses the GeoIP dataset from a file (see 'Load()')
+func LoadFromFile(filePath string) error {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read GeoIP dataset file: %w", err)
+	}
+	return Load(data)
+}
+
+// IsInitialized returns true when a dataset is loaded
+func IsInitialized() bool {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return len(entries) > 0
+}
+
+// Lookup returns location info of an IP address
+// (ok == false - dataset not loaded or address not covered by the dataset)
+func Lookup(ip net.IP) (location Location, ok bool) {
+	if ip == nil {
+		return Location{}, false
+	}
+
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	// dataset is small: linear scan is acceptable
+	for _, e := range entries {
+		if e.network.Contains(ip) {
+			return e.location, true
+		}
+	}
+	return Location{}, false
+}
+
+// LookupHost returns location info of an IP address defined as string
+// (only IP addresses are acceptable: no DNS resolution performed)
+func LookupHost(host string) (location Location, ok bool) {
+	return Lookup(net.ParseIP(host))
+}
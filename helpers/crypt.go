@@ -0,0 +1,123 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package helpers
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"io"
+)
+
+const (
+	// version of the encrypted data format (first byte of the output;
+	// allows to evolve the format: e.g. change KDF parameters)
+	_cryptFormatVersion = byte(1)
+	_saltSize           = 16
+	// PBKDF2 iterations count (hardening against offline passphrase brute-force)
+	_kdfIterations = 600000
+)
+
+// key derivation: PBKDF2-HMAC-SHA256 (RFC 2898) over passphrase+salt
+// (no external dependencies; GCM authentication tag protects data integrity)
+func deriveKey(passphrase string, salt []byte, iterations int) []byte {
+	// single-block PBKDF2: required key size (32 bytes) equals the HMAC-SHA256 output size
+	prf := hmac.New(sha256.New, []byte(passphrase))
+	prf.Write(salt)
+	prf.Write([]byte{0, 0, 0, 1}) // big-endian block index
+	u := prf.Sum(nil)
+
+	key := make([]byte, len(u))
+	copy(key, u)
+	for i := 1; i < iterations; i++ {
+		prf.Reset()
+		prf.Write(u)
+		u = prf.Sum(u[:0])
+		for j := range key {
+			key[j] ^= u[j]
+		}
+	}
+	return key
+}
+
+// EncryptAESGCM encrypts data by a key derived from passphrase (AES-256-GCM)
+// Returned data format: version(1) + salt(16) + nonce + ciphertext
+func EncryptAESGCM(data []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, _saltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	block, err := aes.NewCipher(deriveKey(passphrase, salt, _kdfIterations))
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	ret := append([]byte{_cryptFormatVersion}, salt...)
+	ret = append(ret, nonce...)
+	return gcm.Seal(ret, nonce, data, nil), nil
+}
+
+// DecryptAESGCM decrypts data encrypted by EncryptAESGCM
+// (returns error if data was modified or passphrase is wrong)
+func DecryptAESGCM(data []byte, passphrase string) ([]byte, error) {
+	if len(data) < 1+_saltSize {
+		return nil, fmt.Errorf("corrupted data (too small)")
+	}
+	if data[0] != _cryptFormatVersion {
+		return nil, fmt.Errorf("unsupported encrypted data format version (%d)", data[0])
+	}
+	salt := data[1 : 1+_saltSize]
+
+	block, err := aes.NewCipher(deriveKey(passphrase, salt, _kdfIterations))
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < 1+_saltSize+gcm.NonceSize() {
+		return nil, fmt.Errorf("corrupted data (too small)")
+	}
+	nonce := data[1+_saltSize : 1+_saltSize+gcm.NonceSize()]
+
+	plain, err := gcm.Open(nil, nonce, data[1+_saltSize+gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt data (wrong passphrase or data modified): %w", err)
+	}
+	return plain, nil
+}
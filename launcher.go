@@ -26,14 +26,18 @@ import (
 	"fmt"
 	"math/rand"
 	"os"
+	"os/signal"
 	"runtime"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/ivpn/desktop-app-daemon/api"
 	"github.com/ivpn/desktop-app-daemon/logger"
 	"github.com/ivpn/desktop-app-daemon/netchange"
+	"github.com/ivpn/desktop-app-daemon/power"
+	"github.com/ivpn/desktop-app-daemon/privhelper"
 	"github.com/ivpn/desktop-app-daemon/protocol"
 	"github.com/ivpn/desktop-app-daemon/service"
 	"github.com/ivpn/desktop-app-daemon/service/platform"
@@ -58,6 +62,11 @@ type IProtocol interface {
 
 // Launch -  initialize and start service
 func Launch() {
+	// process was started as the privileged network-configuration helper (privilege separation)
+	if privhelper.IsHelperProcess() {
+		os.Exit(privhelper.RunHelper())
+	}
+
 	defer func() {
 		log.Info("IVPN daemon stopped.")
 
@@ -90,6 +99,18 @@ func Launch() {
 		}
 	}
 
+	// Privilege separation (groundwork): execute privileged network-configuration operations
+	// in a separate minimal helper process (can be enabled from command line: -priv-helper)
+	for _, arg := range os.Args {
+		arg = strings.ToLower(arg)
+		if arg == "-priv-helper" || arg == "--priv-helper" {
+			if err := privhelper.StartHelperProcess(); err != nil {
+				logger.Error(fmt.Errorf("failed to start privileged helper process: %w", err))
+			}
+			break
+		}
+	}
+
 	logger.Info("version:" + version.GetFullVersion())
 
 	if len(warnings) > 0 {
@@ -149,6 +170,16 @@ func Launch() {
 		}
 	}()
 
+	// stop the daemon gracefully on OS signal (system shutdown, 'kill' ...)
+	// (on graceful stop daemon saves its state; see 'IsRestoreStateOnStart' preference)
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-sigChan
+		log.Info("Stopping due to OS signal: ", sig)
+		Stop()
+	}()
+
 	// perform OS-specific preparations (if necessary)
 	if err := doPrepareToRun(); err != nil {
 		logger.Panic(err.Error())
@@ -183,6 +214,9 @@ func launchService(secret uint64, startedOnPort chan<- int) {
 	// network change detector
 	netDetector := netchange.Create()
 
+	// system power events detector (system sleep\resume)
+	powerDetector := power.Create()
+
 	// WireGuard keys manager
 	wgKeysMgr := wgkeys.CreateKeysManager(apiObj, platform.WgToolBinaryPath())
 
@@ -196,7 +230,7 @@ func launchService(secret uint64, startedOnPort chan<- int) {
 	activeProtocol = protocol
 
 	// initialize service
-	serv, err := service.CreateService(protocol, apiObj, updater, netDetector, wgKeysMgr)
+	serv, err := service.CreateService(protocol, apiObj, updater, netDetector, powerDetector, wgKeysMgr)
 	if err != nil {
 		log.Panic("Failed to initialize service:", err)
 	}
@@ -0,0 +1,123 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package logger
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Temporary per-module debug logging.
+// Allows to enable verbose logging for specific subsystems (logger names:
+// e.g. "api", "wg", "frwl", "dns") at runtime without restarting the daemon.
+// When a duration is defined - the previous log level of a module is restored
+// automatically when the duration expires.
+
+// previous log level of a module (to be restored when debug logging disabled)
+type debugSavedLevel struct {
+	level     int
+	isDefined bool // false - module had no dedicated level configured
+}
+
+var (
+	// saved module levels and expiration timers (protected by 'configMutex')
+	debugSavedLevels = map[string]debugSavedLevel{}
+	debugTimers      = map[string]*time.Timer{}
+)
+
+// EnableModulesDebug enables debug logging for the defined modules (logger names).
+// duration > 0: previous log levels are restored automatically when the duration expires
+// (duration == 0: debug logging stays enabled until 'DisableModulesDebug()' call)
+func EnableModulesDebug(modules []string, duration time.Duration) error {
+	if len(modules) == 0 {
+		return fmt.Errorf("modules not defined")
+	}
+
+	configMutex.Lock()
+	defer configMutex.Unlock()
+
+	for _, module := range modules {
+		module = strings.TrimSpace(module)
+		if len(module) == 0 {
+			continue
+		}
+
+		// save current level only once (repeated calls must not overwrite the original level)
+		if _, ok := debugSavedLevels[module]; ok == false {
+			level, isDefined := moduleLogLevels[module]
+			debugSavedLevels[module] = debugSavedLevel{level: level, isDefined: isDefined}
+		}
+		moduleLogLevels[module] = LevelDebug
+
+		// (re)start the expiration timer
+		if timer, ok := debugTimers[module]; ok {
+			timer.Stop()
+			delete(debugTimers, module)
+		}
+		if duration > 0 {
+			m := module
+			debugTimers[m] = time.AfterFunc(duration, func() { restoreModuleLevel(m) })
+		}
+	}
+	return nil
+}
+
+// DisableModulesDebug restores previous log levels of the defined modules
+// (empty list - restore all modules with debug logging currently enabled)
+func DisableModulesDebug(modules []string) {
+	configMutex.Lock()
+	if len(modules) == 0 {
+		for module := range debugSavedLevels {
+			modules = append(modules, module)
+		}
+	}
+	configMutex.Unlock()
+
+	for _, module := range modules {
+		restoreModuleLevel(strings.TrimSpace(module))
+	}
+}
+
+// restoreModuleLevel restores the saved log level of a module
+func restoreModuleLevel(module string) {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+
+	if timer, ok := debugTimers[module]; ok {
+		timer.Stop()
+		delete(debugTimers, module)
+	}
+
+	saved, ok := debugSavedLevels[module]
+	if ok == false {
+		return // debug logging was not enabled for the module
+	}
+	delete(debugSavedLevels, module)
+
+	if saved.isDefined {
+		moduleLogLevels[module] = saved.level
+	} else {
+		delete(moduleLogLevels, module)
+	}
+}
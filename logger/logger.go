@@ -243,40 +243,52 @@ func (l *Logger) Panic(v ...interface{}) {
 func (l *Logger) Enable(enable bool) { l.isDisabled = !enable }
 
 func _info(name string, v ...interface{}) {
+	if isModuleLevelEnabled(name, LevelInfo) == false {
+		return
+	}
 	mes, timeStr, _, _ := getLogPrefixes(fmt.Sprint(v...))
-	write(timeStr, name, mes)
+	writeRecord(timeStr, name, "", "", mes)
 }
 
 func _debug(name string, v ...interface{}) {
+	if isModuleLevelEnabled(name, LevelDebug) == false {
+		return
+	}
 	mes, timeStr, runtimeInfo, _ := getLogPrefixes(fmt.Sprint(v...))
-	write(timeStr, name, "DEBUG", runtimeInfo, mes)
+	writeRecord(timeStr, name, "DEBUG", runtimeInfo, mes)
 }
 
 func _warning(name string, v ...interface{}) {
+	if isModuleLevelEnabled(name, LevelWarning) == false {
+		return
+	}
 	mes, timeStr, runtimeInfo, _ := getLogPrefixes(fmt.Sprint(v...))
-	write(timeStr, name, "WARNING", runtimeInfo, mes)
+	writeRecord(timeStr, name, "WARNING", runtimeInfo, mes)
 }
 
 func _trace(name string, v ...interface{}) {
+	if isModuleLevelEnabled(name, LevelDebug) == false {
+		return
+	}
 	mes, timeStr, runtimeInfo, methodInfo := getLogPrefixes(fmt.Sprint(v...))
-	write(timeStr, name, "TRACE", runtimeInfo+methodInfo, mes)
+	writeRecord(timeStr, name, "TRACE", runtimeInfo+methodInfo, mes)
 }
 
 func _error(name string, v ...interface{}) {
 	mes, timeStr, runtimeInfo, methodInfo := getLogPrefixes(fmt.Sprint(v...))
-	write(timeStr, name, "ERROR", runtimeInfo+methodInfo, mes)
+	writeRecord(timeStr, name, "ERROR", runtimeInfo+methodInfo, mes)
 }
 
 func _errorTrace(name string, err error) {
 	mes, timeStr, runtimeInfo, methodInfo := getLogPrefixes(getErrorDetails(err))
-	write(timeStr, name, "ERROR", runtimeInfo+methodInfo, mes)
+	writeRecord(timeStr, name, "ERROR", runtimeInfo+methodInfo, mes)
 }
 
 func _panic(name string, v ...interface{}) {
 	mes, timeStr, runtimeInfo, methodInfo := getLogPrefixes(fmt.Sprint(v...))
 
 	//fmt.Println(timeStr, "PANIC", runtimeInfo+methodInfo, mes)
-	write(timeStr, name, "PANIC", runtimeInfo+methodInfo, mes)
+	writeRecord(timeStr, name, "PANIC", runtimeInfo+methodInfo, mes)
 
 	panic(runtimeInfo + methodInfo + ": " + mes)
 }
@@ -323,18 +335,25 @@ func write(fields ...interface{}) {
 	defer writeMutex.Unlock()
 
 	if isLoggingEnabled {
+		line := fmt.Sprintln(fields...)
+
 		if isCanPrintToConsole {
 			// printing into console
-			fmt.Println(fields...)
+			fmt.Print(line)
 		}
 
+		// keep record in the in-memory ring buffer (accessible over the protocol)
+		ringBufferAppend(line)
+
 		if globalLogFile == nil {
 			createLogFile()
 		}
 
 		if globalLogFile != nil {
 			// writting into log-file
-			globalLogFile.WriteString(fmt.Sprintln(fields...))
+			globalLogFile.WriteString(line)
+			curLogFileSize += int64(len(line))
+			rotateLogFileIfNeeded()
 		}
 	}
 }
@@ -348,9 +367,14 @@ func deleteLogFile() {
 		globalLogFile = nil
 	}
 
+	ringBufferClear()
+
 	if len(filePath) > 0 {
 		os.Remove(filePath)
-		os.Remove(filePath + ".0")
+		for i := 0; i <= _logMaxBackupsHard; i++ {
+			os.Remove(logBackupFilePath(i))
+			os.Remove(logBackupFilePath(i) + ".gz")
+		}
 	}
 }
 
@@ -362,7 +386,7 @@ func createLogFile() error {
 
 	if len(filePath) > 0 {
 		if _, err := os.Stat(filePath); err == nil {
-			os.Rename(filePath, filePath+".0")
+			shiftLogBackups()
 		}
 
 		var err error
@@ -374,6 +398,7 @@ func createLogFile() error {
 		if err := filerights.WindowsChmod(filePath, 0600); err != nil { // read\write only for privileged user
 			return fmt.Errorf("failed to change log-file permissions: %w", err)
 		}
+		curLogFileSize = 0
 	} else {
 		return fmt.Errorf("logfile name not initialized")
 	}
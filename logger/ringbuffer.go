@@ -0,0 +1,85 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package logger
+
+import (
+	"strings"
+)
+
+// In-memory ring buffer of recent log records.
+// Keeps last log records in memory (size-capped) so clients can request
+// "recent daemon log" over the protocol without access to the log files.
+
+// max size of the ring buffer (sum of lengths of stored records)
+const _ringBufferMaxBytes = 64 * 1024
+
+var (
+	// stored records and their total size (protected by 'writeMutex')
+	ringBufferLines []string
+	ringBufferSize  int
+)
+
+// ringBufferAppend stores a log record in the ring buffer
+// (must be called under 'writeMutex')
+func ringBufferAppend(record string) {
+	record = strings.TrimRight(record, "\n")
+	if len(record) == 0 {
+		return
+	}
+
+	ringBufferLines = append(ringBufferLines, record)
+	ringBufferSize += len(record)
+
+	// erase oldest records when size limit exceeded
+	erased := 0
+	for ringBufferSize > _ringBufferMaxBytes && erased < len(ringBufferLines) {
+		ringBufferSize -= len(ringBufferLines[erased])
+		erased++
+	}
+	if erased > 0 {
+		ringBufferLines = ringBufferLines[erased:]
+	}
+}
+
+// ringBufferClear erases all stored log records
+// (must be called under 'writeMutex')
+func ringBufferClear() {
+	ringBufferLines = nil
+	ringBufferSize = 0
+}
+
+// GetRecentLogRecords returns last log records from the in-memory ring buffer
+// (maxLines<=0 - return all stored records)
+func GetRecentLogRecords(maxLines int) []string {
+	writeMutex.Lock()
+	defer writeMutex.Unlock()
+
+	start := 0
+	if maxLines > 0 && len(ringBufferLines) > maxLines {
+		start = len(ringBufferLines) - maxLines
+	}
+
+	ret := make([]string, len(ringBufferLines)-start)
+	copy(ret, ringBufferLines[start:])
+	return ret
+}
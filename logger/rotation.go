@@ -0,0 +1,166 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/ivpn/desktop-app-daemon/service/platform/filerights"
+)
+
+// Log rotation
+// The active log file is rotated when its size exceeds the configured limit.
+// Rotated files are named '<logfile>.0', '<logfile>.1' ... ('.0' is the most recent one;
+// same naming as the old 'previous session' log, so 'GetLogText()' keeps working).
+// Optionally, rotated files can be compressed ('<logfile>.N.gz').
+
+const (
+	// rotation defaults (one backup file, same behavior as before rotation was implemented)
+	_defaultLogMaxFileSize = int64(1024 * 1024 * 10)
+	_defaultLogMaxBackups  = 1
+	// hard limits of rotation configuration
+	_logMinFileSize    = int64(1024 * 64)
+	_logMaxBackupsHard = 16
+)
+
+var (
+	// rotation configuration (protected by 'writeMutex')
+	logRotateMaxFileSize = _defaultLogMaxFileSize
+	logRotateMaxBackups  = _defaultLogMaxBackups
+	logRotateCompress    bool
+	// size of the active log file (bytes written since it was created)
+	curLogFileSize int64
+)
+
+// SetRotationParams updates log rotation configuration:
+//
+//	maxFileSizeBytes - the active log file is rotated when its size exceeds this value
+//	maxBackups - count of rotated files to keep (0 - old log data is just erased)
+//	isCompress - compress rotated files (gzip)
+func SetRotationParams(maxFileSizeBytes int64, maxBackups int, isCompress bool) error {
+	if maxFileSizeBytes < _logMinFileSize {
+		return fmt.Errorf("acceptable max log file size: %d bytes or more", _logMinFileSize)
+	}
+	if maxBackups < 0 || maxBackups > _logMaxBackupsHard {
+		return fmt.Errorf("acceptable count of log backup files: 0-%d", _logMaxBackupsHard)
+	}
+
+	writeMutex.Lock()
+	defer writeMutex.Unlock()
+
+	logRotateMaxFileSize = maxFileSizeBytes
+	logRotateMaxBackups = maxBackups
+	logRotateCompress = isCompress
+	return nil
+}
+
+// logBackupFilePath returns path of a rotated log file ('.0' - the most recent one)
+func logBackupFilePath(index int) string {
+	return filePath + "." + strconv.Itoa(index)
+}
+
+// shiftLogBackups prepares place for a new '.0' backup:
+// shifts already rotated files by one position and renames the active log file to '.0'
+// (must be called under 'writeMutex'; the active log file must be closed)
+func shiftLogBackups() {
+	// erase the oldest backup
+	os.Remove(logBackupFilePath(logRotateMaxBackups - 1))
+	os.Remove(logBackupFilePath(logRotateMaxBackups-1) + ".gz")
+	// erase backups which are out of current configuration
+	// (e.g. when 'maxBackups' was decreased at runtime)
+	for i := logRotateMaxBackups; i <= _logMaxBackupsHard; i++ {
+		os.Remove(logBackupFilePath(i))
+		os.Remove(logBackupFilePath(i) + ".gz")
+	}
+
+	if logRotateMaxBackups <= 0 {
+		os.Remove(filePath)
+		return
+	}
+
+	// shift the rest of backups by one position
+	for i := logRotateMaxBackups - 2; i >= 0; i-- {
+		os.Rename(logBackupFilePath(i), logBackupFilePath(i+1))
+		os.Rename(logBackupFilePath(i)+".gz", logBackupFilePath(i+1)+".gz")
+	}
+
+	os.Rename(filePath, logBackupFilePath(0))
+	if logRotateCompress {
+		if err := compressLogFile(logBackupFilePath(0)); err != nil {
+			// compression failure is not critical: the plain backup file stays in place
+			fmt.Println("failed to compress rotated log file:", err)
+		}
+	}
+}
+
+// rotateLogFileIfNeeded rotates the active log file when its size limit exceeded
+// (must be called under 'writeMutex')
+func rotateLogFileIfNeeded() {
+	if globalLogFile == nil || curLogFileSize < logRotateMaxFileSize {
+		return
+	}
+
+	globalLogFile.Close()
+	globalLogFile = nil
+	createLogFile() // shifts backups and creates a new active log file
+}
+
+// compressLogFile compresses a file into '<fname>.gz' (the source file is erased)
+func compressLogFile(fname string) error {
+	src, err := os.Open(fname)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dstPath := fname + ".gz"
+	dst, err := os.OpenFile(dstPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600) // read\write only for privileged user
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+	// only for Windows: Golang is not able to change file permissins in Windows style
+	if err := filerights.WindowsChmod(dstPath, 0600); err != nil { // read\write only for privileged user
+		os.Remove(dstPath)
+		return err
+	}
+
+	gzWriter := gzip.NewWriter(dst)
+	if _, err := io.Copy(gzWriter, src); err != nil {
+		gzWriter.Close()
+		os.Remove(dstPath)
+		return err
+	}
+	if err := gzWriter.Close(); err != nil {
+		os.Remove(dstPath)
+		return err
+	}
+
+	src.Close()
+	os.Remove(fname)
+	return nil
+}
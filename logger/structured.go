@@ -0,0 +1,235 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Structured logging
+// When structured mode enabled, log records are written as JSON objects (one object per line)
+// so support bundles and log processors can parse daemon logs reliably.
+// Additionally:
+//	- log level can be configured at runtime for each module (logger name) separately
+//	- high-frequency messages are sampled (repeated messages are suppressed and reported by a summary record)
+
+// Log levels
+const (
+	LevelDebug   = 0
+	LevelInfo    = 1
+	LevelWarning = 2
+	LevelError   = 3
+)
+
+// sampling of high-frequency messages:
+// only first '_samplingMaxPerWindow' occurrences of same message are logged within a time window
+const (
+	_samplingWindow       = time.Second * 10
+	_samplingMaxPerWindow = 20
+	// protection from unlimited memory consumption by sampling statistics
+	_samplingMaxTrackedMessages = 512
+)
+
+var (
+	configMutex      sync.Mutex
+	isStructuredLog  bool
+	defaultLogLevel  = LevelDebug
+	moduleLogLevels  = map[string]int{}
+	samplingStats    = map[string]*samplingInfo{}
+	samplingDisabled bool
+)
+
+type samplingInfo struct {
+	windowStart time.Time
+	count       int
+}
+
+// ParseLevel converts level name to a level value
+func ParseLevel(level string) (int, error) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warning":
+		return LevelWarning, nil
+	case "error":
+		return LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log level: '%s' (acceptable values: debug, info, warning, error)", level)
+}
+
+// SetStructuredLogging enables\disables structured (JSON) log output
+func SetStructuredLogging(enabled bool) {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+	isStructuredLog = enabled
+}
+
+// SetLogSampling enables\disables sampling of high-frequency messages
+func SetLogSampling(enabled bool) {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+	samplingDisabled = !enabled
+}
+
+// SetModuleLogLevel defines minimal log level for a module (logger name)
+// Module "*" defines the default level (applicable for modules which have no dedicated configuration)
+func SetModuleLogLevel(module string, level string) error {
+	levelVal, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+
+	module = strings.TrimSpace(module)
+	if len(module) == 0 {
+		return fmt.Errorf("module name not defined")
+	}
+
+	configMutex.Lock()
+	defer configMutex.Unlock()
+	if module == "*" {
+		defaultLogLevel = levelVal
+		return nil
+	}
+	moduleLogLevels[module] = levelVal
+	return nil
+}
+
+// isModuleLevelEnabled checks if a message of a level has to be logged for a module
+// ('module' value is in internal prefix format: "[name  ]")
+func isModuleLevelEnabled(module string, level int) bool {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+
+	moduleLevel, ok := moduleLogLevels[strings.Trim(module, "[] ")]
+	if ok == false {
+		moduleLevel = defaultLogLevel
+	}
+	return level >= moduleLevel
+}
+
+// samplingCheck updates sampling statistics of a message
+// Returns:
+//
+//	skip - true when the message has to be suppressed (too many occurrences within current time window)
+//	suppressed - count of occurrences suppressed in the previous time window (>0 - summary record has to be logged)
+func samplingCheck(messageKey string) (skip bool, suppressed int) {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+
+	if samplingDisabled {
+		return false, 0
+	}
+
+	// protection from unlimited memory consumption
+	if len(samplingStats) > _samplingMaxTrackedMessages {
+		samplingStats = map[string]*samplingInfo{}
+	}
+
+	now := time.Now()
+	stats, ok := samplingStats[messageKey]
+	if ok == false {
+		samplingStats[messageKey] = &samplingInfo{windowStart: now, count: 1}
+		return false, 0
+	}
+
+	if now.Sub(stats.windowStart) > _samplingWindow {
+		// new time window started
+		suppressed = stats.count - _samplingMaxPerWindow
+		if suppressed < 0 {
+			suppressed = 0
+		}
+		stats.windowStart = now
+		stats.count = 1
+		return false, suppressed
+	}
+
+	stats.count++
+	return stats.count > _samplingMaxPerWindow, 0
+}
+
+// structured log record format
+type logRecord struct {
+	Time    string `json:"time"`
+	Level   string `json:"level"`
+	Module  string `json:"module,omitempty"`
+	Caller  string `json:"caller,omitempty"`
+	Message string `json:"message"`
+}
+
+// writeRecord writes one log record (plain or structured - according to current configuration)
+func writeRecord(timeStr string, module string, level string, callerInfo string, message string) {
+	moduleName := strings.Trim(module, "[] ")
+
+	skip, suppressed := samplingCheck(moduleName + "|" + level + "|" + message)
+	if suppressed > 0 {
+		writeRecordRaw(timeStr, module, level, callerInfo,
+			fmt.Sprintf("last message suppressed %d times (high-frequency messages sampling)", suppressed))
+	}
+	if skip {
+		return
+	}
+
+	writeRecordRaw(timeStr, module, level, callerInfo, message)
+}
+
+func writeRecordRaw(timeStr string, module string, level string, callerInfo string, message string) {
+	configMutex.Lock()
+	isStructured := isStructuredLog
+	configMutex.Unlock()
+
+	if isStructured == false {
+		// plain log format
+		if len(level) == 0 {
+			write(timeStr, module, message)
+		} else {
+			write(timeStr, module, level, callerInfo, message)
+		}
+		return
+	}
+
+	levelName := level
+	if len(levelName) == 0 {
+		levelName = "INFO"
+	}
+
+	record := logRecord{
+		Time:    time.Now().Format("2006-01-02T15:04:05.000Z07:00"),
+		Level:   levelName,
+		Module:  strings.Trim(module, "[] "),
+		Caller:  strings.TrimSuffix(callerInfo, ":"),
+		Message: message}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		// unable to serialize record - fallback to plain format
+		write(timeStr, module, level, callerInfo, message)
+		return
+	}
+	write(string(data))
+}
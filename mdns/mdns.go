@@ -0,0 +1,292 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+// Package mdns implements minimal mDNS\DNS-SD (Bonjour) discovery of background LAN services
+// (printers, casting devices). Discovery is best-effort: queries are sent with the
+// 'unicast response' bit, so no multicast group membership is required.
+// Used to create narrowly scoped firewall exceptions while the kill switch is on.
+package mdns
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/ivpn/desktop-app-daemon/logger"
+)
+
+var log *logger.Logger
+
+func init() {
+	log = logger.NewLogger("mdns")
+}
+
+// mDNS multicast endpoint (IPv4)
+const _mdnsEndpoint = "224.0.0.251:5353"
+
+// DNS record types in use
+const (
+	_typeA   = 1
+	_typePTR = 12
+	_typeSRV = 33
+)
+
+// service types to discover (DNS-SD service type -> human-readable device kind)
+var serviceTypes = map[string]string{
+	"_ipp._tcp.local":            "printer",
+	"_printer._tcp.local":        "printer",
+	"_pdl-datastream._tcp.local": "printer",
+	"_googlecast._tcp.local":     "casting device",
+	"_airplay._tcp.local":        "casting device",
+	"_raop._tcp.local":           "casting device",
+}
+
+// Device - discovered LAN service
+type Device struct {
+	// Name - service instance name (e.g. "Office printer")
+	Name string
+	// Kind - human-readable device kind ("printer", "casting device")
+	Kind string
+	// IP - IPv4 address of the device
+	IP net.IP
+}
+
+// Discover sends mDNS queries for known service types and collects responses
+// until 'timeout' expires. Returns discovered devices (deduplicated by IP).
+func Discover(timeout time.Duration) ([]Device, error) {
+	if timeout <= 0 {
+		timeout = time.Second * 3
+	}
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mDNS socket: %w", err)
+	}
+	defer conn.Close()
+
+	dst, err := net.ResolveUDPAddr("udp4", _mdnsEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	for serviceType := range serviceTypes {
+		if _, err := conn.WriteToUDP(buildQuery(serviceType), dst); err != nil {
+			return nil, fmt.Errorf("failed to send mDNS query: %w", err)
+		}
+	}
+
+	// collected records (across all response packets)
+	collector := newRecordCollector()
+
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	buff := make([]byte, 9000)
+	for {
+		n, _, err := conn.ReadFromUDP(buff)
+		if err != nil {
+			break // timeout expired (or socket closed)
+		}
+		if err := collector.parsePacket(buff[:n]); err != nil {
+			log.Warning("Failed to parse mDNS response: ", err)
+		}
+	}
+
+	return collector.devices(), nil
+}
+
+// buildQuery creates one-question DNS query packet: PTR of a service type
+// with the 'unicast response' bit set (top bit of QCLASS)
+func buildQuery(serviceType string) []byte {
+	packet := make([]byte, 12, 12+len(serviceType)+6)
+	binary.BigEndian.PutUint16(packet[4:6], 1) // QDCOUNT
+
+	for _, label := range strings.Split(serviceType, ".") {
+		packet = append(packet, byte(len(label)))
+		packet = append(packet, label...)
+	}
+	packet = append(packet, 0) // end of name
+
+	packet = append(packet, 0, _typePTR)
+	packet = append(packet, 0x80, 0x01) // class IN + 'unicast response' bit
+	return packet
+}
+
+// recordCollector accumulates DNS records from response packets and joins them into devices:
+// PTR (service type -> instance), SRV (instance -> hostname), A (hostname -> IP)
+type recordCollector struct {
+	instances map[string]string // instance name -> device kind
+	srvHosts  map[string]string // instance name -> target hostname
+	hostIPs   map[string]net.IP // hostname -> IPv4 address
+}
+
+func newRecordCollector() *recordCollector {
+	return &recordCollector{
+		instances: map[string]string{},
+		srvHosts:  map[string]string{},
+		hostIPs:   map[string]net.IP{}}
+}
+
+// devices joins collected records into the resulting devices list (deduplicated by IP)
+func (c *recordCollector) devices() []Device {
+	var result []Device
+	seenIPs := map[string]struct{}{}
+
+	for instance, kind := range c.instances {
+		hostname, ok := c.srvHosts[instance]
+		if ok == false {
+			continue
+		}
+		ip, ok := c.hostIPs[hostname]
+		if ok == false {
+			continue
+		}
+		if _, seen := seenIPs[ip.String()]; seen {
+			continue
+		}
+		seenIPs[ip.String()] = struct{}{}
+
+		// instance name format: "<name>.<service type>"
+		name := instance
+		if idx := strings.Index(instance, "._"); idx > 0 {
+			name = instance[:idx]
+		}
+		result = append(result, Device{Name: name, Kind: kind, IP: ip})
+	}
+	return result
+}
+
+// parsePacket extracts PTR\SRV\A records from a DNS response packet
+func (c *recordCollector) parsePacket(packet []byte) error {
+	if len(packet) < 12 {
+		return fmt.Errorf("packet too short")
+	}
+
+	questions := int(binary.BigEndian.Uint16(packet[4:6]))
+	records := int(binary.BigEndian.Uint16(packet[6:8])) + // answers
+		int(binary.BigEndian.Uint16(packet[8:10])) + // authority
+		int(binary.BigEndian.Uint16(packet[10:12])) // additional
+
+	offset := 12
+
+	// skip question section
+	for i := 0; i < questions; i++ {
+		_, next, err := readName(packet, offset)
+		if err != nil {
+			return err
+		}
+		offset = next + 4 // QTYPE + QCLASS
+		if offset > len(packet) {
+			return fmt.Errorf("packet truncated")
+		}
+	}
+
+	// resource records
+	for i := 0; i < records; i++ {
+		name, next, err := readName(packet, offset)
+		if err != nil {
+			return err
+		}
+		offset = next
+		if offset+10 > len(packet) {
+			return fmt.Errorf("packet truncated")
+		}
+
+		recordType := binary.BigEndian.Uint16(packet[offset : offset+2])
+		dataLen := int(binary.BigEndian.Uint16(packet[offset+8 : offset+10]))
+		offset += 10
+		if offset+dataLen > len(packet) {
+			return fmt.Errorf("packet truncated")
+		}
+		data := packet[offset : offset+dataLen]
+
+		switch recordType {
+		case _typePTR:
+			if kind, ok := serviceTypes[strings.ToLower(name)]; ok {
+				if instance, _, err := readName(packet, offset); err == nil {
+					c.instances[strings.ToLower(instance)] = kind
+				}
+			}
+		case _typeSRV:
+			// SRV rdata: priority (2) + weight (2) + port (2) + target name
+			if dataLen > 6 {
+				if target, _, err := readName(packet, offset+6); err == nil {
+					c.srvHosts[strings.ToLower(name)] = strings.ToLower(target)
+				}
+			}
+		case _typeA:
+			if dataLen == 4 {
+				c.hostIPs[strings.ToLower(name)] = net.IPv4(data[0], data[1], data[2], data[3])
+			}
+		}
+
+		offset += dataLen
+	}
+	return nil
+}
+
+// readName decodes a (possibly compressed) DNS name starting at 'offset'
+// Returns the name and offset of the next byte after the name field
+func readName(packet []byte, offset int) (name string, next int, err error) {
+	var labels []string
+	jumped := false
+	next = offset
+
+	for iterations := 0; ; iterations++ {
+		if iterations > 127 {
+			return "", 0, fmt.Errorf("too many compression jumps")
+		}
+		if offset >= len(packet) {
+			return "", 0, fmt.Errorf("name out of packet bounds")
+		}
+
+		length := int(packet[offset])
+
+		// compression pointer (two top bits set)
+		if length&0xC0 == 0xC0 {
+			if offset+1 >= len(packet) {
+				return "", 0, fmt.Errorf("name out of packet bounds")
+			}
+			if jumped == false {
+				next = offset + 2
+				jumped = true
+			}
+			offset = int(binary.BigEndian.Uint16(packet[offset:offset+2]) & 0x3FFF)
+			continue
+		}
+
+		if length == 0 {
+			if jumped == false {
+				next = offset + 1
+			}
+			break
+		}
+
+		if offset+1+length > len(packet) {
+			return "", 0, fmt.Errorf("name out of packet bounds")
+		}
+		labels = append(labels, string(packet[offset+1:offset+1+length]))
+		offset += 1 + length
+	}
+
+	return strings.Join(labels, "."), next, nil
+}
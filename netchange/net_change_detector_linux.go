@@ -22,18 +22,118 @@
 
 package netchange
 
-// structure contains properties required for for macOS implementation
+import (
+	"os"
+	"regexp"
+	"syscall"
+
+	"github.com/ivpn/desktop-app-daemon/shell"
+
+	"golang.org/x/sys/unix"
+)
+
+// structure contains properties required for for Linux implementation
 type osSpecificProperties struct {
+	socket int
 }
 
 func (d *Detector) isRoutingChanged() (bool, error) {
-	// TODO: not implemented
-	return false, nil
+	if d.interfaceToProtect == nil {
+		log.Error("failed to check route change. Initial interface not defined")
+		return false, nil
+	}
+
+	// Expected output of "/sbin/ip route get 1.1.1.1" command:
+	// 1.1.1.1 via 10.59.44.1 dev tun0 src 10.59.44.4 uid 0
+	// (the route check by destination correctly accounts split-gateway routes '0.0.0.0/1'+'128.0.0.0/1' used by VPN)
+	routeInterfaceName := ""
+	outRegexp := regexp.MustCompile(" dev ([^ ]+)")
+
+	outParse := func(text string, isError bool) {
+		if isError {
+			return
+		}
+		if len(routeInterfaceName) > 0 {
+			return
+		}
+		columns := outRegexp.FindStringSubmatch(text)
+		if len(columns) <= 1 {
+			return
+		}
+		routeInterfaceName = columns[1]
+	}
+
+	if err := shell.ExecAndProcessOutput(log, outParse, "", "/sbin/ip", "route", "get", "1.1.1.1"); err != nil {
+		log.Error("Failed to check route change:", err)
+		return false, err
+	}
+
+	if len(routeInterfaceName) == 0 {
+		// no route to destination at all - the VPN interface is not in use anymore
+		return true, nil
+	}
+
+	return routeInterfaceName != d.interfaceToProtect.Name, nil
 }
 
 func (d *Detector) doStart() {
-	// TODO: not implemented
+	// subscribe to netlink route events: routing table, links and addresses changes (IPv4 and IPv6)
+	sock, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW, syscall.NETLINK_ROUTE)
+	if err != nil {
+		log.Error("Failed to start route change detector:", err)
+		return
+	}
+
+	sockAddr := &syscall.SockaddrNetlink{
+		Family: syscall.AF_NETLINK,
+		Groups: unix.RTMGRP_IPV4_ROUTE | unix.RTMGRP_IPV6_ROUTE |
+			unix.RTMGRP_LINK | unix.RTMGRP_IPV4_IFADDR | unix.RTMGRP_IPV6_IFADDR}
+
+	if err := syscall.Bind(sock, sockAddr); err != nil {
+		log.Error("Failed to start route change detector (bind error):", err)
+		syscall.Close(sock)
+		return
+	}
+	d.props.socket = sock
+
+	log.Info("Route change detector started")
+	defer func() {
+		log.Info("Route change detector stopped")
+		d.doStop()
+	}()
+
+	// Loop waiting for messages.
+	b := make([]byte, os.Getpagesize())
+	for {
+		nr, err := syscall.Read(d.props.socket, b)
+		if err != nil {
+			if d.props.socket == 0 {
+				break
+			}
+			log.Error("Route change detector (error on socket read):", err)
+			return
+		}
+
+		messages, err := syscall.ParseNetlinkMessage(b[:nr])
+		if err != nil {
+			continue
+		}
+
+		for _, msg := range messages {
+			switch msg.Header.Type {
+			case syscall.RTM_NEWROUTE, syscall.RTM_DELROUTE,
+				syscall.RTM_NEWLINK, syscall.RTM_DELLINK,
+				syscall.RTM_NEWADDR, syscall.RTM_DELADDR:
+				d.routingChangeDetected()
+			}
+		}
+	}
 }
 
 func (d *Detector) doStop() {
+	s := d.props.socket
+	d.props.socket = 0
+	if s != 0 {
+		syscall.Close(s)
+	}
 }
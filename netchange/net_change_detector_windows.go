@@ -30,13 +30,15 @@ import (
 
 	"github.com/ivpn/desktop-app-daemon/netinfo"
 	"github.com/ivpn/desktop-app-daemon/oshelpers/windows/iphlpapi"
-	"github.com/ivpn/desktop-app-daemon/oshelpers/windows/kernel32"
-	"github.com/ivpn/desktop-app-daemon/oshelpers/windows/winsock2"
 )
 
-// structure contains properties required for for macOS implementation
+// structure contains properties required for for Windows implementation
 type osSpecificProperties struct {
-	overlapped syscall.Overlapped
+	// handle of an active route change notification registration (0 - not registered)
+	notificationHandle syscall.Handle
+	// callback value created by 'syscall.NewCallback()'
+	// (created only once: callback allocations cannot be released)
+	notifyCallback uintptr
 }
 
 func (d *Detector) isRoutingChanged() (bool, error) {
@@ -77,70 +79,38 @@ func (d *Detector) isRoutingChanged() (bool, error) {
 }
 
 func (d *Detector) doStart() {
+	if d.props.notificationHandle != 0 {
+		return // notifications already registered
+	}
 
-	log.Info("Route change detector started")
-	defer func() {
-		log.Info("Route change detector stopped")
-	}()
+	// callback is created only once ('syscall.NewCallback' allocations cannot be released)
+	if d.props.notifyCallback == 0 {
+		d.props.notifyCallback = syscall.NewCallback(func(callerContext uintptr, row uintptr, notificationType uint32) uintptr {
+			// notification about routing change (notification will be sent after the debounce delay)
+			d.routingChangeDetected()
+			return 0
+		})
+	}
 
+	// subscribe to routing table change events (AF_UNSPEC: both IPv4 and IPv6 routing tables)
 	var handle syscall.Handle
-
-	d.props.overlapped = syscall.Overlapped{}
-	d.props.overlapped.HEvent, _ = winsock2.WSACreateEvent()
-
-	for {
-		if d.props.overlapped.HEvent == 0 {
-			break
-		}
-
-		// register route change handler
-		err := iphlpapi.APINotifyRouteChange(&handle, &d.props.overlapped)
-		if err != nil {
-			log.Error(err)
-			return
-		}
-
-		evtHandle := d.props.overlapped.HEvent
-		if evtHandle == 0 {
-			return
-		}
-
-		_, err = syscall.WaitForSingleObject(evtHandle, syscall.INFINITE)
-		if err != nil {
-			log.Error(err)
-			return
-		}
-
-		if d.props.overlapped.HEvent == 0 {
-			break
-		}
-
-		// notify about routing change
-		d.notifyRoutingChange()
+	if err := iphlpapi.APINotifyRouteChange2(uint16(syscall.AF_UNSPEC), d.props.notifyCallback, 0, &handle); err != nil {
+		log.Error("Failed to start route change detector:", err)
+		return
 	}
+	d.props.notificationHandle = handle
+
+	log.Info("Route change detector started")
 }
 
 func (d *Detector) doStop() {
-	overlapped := d.props.overlapped
-
-	if overlapped.HEvent != 0 {
-		// do not start new route change
-		d.props.overlapped.HEvent = 0
-
-		_, err := kernel32.SetEvent(overlapped.HEvent)
-		if err != nil {
-			log.Error("Failed to stop route change detection (SetEvent 1): ", err)
-		}
+	handle := d.props.notificationHandle
+	d.props.notificationHandle = 0
 
-		// stop route change detection
-		err = iphlpapi.CancelIPChangeNotify(&overlapped)
-		if err != nil {
-			log.Error("Failed to stop route change detection (CancelIPChangeNotify):", err)
-		}
-
-		_, err = kernel32.SetEvent(overlapped.HEvent)
-		if err != nil {
-			log.Error("Failed to stop route change detection (SetEvent 2):", err)
+	if handle != 0 {
+		if err := iphlpapi.APICancelMibChangeNotify2(handle); err != nil {
+			log.Error("Failed to stop route change detection (CancelMibChangeNotify2):", err)
 		}
+		log.Info("Route change detector stopped")
 	}
 }
@@ -143,6 +143,29 @@ func GetAllLocalV6Addresses() ([]net.IPNet, error) {
 	return getAllLocalAddresses(nil, true)
 }
 
+// HaveIPv6Connectivity - returns true when any local interface has a global unicast IPv6 address
+// (link-local and unique-local addresses do not count: they do not provide internet connectivity)
+func HaveIPv6Connectivity() (bool, error) {
+	addrs, err := GetAllLocalV6Addresses()
+	if err != nil {
+		return false, err
+	}
+
+	for _, a := range addrs {
+		ip := a.IP
+		if ip.IsGlobalUnicast() == false {
+			continue
+		}
+		// skip unique-local addresses (fc00::/7)
+		if len(ip) == net.IPv6len && (ip[0]&0xfe) == 0xfc {
+			continue
+		}
+		return true, nil
+	}
+
+	return false, nil
+}
+
 /*
 // GetInterfaceV4Addresses - returns IPv4 addresses of the local interface
 func GetInterfaceV4Addresses(inf net.Interface) ([]net.IPNet, error) {
@@ -40,12 +40,15 @@ func init() {
 }
 
 var (
-	_dll                   = syscall.NewLazyDLL("iphlpapi.dll")
-	_fNotifyRouteChange    = _dll.NewProc("NotifyRouteChange")
-	_fCancelIPChangeNotify = _dll.NewProc("CancelIPChangeNotify")
-	_fGetBestRoute         = _dll.NewProc("GetBestRoute")
-	_fGetIPForwardTable    = _dll.NewProc("GetIpForwardTable")
-	_fGetExtendedTcpTable  = _dll.NewProc("GetExtendedTcpTable")
+	_dll                     = syscall.NewLazyDLL("iphlpapi.dll")
+	_fNotifyRouteChange      = _dll.NewProc("NotifyRouteChange")
+	_fNotifyRouteChange2     = _dll.NewProc("NotifyRouteChange2")
+	_fCancelIPChangeNotify   = _dll.NewProc("CancelIPChangeNotify")
+	_fCancelMibChangeNotify2 = _dll.NewProc("CancelMibChangeNotify2")
+	_fGetBestRoute           = _dll.NewProc("GetBestRoute")
+	_fGetIPForwardTable      = _dll.NewProc("GetIpForwardTable")
+	_fGetExtendedTcpTable    = _dll.NewProc("GetExtendedTcpTable")
+	_fGetIfEntry             = _dll.NewProc("GetIfEntry")
 )
 
 // APINotifyRouteChange - The GetBestRoute function retrieves the best route to the specified destination IP address.
@@ -61,6 +64,34 @@ func APINotifyRouteChange(handle *syscall.Handle, overlapped *syscall.Overlapped
 	return checkDefaultAPIResp(retval, err)
 }
 
+// APINotifyRouteChange2 - The NotifyRouteChange2 function registers to be notified for changes to IP route entries
+// (modern callback-based replacement of 'NotifyRouteChange'; covers IPv6 routing table as well).
+// 'callback' must be a value created by 'syscall.NewCallback()' with a signature:
+//	func(callerContext uintptr, row uintptr, notificationType uint32) uintptr
+// https://docs.microsoft.com/en-us/windows/win32/api/netioapi/nf-netioapi-notifyroutechange2
+func APINotifyRouteChange2(addressFamily uint16, callback uintptr, callerContext uintptr, notificationHandle *syscall.Handle) (err error) {
+	defer catchPanic(&err)
+
+	retval, _, err := _fNotifyRouteChange2.Call(
+		uintptr(addressFamily),
+		callback,
+		callerContext,
+		uintptr(0), // InitialNotification == FALSE
+		uintptr(unsafe.Pointer(notificationHandle)))
+
+	return checkDefaultAPIResp(retval, err)
+}
+
+// APICancelMibChangeNotify2 - The CancelMibChangeNotify2 function deregisters the driver change notifications
+// previously registered by 'NotifyRouteChange2' (and similar Notify* functions)
+// https://docs.microsoft.com/en-us/windows/win32/api/netioapi/nf-netioapi-cancelmibchangenotify2
+func APICancelMibChangeNotify2(notificationHandle syscall.Handle) (err error) {
+	defer catchPanic(&err)
+
+	retval, _, err := _fCancelMibChangeNotify2.Call(uintptr(notificationHandle))
+	return checkDefaultAPIResp(retval, err)
+}
+
 // CancelIPChangeNotify - The CancelIPChangeNotify function cancels notification of IPv4 address and route changes previously requested with successful calls to the NotifyAddrChange or NotifyRouteChange functions.
 // https://docs.microsoft.com/ru-ru/windows/win32/api/iphlpapi/nf-iphlpapi-cancelipchangenotify
 func CancelIPChangeNotify(overlapped *syscall.Overlapped) (err error) {
@@ -105,6 +136,16 @@ func GetIPForwardTable(pIPForwardTable []byte, pdwSize *uint32, bOrder bool) (r
 	return syscall.Errno(retval), nil
 }
 
+// APIGetIfEntry - The GetIfEntry function retrieves information (including statistics counters) for the specified interface.
+// 'DwIndex' field of the 'row' argument must be initialized by the interface index.
+// https://docs.microsoft.com/en-us/windows/win32/api/iphlpapi/nf-iphlpapi-getifentry
+func APIGetIfEntry(row *APIMibIfRow) (err error) {
+	defer catchPanic(&err)
+
+	retval, _, err := _fGetIfEntry.Call(uintptr(unsafe.Pointer(row)))
+	return checkDefaultAPIResp(retval, err)
+}
+
 // GetExtendedTCPTable - function retrieves a table that contains a list of TCP endpoints available to the application.
 // https://docs.microsoft.com/en-us/windows/win32/api/iphlpapi/nf-iphlpapi-getextendedtcptable
 func GetExtendedTCPTable(tcpTable []byte, pdwSize *uint32, order bool, afType int, tableClass TCPTableClass) (r syscall.Errno, err error) {
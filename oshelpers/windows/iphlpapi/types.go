@@ -58,6 +58,35 @@ type APIMibIPForwardRow struct {
 	DwForwardMetric5   uint32
 }
 
+// APIMibIfRow - MIB_IFROW structure. Stores information about a particular interface.
+// https://docs.microsoft.com/en-us/windows/win32/api/ifmib/ns-ifmib-mib_ifrow
+type APIMibIfRow struct {
+	WszName           [256]uint16
+	DwIndex           uint32
+	DwType            uint32
+	DwMtu             uint32
+	DwSpeed           uint32
+	DwPhysAddrLen     uint32
+	BPhysAddr         [8]byte
+	DwAdminStatus     uint32
+	DwOperStatus      uint32
+	DwLastChange      uint32
+	DwInOctets        uint32
+	DwInUcastPkts     uint32
+	DwInNUcastPkts    uint32
+	DwInDiscards      uint32
+	DwInErrors        uint32
+	DwInUnknownProtos uint32
+	DwOutOctets       uint32
+	DwOutUcastPkts    uint32
+	DwOutNUcastPkts   uint32
+	DwOutDiscards     uint32
+	DwOutErrors       uint32
+	DwOutQLen         uint32
+	DwDescrLen        uint32
+	BDescr            [256]byte
+}
+
 // MibTCPRowOwnerPid - The MIB_TCPROW_OWNER_PID structure contains information that describes an IPv4 TCP connection with IPv4 addresses, ports used by the TCP connection, and the specific process ID (PID) associated with connection.
 // https://docs.microsoft.com/en-us/windows/win32/api/tcpmib/ns-tcpmib-mib_tcprow_owner_pid
 type MibTCPRowOwnerPid struct {
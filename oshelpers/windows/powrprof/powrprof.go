@@ -0,0 +1,84 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+// +build windows
+
+package powrprof
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	_dll                                       = windows.NewLazySystemDLL("powrprof.dll")
+	_fPowerRegisterSuspendResumeNotification   = _dll.NewProc("PowerRegisterSuspendResumeNotification")
+	_fPowerUnregisterSuspendResumeNotification = _dll.NewProc("PowerUnregisterSuspendResumeNotification")
+)
+
+// DeviceNotifyCallback - receive power notifications on a callback function
+// (usable by services and applications without a message pump)
+const DeviceNotifyCallback uint32 = 2
+
+// Power event types of the notification callback (same values as of WM_POWERBROADCAST message)
+const (
+	// PbtApmSuspend - the system is suspending operation (PBT_APMSUSPEND)
+	PbtApmSuspend uint32 = 4
+	// PbtApmResumeSuspend - operation is resuming after suspend, triggered by user input (PBT_APMRESUMESUSPEND)
+	PbtApmResumeSuspend uint32 = 7
+	// PbtApmResumeAutomatic - operation is resuming automatically after suspend (PBT_APMRESUMEAUTOMATIC)
+	PbtApmResumeAutomatic uint32 = 18
+)
+
+// DeviceNotifySubscribeParameters - DEVICE_NOTIFY_SUBSCRIBE_PARAMETERS structure
+// ('Callback' must be a value created by 'syscall.NewCallback()')
+type DeviceNotifySubscribeParameters struct {
+	Callback uintptr
+	Context  uintptr
+}
+
+// PowerRegisterSuspendResumeNotification - registers to receive notifications when the system is suspended or resumed
+// https://docs.microsoft.com/en-us/windows/win32/api/powersetting/nf-powersetting-powerregistersuspendresumenotification
+func PowerRegisterSuspendResumeNotification(params *DeviceNotifySubscribeParameters) (registrationHandle uintptr, err error) {
+	retval, _, _ := _fPowerRegisterSuspendResumeNotification.Call(
+		uintptr(DeviceNotifyCallback),
+		uintptr(unsafe.Pointer(params)),
+		uintptr(unsafe.Pointer(&registrationHandle)))
+
+	if retval != 0 {
+		return 0, fmt.Errorf("PowerRegisterSuspendResumeNotification failed (error code: %d)", retval)
+	}
+	return registrationHandle, nil
+}
+
+// PowerUnregisterSuspendResumeNotification - cancels a registration to receive power notifications
+// https://docs.microsoft.com/en-us/windows/win32/api/powersetting/nf-powersetting-powerunregistersuspendresumenotification
+func PowerUnregisterSuspendResumeNotification(registrationHandle uintptr) error {
+	retval, _, _ := _fPowerUnregisterSuspendResumeNotification.Call(registrationHandle)
+
+	if retval != 0 {
+		return fmt.Errorf("PowerUnregisterSuspendResumeNotification failed (error code: %d)", retval)
+	}
+	return nil
+}
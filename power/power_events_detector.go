@@ -0,0 +1,151 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package power
+
+import (
+	"time"
+
+	"github.com/ivpn/desktop-app-daemon/logger"
+)
+
+var log *logger.Logger
+
+func init() {
+	log = logger.NewLogger("power")
+}
+
+const (
+	// interval of the generic resume detection loop (see 'detectTimeShiftLoop()')
+	timeShiftCheckInterval = time.Second * 2
+	// minimum unexpected wall-clock shift between two loop iterations to interpret as a resume after system sleep
+	timeShiftThreshold = time.Second * 10
+	// minimum interval between two resume notifications
+	// (deduplicates resume events coming from multiple detection sources)
+	resumeNotifyDebounce = time.Second * 5
+)
+
+// Detector - object is detecting system power events (system sleep\resume)
+// Resume is detected on all platforms by an unexpected wall-clock shift of the detection loop
+// (when system sleeps the loop is not running); platform-specific event sources
+// (e.g. Windows power notifications) additionally provide suspend events
+type Detector struct {
+	// Signaling when the system is going to sleep
+	suspendNotifyChan chan<- struct{}
+	// Signaling when the system resumed after sleep
+	resumeNotifyChan chan<- struct{}
+
+	stopChan       chan struct{}
+	lastResumeUnix int64 // unix time (seconds) of the last resume notification
+
+	// Must be implemented (AND USED) in correspond file for concrete platform. Must contain platform-specified properties (or can be empty struct)
+	props osSpecificProperties
+}
+
+// Create - create new power events detector
+func Create() *Detector {
+	return &Detector{}
+}
+
+// Start - start power events detector (asynchronous)
+//    'suspendChan' is the channel for notifying when the system is going to sleep
+//    'resumeChan' is the channel for notifying when the system resumed after sleep
+func (d *Detector) Start(suspendChan chan<- struct{}, resumeChan chan<- struct{}) {
+	// Ensure that detector is stopped
+	d.Stop()
+
+	// set notification channels (it is important to do it after we are ensure that detector is stopped)
+	d.suspendNotifyChan = suspendChan
+	d.resumeNotifyChan = resumeChan
+
+	// generic resume detection (applicable for all platforms)
+	d.stopChan = make(chan struct{})
+	go d.detectTimeShiftLoop(d.stopChan)
+
+	// method should be implemented in platform-specific file
+	go d.doStart()
+}
+
+// Stop - stop power events detector
+func (d *Detector) Stop() {
+	if d.stopChan != nil {
+		close(d.stopChan)
+		d.stopChan = nil
+	}
+	// method should be implemented in platform-specific file
+	d.doStop()
+}
+
+// detectTimeShiftLoop detects a resume after system sleep:
+// when the system sleeps the loop is not running, therefore a much bigger wall-clock interval
+// than 'timeShiftCheckInterval' between two iterations means the system was asleep
+// (monotonic clock is not usable here: on some platforms it does not advance during sleep)
+func (d *Detector) detectTimeShiftLoop(stop <-chan struct{}) {
+	lastTickUnix := time.Now().Unix()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-time.After(timeShiftCheckInterval):
+		}
+
+		nowUnix := time.Now().Unix()
+		if nowUnix-lastTickUnix > int64((timeShiftCheckInterval+timeShiftThreshold)/time.Second) {
+			d.notifyResume()
+		}
+		lastTickUnix = nowUnix
+	}
+}
+
+// must be called when the system is going to sleep (called from platform-specific sources)
+func (d *Detector) notifySuspend() {
+	log.Info("System suspend detected")
+
+	if d.suspendNotifyChan == nil {
+		return
+	}
+	select {
+	case d.suspendNotifyChan <- struct{}{}:
+	default:
+	}
+}
+
+// must be called when the system resumed after sleep
+// (called from platform-specific sources or from the generic time-shift loop)
+func (d *Detector) notifyResume() {
+	// do not notify twice about same resume (resume can be detected by multiple sources)
+	nowUnix := time.Now().Unix()
+	if nowUnix-d.lastResumeUnix < int64(resumeNotifyDebounce/time.Second) {
+		return
+	}
+	d.lastResumeUnix = nowUnix
+
+	log.Info("System resume detected")
+
+	if d.resumeNotifyChan == nil {
+		return
+	}
+	select {
+	case d.resumeNotifyChan <- struct{}{}:
+	default:
+	}
+}
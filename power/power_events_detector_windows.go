@@ -0,0 +1,71 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package power
+
+import (
+	"syscall"
+
+	"github.com/ivpn/desktop-app-daemon/oshelpers/windows/powrprof"
+)
+
+// structure contains properties required for for Windows implementation
+type osSpecificProperties struct {
+	registrationHandle uintptr
+	// keep reference on subscription parameters while registration is active
+	// (to prevent them from being garbage-collected)
+	subscribeParams *powrprof.DeviceNotifySubscribeParameters
+}
+
+func (d *Detector) doStart() {
+	params := &powrprof.DeviceNotifySubscribeParameters{
+		Callback: syscall.NewCallback(func(context uintptr, eventType uint32, setting uintptr) uintptr {
+			switch eventType {
+			case powrprof.PbtApmSuspend:
+				d.notifySuspend()
+			case powrprof.PbtApmResumeSuspend, powrprof.PbtApmResumeAutomatic:
+				d.notifyResume()
+			}
+			return 0
+		})}
+	d.props.subscribeParams = params
+
+	handle, err := powrprof.PowerRegisterSuspendResumeNotification(params)
+	if err != nil {
+		log.Error("Failed to register power notifications: ", err)
+		return
+	}
+	d.props.registrationHandle = handle
+
+	log.Info("Power events detector started")
+}
+
+func (d *Detector) doStop() {
+	if d.props.registrationHandle != 0 {
+		if err := powrprof.PowerUnregisterSuspendResumeNotification(d.props.registrationHandle); err != nil {
+			log.Error(err)
+		}
+		d.props.registrationHandle = 0
+
+		log.Info("Power events detector stopped")
+	}
+}
@@ -0,0 +1,158 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package privhelper
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+)
+
+// client side of the privileged helper channel (used by the core process)
+
+var (
+	_helperMutex sync.Mutex
+	_helper      *helperClient
+)
+
+type helperClient struct {
+	mutex  sync.Mutex
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+}
+
+// StartHelperProcess starts the privileged helper process
+// (does nothing when the helper is already running)
+func StartHelperProcess() error {
+	_helperMutex.Lock()
+	defer _helperMutex.Unlock()
+
+	if _helper != nil {
+		return nil
+	}
+
+	binaryPath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get own binary location: %w", err)
+	}
+
+	secretBytes := make([]byte, 16)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return fmt.Errorf("failed to generate channel secret: %w", err)
+	}
+	secret := hex.EncodeToString(secretBytes)
+
+	cmd := exec.Command(binaryPath, _helperProcessArg)
+	cmd.Env = append(os.Environ(), _helperSecretEnvVar+"="+secret)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start helper process: %w", err)
+	}
+
+	// channel authentication: the secret must be the first line of the channel
+	if _, err := stdin.Write([]byte(secret + "\n")); err != nil {
+		cmd.Process.Kill()
+		return fmt.Errorf("failed to authenticate helper channel: %w", err)
+	}
+
+	log.Info(fmt.Sprintf("privileged helper process started (pid: %d)", cmd.Process.Pid))
+	_helper = &helperClient{cmd: cmd, stdin: stdin, stdout: bufio.NewReader(stdout)}
+
+	// detect unexpected helper termination
+	go func() {
+		err := cmd.Wait()
+
+		_helperMutex.Lock()
+		defer _helperMutex.Unlock()
+		if _helper != nil && _helper.cmd == cmd {
+			_helper = nil
+			log.Warning("privileged helper process stopped: ", err)
+		}
+	}()
+
+	return nil
+}
+
+// IsHelperInUse returns true when the privileged helper process is running
+func IsHelperInUse() bool {
+	_helperMutex.Lock()
+	defer _helperMutex.Unlock()
+	return _helper != nil
+}
+
+// ExecFirewallScript executes the firewall script over the privileged helper
+// (executed == false when the helper is not in use: caller must execute the script directly)
+func ExecFirewallScript(args ...string) (executed bool, err error) {
+	_helperMutex.Lock()
+	helper := _helper
+	_helperMutex.Unlock()
+
+	if helper == nil {
+		return false, nil
+	}
+	return true, helper.exec(Request{Command: CommandFirewallScript, Args: args})
+}
+
+func (c *helperClient) exec(req Request) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	data, err := json.Marshal(&req)
+	if err != nil {
+		return err
+	}
+	if _, err := c.stdin.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to send request to helper: %w", err)
+	}
+
+	line, err := c.stdout.ReadBytes('\n')
+	if err != nil {
+		return fmt.Errorf("failed to read helper response: %w", err)
+	}
+
+	var resp Response
+	if err := json.Unmarshal(line, &resp); err != nil {
+		return fmt.Errorf("failed to parse helper response: %w", err)
+	}
+
+	if len(resp.Error) > 0 {
+		return &CommandError{ExitCode: resp.ExitCode, Message: resp.Error}
+	}
+	return nil
+}
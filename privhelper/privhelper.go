@@ -0,0 +1,110 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package privhelper
+
+import (
+	"errors"
+	"os"
+
+	"github.com/ivpn/desktop-app-daemon/logger"
+	"github.com/ivpn/desktop-app-daemon/shell"
+)
+
+// Privilege separation of the daemon (groundwork).
+//
+// The daemon binary can be started as a minimal privileged helper process
+// ('-privileged-helper' command-line argument): a small command loop which executes
+// only a fixed set of network-configuration operations requested by the core process
+// over an authenticated channel (stdin\stdout pipes of the helper).
+//
+// The goal is to gradually move all privileged operations (routes, firewall, service install)
+// behind the helper so the always-running core process (API, protocol, logic) can drop
+// root\SYSTEM privileges. Currently the firewall-script execution is available over the channel;
+// the core process keeps its privileges and uses the helper only when started
+// with the '-priv-helper' argument.
+//
+// Channel authentication: the core process generates a random secret, passes it to the helper
+// over the environment (readable only by the privileged user) and must send it
+// as the first line of the channel; the helper terminates on a mismatch.
+
+var log *logger.Logger
+
+func init() {
+	log = logger.NewLogger("privh")
+}
+
+const (
+	// command-line argument of the helper process mode
+	_helperProcessArg = "-privileged-helper"
+	// environment variable caring the expected channel secret (set for the helper process only)
+	_helperSecretEnvVar = "IVPN_HELPER_SECRET"
+)
+
+// commands of the privileged helper
+const (
+	// CommandFirewallScript - execute the firewall script of the platform with given arguments
+	// (script path is defined by the helper itself; the core process can not define it)
+	CommandFirewallScript = "firewall-script"
+)
+
+// Request - command request of the core process to the privileged helper
+type Request struct {
+	Command string
+	Args    []string
+}
+
+// Response - result of a command executed by the privileged helper
+type Response struct {
+	Error    string // empty - command succeeded
+	ExitCode int    // exit code of the executed process (when applicable)
+}
+
+// CommandError - error of a command executed over the privileged helper
+// (keeps exit code of the executed process)
+type CommandError struct {
+	ExitCode int
+	Message  string
+}
+
+func (e *CommandError) Error() string {
+	return e.Message
+}
+
+// IsHelperProcess returns true when the current process was started as the privileged helper
+func IsHelperProcess() bool {
+	for _, arg := range os.Args {
+		if arg == _helperProcessArg {
+			return true
+		}
+	}
+	return false
+}
+
+// GetCmdExitCode - exit code of a command executed directly or over the privileged helper
+func GetCmdExitCode(err error) (int, error) {
+	var cmdErr *CommandError
+	if errors.As(err, &cmdErr) {
+		return cmdErr.ExitCode, nil
+	}
+	return shell.GetCmdExitCode(err)
+}
@@ -0,0 +1,85 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package privhelper
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/ivpn/desktop-app-daemon/service/platform"
+	"github.com/ivpn/desktop-app-daemon/shell"
+)
+
+// RunHelper - command loop of the privileged helper process
+// (process started with the '-privileged-helper' argument; returns process exit code)
+func RunHelper() int {
+	if _, errors := platform.Init(); len(errors) > 0 {
+		for _, e := range errors {
+			fmt.Fprintln(os.Stderr, "helper initialization error:", e)
+		}
+		return 1
+	}
+
+	expectedSecret := os.Getenv(_helperSecretEnvVar)
+	os.Unsetenv(_helperSecretEnvVar)
+	if len(expectedSecret) == 0 {
+		fmt.Fprintln(os.Stderr, "helper channel secret is not defined")
+		return 1
+	}
+
+	in := bufio.NewScanner(os.Stdin)
+
+	// channel authentication: the first line must be the channel secret
+	if in.Scan() == false || in.Text() != expectedSecret {
+		fmt.Fprintln(os.Stderr, "helper channel authentication failed")
+		return 1
+	}
+
+	for in.Scan() {
+		var req Request
+		resp := Response{}
+
+		if err := json.Unmarshal(in.Bytes(), &req); err != nil {
+			resp.Error = fmt.Sprintf("failed to parse request: %v", err)
+		} else if err := implExecuteCommand(req); err != nil {
+			resp.Error = err.Error()
+			if exitCode, e := shell.GetCmdExitCode(err); e == nil {
+				resp.ExitCode = exitCode
+			}
+		}
+
+		data, err := json.Marshal(&resp)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "failed to marshal response:", err)
+			return 1
+		}
+		if _, err := os.Stdout.Write(append(data, '\n')); err != nil {
+			return 1
+		}
+	}
+
+	// core process closed the channel (e.g. daemon stopped)
+	return 0
+}
@@ -24,12 +24,14 @@ package protocol
 
 import (
 	"bufio"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"math/rand"
 	"net"
+	"net/http"
 	"regexp"
 	"strconv"
 	"strings"
@@ -74,30 +76,136 @@ type Service interface {
 
 	ServersList() (*apitypes.ServersInfoResponse, error)
 	PingServers(retryCount int, timeoutMs int) (map[string]int, error)
+	// PingServersParallel measures latency to all servers concurrently
+	// (returns cached results immediately; the rest of servers are probed in background)
+	PingServersParallel(timeoutMs int, probeType string) (map[string]int, error)
+
+	// fastest-server selection (nil - when selection is not possible)
+	FastestWireGuardServer() *apitypes.WireGuardServerInfo
+	FastestOpenvpnServer() *apitypes.OpenvpnServerInfo
 	ServersUpdateNotifierChannel() chan struct{}
+	// ServersUpdateDiff returns changes of the servers list detected by the last update
+	// (nil - no background update happened yet)
+	ServersUpdateDiff() *apitypes.ServersListDiff
 
 	APIRequest(apiAlias string) (responseData []byte, err error)
+	// APIRequestStream - custom request to API with streaming of response body by chunks
+	// (chunks are forwarded to the requesting client; avoids large memory spikes)
+	APIRequestStream(apiAlias string, maxSizeBytes int64, onChunk func(chunk []byte, received int64, contentLength int64) error) error
+
+	// GeoLookupCompare - geo-lookup through the VPN tunnel and outside of it
+	GeoLookupCompare(timeoutMs int) (inTunnel, outOfTunnel *apitypes.GeoLookupResponse, inTunnelErr, outOfTunnelErr error)
+
+	// SetAPIEndpoints - override API\update hosts (allowed only when enabled by administrator in settings file)
+	SetAPIEndpoints(apiHost string, updateHost string, apiPinnedKeys []string, updatePinnedKeys []string) error
 
 	KillSwitchState() (isEnabled, isPersistant, isAllowLAN, isAllowLanMulticast bool, err error)
 	SetKillSwitchState(bool) error
 	SetKillSwitchIsPersistent(isPersistant bool) error
 	SetKillSwitchAllowLANMulticast(isAllowLanMulticast bool) error
 	SetKillSwitchAllowLAN(isAllowLan bool) error
+	SetKillSwitchAllowLANTrustedOnly(isTrustedOnly bool) error
+	SetKillSwitchAllowLANSubnets(subnets []string) error
 
 	Preferences() preferences.Preferences
 	SetPreference(key string, val string) error
 
+	// SaveLastConnectionParams saves raw data of the last 'Connect' request (used by autoconnect functionality)
+	SaveLastConnectionParams(connectionParams string)
+	// SaveDaemonStopState persists current daemon state (used by the state restoration functionality)
+	SaveDaemonStopState()
+	// SetConnectionIPv4FallbackHost - IPv4 endpoint to fall back to when connection over an IPv6 endpoint fails
+	// (nil - connecting over IPv4, no fallback applicable)
+	SetConnectionIPv4FallbackHost(host net.IP)
+
+	// scheduler: scheduled connect\disconnect rules (raw JSON of a rules list)
+	SetSchedulerRules(rulesJSON string) error
+	SchedulerRules() string
+
+	// connection profiles: named sets of connection parameters (raw JSON of a 'Connect' request)
+	SaveConnectionProfile(name string, connectionParams string) error
+	DeleteConnectionProfile(name string) error
+	ConnectionProfile(name string) (connectionParams string, err error)
+	ConnectionProfiles() []string
+
+	// user-defined metadata of VPN servers (favorites, custom labels...; server gateway -> metadata)
+	SetServerMetadata(gateway string, metadata preferences.ServerMetadata) error
+	ServersMetadata() map[string]preferences.ServerMetadata
+
+	// imported third-party OpenVPN profiles (with their credentials)
+	ImportOpenvpnProfile(name string, config string, username string, password string) error
+	DeleteOpenvpnProfile(name string) error
+	OpenvpnProfiles() []string
+	// OpenvpnProfilesInfo returns imported profiles with country annotations from the local GeoIP dataset
+	OpenvpnProfilesInfo() []types.OvpnProfileInfo
+	ConnectImportedOpenvpnProfile(name string, manualDNS net.IP, firewallOn bool, firewallDuringConnection bool, stateChan chan<- vpn.StateInfo) error
+
+	// GenerateDiagnosticBundle assembles a support bundle (base64 of a zip archive; secrets are redacted)
+	GenerateDiagnosticBundle() (bundleBase64 string, err error)
+
+	// RunSelfTest performs connectivity and leak checks of the active VPN connection
+	RunSelfTest() (checks []types.SelfTestCheck, err error)
+
+	// DaemonHealth returns daemon uptime and health of internal subsystems (liveness probe)
+	DaemonHealth() types.DaemonHealthResp
+
+	// ConnectionHistory returns last records of local connection history (newest first)
+	ConnectionHistory(maxRecords int) ([]types.ConnectionHistoryRecord, error)
+	ClearConnectionHistory() error
+
+	// ConnectionDiagnosticsReport returns opt-in connection diagnostics report (JSON; newest first)
+	ConnectionDiagnosticsReport() (reportJSON string, err error)
+	ClearConnectionDiagnostics() error
+
+	// LanServicesDiscover discovers background LAN services over mDNS (printers, casting devices)
+	LanServicesDiscover() ([]types.LanServiceDeviceInfo, error)
+	LanServiceSetAllowed(ip string, allowed bool) error
+
+	// BandwidthStats returns per-session and per-month counters of transferred bytes
+	BandwidthStats() types.BandwidthStats
+	ResetBandwidthStats() error
+
+	ExportSettings(passphrase string, isIncludeSecrets bool) (bundleBase64 string, err error)
+	ImportSettings(bundleBase64 string, passphrase string) error
+
+	// SetOpenVPNCredentials supplies new credentials to the active OpenVPN connection attempt
+	// (applicable when the server rejected current credentials: 'auth-failure')
+	SetOpenVPNCredentials(username string, password string) error
+
+	// SetOpenVPNTlsCryptV2Key provisions a per-client tls-crypt-v2 key (empty value erases the key)
+	SetOpenVPNTlsCryptV2Key(keyPem string) error
+
+	// StartServerSwitch opens the server-switch window: firewall protection stays
+	// continuously engaged between the old and the new connection
+	StartServerSwitch()
+
+	// CaptivePortalBypassStart temporarily disables kill switch to pass a captive portal
+	// login page; full protection is restored automatically ('durationSec == 0' - default duration)
+	CaptivePortalBypassStart(durationSec int) error
+
+	// CleanupNetworkArtifacts removes network artifacts leftover from crashed sessions
+	CleanupNetworkArtifacts() error
+
+	// Port-forwarding management (applicable only when connected)
+	PortForwardRequest() (port int, expiresAt int64, err error)
+	PortForwardRelease() error
+	PortForwardState() (port int, expiresAt int64)
+
 	SetManualDNS(dns net.IP) error
 	ResetManualDNS() error
 
 	ConnectOpenVPN(connectionParams openvpn.ConnectionParams, manualDNS net.IP, firewallOn bool, firewallDuringConnection bool, stateChan chan<- vpn.StateInfo) error
 	ConnectWireGuard(connectionParams wireguard.ConnectionParams, manualDNS net.IP, firewallOn bool, firewallDuringConnection bool, stateChan chan<- vpn.StateInfo) error
+	// ConnectCustomWireGuard - connect using parameters of a user-provided (non-IVPN) WireGuard configuration
+	ConnectCustomWireGuard(cfg *wireguard.CustomConfig, manualDNS net.IP, firewallOn bool, firewallDuringConnection bool, stateChan chan<- vpn.StateInfo) error
 	Disconnect() error
 	Connected() bool
 
-	Pause() error
+	Pause(durationSeconds int) error
 	Resume() error
 	IsPaused() bool
+	// PauseResumeDeadline - unix time (seconds) of scheduled auto-resume (0 - no auto-resume)
+	PauseResumeDeadline() int64
 
 	SessionNew(accountID string, forceLogin bool, captchaID string, captcha string, confirmation2FA string) (
 		apiCode int,
@@ -113,11 +221,19 @@ type Service interface {
 		sessionToken string,
 		accountInfo preferences.AccountStatus,
 		err error)
+	// AccountStatus returns the last known account status (empty 'sessionToken' - no status received yet)
+	AccountStatus() (sessionToken string, stat preferences.AccountStatus)
+	AccountCreate(productName string) (
+		apiCode int,
+		apiErrorMsg string,
+		accountID string,
+		rawResponse string,
+		err error)
 
 	WireGuardGenerateKeys(updateIfNecessary bool) error
 	WireGuardSetKeysRotationInterval(interval int64)
 
-	GetWiFiCurrentState() (ssid string, isInsecureNetwork bool)
+	GetWiFiCurrentState() (ssid string, bssid string, isInsecureNetwork bool)
 	GetWiFiAvailableNetworks() []string
 }
 
@@ -135,6 +251,32 @@ type Protocol struct {
 
 	_connectionsMutex sync.RWMutex
 	_connections      map[net.Conn]struct{}
+	// capabilities of restricted connections (authenticated by a capability token instead of the secret)
+	// connections which are not in this map have full access
+	_connCapabilities map[net.Conn][]string
+	// event filters of connections which are subscribed only to specific event classes
+	// connections which are not in this map receive all notifications
+	_connEventFilters map[net.Conn]map[string]struct{}
+
+	_capabilityTokensMutex sync.Mutex
+	_capabilityTokens      map[string][]string
+
+	// multi-client arbitration (see 'protocol_arbitration.go')
+	_arbitrationMutex sync.Mutex
+	// connection holding the exclusive control lock (nil - lock not held)
+	_controlLockOwner net.Conn
+	// client names provided by 'Hello' requests
+	_connClientNames map[net.Conn]string
+	// connections which declared themselves as read-only observers
+	_observerConns map[net.Conn]struct{}
+	// protocol schema versions declared by 'Hello' requests (see 'protocol_negotiation.go')
+	// connections which are not in this map support only the initial schema (version 0)
+	_connSchemaVersions map[net.Conn]int
+
+	// WebSocket bridge of the event stream (see 'protocol_websocket.go')
+	_webSocketMutex  sync.Mutex
+	_webSocketServer *http.Server
+	_webSocketConns  map[net.Conn]struct{}
 
 	_service Service
 
@@ -153,6 +295,11 @@ type Protocol struct {
 func (p *Protocol) Stop() {
 	log.Info("Stopping")
 
+	// save current state (to be able to restore it on next daemon start; see 'IsRestoreStateOnStart' preference)
+	if p._service != nil {
+		p._service.SaveDaemonStopState()
+	}
+
 	listener := p._connListener
 	if listener != nil {
 		// do not accept new incoming connections
@@ -205,6 +352,39 @@ func (p *Protocol) Start(secret uint64, startedOnPort chan<- int, service Servic
 	startedOnPort <- openedPort
 
 	log.Info(fmt.Sprintf("IVPN service started: %d [...%s]", openedPort, fmt.Sprintf("%016x", secret)[12:]))
+
+	// start listener of the platform-specific alternate transport (when supported):
+	// named pipe on Windows, abstract Unix-domain socket on Linux
+	// (clients connect by the well-known name: no connection port info file required;
+	// peer process is authenticated by OS-level peer credentials)
+	if altListener, err := implCreateAlternateListener(); err != nil {
+		log.Warning("Failed to start alternate transport listener: ", err)
+	} else if altListener != nil {
+		log.Info("Alternate transport listener started: ", altListener.Addr().String())
+		defer altListener.Close()
+
+		go func() {
+			for {
+				conn, err := altListener.Accept()
+				if err != nil {
+					log.Warning("Alternate transport listener stopped: ", err)
+					return
+				}
+				go p.processClient(conn)
+			}
+		}()
+	}
+
+	// start WebSocket bridge of the event stream (when enabled by 'websocket_port' preference)
+	p.applyWebSocketBridgeSettings()
+	defer p.closeWebSocketBridge()
+
+	// restore state saved on previous graceful daemon stop (if state restoration enabled)
+	p.restoreStateOnLaunch()
+	// restore last connection (if autoconnect on daemon start enabled)
+	go p.autoConnectOnLaunch()
+	// start VPN scheduler (scheduled connect\disconnect rules)
+	go p.schedulerLoop()
 	defer func() {
 		listener.Close()
 		log.Info("IVPN service stopped")
@@ -221,22 +401,40 @@ func (p *Protocol) Start(secret uint64, startedOnPort chan<- int, service Servic
 	}
 }
 
-// isNewConnectionAllowed is checking who is owner (PID & binary path) of this port
+// isNewConnectionAllowed is checking who is owner (PID & binary path) of this connection
+// PID of the peer process is resolved transport-specifically:
+// owner of the remote TCP port or peer credentials of the alternate transport (named pipe\Unix socket)
+func (p *Protocol) isNewConnectionAllowed(conn net.Conn) error {
+	var pid int
+	if tcpAddr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+		var err error
+		pid, err = process.GetPortOwnerPID(tcpAddr.Port)
+		if err != nil {
+			return fmt.Errorf("unable to check connected TCP port owner: %w", err)
+		}
+	} else {
+		var err error
+		pid, err = implGetConnectionPeerPID(conn)
+		if err != nil {
+			return err
+		}
+	}
+
+	return p.isClientBinaryAllowed(pid, conn.RemoteAddr())
+}
+
+// isClientBinaryAllowed is checking binary of the connected client process
 // If connecting binary is not in the list of allowed clients
 // OR connected binary has wrong properties (owner, access rights, location) - return error
-func (p *Protocol) isNewConnectionAllowed(clientRemoteAddrTCP *net.TCPAddr) error {
-	pid, err := process.GetPortOwnerPID(clientRemoteAddrTCP.Port)
-	if err != nil {
-		return fmt.Errorf("unable to check connected TCP port owner: %w", err)
-	}
+func (p *Protocol) isClientBinaryAllowed(pid int, remoteAddr net.Addr) error {
 	binPath, err := process.GetBinaryPathByPID(pid)
 	if err != nil {
-		return fmt.Errorf("unable to check connected TCP port owner binary %w", err)
+		return fmt.Errorf("unable to check connected client binary %w", err)
 	}
 	if len(binPath) == 0 {
-		return fmt.Errorf("unable to check connected TCP port owner binary")
+		return fmt.Errorf("unable to check connected client binary")
 	}
-	log.Info(fmt.Sprintf("Connected binary (%v): '%s'", clientRemoteAddrTCP, binPath))
+	log.Info(fmt.Sprintf("Connected binary (%v): '%s'", remoteAddr, binPath))
 
 	// check is connected binary is allowed to connect
 	isClientAllowed := false
@@ -316,12 +514,7 @@ func (p *Protocol) processClient(conn net.Conn) {
 	}()
 
 	// check is connection allowed fir this client
-	clientRemoteAddrTCP, err := net.ResolveTCPAddr("tcp4", clientRemoteAddr.String())
-	if err != nil {
-		p.sendResponse(conn, &types.ErrorResp{ErrorMessage: fmt.Sprintf("Refusing connection: Unable to determine remote TCP4 address of connected client: %s", err.Error())}, 0)
-		return
-	}
-	if err := p.isNewConnectionAllowed(clientRemoteAddrTCP); err != nil {
+	if err := p.isNewConnectionAllowed(conn); err != nil {
 		p.sendError(conn, fmt.Sprintf("Refusing connection: %s", err.Error()), 0)
 		return
 	}
@@ -339,8 +532,7 @@ func (p *Protocol) processClient(conn net.Conn) {
 			select {
 			case <-p._service.ServersUpdateNotifierChannel():
 				// servers update notifier
-				serv, _ := p._service.ServersList()
-				p.sendResponse(conn, &types.ServerListResp{VpnServers: *serv}, 0)
+				p.notifyClientAboutServersUpdate(conn)
 			case <-stopChannel:
 				return // stop loop
 			}
@@ -380,9 +572,15 @@ func (p *Protocol) processClient(conn net.Conn) {
 				return
 			}
 			if hello.Secret != p._secret {
-				log.Warning(fmt.Errorf("Refusing connection: secret verification error"))
-				p.sendErrorResponse(conn, cmd, fmt.Errorf("secret verification error"))
-				return
+				// the client may be authenticated by a capability token (restricted access)
+				capabilities, isTokenOk := p.capabilitiesByToken(hello.CapabilityToken)
+				if isTokenOk == false {
+					log.Warning(fmt.Errorf("Refusing connection: secret verification error"))
+					p.sendErrorResponse(conn, cmd, fmt.Errorf("secret verification error"))
+					return
+				}
+				log.Info(fmt.Sprintf("%sClient authenticated by capability token: %v", p.connLogID(conn), capabilities))
+				p.setConnectionCapabilities(conn, capabilities)
 			}
 
 			// AUTHENTICATED
@@ -397,6 +595,20 @@ func (p *Protocol) processClient(conn net.Conn) {
 	}
 }
 
+// parseOnOffValue interprets tri-state setting value of a request
+// ("on"\"off"; empty value - setting not defined, keep current state)
+func parseOnOffValue(val string) (isEnable bool, isDefined bool, err error) {
+	switch strings.ToLower(strings.TrimSpace(val)) {
+	case "":
+		return false, false, nil
+	case "on", "true", "1":
+		return true, true, nil
+	case "off", "false", "0":
+		return false, true, nil
+	}
+	return false, false, fmt.Errorf("'%s' (acceptable values: 'on', 'off' or empty)", val)
+}
+
 func (p *Protocol) processRequest(conn net.Conn, message string) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -419,6 +631,28 @@ func (p *Protocol) processRequest(conn net.Conn, message string) {
 
 	log.Info("[<--] ", p.connLogID(conn), reqCmd.Command)
 
+	// clients authenticated by a capability token have restricted access
+	// ('Hello' is always allowed: it is required for authentication;
+	// 'SetEventsSubscription' affects only notifications of current connection)
+	isAlwaysAllowed := reqCmd.Command == "Hello" || reqCmd.Command == "SetEventsSubscription"
+	if isAlwaysAllowed == false && conn != nil && p.isCommandAllowed(conn, reqCmd.Command) == false {
+		log.Warning(fmt.Sprintf("%sCommand '%s' not allowed for restricted client", p.connLogID(conn), reqCmd.Command))
+		p.sendErrorResponse(conn, reqCmd, fmt.Errorf("not enough rights to execute command '%s'", reqCmd.Command))
+		return
+	}
+
+	// multi-client arbitration: read-only observers and the exclusive control lock
+	// (applicable only to state-changing commands)
+	if conn != nil && isStateChangingCommand(reqCmd.Command) {
+		if err := p.checkClientArbitration(conn); err != nil {
+			log.Warning(fmt.Sprintf("%sCommand '%s': %s", p.connLogID(conn), reqCmd.Command, err))
+			p.sendErrorResponse(conn, reqCmd, err)
+			return
+		}
+		// inform other clients about who initiated the change
+		p.notifyClientActivity(conn, reqCmd.Command)
+	}
+
 	sendState := func(reqIdx int, isOnlyIfConnected bool) {
 		vpnState := p._lastVPNState
 		if vpnState.State == vpn.CONNECTED {
@@ -450,8 +684,16 @@ func (p *Protocol) processRequest(conn net.Conn, message string) {
 
 		log.Info(fmt.Sprintf("%sConnected client version: '%s' [set KeepDaemonAlone = %t]", p.connLogID(conn), req.Version, req.KeepDaemonAlone))
 
+		// save arbitration-related client info (client name, read-only observer mode)
+		p.registerClientInfo(conn, req.ClientName, req.ObserverMode, req.ProtocolSchemaVersion)
+
 		// send back Hello message with account session info
-		p.sendResponse(conn, p.createHelloResponse(), req.Idx)
+		helloResp := p.createHelloResponse()
+		if p.isConnectionRestricted(conn) {
+			// do not expose session token to clients with restricted access
+			helloResp.Session.Session = ""
+		}
+		p.sendResponse(conn, helloResp, req.Idx)
 
 		if req.GetServersList == true {
 			serv, _ := p._service.ServersList()
@@ -466,7 +708,8 @@ func (p *Protocol) processRequest(conn net.Conn, message string) {
 
 			// send Firewall state
 			if isEnabled, isPersistant, isAllowLAN, isAllowLanMulticast, err := p._service.KillSwitchState(); err == nil {
-				p.sendResponse(conn, &types.KillSwitchStatusResp{IsEnabled: isEnabled, IsPersistent: isPersistant, IsAllowLAN: isAllowLAN, IsAllowMulticast: isAllowLanMulticast}, reqCmd.Idx)
+				prefs := p._service.Preferences()
+				p.sendResponse(conn, &types.KillSwitchStatusResp{IsEnabled: isEnabled, IsPersistent: isPersistant, IsAllowLAN: isAllowLAN, IsAllowMulticast: isAllowLanMulticast, IsAllowLANTrustedOnly: prefs.IsFwAllowLANTrustedOnly, AllowLANSubnets: prefs.FwAllowLANSubnets}, reqCmd.Idx)
 			}
 		}
 
@@ -476,6 +719,12 @@ func (p *Protocol) processRequest(conn net.Conn, message string) {
 				reqCmd.Idx)
 		}
 
+		// sending last known account status (if available)
+		// (new client does not have to request it from the API)
+		if sessionToken, accStatus := p._service.AccountStatus(); len(sessionToken) > 0 {
+			p.sendResponse(conn, &types.AccountStatusResp{SessionToken: sessionToken, Account: accStatus}, reqCmd.Idx)
+		}
+
 		// sending WIFI info
 		p.OnWiFiChanged(p._service.GetWiFiCurrentState())
 		break
@@ -511,12 +760,25 @@ func (p *Protocol) processRequest(conn net.Conn, message string) {
 			break
 		}
 
-		var results []types.PingResultType
-		for k, v := range retMap {
-			results = append(results, types.PingResultType{Host: k, Ping: v})
+		p.sendResponse(conn, &types.PingServersResp{PingResults: pingResultsFromMap(retMap)}, req.Idx)
+		break
+
+	case "PingServersEx":
+		var req types.PingServersEx
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+
+		retMap, err := p._service.PingServersParallel(req.TimeOutMs, req.ProbeType)
+		if err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
 		}
 
-		p.sendResponse(conn, &types.PingServersResp{PingResults: results}, req.Idx)
+		// immediate response contains cached results only
+		// (the rest of results are streamed by 'PingServersResp' notifications)
+		p.sendResponse(conn, &types.PingServersResp{PingResults: pingResultsFromMap(retMap)}, req.Idx)
 		break
 
 	case "APIRequest":
@@ -534,6 +796,29 @@ func (p *Protocol) processRequest(conn net.Conn, message string) {
 		p.sendResponse(conn, &types.APIResponse{APIPath: req.APIPath, ResponseData: string(data)}, req.Idx)
 		break
 
+	case "APIRequestStream":
+		var req types.APIRequestStream
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+
+		err := p._service.APIRequestStream(req.APIPath, req.MaxResponseSize,
+			func(chunk []byte, received int64, contentLength int64) error {
+				return p.sendResponse(conn, &types.APIResponseChunk{
+					APIPath:       req.APIPath,
+					Data:          base64.StdEncoding.EncodeToString(chunk),
+					Received:      received,
+					ContentLength: contentLength}, req.Idx)
+			})
+
+		if err != nil {
+			p.sendResponse(conn, &types.APIResponseChunk{APIPath: req.APIPath, IsFinished: true, Error: err.Error()}, req.Idx)
+			break
+		}
+		p.sendResponse(conn, &types.APIResponseChunk{APIPath: req.APIPath, IsFinished: true}, req.Idx)
+		break
+
 	case "WiFiAvailableNetworks":
 		networks := p._service.GetWiFiAvailableNetworks()
 		nets := make([]types.WiFiNetworkInfo, 0, len(networks))
@@ -549,10 +834,27 @@ func (p *Protocol) processRequest(conn net.Conn, message string) {
 		if isEnabled, isPersistant, isAllowLAN, isAllowLanMulticast, err := p._service.KillSwitchState(); err != nil {
 			p.sendErrorResponse(conn, reqCmd, err)
 		} else {
-			p.sendResponse(conn, &types.KillSwitchStatusResp{IsEnabled: isEnabled, IsPersistent: isPersistant, IsAllowLAN: isAllowLAN, IsAllowMulticast: isAllowLanMulticast}, reqCmd.Idx)
+			prefs := p._service.Preferences()
+			p.sendResponse(conn, &types.KillSwitchStatusResp{IsEnabled: isEnabled, IsPersistent: isPersistant, IsAllowLAN: isAllowLAN, IsAllowMulticast: isAllowLanMulticast, IsAllowLANTrustedOnly: prefs.IsFwAllowLANTrustedOnly, AllowLANSubnets: prefs.FwAllowLANSubnets}, reqCmd.Idx)
 		}
 		break
 
+	case "CaptivePortalBypass":
+		var req types.CaptivePortalBypass
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+
+		if err := p._service.CaptivePortalBypassStart(req.DurationSec); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+
+		// send the response to the requestor
+		// (all clients are notified about the bypass by OnCaptivePortalBypass() handler)
+		p.sendResponse(conn, &types.EmptyResp{}, req.Idx)
+
 	case "KillSwitchSetEnabled":
 		var req types.KillSwitchSetEnabled
 		if err := json.Unmarshal(messageData, &req); err != nil {
@@ -599,6 +901,37 @@ func (p *Protocol) processRequest(conn net.Conn, message string) {
 		// all clients will be notified in case of successfull change by OnKillSwitchStateChanged() handler
 		break
 
+	case "KillSwitchSetAllowLANTrustedOnly":
+		var req types.KillSwitchSetAllowLANTrustedOnly
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+
+		p._service.SetKillSwitchAllowLANTrustedOnly(req.AllowLANTrustedOnly)
+		if req.Synchronously {
+			p.sendResponse(conn, &types.EmptyResp{}, req.Idx)
+		}
+		// all clients will be notified in case of successfull change by OnKillSwitchStateChanged() handler
+		break
+
+	case "KillSwitchSetAllowLANSubnets":
+		var req types.KillSwitchSetAllowLANSubnets
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+
+		if err := p._service.SetKillSwitchAllowLANSubnets(req.Subnets); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		if req.Synchronously {
+			p.sendResponse(conn, &types.EmptyResp{}, req.Idx)
+		}
+		// all clients will be notified in case of successfull change by OnKillSwitchStateChanged() handler
+		break
+
 	case "KillSwitchSetIsPersistent":
 		var req types.KillSwitchSetIsPersistent
 		if err := json.Unmarshal(messageData, &req); err != nil {
@@ -633,164 +966,511 @@ func (p *Protocol) processRequest(conn net.Conn, message string) {
 
 		if err := p._service.SetPreference(req.Key, req.Value); err != nil {
 			p.sendErrorResponse(conn, reqCmd, err)
+			break
 		}
-		break
 
-	case "GenerateDiagnostics":
-		if log, log0, err := logger.GetLogText(1024 * 64); err != nil {
-			p.sendErrorResponse(conn, reqCmd, err)
-		} else {
-			p.sendResponse(conn, &types.DiagnosticsGeneratedResp{ServiceLog: log, ServiceLog0: log0}, reqCmd.Idx)
+		if req.Key == "websocket_port" {
+			p.applyWebSocketBridgeSettings()
 		}
 		break
 
-	case "SetAlternateDns":
-		var req types.SetAlternateDns
+	case "SetLoggerConfig":
+		var req types.SetLoggerConfig
 		if err := json.Unmarshal(messageData, &req); err != nil {
 			p.sendErrorResponse(conn, reqCmd, err)
 			break
 		}
 
-		var err error
-		if ip := net.ParseIP(req.DNS); ip == nil || ip.Equal(net.IPv4zero) || ip.Equal(net.IPv4bcast) {
-			err = p._service.ResetManualDNS()
-		} else {
-			err = p._service.SetManualDNS(ip)
-
-			if err != nil {
-				// DNS set failed. Trying to reset DNS
-				errReset := p._service.ResetManualDNS()
-				if errReset != nil {
-					log.ErrorTrace(errReset)
-				}
+		isErr := false
+		for module, level := range req.ModuleLevels {
+			if err := logger.SetModuleLogLevel(module, level); err != nil {
+				p.sendErrorResponse(conn, reqCmd, err)
+				isErr = true
+				break
 			}
 		}
+		if isErr {
+			break
+		}
 
-		if err != nil {
-			log.ErrorTrace(err)
-			// send the response to the requestor
-			p.sendResponse(conn, &types.SetAlternateDNSResp{IsSuccess: false, ChangedDNS: net.IPv4zero.String()}, req.Idx)
-		} else {
-			// send the response to the requestor
-			p.sendResponse(conn, &types.SetAlternateDNSResp{IsSuccess: true, ChangedDNS: req.DNS}, req.Idx)
-			// all clients will be notified in case of successfull change by OnDNSChanged() handler
+		if req.RotationMaxFileSize > 0 {
+			if err := logger.SetRotationParams(req.RotationMaxFileSize, req.RotationMaxBackups, req.RotationCompress); err != nil {
+				p.sendErrorResponse(conn, reqCmd, err)
+				break
+			}
 		}
+
+		logger.SetStructuredLogging(req.StructuredLogging)
+		logger.SetLogSampling(req.DisableSampling == false)
+		p.sendResponse(conn, &types.EmptyResp{}, req.Idx)
 		break
 
-	case "PauseConnection":
-		if err := p._service.Pause(); err != nil {
+	case "SetModulesDebug":
+		var req types.SetModulesDebug
+		if err := json.Unmarshal(messageData, &req); err != nil {
 			p.sendErrorResponse(conn, reqCmd, err)
 			break
 		}
 
-		p.sendResponse(conn, &types.EmptyResp{}, reqCmd.Idx)
+		if req.IsEnable {
+			if err := logger.EnableModulesDebug(req.Modules, time.Duration(req.DurationMins)*time.Minute); err != nil {
+				p.sendErrorResponse(conn, reqCmd, err)
+				break
+			}
+		} else {
+			logger.DisableModulesDebug(req.Modules)
+		}
+		p.sendResponse(conn, &types.EmptyResp{}, req.Idx)
 		break
 
-	case "ResumeConnection":
-		if err := p._service.Resume(); err != nil {
+	case "GetRecentLogs":
+		var req types.GetRecentLogs
+		if err := json.Unmarshal(messageData, &req); err != nil {
 			p.sendErrorResponse(conn, reqCmd, err)
 			break
 		}
-
-		p.sendResponse(conn, &types.EmptyResp{}, reqCmd.Idx)
+		p.sendResponse(conn, &types.RecentLogsResp{Lines: logger.GetRecentLogRecords(req.MaxLines)}, req.Idx)
 		break
 
-	case "SessionNew":
-		var req types.SessionNew
-		if err := json.Unmarshal(messageData, &req); err != nil {
+	case "PortForwardRequest":
+		port, expiresAt, err := p._service.PortForwardRequest()
+		if err != nil {
 			p.sendErrorResponse(conn, reqCmd, err)
 			break
 		}
+		p.sendResponse(conn, &types.PortForwardResp{Port: port, ExpiresAt: expiresAt}, reqCmd.Idx)
+		break
 
-		// validate AccountID value
-		matched, err := regexp.MatchString("^(i-....-....-....)|(ivpn[a-zA-Z0-9]{7,8})$", req.AccountID)
-		if err != nil {
-			p.sendError(conn, fmt.Sprintf("[daemon] Account ID validation failed: %s", err), reqCmd.Idx)
+	case "PortForwardRelease":
+		if err := p._service.PortForwardRelease(); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
 			break
 		}
-		if !matched {
-			p.sendError(conn, "[daemon] Your account ID has to be in 'i-XXXX-XXXX-XXXX' or 'ivpnXXXXXXXX' format.", reqCmd.Idx)
+		p.sendResponse(conn, &types.EmptyResp{}, reqCmd.Idx)
+		break
+
+	case "SetEventsSubscription":
+		var req types.SetEventsSubscription
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
 			break
 		}
 
-		var resp types.SessionNewResp
-		apiCode, apiErrMsg, accountInfo, rawResponse, err := p._service.SessionNew(req.AccountID, req.ForceLogin, req.CaptchaID, req.Captcha, req.Confirmation2FA)
-		if err != nil {
-			if apiCode == 0 {
-				// if apiCode == 0 - it is not API error. Sending error response
-				p.sendErrorResponse(conn, reqCmd, err)
+		isErr := false
+		for _, class := range req.EventClasses {
+			if isKnownEventClass(class) == false {
+				p.sendErrorResponse(conn, reqCmd, fmt.Errorf("unknown event class: '%s'", class))
+				isErr = true
 				break
 			}
-			// sending API error info
-			resp = types.SessionNewResp{
-				APIStatus:       apiCode,
-				APIErrorMessage: apiErrMsg,
-				Session:         types.SessionResp{}, // empty session info
-				Account:         accountInfo,
-				RawResponse:     rawResponse}
-		} else {
-			// Success. Sending session info
-			resp = types.SessionNewResp{
-				APIStatus:       apiCode,
-				APIErrorMessage: apiErrMsg,
-				Session:         types.CreateSessionResp(p._service.Preferences().Session),
-				Account:         accountInfo,
-				RawResponse:     rawResponse}
+		}
+		if isErr {
+			break
 		}
 
-		// send response
-		p.sendResponse(conn, &resp, reqCmd.Idx)
-
-		// notify all clients about changed session status
-		p.notifyClients(p.createHelloResponse())
+		p.setConnectionEventsFilter(conn, req.EventClasses)
+		p.sendResponse(conn, &types.EmptyResp{}, req.Idx)
 		break
 
-	case "SessionDelete":
-		err := p._service.SessionDelete()
-		if err != nil {
-			p.sendErrorResponse(conn, reqCmd, err)
+	case "ControlLockAcquire":
+		if p.isObserverConnection(conn) {
+			p.sendErrorResponse(conn, reqCmd, fmt.Errorf("read-only observer cannot acquire the control lock"))
 			break
 		}
-		p.sendResponse(conn, &types.EmptyResp{}, reqCmd.Idx)
 
-		// notify all clients about changed session status
-		p.notifyClients(p.createHelloResponse())
+		isAcquired, ownerName := p.acquireControlLock(conn)
+		if isAcquired {
+			log.Info(fmt.Sprintf("%sExclusive control lock acquired by '%s'", p.connLogID(conn), ownerName))
+			p.notifyClientActivity(conn, reqCmd.Command)
+		}
+		p.sendResponse(conn, &types.ControlLockResp{IsAcquired: isAcquired, OwnerClientName: ownerName}, reqCmd.Idx)
 		break
 
-	case "AccountStatus":
-		var resp types.AccountStatusResp
-		apiCode, apiErrMsg, sessionToken, accountInfo, err := p._service.RequestSessionStatus()
-		if err != nil && apiCode == 0 {
-			// if apiCode == 0 - it is not API error. Sending error response
-			p.sendErrorResponse(conn, reqCmd, err)
-			break
+	case "ControlLockRelease":
+		if p.releaseControlLock(conn) {
+			log.Info(fmt.Sprintf("%sExclusive control lock released", p.connLogID(conn)))
+			p.notifyClientActivity(conn, reqCmd.Command)
 		}
-		// Sending session info
-		resp = types.AccountStatusResp{
-			APIStatus:       apiCode,
-			APIErrorMessage: apiErrMsg,
-			SessionToken:    sessionToken,
-			Account:         accountInfo}
-
-		// send response
-		p.sendResponse(conn, &resp, reqCmd.Idx)
+		p.sendResponse(conn, &types.ControlLockResp{IsAcquired: false}, reqCmd.Idx)
 		break
 
-	case "WireGuardGenerateNewKeys":
-		var req types.WireGuardGenerateNewKeys
+	case "GenerateCapabilityToken":
+		var req types.GenerateCapabilityToken
 		if err := json.Unmarshal(messageData, &req); err != nil {
 			p.sendErrorResponse(conn, reqCmd, err)
 			break
 		}
-		if err := p._service.WireGuardGenerateKeys(req.OnlyUpdateIfNecessary); err != nil {
+
+		token, err := p.generateCapabilityToken(req.Capabilities)
+		if err != nil {
 			p.sendErrorResponse(conn, reqCmd, err)
 			break
 		}
-		p.sendResponse(conn, &types.EmptyResp{}, reqCmd.Idx)
+		p.sendResponse(conn, &types.CapabilityTokenResp{Token: token, Capabilities: req.Capabilities}, req.Idx)
 		break
 
-	case "WireGuardSetKeysRotationInterval":
-		var req types.WireGuardSetKeysRotationInterval
+	case "SetAPIEndpoints":
+		var req types.SetAPIEndpoints
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+
+		if err := p._service.SetAPIEndpoints(req.APIHost, req.UpdateHost, req.APIPinnedKeys, req.UpdatePinnedKeys); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.EmptyResp{}, req.Idx)
+		break
+
+	case "GeoLookup":
+		var req types.GeoLookup
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+
+		inTunnel, outOfTunnel, inErr, outErr := p._service.GeoLookupCompare(req.TimeOutMs)
+		resp := types.GeoLookupResp{InTunnel: inTunnel, OutOfTunnel: outOfTunnel}
+		if inErr != nil {
+			resp.InTunnelError = inErr.Error()
+		}
+		if outErr != nil {
+			resp.OutOfTunnelError = outErr.Error()
+		}
+		p.sendResponse(conn, &resp, req.Idx)
+		break
+
+	case "CleanupNetworkArtifacts":
+		if err := p._service.CleanupNetworkArtifacts(); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.EmptyResp{}, reqCmd.Idx)
+		break
+
+	case "ExportSettings":
+		var req types.ExportSettings
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+
+		bundle, err := p._service.ExportSettings(req.Passphrase, req.IsIncludeSecrets)
+		if err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.ExportedSettingsResp{Bundle: bundle}, req.Idx)
+		break
+
+	case "ImportSettings":
+		var req types.ImportSettings
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+
+		if err := p._service.ImportSettings(req.Bundle, req.Passphrase); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.EmptyResp{}, req.Idx)
+		break
+
+	case "GenerateDiagnostics":
+		if log, log0, err := logger.GetLogText(1024 * 64); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+		} else {
+			p.sendResponse(conn, &types.DiagnosticsGeneratedResp{ServiceLog: log, ServiceLog0: log0}, reqCmd.Idx)
+		}
+		break
+
+	case "RunSelfTest":
+		checks, err := p._service.RunSelfTest()
+		if err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.SelfTestResp{Checks: checks}, reqCmd.Idx)
+		break
+
+	case "GetDaemonHealth":
+		health := p._service.DaemonHealth()
+		p.sendResponse(conn, &health, reqCmd.Idx)
+		break
+
+	case "GenerateDiagnosticBundle":
+		bundle, err := p._service.GenerateDiagnosticBundle()
+		if err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.DiagnosticBundleResp{Bundle: bundle}, reqCmd.Idx)
+		break
+
+	case "GetConnectionHistory":
+		var req types.GetConnectionHistory
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+
+		records, err := p._service.ConnectionHistory(req.MaxRecords)
+		if err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.ConnectionHistoryResp{Records: records}, reqCmd.Idx)
+		break
+
+	case "GetBandwidthStats":
+		p.sendResponse(conn, &types.BandwidthStatsResp{Stats: p._service.BandwidthStats()}, reqCmd.Idx)
+		break
+
+	case "ResetBandwidthStats":
+		if err := p._service.ResetBandwidthStats(); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.EmptyResp{}, reqCmd.Idx)
+		break
+
+	case "ClearConnectionHistory":
+		if err := p._service.ClearConnectionHistory(); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.EmptyResp{}, reqCmd.Idx)
+		break
+
+	case "GetConnectionDiagnostics":
+		report, err := p._service.ConnectionDiagnosticsReport()
+		if err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.ConnectionDiagnosticsResp{Report: report}, reqCmd.Idx)
+		break
+
+	case "ClearConnectionDiagnostics":
+		if err := p._service.ClearConnectionDiagnostics(); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.EmptyResp{}, reqCmd.Idx)
+		break
+
+	case "LanServicesDiscover":
+		devices, err := p._service.LanServicesDiscover()
+		if err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.LanServicesResp{Devices: devices}, reqCmd.Idx)
+		break
+
+	case "LanServiceSetAllowed":
+		var req types.LanServiceSetAllowed
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+
+		if err := p._service.LanServiceSetAllowed(req.IP, req.Allowed); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.EmptyResp{}, req.Idx)
+		break
+
+	case "SetAlternateDns":
+		var req types.SetAlternateDns
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+
+		var err error
+		if ip := net.ParseIP(req.DNS); ip == nil || ip.Equal(net.IPv4zero) || ip.Equal(net.IPv4bcast) {
+			err = p._service.ResetManualDNS()
+		} else {
+			err = p._service.SetManualDNS(ip)
+
+			if err != nil {
+				// DNS set failed. Trying to reset DNS
+				errReset := p._service.ResetManualDNS()
+				if errReset != nil {
+					log.ErrorTrace(errReset)
+				}
+			}
+		}
+
+		if err != nil {
+			log.ErrorTrace(err)
+			// send the response to the requestor
+			p.sendResponse(conn, &types.SetAlternateDNSResp{IsSuccess: false, ChangedDNS: net.IPv4zero.String()}, req.Idx)
+		} else {
+			// send the response to the requestor
+			p.sendResponse(conn, &types.SetAlternateDNSResp{IsSuccess: true, ChangedDNS: req.DNS}, req.Idx)
+			// all clients will be notified in case of successfull change by OnDNSChanged() handler
+		}
+		break
+
+	case "PauseConnection":
+		var req types.PauseConnection
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+
+		if err := p._service.Pause(req.Duration); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+
+		p.sendResponse(conn, &types.EmptyResp{}, reqCmd.Idx)
+		break
+
+	case "GetPauseState":
+		p.sendResponse(conn, &types.PauseStateResp{
+			IsPaused:     p._service.IsPaused(),
+			AutoResumeAt: p._service.PauseResumeDeadline()}, reqCmd.Idx)
+		break
+
+	case "ResumeConnection":
+		if err := p._service.Resume(); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+
+		p.sendResponse(conn, &types.EmptyResp{}, reqCmd.Idx)
+		break
+
+	case "SessionNew":
+		var req types.SessionNew
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+
+		// validate AccountID value
+		// (empty AccountID - continuing a pending multi-step login: e.g. sending captcha value or 2FA token)
+		if len(req.AccountID) > 0 {
+			matched, err := regexp.MatchString("^(i-....-....-....)|(ivpn[a-zA-Z0-9]{7,8})$", req.AccountID)
+			if err != nil {
+				p.sendError(conn, fmt.Sprintf("[daemon] Account ID validation failed: %s", err), reqCmd.Idx)
+				break
+			}
+			if !matched {
+				p.sendError(conn, "[daemon] Your account ID has to be in 'i-XXXX-XXXX-XXXX' or 'ivpnXXXXXXXX' format.", reqCmd.Idx)
+				break
+			}
+		}
+
+		var resp types.SessionNewResp
+		apiCode, apiErrMsg, accountInfo, rawResponse, err := p._service.SessionNew(req.AccountID, req.ForceLogin, req.CaptchaID, req.Captcha, req.Confirmation2FA)
+		if err != nil {
+			if apiCode == 0 {
+				// if apiCode == 0 - it is not API error. Sending error response
+				p.sendErrorResponse(conn, reqCmd, err)
+				break
+			}
+			// sending API error info
+			resp = types.SessionNewResp{
+				APIStatus:       apiCode,
+				APIErrorMessage: apiErrMsg,
+				Session:         types.SessionResp{}, // empty session info
+				Account:         accountInfo,
+				RawResponse:     rawResponse}
+		} else {
+			// Success. Sending session info
+			resp = types.SessionNewResp{
+				APIStatus:       apiCode,
+				APIErrorMessage: apiErrMsg,
+				Session:         types.CreateSessionResp(p._service.Preferences().Session),
+				Account:         accountInfo,
+				RawResponse:     rawResponse}
+		}
+
+		// send response
+		p.sendResponse(conn, &resp, reqCmd.Idx)
+
+		// notify all clients about changed session status
+		p.notifyClients(p.createHelloResponse())
+		break
+
+	case "SessionDelete":
+		err := p._service.SessionDelete()
+		if err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.EmptyResp{}, reqCmd.Idx)
+
+		// notify all clients about changed session status
+		p.notifyClients(p.createHelloResponse())
+		break
+
+	case "AccountStatus":
+		var resp types.AccountStatusResp
+		apiCode, apiErrMsg, sessionToken, accountInfo, err := p._service.RequestSessionStatus()
+		if err != nil && apiCode == 0 {
+			// if apiCode == 0 - it is not API error. Sending error response
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		// Sending session info
+		resp = types.AccountStatusResp{
+			APIStatus:       apiCode,
+			APIErrorMessage: apiErrMsg,
+			SessionToken:    sessionToken,
+			Account:         accountInfo}
+
+		// send response
+		p.sendResponse(conn, &resp, reqCmd.Idx)
+		break
+
+	case "AccountCreate":
+		var req types.AccountCreate
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+
+		var resp types.AccountCreateResp
+		apiCode, apiErrMsg, accountID, rawResponse, err := p._service.AccountCreate(req.ProductName)
+		if err != nil && apiCode == 0 {
+			// if apiCode == 0 - it is not API error. Sending error response
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		// Sending account creation result (or API error info)
+		resp = types.AccountCreateResp{
+			APIStatus:       apiCode,
+			APIErrorMessage: apiErrMsg,
+			AccountID:       accountID,
+			RawResponse:     rawResponse}
+
+		// send response
+		p.sendResponse(conn, &resp, reqCmd.Idx)
+		break
+
+	case "WireGuardGenerateNewKeys":
+		var req types.WireGuardGenerateNewKeys
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		if err := p._service.WireGuardGenerateKeys(req.OnlyUpdateIfNecessary); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.EmptyResp{}, reqCmd.Idx)
+		break
+
+	case "WireGuardSetKeysRotationInterval":
+		var req types.WireGuardSetKeysRotationInterval
 		if err := json.Unmarshal(messageData, &req); err != nil {
 			p.sendErrorResponse(conn, reqCmd, err)
 			break
@@ -800,6 +1480,276 @@ func (p *Protocol) processRequest(conn net.Conn, message string) {
 		p.sendResponse(conn, &types.EmptyResp{}, reqCmd.Idx)
 		break
 
+	case "SchedulerSetRules":
+		var req types.SchedulerSetRules
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+
+		isErr := false
+		for _, rule := range req.Rules {
+			if err := validateSchedulerRule(rule); err != nil {
+				p.sendErrorResponse(conn, reqCmd, err)
+				isErr = true
+				break
+			}
+		}
+		if isErr {
+			break
+		}
+
+		rulesJSON := ""
+		if len(req.Rules) > 0 {
+			data, err := json.Marshal(req.Rules)
+			if err != nil {
+				p.sendErrorResponse(conn, reqCmd, err)
+				break
+			}
+			rulesJSON = string(data)
+		}
+
+		if err := p._service.SetSchedulerRules(rulesJSON); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.EmptyResp{}, req.Idx)
+		break
+
+	case "SchedulerGetRules":
+		p.sendResponse(conn, &types.SchedulerRulesResp{Rules: p.schedulerRules()}, reqCmd.Idx)
+		break
+
+	case "ProfileSave":
+		var req types.ProfileSave
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+
+		// profile is stored as a raw 'Connect' request (can be replayed by 'ConnectProfile')
+		req.Params.Command = "Connect"
+		params, err := json.Marshal(req.Params)
+		if err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+
+		if err := p._service.SaveConnectionProfile(req.Name, string(params)); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.EmptyResp{}, req.Idx)
+		break
+
+	case "ProfileDelete":
+		var req types.ProfileDelete
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+
+		if err := p._service.DeleteConnectionProfile(req.Name); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.EmptyResp{}, req.Idx)
+		break
+
+	case "ProfileList":
+		p.sendResponse(conn, &types.ProfileListResp{Profiles: p._service.ConnectionProfiles()}, reqCmd.Idx)
+		break
+
+	case "OvpnProfileImport":
+		var req types.OvpnProfileImport
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+
+		if err := p._service.ImportOpenvpnProfile(req.Name, req.Config, req.Username, req.Password); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.EmptyResp{}, req.Idx)
+		break
+
+	case "OvpnProfileDelete":
+		var req types.OvpnProfileDelete
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+
+		if err := p._service.DeleteOpenvpnProfile(req.Name); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.EmptyResp{}, req.Idx)
+		break
+
+	case "OvpnProfileList":
+		p.sendResponse(conn, &types.OvpnProfileListResp{
+			Profiles:     p._service.OpenvpnProfiles(),
+			ProfilesInfo: p._service.OpenvpnProfilesInfo()}, reqCmd.Idx)
+		break
+
+	case "SetServerMetadata":
+		var req types.SetServerMetadata
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+
+		if err := p._service.SetServerMetadata(req.Gateway, req.Metadata); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.EmptyResp{}, req.Idx)
+		// notify all clients about changed metadata (favorites follow the machine)
+		p.notifyClients(&types.ServersMetadataResp{ServersMetadata: p._service.ServersMetadata()})
+		break
+
+	case "GetServersMetadata":
+		p.sendResponse(conn, &types.ServersMetadataResp{ServersMetadata: p._service.ServersMetadata()}, reqCmd.Idx)
+		break
+
+	case "ConnectProfile":
+		var req types.ConnectProfile
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+
+		params, err := p._service.ConnectionProfile(req.Name)
+		if err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+
+		log.Info(fmt.Sprintf("Connecting using profile '%s'", req.Name))
+		// process saved 'Connect' request in same way as a request from a client
+		p.processRequest(conn, params)
+		break
+
+	case "ConnectWithSettings":
+		var req types.ConnectWithSettings
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+
+		// remember current state (to be able to roll back the applied settings on failure)
+		oldFirewallEnabled, _, _, _, err := p._service.KillSwitchState()
+		if err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		oldObfsproxy := p._service.Preferences().IsObfsproxy
+		oldManualDNS := net.ParseIP(dns.GetLastManualDNS())
+
+		// rollback functions of the already applied settings (called in reverse order)
+		rollbackFuncs := []func() error{}
+		rollback := func() {
+			for i := len(rollbackFuncs) - 1; i >= 0; i-- {
+				if err := rollbackFuncs[i](); err != nil {
+					log.ErrorTrace(err)
+				}
+			}
+		}
+
+		applySettings := func() error {
+			if isEnable, isDefined, err := parseOnOffValue(req.Firewall); err != nil {
+				return fmt.Errorf("unacceptable 'Firewall' value: %w", err)
+			} else if isDefined && isEnable != oldFirewallEnabled {
+				if err := p._service.SetKillSwitchState(isEnable); err != nil {
+					return fmt.Errorf("failed to apply firewall state: %w", err)
+				}
+				rollbackFuncs = append(rollbackFuncs, func() error { return p._service.SetKillSwitchState(oldFirewallEnabled) })
+			}
+
+			if isEnable, isDefined, err := parseOnOffValue(req.Obfsproxy); err != nil {
+				return fmt.Errorf("unacceptable 'Obfsproxy' value: %w", err)
+			} else if isDefined && isEnable != oldObfsproxy {
+				if err := p._service.SetPreference("enable_obfsproxy", strconv.FormatBool(isEnable)); err != nil {
+					return fmt.Errorf("failed to apply obfsproxy state: %w", err)
+				}
+				rollbackFuncs = append(rollbackFuncs, func() error { return p._service.SetPreference("enable_obfsproxy", strconv.FormatBool(oldObfsproxy)) })
+			}
+
+			if len(req.ManualDNS) > 0 {
+				var err error
+				if ip := net.ParseIP(req.ManualDNS); ip == nil || ip.Equal(net.IPv4zero) {
+					err = p._service.ResetManualDNS()
+				} else {
+					err = p._service.SetManualDNS(ip)
+				}
+				if err != nil {
+					return fmt.Errorf("failed to apply DNS settings: %w", err)
+				}
+				rollbackFuncs = append(rollbackFuncs, func() error {
+					if oldManualDNS == nil || oldManualDNS.Equal(net.IPv4zero) {
+						return p._service.ResetManualDNS()
+					}
+					return p._service.SetManualDNS(oldManualDNS)
+				})
+			}
+
+			return nil
+		}
+
+		if err := applySettings(); err != nil {
+			rollback()
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+
+		// process the connection parameters in same way as a 'Connect' request from a client
+		req.Params.Command = "Connect"
+		req.Params.Idx = req.Idx
+		connectParams, err := json.Marshal(req.Params)
+		if err != nil {
+			rollback()
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.processRequest(conn, string(connectParams))
+
+		// the 'Connect' request is processed synchronously: when connection failed - restore settings
+		if p._service.Connected() == false {
+			log.Info("Connection failed. Rolling back settings applied by 'ConnectWithSettings' request.")
+			rollback()
+		}
+		break
+
+	case "SetOpenVPNCredentials":
+		var req types.SetOpenVPNCredentials
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+
+		if err := p._service.SetOpenVPNCredentials(req.Username, req.Password); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.EmptyResp{}, req.Idx)
+		break
+
+	case "SetOpenVPNTlsCryptV2Key":
+		var req types.SetOpenVPNTlsCryptV2Key
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+
+		if err := p._service.SetOpenVPNTlsCryptV2Key(req.Key); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.EmptyResp{}, req.Idx)
+		break
+
 	case "Disconnect":
 		p._disconnectRequested = true
 
@@ -813,7 +1763,7 @@ func (p *Protocol) processRequest(conn net.Conn, message string) {
 		}
 		break
 
-	case "Connect":
+	case "Connect", "SwitchServer":
 		p._disconnectRequested = false
 		requestTime := p.vpnConnectReqCounterIncrease()
 
@@ -822,6 +1772,11 @@ func (p *Protocol) processRequest(conn net.Conn, message string) {
 		disconnectAuthError := false
 		var connectionError error
 
+		// server switch: keep firewall protection engaged between the connections
+		if reqCmd.Command == "SwitchServer" {
+			p._service.StartServerSwitch()
+		}
+
 		// disconnect active connection (if connected)
 		if err := p._service.Disconnect(); err != nil {
 			log.ErrorTrace(err)
@@ -935,6 +1890,19 @@ func (p *Protocol) processRequest(conn net.Conn, message string) {
 		// SYNCHRONOUSLY start VPN connection process (wait until it finished)
 		if connectionError = p.processConnectRequest(messageData, stateChan); connectionError != nil {
 			log.ErrorTrace(connectionError)
+
+			// WireGuard connection failed (e.g. UDP is blocked by the network):
+			// retry with OpenVPN over TCP 443 on the same location (when enabled by preferences)
+			if p._disconnectRequested == false {
+				if fallbackData, fallbackInfo, ok := p.prepareWgToOpenvpnFallback(messageData); ok {
+					log.Info(fallbackInfo)
+					stateChan <- vpn.StateInfo{State: vpn.RECONNECTING, StateAdditionalInfo: fallbackInfo}
+
+					if connectionError = p.processConnectRequest(fallbackData, stateChan); connectionError != nil {
+						log.ErrorTrace(connectionError)
+					}
+				}
+			}
 		}
 
 		break
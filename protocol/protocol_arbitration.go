@@ -0,0 +1,180 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package protocol
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/ivpn/desktop-app-daemon/protocol/types"
+)
+
+// Multi-client arbitration
+// When several UI\CLI clients are attached to the daemon, conflicting state-changing commands can interleave.
+// Arbitration rules:
+//	- a client can acquire the exclusive control lock ('ControlLockAcquire'):
+//	  while the lock is held, state-changing commands of other clients are rejected
+//	- a client can declare itself as a read-only observer ('Hello' request):
+//	  all state-changing commands of such connection are rejected
+//	- other clients are notified about who initiated a state change (see 'ClientActivityResp')
+
+// registerClientInfo saves arbitration-related info about a client connection (provided by 'Hello' request)
+func (p *Protocol) registerClientInfo(c net.Conn, clientName string, isObserver bool, schemaVersion int) {
+	p._arbitrationMutex.Lock()
+	defer p._arbitrationMutex.Unlock()
+
+	if p._connClientNames == nil {
+		p._connClientNames = make(map[net.Conn]string)
+	}
+	p._connClientNames[c] = clientName
+
+	if p._connSchemaVersions == nil {
+		p._connSchemaVersions = make(map[net.Conn]int)
+	}
+	p._connSchemaVersions[c] = schemaVersion
+
+	if isObserver {
+		if p._observerConns == nil {
+			p._observerConns = make(map[net.Conn]struct{})
+		}
+		p._observerConns[c] = struct{}{}
+	} else {
+		delete(p._observerConns, c)
+	}
+}
+
+// forgetClientInfo erases arbitration info of a disconnected client
+// (the exclusive control lock is released when it was held by this client)
+func (p *Protocol) forgetClientInfo(c net.Conn) {
+	p._arbitrationMutex.Lock()
+	defer p._arbitrationMutex.Unlock()
+
+	if p._controlLockOwner == c {
+		log.Info("Exclusive control lock released (client disconnected)")
+		p._controlLockOwner = nil
+	}
+	delete(p._connClientNames, c)
+	delete(p._observerConns, c)
+	delete(p._connSchemaVersions, c)
+}
+
+// isObserverConnection returns true when the client declared itself as a read-only observer
+func (p *Protocol) isObserverConnection(c net.Conn) bool {
+	p._arbitrationMutex.Lock()
+	defer p._arbitrationMutex.Unlock()
+	_, isObserver := p._observerConns[c]
+	return isObserver
+}
+
+// clientName returns name of a client (defined by 'Hello' request;
+// address of the connection when the name was not defined)
+func (p *Protocol) clientName(c net.Conn) string {
+	p._arbitrationMutex.Lock()
+	defer p._arbitrationMutex.Unlock()
+	return p.clientNameNoLock(c)
+}
+
+func (p *Protocol) clientNameNoLock(c net.Conn) string {
+	if name := p._connClientNames[c]; len(name) > 0 {
+		return name
+	}
+	if c != nil {
+		return c.RemoteAddr().String()
+	}
+	return ""
+}
+
+// acquireControlLock tries to acquire the exclusive control lock for a connection
+// (acquiring an already owned lock succeeds; 'ownerName' informs who holds the lock)
+func (p *Protocol) acquireControlLock(c net.Conn) (isAcquired bool, ownerName string) {
+	p._arbitrationMutex.Lock()
+	defer p._arbitrationMutex.Unlock()
+
+	if p._controlLockOwner != nil && p._controlLockOwner != c {
+		return false, p.clientNameNoLock(p._controlLockOwner)
+	}
+
+	p._controlLockOwner = c
+	return true, p.clientNameNoLock(c)
+}
+
+// releaseControlLock releases the exclusive control lock (when held by this connection)
+func (p *Protocol) releaseControlLock(c net.Conn) (wasHeld bool) {
+	p._arbitrationMutex.Lock()
+	defer p._arbitrationMutex.Unlock()
+
+	if p._controlLockOwner != c {
+		return false
+	}
+	p._controlLockOwner = nil
+	return true
+}
+
+// checkClientArbitration returns an error when a state-changing command of this connection must be rejected
+// (connection is a read-only observer or the exclusive control lock is held by another client)
+func (p *Protocol) checkClientArbitration(c net.Conn) error {
+	p._arbitrationMutex.Lock()
+	defer p._arbitrationMutex.Unlock()
+
+	if _, isObserver := p._observerConns[c]; isObserver {
+		return fmt.Errorf("command rejected: client is in read-only observer mode")
+	}
+	if p._controlLockOwner != nil && p._controlLockOwner != c {
+		return fmt.Errorf("command rejected: exclusive control lock is held by '%s'", p.clientNameNoLock(p._controlLockOwner))
+	}
+	return nil
+}
+
+// isStateChangingCommand returns true for commands which modify daemon state
+// (arbitration does not apply to read-only commands)
+func isStateChangingCommand(command string) bool {
+	if requiredCapability[command] == CapabilityStatus {
+		return false
+	}
+	switch command {
+	case "Hello", "SetEventsSubscription", "GenerateCapabilityToken",
+		"APIRequest", "APIRequestStream", "ExportSettings",
+		"ControlLockAcquire", "ControlLockRelease":
+		return false
+	}
+	return true
+}
+
+// notifyClientActivity informs other connected clients about a state-changing command initiated by a client
+func (p *Protocol) notifyClientActivity(initiator net.Conn, command string) {
+	notification := &types.ClientActivityResp{ClientName: p.clientName(initiator), InitiatedCommand: command}
+	notificationTypeName := types.GetTypeName(notification)
+
+	p._connectionsMutex.RLock()
+	defer p._connectionsMutex.RUnlock()
+	for conn := range p._connections {
+		if conn == initiator {
+			continue
+		}
+		// skip notification if client subscribed only to specific event classes
+		if p.isEventSubscribed(conn, notificationTypeName) == false {
+			continue
+		}
+		p.sendResponse(conn, notification, 0)
+	}
+}
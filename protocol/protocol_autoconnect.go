@@ -0,0 +1,92 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package protocol
+
+// autoConnectOnLaunch restores the last VPN connection on daemon start
+// (has effect only when 'IsAutoconnectOnLaunch' preference enabled)
+func (p *Protocol) autoConnectOnLaunch() {
+	if p._service.Preferences().IsAutoconnectOnLaunch == false {
+		return
+	}
+	p.autoConnect("daemon start")
+}
+
+// restoreStateOnLaunch restores daemon state saved on previous graceful stop:
+// re-enables firewall and reconnects VPN (if they were active when daemon stopped)
+// (has effect only when 'IsRestoreStateOnStart' preference enabled)
+func (p *Protocol) restoreStateOnLaunch() {
+	prefs := p._service.Preferences()
+	if prefs.IsRestoreStateOnStart == false {
+		return
+	}
+
+	if prefs.LastStopStateFirewallEnabled {
+		log.Info("State restoration: enabling firewall (was enabled on previous daemon stop)")
+		if err := p._service.SetKillSwitchState(true); err != nil {
+			log.Error("State restoration: failed to enable firewall: ", err)
+		}
+	}
+
+	if prefs.LastStopStateVpnConnected {
+		go p.autoConnect("state restoration (was connected on previous daemon stop)")
+	}
+}
+
+// autoConnectOnUntrustedNetwork establishes VPN connection when an insecure WiFi network detected
+// (has effect only when 'IsAutoconnectOnUntrustedNetwork' preference enabled)
+func (p *Protocol) autoConnectOnUntrustedNetwork() {
+	if p._service.Preferences().IsAutoconnectOnUntrustedNetwork == false {
+		return
+	}
+	p.autoConnect("untrusted WiFi network")
+}
+
+// autoConnect restores the last known connection (saved parameters of the last 'Connect' request)
+func (p *Protocol) autoConnect(reason string) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error("PANIC on autoconnect: ", r)
+		}
+	}()
+
+	prefs := p._service.Preferences()
+
+	if prefs.Session.IsLoggedIn() == false {
+		log.Info("Autoconnect skipped (not logged in)")
+		return
+	}
+
+	if p._service.Connected() {
+		return // already connected - nothing to do
+	}
+
+	connectionParams := prefs.LastConnectionParams
+	if len(connectionParams) == 0 {
+		log.Info("Autoconnect skipped (no saved connection parameters)")
+		return
+	}
+
+	log.Info("Autoconnect: ", reason)
+	// process saved 'Connect' request in same way as a request from a client
+	p.processRequest(nil, connectionParams)
+}
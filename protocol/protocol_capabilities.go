@@ -0,0 +1,200 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package protocol
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+)
+
+// Capability names of the local API
+// A client authenticated by a capability token (instead of the daemon secret)
+// has access only to commands covered by capabilities of its token
+const (
+	// CapabilityStatus - read-only information (VPN state, servers, kill-switch status ...)
+	CapabilityStatus = "status"
+	// CapabilityConnect - connection management (Connect, Disconnect, Pause, Resume)
+	CapabilityConnect = "connect"
+	// CapabilitySettings - daemon settings modification (preferences, kill-switch, DNS ...)
+	CapabilitySettings = "settings"
+	// CapabilityAccount - account\session management (login, logout, WG keys ...)
+	CapabilityAccount = "account"
+)
+
+// requiredCapability maps command name to the capability required to execute it
+// (commands not mentioned here are allowed only for full-access clients)
+var requiredCapability = map[string]string{
+	"GetVPNState":               CapabilityStatus,
+	"GetServers":                CapabilityStatus,
+	"PingServers":               CapabilityStatus,
+	"PingServersEx":             CapabilityStatus,
+	"KillSwitchGetStatus":       CapabilityStatus,
+	"KillSwitchGetIsPestistent": CapabilityStatus,
+	"WiFiAvailableNetworks":     CapabilityStatus,
+	"WiFiCurrentNetwork":        CapabilityStatus,
+	"GenerateDiagnostics":       CapabilityStatus,
+	"GetRecentLogs":             CapabilityStatus,
+	"GenerateDiagnosticBundle":  CapabilityStatus,
+	"RunSelfTest":               CapabilityStatus,
+	"GetDaemonHealth":           CapabilityStatus,
+	"GeoLookup":                 CapabilityStatus,
+	"ProfileList":               CapabilityStatus,
+	"SchedulerGetRules":         CapabilityStatus,
+	"GetConnectionHistory":      CapabilityStatus,
+	"GetConnectionDiagnostics":  CapabilityStatus,
+	"LanServicesDiscover":       CapabilityStatus,
+	"GetBandwidthStats":         CapabilityStatus,
+	"GetPauseState":             CapabilityStatus,
+	"GetServersMetadata":        CapabilityStatus,
+	"OvpnProfileList":           CapabilityStatus,
+
+	"Connect":            CapabilityConnect,
+	"SwitchServer":       CapabilityConnect,
+	"Disconnect":         CapabilityConnect,
+	"PauseConnection":    CapabilityConnect,
+	"ResumeConnection":   CapabilityConnect,
+	"PortForwardRequest": CapabilityConnect,
+	"PortForwardRelease": CapabilityConnect,
+	"ConnectProfile":     CapabilityConnect,
+	// supplying new credentials is part of the connection flow
+	"SetOpenVPNCredentials": CapabilityConnect,
+	// 'ConnectWithSettings' modifies settings too, but only as part of the connection setup
+	"ConnectWithSettings": CapabilityConnect,
+	"ControlLockAcquire":  CapabilityConnect,
+	"ControlLockRelease":  CapabilityConnect,
+
+	"SetPreference":                    CapabilitySettings,
+	"ProfileSave":                      CapabilitySettings,
+	"ProfileDelete":                    CapabilitySettings,
+	"SetServerMetadata":                CapabilitySettings,
+	"OvpnProfileImport":                CapabilitySettings,
+	"OvpnProfileDelete":                CapabilitySettings,
+	"SchedulerSetRules":                CapabilitySettings,
+	"SetLoggerConfig":                  CapabilitySettings,
+	"SetModulesDebug":                  CapabilitySettings,
+	"ClearConnectionHistory":           CapabilitySettings,
+	"ClearConnectionDiagnostics":       CapabilitySettings,
+	"LanServiceSetAllowed":             CapabilitySettings,
+	"ResetBandwidthStats":              CapabilitySettings,
+	"SetAlternateDns":                  CapabilitySettings,
+	"KillSwitchSetEnabled":             CapabilitySettings,
+	"CaptivePortalBypass":              CapabilitySettings,
+	"KillSwitchSetAllowLAN":            CapabilitySettings,
+	"KillSwitchSetAllowLANMulticast":   CapabilitySettings,
+	"KillSwitchSetAllowLANTrustedOnly": CapabilitySettings,
+	"KillSwitchSetAllowLANSubnets":     CapabilitySettings,
+	"KillSwitchSetIsPersistent":        CapabilitySettings,
+	"CleanupNetworkArtifacts":          CapabilitySettings,
+
+	"SessionNew":                       CapabilityAccount,
+	"AccountCreate":                    CapabilityAccount,
+	"SessionDelete":                    CapabilityAccount,
+	"AccountStatus":                    CapabilityAccount,
+	"WireGuardGenerateNewKeys":         CapabilityAccount,
+	"SetOpenVPNTlsCryptV2Key":          CapabilityAccount,
+	"WireGuardSetKeysRotationInterval": CapabilityAccount,
+}
+
+// generateCapabilityToken creates new token restricted to the defined capabilities
+func (p *Protocol) generateCapabilityToken(capabilities []string) (token string, err error) {
+	for _, c := range capabilities {
+		switch c {
+		case CapabilityStatus, CapabilityConnect, CapabilitySettings, CapabilityAccount:
+		default:
+			return "", fmt.Errorf("unknown capability: '%s'", c)
+		}
+	}
+	if len(capabilities) == 0 {
+		return "", fmt.Errorf("capabilities not defined")
+	}
+
+	tokenBytes := make([]byte, 16)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	token = hex.EncodeToString(tokenBytes)
+
+	p._capabilityTokensMutex.Lock()
+	defer p._capabilityTokensMutex.Unlock()
+	if p._capabilityTokens == nil {
+		p._capabilityTokens = make(map[string][]string)
+	}
+	p._capabilityTokens[token] = capabilities
+
+	return token, nil
+}
+
+// capabilitiesByToken returns capabilities of a token (ok==false when token not known)
+func (p *Protocol) capabilitiesByToken(token string) (capabilities []string, ok bool) {
+	if len(token) == 0 {
+		return nil, false
+	}
+	p._capabilityTokensMutex.Lock()
+	defer p._capabilityTokensMutex.Unlock()
+	capabilities, ok = p._capabilityTokens[token]
+	return capabilities, ok
+}
+
+// setConnectionCapabilities stores capabilities of a restricted connection
+// (connections authenticated by the daemon secret have no record here: full access)
+func (p *Protocol) setConnectionCapabilities(c net.Conn, capabilities []string) {
+	p._connectionsMutex.Lock()
+	defer p._connectionsMutex.Unlock()
+	if p._connCapabilities == nil {
+		p._connCapabilities = make(map[net.Conn][]string)
+	}
+	p._connCapabilities[c] = capabilities
+}
+
+// isConnectionRestricted returns true for connections authenticated by a capability token
+func (p *Protocol) isConnectionRestricted(c net.Conn) bool {
+	p._connectionsMutex.RLock()
+	defer p._connectionsMutex.RUnlock()
+	_, isRestricted := p._connCapabilities[c]
+	return isRestricted
+}
+
+// isCommandAllowed checks if a connection is allowed to execute a command
+func (p *Protocol) isCommandAllowed(c net.Conn, command string) bool {
+	p._connectionsMutex.RLock()
+	capabilities, isRestricted := p._connCapabilities[c]
+	p._connectionsMutex.RUnlock()
+
+	if isRestricted == false {
+		return true // full access (authenticated by the daemon secret)
+	}
+
+	required, ok := requiredCapability[command]
+	if ok == false {
+		return false // commands without capability mapping are for full-access clients only
+	}
+
+	for _, c := range capabilities {
+		if c == required {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+
+	apitypes "github.com/ivpn/desktop-app-daemon/api/types"
+	"github.com/ivpn/desktop-app-daemon/protocol/types"
+	"github.com/ivpn/desktop-app-daemon/vpn"
+)
+
+// Automatic WireGuard->OpenVPN fallback for blocked networks
+// (optional functionality; can be enabled by the 'wg_to_openvpn_fallback' preference).
+// When a WireGuard connection fails (e.g. UDP is blocked by the network), the daemon
+// retries with OpenVPN over TCP 443 on the same location and reports the substitution to clients.
+
+const (
+	_fallbackOpenvpnPort     = 443
+	_fallbackOpenvpnProtocol = 1 // TCP (see 'Connect.OpenVpnParameters.Port.Protocol')
+)
+
+// prepareWgToOpenvpnFallback builds an OpenVPN variant (TCP 443, same location)
+// of a failed WireGuard 'Connect' request (ok == false - fallback is not applicable)
+func (p *Protocol) prepareWgToOpenvpnFallback(messageData []byte) (fallbackData []byte, info string, ok bool) {
+	if p._service.Preferences().IsWgToOpenvpnFallback == false {
+		return nil, "", false
+	}
+
+	var r types.Connect
+	if err := json.Unmarshal(messageData, &r); err != nil {
+		return nil, "", false
+	}
+	if vpn.Type(r.VpnType) != vpn.WireGuard {
+		return nil, "", false
+	}
+	// user-provided configurations: exact connection parameters must be honored
+	if len(r.WireGuardParameters.CustomConfig) > 0 {
+		return nil, "", false
+	}
+
+	wgHosts := r.WireGuardParameters.EntryVpnServer.Hosts
+	if len(wgHosts) == 0 {
+		return nil, "", false
+	}
+
+	servers, err := p._service.ServersList()
+	if err != nil || servers == nil {
+		return nil, "", false
+	}
+
+	// location of the requested WireGuard server
+	countryCode := ""
+	city := ""
+	for _, srv := range servers.WireguardServers {
+		for _, host := range srv.Hosts {
+			for _, reqHost := range wgHosts {
+				if host.Host == reqHost.Host {
+					countryCode = srv.CountryCode
+					city = srv.City
+				}
+			}
+		}
+	}
+	if len(countryCode) == 0 {
+		return nil, "", false
+	}
+
+	// OpenVPN server of the same location
+	var ovpnServer *apitypes.OpenvpnServerInfo
+	for i, srv := range servers.OpenvpnServers {
+		if srv.CountryCode == countryCode && srv.City == city {
+			ovpnServer = &servers.OpenvpnServers[i]
+			break
+		}
+	}
+	if ovpnServer == nil || len(ovpnServer.IPAddresses) == 0 {
+		return nil, "", false
+	}
+
+	fallback := types.Connect{
+		VpnType:                    vpn.OpenVPN,
+		CurrentDNS:                 r.CurrentDNS,
+		FirewallOn:                 r.FirewallOn,
+		FirewallOnDuringConnection: r.FirewallOnDuringConnection}
+	fallback.OpenVpnParameters.EntryVpnServer.IPAddresses = ovpnServer.IPAddresses
+	fallback.OpenVpnParameters.Port.Port = _fallbackOpenvpnPort
+	fallback.OpenVpnParameters.Port.Protocol = _fallbackOpenvpnProtocol
+
+	data, err := json.Marshal(&fallback)
+	if err != nil {
+		return nil, "", false
+	}
+
+	info = fmt.Sprintf("WireGuard connection failed; retrying with OpenVPN '%s' (TCP %d)", ovpnServer.Gateway, _fallbackOpenvpnPort)
+	return data, info, true
+}
@@ -23,8 +23,10 @@
 package protocol
 
 import (
+	"encoding/json"
 	"net"
 
+	"github.com/ivpn/desktop-app-daemon/geoip"
 	"github.com/ivpn/desktop-app-daemon/protocol/types"
 	"github.com/ivpn/desktop-app-daemon/service/preferences"
 	"github.com/ivpn/desktop-app-daemon/version"
@@ -72,22 +74,143 @@ func (p *Protocol) OnKillSwitchStateChanged() {
 	if isEnabled, isPersistant, isAllowLAN, isAllowLanMulticast, err := p._service.KillSwitchState(); err != nil {
 		log.Error(err)
 	} else {
-		p.notifyClients(&types.KillSwitchStatusResp{IsEnabled: isEnabled, IsPersistent: isPersistant, IsAllowLAN: isAllowLAN, IsAllowMulticast: isAllowLanMulticast})
+		prefs := p._service.Preferences()
+		p.notifyClients(&types.KillSwitchStatusResp{IsEnabled: isEnabled, IsPersistent: isPersistant, IsAllowLAN: isAllowLAN, IsAllowMulticast: isAllowLanMulticast, IsAllowLANTrustedOnly: prefs.IsFwAllowLANTrustedOnly, AllowLANSubnets: prefs.FwAllowLANSubnets})
 	}
 }
 
+// OnFirewallTampered - handler of firewall tampering detection. Notifying clients.
+func (p *Protocol) OnFirewallTampered(rulesReApplied bool) {
+	p.notifyClients(&types.FirewallTamperedResp{RulesReApplied: rulesReApplied})
+	// the actual kill-switch status could be changed by the restoration
+	p.OnKillSwitchStateChanged()
+}
+
 // OnWiFiChanged - handler of WiFi status change. Notifying clients.
-func (p *Protocol) OnWiFiChanged(ssid string, isInsecureNetwork bool) {
+func (p *Protocol) OnWiFiChanged(ssid string, bssid string, isInsecureNetwork bool) {
 	p.notifyClients(&types.WiFiCurrentNetworkResp{
 		SSID:              ssid,
+		BSSID:             bssid,
 		IsInsecureNetwork: isInsecureNetwork})
+
+	if isInsecureNetwork {
+		// connect automatically (if 'IsAutoconnectOnUntrustedNetwork' preference enabled)
+		go p.autoConnectOnUntrustedNetwork()
+	}
 }
 
-// OnPingStatus - servers ping status
-func (p *Protocol) OnPingStatus(retMap map[string]int) {
+// OnOperationProgress - handler of long operation progress. Notifying clients.
+func (p *Protocol) OnOperationProgress(operationID string, operation string, phase string, progress int) {
+	p.notifyClients(&types.OperationProgressResp{
+		OperationID: operationID,
+		Operation:   operation,
+		Phase:       phase,
+		Progress:    progress})
+}
+
+// OnPortForwardChanged - handler of port-forwarding state change. Notifying clients.
+func (p *Protocol) OnPortForwardChanged(port int, expiresAt int64) {
+	p.notifyClients(&types.PortForwardResp{Port: port, ExpiresAt: expiresAt})
+}
+
+// OnBandwidthAlert - handler of bandwidth usage alert. Notifying clients.
+func (p *Protocol) OnBandwidthAlert(usedBytes int64, capBytes int64, thresholdPercent int) {
+	p.notifyClients(&types.BandwidthAlertResp{
+		UsedBytes:        usedBytes,
+		CapBytes:         capBytes,
+		ThresholdPercent: thresholdPercent})
+}
+
+// OnPauseStateChanged - handler of paused\resumed connection state change. Notifying clients.
+func (p *Protocol) OnPauseStateChanged(isPaused bool, autoResumeAt int64) {
+	p.notifyClients(&types.PauseStateResp{IsPaused: isPaused, AutoResumeAt: autoResumeAt})
+}
+
+// OnConnectionQuality - handler of in-tunnel latency\jitter measurement results. Notifying clients.
+func (p *Protocol) OnConnectionQuality(latencyMs int, jitterMs int) {
+	p.notifyClients(&types.ConnectionQualityResp{LatencyMs: latencyMs, JitterMs: jitterMs})
+}
+
+// OnBetterServerAvailable - handler of better-server advisory. Notifying clients.
+// When daemon decided to migrate by itself ('isAutoMigrate == true') - performs server switch
+// based on the saved parameters of the last 'Connect' request.
+func (p *Protocol) OnBetterServerAvailable(gateway string, score float64, currentGateway string, currentScore float64, isAutoMigrate bool) {
+	p.notifyClients(&types.BetterServerAvailableResp{
+		Gateway:        gateway,
+		Score:          score,
+		CurrentGateway: currentGateway,
+		CurrentScore:   currentScore,
+		IsAutoMigrate:  isAutoMigrate})
+
+	if isAutoMigrate == false {
+		return
+	}
+
+	connectionParams := p._service.Preferences().LastConnectionParams
+	if len(connectionParams) == 0 {
+		log.Warning("Better-server migration skipped (no saved connection parameters)")
+		return
+	}
+
+	// replay the last connection request as a 'SwitchServer' (firewall protection stays engaged)
+	// with the entry server overridden by the daemon-side "fastest server" choice
+	var req types.SwitchServer
+	if err := json.Unmarshal([]byte(connectionParams), &req); err != nil {
+		log.Warning("Better-server migration skipped (unable to parse saved connection parameters)")
+		return
+	}
+	req.Command = "SwitchServer"
+	req.FastestServer = true
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		return
+	}
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Error("PANIC on better-server migration: ", r)
+			}
+		}()
+
+		log.Info("Migrating to a better server: ", gateway)
+		// process the request in same way as a request from a client
+		p.processRequest(nil, string(data))
+	}()
+}
+
+// OnCaptivePortalDetected - handler of captive portal detection. Notifying clients.
+func (p *Protocol) OnCaptivePortalDetected() {
+	p.notifyClients(&types.CaptivePortalResp{IsDetected: true})
+}
+
+// OnCaptivePortalBypass - handler of started\finished captive portal bypass. Notifying clients.
+func (p *Protocol) OnCaptivePortalBypass(isActive bool, expiresAt int64) {
+	p.notifyClients(&types.CaptivePortalBypassResp{IsActive: isActive, ExpiresAt: expiresAt})
+
+	if isActive == false {
+		// protection is restored: re-establish the VPN connection
+		go p.autoConnect("captive portal bypass finished")
+	}
+}
+
+// pingResultsFromMap converts ping results into response format
+// (hosts are annotated with country info from the local GeoIP dataset, when available)
+func pingResultsFromMap(retMap map[string]int) []types.PingResultType {
 	var results []types.PingResultType
 	for k, v := range retMap {
-		results = append(results, types.PingResultType{Host: k, Ping: v})
+		result := types.PingResultType{Host: k, Ping: v}
+		if location, ok := geoip.LookupHost(k); ok {
+			result.CountryCode = location.CountryCode
+			result.Country = location.Country
+		}
+		results = append(results, result)
 	}
-	p.notifyClients(&types.PingServersResp{PingResults: results})
+	return results
+}
+
+// OnPingStatus - servers ping status
+func (p *Protocol) OnPingStatus(retMap map[string]int) {
+	p.notifyClients(&types.PingServersResp{PingResults: pingResultsFromMap(retMap)})
 }
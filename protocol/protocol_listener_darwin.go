@@ -0,0 +1,39 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package protocol
+
+import (
+	"fmt"
+	"net"
+)
+
+// implCreateAlternateListener starts listener of the platform-specific alternate transport
+// (alternate transport not implemented for macOS: clients use TCP on localhost)
+func implCreateAlternateListener() (net.Listener, error) {
+	return nil, nil
+}
+
+// implGetConnectionPeerPID returns PID of the process connected over the alternate transport
+func implGetConnectionPeerPID(conn net.Conn) (int, error) {
+	return 0, fmt.Errorf("unable to determine peer process (transport not supported)")
+}
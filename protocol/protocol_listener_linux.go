@@ -0,0 +1,67 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package protocol
+
+import (
+	"fmt"
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// alternate transport for Linux: Unix-domain socket in the abstract namespace
+// (no filesystem presence: no socket-file cleanup or permissions dance;
+// clients connect by the well-known name instead of reading TCP port info file)
+const _abstractSocketName = "@ivpn-daemon"
+
+// implCreateAlternateListener starts listener of the platform-specific alternate transport
+func implCreateAlternateListener() (net.Listener, error) {
+	return net.Listen("unix", _abstractSocketName)
+}
+
+// implGetConnectionPeerPID returns PID of the process connected over the alternate transport
+// (peer process is authenticated by SO_PEERCRED credentials of the Unix-domain socket)
+func implGetConnectionPeerPID(conn net.Conn) (int, error) {
+	unixConn, ok := conn.(*net.UnixConn)
+	if ok == false {
+		return 0, fmt.Errorf("unable to determine peer process (unexpected connection type)")
+	}
+
+	rawConn, err := unixConn.SyscallConn()
+	if err != nil {
+		return 0, fmt.Errorf("unable to access raw connection: %w", err)
+	}
+
+	var ucred *unix.Ucred
+	var ucredErr error
+	if err := rawConn.Control(func(fd uintptr) {
+		ucred, ucredErr = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	}); err != nil {
+		return 0, fmt.Errorf("unable to read peer credentials: %w", err)
+	}
+	if ucredErr != nil {
+		return 0, fmt.Errorf("unable to read peer credentials: %w", ucredErr)
+	}
+
+	return int(ucred.Pid), nil
+}
@@ -0,0 +1,228 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package protocol
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// alternate transport for Windows: named pipe
+// (clients connect by the well-known pipe name instead of reading TCP port info file;
+// peer process is authenticated by PID of the pipe client)
+const _pipePath = `\\.\pipe\IVPN-daemon`
+
+var (
+	_kernel32Dll                  = syscall.NewLazyDLL("kernel32.dll")
+	_fCreateNamedPipeW            = _kernel32Dll.NewProc("CreateNamedPipeW")
+	_fConnectNamedPipe            = _kernel32Dll.NewProc("ConnectNamedPipe")
+	_fGetNamedPipeClientProcessId = _kernel32Dll.NewProc("GetNamedPipeClientProcessId")
+	_fLocalFree                   = _kernel32Dll.NewProc("LocalFree")
+
+	_advapi32Dll                                           = syscall.NewLazyDLL("advapi32.dll")
+	_fConvertStringSecurityDescriptorToSecurityDescriptorW = _advapi32Dll.NewProc("ConvertStringSecurityDescriptorToSecurityDescriptorW")
+)
+
+const (
+	_pipeAccessDuplex          = 0x00000003
+	_fileFlagFirstPipeInstance = 0x00080000
+	_pipeTypeByte              = 0x00000000
+	_pipeWait                  = 0x00000000
+	_pipeUnlimitedInstances    = 255
+	_pipeBufferSize            = 64 * 1024
+	_invalidHandleValue        = ^uintptr(0)
+	_errorPipeConnected        = syscall.Errno(535) // ERROR_PIPE_CONNECTED
+	_sddlRevision1             = 1
+
+	// DACL of the pipe: full access for SYSTEM and Administrators;
+	// read\write (connect) for authenticated users (client UI applications)
+	_pipeSecurityDescriptor = "D:P(A;;GA;;;SY)(A;;GA;;;BA)(A;;GRGW;;;AU)"
+)
+
+// securityAttributes - SECURITY_ATTRIBUTES structure
+type securityAttributes struct {
+	length             uint32
+	securityDescriptor uintptr
+	inheritHandle      uint32
+}
+
+// createPipeSecurityAttributes initializes SECURITY_ATTRIBUTES with the pipe DACL
+// (returned security descriptor must be released by 'LocalFree')
+func createPipeSecurityAttributes() (*securityAttributes, error) {
+	sddlPtr, err := syscall.UTF16PtrFromString(_pipeSecurityDescriptor)
+	if err != nil {
+		return nil, err
+	}
+
+	var sd uintptr
+	ret, _, callErr := _fConvertStringSecurityDescriptorToSecurityDescriptorW.Call(
+		uintptr(unsafe.Pointer(sddlPtr)),
+		_sddlRevision1,
+		uintptr(unsafe.Pointer(&sd)),
+		0)
+	if ret == 0 {
+		return nil, fmt.Errorf("failed to initialize pipe security descriptor: %w", callErr)
+	}
+
+	sa := &securityAttributes{securityDescriptor: sd}
+	sa.length = uint32(unsafe.Sizeof(*sa))
+	return sa, nil
+}
+
+type pipeAddr struct{}
+
+func (pipeAddr) Network() string { return "pipe" }
+func (pipeAddr) String() string  { return _pipePath }
+
+// pipeConn - net.Conn adapter of a connected named pipe instance
+type pipeConn struct {
+	file   *os.File
+	handle syscall.Handle
+}
+
+func (c *pipeConn) Read(b []byte) (int, error)         { return c.file.Read(b) }
+func (c *pipeConn) Write(b []byte) (int, error)        { return c.file.Write(b) }
+func (c *pipeConn) Close() error                       { return c.file.Close() }
+func (c *pipeConn) LocalAddr() net.Addr                { return pipeAddr{} }
+func (c *pipeConn) RemoteAddr() net.Addr               { return pipeAddr{} }
+func (c *pipeConn) SetDeadline(t time.Time) error      { return nil }
+func (c *pipeConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *pipeConn) SetWriteDeadline(t time.Time) error { return nil }
+
+// pipeListener - net.Listener adapter of a named pipe server
+type pipeListener struct {
+	mutex    sync.Mutex
+	isClosed bool
+	// pipe instance waiting for a client (closed by Close() to abort pending Accept())
+	pendingHandle syscall.Handle
+	// true after the first pipe instance was created successfully
+	isFirstInstanceCreated bool
+}
+
+func (l *pipeListener) Accept() (net.Conn, error) {
+	pathPtr, err := syscall.UTF16PtrFromString(_pipePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// explicit DACL on the pipe (default descriptor is too permissive)
+	sa, err := createPipeSecurityAttributes()
+	if err != nil {
+		return nil, err
+	}
+	defer _fLocalFree.Call(sa.securityDescriptor)
+
+	// FILE_FLAG_FIRST_PIPE_INSTANCE on the first instance: fail when the pipe name
+	// is already taken (protection from impersonation of the daemon by another local process)
+	openMode := uintptr(_pipeAccessDuplex)
+	if l.isFirstInstanceCreated == false {
+		openMode |= _fileFlagFirstPipeInstance
+	}
+
+	ret, _, callErr := _fCreateNamedPipeW.Call(
+		uintptr(unsafe.Pointer(pathPtr)),
+		openMode,
+		_pipeTypeByte|_pipeWait,
+		_pipeUnlimitedInstances,
+		_pipeBufferSize,
+		_pipeBufferSize,
+		0,
+		uintptr(unsafe.Pointer(sa)))
+	if ret == _invalidHandleValue {
+		if l.isFirstInstanceCreated == false {
+			return nil, fmt.Errorf("failed to create named pipe instance (pipe name can be already taken by another process): %w", callErr)
+		}
+		return nil, fmt.Errorf("failed to create named pipe instance: %w", callErr)
+	}
+	handle := syscall.Handle(ret)
+	l.isFirstInstanceCreated = true
+
+	l.mutex.Lock()
+	if l.isClosed {
+		l.mutex.Unlock()
+		syscall.CloseHandle(handle)
+		return nil, fmt.Errorf("listener closed")
+	}
+	l.pendingHandle = handle
+	l.mutex.Unlock()
+
+	// wait for a client
+	// (ERROR_PIPE_CONNECTED - client connected between CreateNamedPipe and ConnectNamedPipe calls: not an error)
+	connectRet, _, callErr := _fConnectNamedPipe.Call(uintptr(handle), 0)
+
+	l.mutex.Lock()
+	l.pendingHandle = syscall.InvalidHandle
+	isClosed := l.isClosed
+	l.mutex.Unlock()
+
+	if isClosed {
+		syscall.CloseHandle(handle)
+		return nil, fmt.Errorf("listener closed")
+	}
+	if connectRet == 0 && callErr != _errorPipeConnected {
+		syscall.CloseHandle(handle)
+		return nil, fmt.Errorf("failed to accept named pipe connection: %w", callErr)
+	}
+
+	return &pipeConn{file: os.NewFile(uintptr(handle), _pipePath), handle: handle}, nil
+}
+
+func (l *pipeListener) Close() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.isClosed = true
+	if l.pendingHandle != syscall.InvalidHandle {
+		// closing the pipe instance aborts the pending ConnectNamedPipe call
+		syscall.CloseHandle(l.pendingHandle)
+		l.pendingHandle = syscall.InvalidHandle
+	}
+	return nil
+}
+
+func (l *pipeListener) Addr() net.Addr { return pipeAddr{} }
+
+// implCreateAlternateListener starts listener of the platform-specific alternate transport
+func implCreateAlternateListener() (net.Listener, error) {
+	return &pipeListener{pendingHandle: syscall.InvalidHandle}, nil
+}
+
+// implGetConnectionPeerPID returns PID of the process connected over the alternate transport
+func implGetConnectionPeerPID(conn net.Conn) (int, error) {
+	pipe, ok := conn.(*pipeConn)
+	if ok == false {
+		return 0, fmt.Errorf("unable to determine peer process (unexpected connection type)")
+	}
+
+	var pid uint32
+	ret, _, callErr := _fGetNamedPipeClientProcessId.Call(uintptr(pipe.handle), uintptr(unsafe.Pointer(&pid)))
+	if ret == 0 {
+		return 0, fmt.Errorf("failed to get named pipe client PID: %w", callErr)
+	}
+	return int(pid), nil
+}
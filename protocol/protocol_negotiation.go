@@ -0,0 +1,84 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package protocol
+
+import (
+	"net"
+
+	"github.com/ivpn/desktop-app-daemon/protocol/types"
+	"github.com/ivpn/desktop-app-daemon/service/platform"
+	"github.com/ivpn/desktop-app-daemon/service/platform/filerights"
+)
+
+// Protocol schema negotiation
+// Daemon and client exchange supported schema versions and feature flags by the 'Hello' request\response
+// (see 'types.ProtocolSchemaVersion'). It allows older UIs to degrade gracefully:
+// daemon does not send notifications of newer schema versions to such connections,
+// client enables only functionality which the daemon build supports.
+
+// minimal required client schema version of the notification types
+// (notification types which are not in this map are applicable to all clients)
+var _notificationMinSchemaVersion = map[string]int{
+	"ClientActivityResp":  1, // multi-client arbitration notifications
+	"ServerListDiffResp":  2, // servers list delta notifications
+	"ServersMetadataResp": 3, // servers metadata notifications
+}
+
+// isNotificationApplicable returns false when the notification type
+// belongs to a newer protocol schema version than supported by the client
+func (p *Protocol) isNotificationApplicable(c net.Conn, notificationTypeName string) bool {
+	minVersion, ok := _notificationMinSchemaVersion[notificationTypeName]
+	if ok == false {
+		return true
+	}
+
+	p._arbitrationMutex.Lock()
+	defer p._arbitrationMutex.Unlock()
+	return p._connSchemaVersions[c] >= minVersion
+}
+
+// clientSchemaVersion returns protocol schema version declared by the client
+// (0 - client did not declare its version: only the initial schema is supported)
+func (p *Protocol) clientSchemaVersion(c net.Conn) int {
+	p._arbitrationMutex.Lock()
+	defer p._arbitrationMutex.Unlock()
+	return p._connSchemaVersions[c]
+}
+
+// createDaemonFeaturesInfo returns feature flags of the daemon build (for 'Hello' response)
+func (p *Protocol) createDaemonFeaturesInfo() types.DaemonFeaturesInfo {
+	_, ovpnErr, obfspErr := p._service.GetDisabledFunctions()
+
+	obfuscationTypes := []string{}
+	if obfspErr == nil {
+		obfuscationTypes = append(obfuscationTypes, "obfsproxy")
+	}
+	if err := filerights.CheckFileAccessRightsExecutable(platform.V2RayBinaryPath()); err == nil {
+		obfuscationTypes = append(obfuscationTypes, "v2ray")
+	}
+
+	return types.DaemonFeaturesInfo{
+		IsSplitTunnelSupported: false, // split-tunnel functionality not implemented yet
+		IsMultihopSupported:    ovpnErr == nil,
+		ObfuscationTypes:       obfuscationTypes}
+}
@@ -31,7 +31,9 @@ import (
 	"strings"
 	"time"
 
+	apitypes "github.com/ivpn/desktop-app-daemon/api/types"
 	"github.com/ivpn/desktop-app-daemon/helpers"
+	"github.com/ivpn/desktop-app-daemon/netinfo"
 	"github.com/ivpn/desktop-app-daemon/protocol/types"
 	"github.com/ivpn/desktop-app-daemon/version"
 	"github.com/ivpn/desktop-app-daemon/vpn"
@@ -62,11 +64,43 @@ func (p *Protocol) connLogID(c net.Conn) string {
 
 // -------------- send message to all active connections ---------------
 func (p *Protocol) notifyClients(cmd interface{}) {
+	notificationTypeName := types.GetTypeName(cmd)
+
 	p._connectionsMutex.RLock()
 	defer p._connectionsMutex.RUnlock()
 	for conn := range p._connections {
+		// skip notification if client subscribed only to specific event classes
+		if p.isEventSubscribed(conn, notificationTypeName) == false {
+			continue
+		}
+		// skip notification if its type belongs to a newer protocol schema version than supported by the client
+		if p.isNotificationApplicable(conn, notificationTypeName) == false {
+			continue
+		}
 		p.sendResponse(conn, cmd, 0)
 	}
+
+	// mirror notification to the WebSocket subscribers (if any)
+	p.notifyWebSocketClients(cmd)
+}
+
+// notifyClientAboutServersUpdate sends info about the updated servers list to a client connection:
+// clients supporting servers delta notifications receive only the changes since the previous update,
+// legacy clients receive the entire list
+func (p *Protocol) notifyClientAboutServersUpdate(conn net.Conn) {
+	if diff := p._service.ServersUpdateDiff(); diff != nil && p.clientSchemaVersion(conn) >= 2 {
+		if diff.HasChanges() == false {
+			return // servers list stayed the same: nothing to notify about
+		}
+		p.sendResponse(conn, &types.ServerListDiffResp{Changes: *diff}, 0)
+		return
+	}
+
+	serv, _ := p._service.ServersList()
+	if serv == nil {
+		return
+	}
+	p.sendResponse(conn, &types.ServerListResp{VpnServers: *serv}, 0)
 }
 
 // -------------- clients connections ---------------
@@ -77,11 +111,16 @@ func (p *Protocol) clientConnected(c net.Conn) {
 }
 
 func (p *Protocol) clientDisconnected(c net.Conn) {
+	// erase arbitration info (releases the exclusive control lock when held by this client)
+	p.forgetClientInfo(c)
+
 	p._connectionsMutex.Lock()
 	defer p._connectionsMutex.Unlock()
 	if _, ok := p._connections[c]; ok {
 		delete(p._connections, c)
 	}
+	delete(p._connCapabilities, c)
+	delete(p._connEventFilters, c)
 	c.Close()
 }
 
@@ -114,12 +153,12 @@ func (p *Protocol) notifyClientsDaemonExiting() {
 // -------------- sending responses ---------------
 func (p *Protocol) sendError(conn net.Conn, errorText string, cmdIdx int) {
 	log.Error(errorText)
-	p.sendResponse(conn, &types.ErrorResp{ErrorMessage: errorText}, cmdIdx)
+	p.sendResponse(conn, &types.ErrorResp{ErrorMessage: errorText, ErrorCode: types.GetErrCode(errors.New(errorText))}, cmdIdx)
 }
 
 func (p *Protocol) sendErrorResponse(conn net.Conn, request types.CommandBase, err error) {
 	log.Error(fmt.Sprintf("%sError processing request '%s': %s", p.connLogID(conn), request.Command, err))
-	p.sendResponse(conn, &types.ErrorResp{ErrorMessage: err.Error()}, request.Idx)
+	p.sendResponse(conn, &types.ErrorResp{ErrorMessage: err.Error(), ErrorCode: types.GetErrCode(err)}, request.Idx)
 }
 
 func (p *Protocol) sendResponse(conn net.Conn, cmd interface{}, idx int) (retErr error) {
@@ -188,7 +227,9 @@ func (p *Protocol) createHelloResponse() *types.HelloResp {
 		DisabledFunctions: types.DisabledFunctionality{
 			WireGuardError: wgErr,
 			OpenVPNError:   ovpnErr,
-			ObfsproxyError: obfspErr}}
+			ObfsproxyError: obfspErr},
+		ProtocolSchemaVersion: types.ProtocolSchemaVersion,
+		Features:              p.createDaemonFeaturesInfo()}
 	return &helloResp
 }
 
@@ -212,9 +253,31 @@ func (p *Protocol) processConnectRequest(messageData []byte, stateChan chan<- vp
 		return fmt.Errorf("failed to unmarshal json 'Connect' request: %w", err)
 	}
 
+	// save connection parameters (will be used to restore connection by autoconnect functionality)
+	p._service.SaveLastConnectionParams(string(messageData))
+
+	// override requested entry server by daemon-side "fastest server" selection (if requested)
+	if r.FastestServer {
+		p.applyFastestServer(&r)
+	}
+
+	// override requested port by a random one (if enabled by preferences)
+	if p._service.Preferences().IsRandomPort {
+		p.applyRandomPort(&r)
+	}
+
+	// prefer IPv6 server endpoints (when enabled by preferences and local network has IPv6 connectivity)
+	isIPv6Endpoint := p.isIPv6EndpointApplicable()
+
 	retManualDNS := net.ParseIP(r.CurrentDNS)
 
 	if vpn.Type(r.VpnType) == vpn.OpenVPN {
+		// imported third-party '.ovpn' profile: all connection parameters are taken from the profile
+		if len(r.OpenVpnParameters.ImportedProfileName) > 0 {
+			p._service.SetConnectionIPv4FallbackHost(nil)
+			return p._service.ConnectImportedOpenvpnProfile(r.OpenVpnParameters.ImportedProfileName, retManualDNS, r.FirewallOn, r.FirewallOnDuringConnection, stateChan)
+		}
+
 		// PARAMETERS VALIDATION
 		// parsing hosts
 		var hosts []net.IP
@@ -227,6 +290,22 @@ func (p *Protocol) processConnectRequest(messageData []byte, stateChan chan<- vp
 		// in case of multiple hosts - take random host from the list
 		host := hosts[rand.Intn(len(hosts))]
 
+		// prefer an IPv6 endpoint (if applicable); the IPv4 host stays remembered as a fallback
+		var ipv4FallbackHost net.IP
+		if isIPv6Endpoint {
+			var hostsV6 []net.IP
+			for _, v := range r.OpenVpnParameters.EntryVpnServer.IPv6Addresses {
+				if ip := net.ParseIP(v); ip != nil && ip.To4() == nil {
+					hostsV6 = append(hostsV6, ip)
+				}
+			}
+			if len(hostsV6) > 0 {
+				ipv4FallbackHost = host
+				host = hostsV6[rand.Intn(len(hostsV6))]
+			}
+		}
+		p._service.SetConnectionIPv4FallbackHost(ipv4FallbackHost)
+
 		// only one-line parameter is allowed
 		multihopExitSrvID := strings.Split(r.OpenVpnParameters.MultihopExitSrvID, "\n")[0]
 		// nothing from supported proxy types should be in this parameter
@@ -254,6 +333,19 @@ func (p *Protocol) processConnectRequest(messageData []byte, stateChan chan<- vp
 		return p._service.ConnectOpenVPN(connectionParams, retManualDNS, r.FirewallOn, r.FirewallOnDuringConnection, stateChan)
 
 	} else if vpn.Type(r.VpnType) == vpn.WireGuard {
+		// user-provided (non-IVPN) WireGuard configuration: all connection parameters are taken from the config
+		if len(r.WireGuardParameters.CustomConfig) > 0 {
+			customCfg, err := wireguard.ParseCustomConfig(r.WireGuardParameters.CustomConfig)
+			if err != nil {
+				return err
+			}
+			p._service.SetConnectionIPv4FallbackHost(nil)
+			return p._service.ConnectCustomWireGuard(customCfg, retManualDNS, r.FirewallOn, r.FirewallOnDuringConnection, stateChan)
+		}
+
+		if len(r.WireGuardParameters.EntryVpnServer.Hosts) == 0 {
+			return fmt.Errorf("VPN host not defined")
+		}
 		hostValue := r.WireGuardParameters.EntryVpnServer.Hosts[rand.Intn(len(r.WireGuardParameters.EntryVpnServer.Hosts))]
 
 		// prevent user-defined data injection: ensure that nothing except the base64 public key will be stored in the configuration
@@ -261,9 +353,20 @@ func (p *Protocol) processConnectRequest(messageData []byte, stateChan chan<- vp
 			return fmt.Errorf("WG public key is not base64 string")
 		}
 
+		hostIP := net.ParseIP(hostValue.Host)
+		// prefer the IPv6 endpoint (if applicable); the IPv4 host stays remembered as a fallback
+		var ipv4FallbackHost net.IP
+		if isIPv6Endpoint && len(hostValue.HostIPv6) > 0 {
+			if ipv6 := net.ParseIP(hostValue.HostIPv6); ipv6 != nil && ipv6.To4() == nil {
+				ipv4FallbackHost = hostIP
+				hostIP = ipv6
+			}
+		}
+		p._service.SetConnectionIPv4FallbackHost(ipv4FallbackHost)
+
 		connectionParams := wireguard.CreateConnectionParams(
 			r.WireGuardParameters.Port.Port,
-			net.ParseIP(hostValue.Host),
+			hostIP,
 			hostValue.PublicKey,
 			net.ParseIP(strings.Split(hostValue.LocalIP, "/")[0]))
 
@@ -273,3 +376,92 @@ func (p *Protocol) processConnectRequest(messageData []byte, stateChan chan<- vp
 
 	return fmt.Errorf("unexpected VPN type to connect (%v)", r.VpnType)
 }
+
+// isIPv6EndpointApplicable returns true when connection over an IPv6 server endpoint should be preferred:
+// IPv6 endpoints are enabled by preferences and the local network has IPv6 connectivity
+func (p *Protocol) isIPv6EndpointApplicable() bool {
+	if p._service.Preferences().IsIPv6Endpoints == false {
+		return false
+	}
+
+	haveIPv6, err := netinfo.HaveIPv6Connectivity()
+	if err != nil {
+		log.Warning("Failed to check local IPv6 connectivity: ", err)
+		return false
+	}
+	if haveIPv6 == false {
+		log.Info("IPv6 endpoints are enabled but local network has no IPv6 connectivity. Using IPv4 endpoint.")
+	}
+	return haveIPv6
+}
+
+// applyFastestServer overrides entry server of a connection request by daemon-side "fastest server" choice
+// (combining latency, server load and historical connection success rate)
+// Request stays unchanged when selection is not possible (e.g. servers list not available)
+func (p *Protocol) applyFastestServer(r *types.Connect) {
+	if vpn.Type(r.VpnType) == vpn.WireGuard {
+		srv := p._service.FastestWireGuardServer()
+		if srv == nil || len(srv.Hosts) == 0 {
+			return
+		}
+		hosts := make([]types.WGHost, 0, len(srv.Hosts))
+		for _, h := range srv.Hosts {
+			hosts = append(hosts, types.WGHost{Host: h.Host, PublicKey: h.PublicKey, LocalIP: h.LocalIP})
+		}
+		r.WireGuardParameters.EntryVpnServer.Hosts = hosts
+		return
+	}
+
+	srv := p._service.FastestOpenvpnServer()
+	if srv == nil || len(srv.IPAddresses) == 0 {
+		return
+	}
+	r.OpenVpnParameters.EntryVpnServer.IPAddresses = srv.IPAddresses
+}
+
+// applyRandomPort overrides port of a connection request by a random port
+// from applicable port definitions of the servers configuration ('config'->'ports' section of servers.json)
+// Port-range definitions (e.g. UDP 5500-19999) are supported: a random port within the range is used
+func (p *Protocol) applyRandomPort(r *types.Connect) {
+	servers, err := p._service.ServersList()
+	if err != nil || servers == nil {
+		return
+	}
+
+	var applicablePorts []apitypes.PortInfo
+	if vpn.Type(r.VpnType) == vpn.WireGuard {
+		// WireGuard uses UDP only
+		for _, pi := range servers.Config.Ports.WireGuard {
+			if pi.IsTCP() == false {
+				applicablePorts = append(applicablePorts, pi)
+			}
+		}
+	} else {
+		isTCP := r.OpenVpnParameters.Port.Protocol > 0
+		for _, pi := range servers.Config.Ports.OpenVPN {
+			if pi.IsTCP() == isTCP {
+				applicablePorts = append(applicablePorts, pi)
+			}
+		}
+	}
+	if len(applicablePorts) == 0 {
+		return // servers configuration has no applicable ports info
+	}
+
+	portInfo := applicablePorts[rand.Intn(len(applicablePorts))]
+	port := portInfo.Port
+	if portInfo.IsRange() {
+		port = portInfo.Range.Min + rand.Intn(portInfo.Range.Max-portInfo.Range.Min+1)
+	}
+	if port <= 0 {
+		return
+	}
+
+	if vpn.Type(r.VpnType) == vpn.WireGuard {
+		log.Info(fmt.Sprintf("Random port: %d (requested: %d)", port, r.WireGuardParameters.Port.Port))
+		r.WireGuardParameters.Port.Port = port
+	} else {
+		log.Info(fmt.Sprintf("Random port: %d (requested: %d)", port, r.OpenVpnParameters.Port.Port))
+		r.OpenVpnParameters.Port.Port = port
+	}
+}
@@ -0,0 +1,164 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ivpn/desktop-app-daemon/protocol/types"
+)
+
+// VPN scheduler
+// Performs connect\disconnect actions at user-configured times (e.g. "always connect 09:00-18:00":
+// rule 'connect' at "09:00" + rule 'disconnect' at "18:00").
+// Rules are persisted in daemon preferences (survive daemon restart).
+// Scheduled 'connect' restores the last known connection (same mechanism as autoconnect functionality).
+
+// interval of checking for rules to be performed
+const _schedulerCheckInterval = time.Minute
+
+// scheduler actions
+const (
+	SchedulerActionConnect    = "connect"
+	SchedulerActionDisconnect = "disconnect"
+)
+
+// validateSchedulerRule checks format of a scheduler rule
+func validateSchedulerRule(r types.SchedulerRule) error {
+	if r.Action != SchedulerActionConnect && r.Action != SchedulerActionDisconnect {
+		return fmt.Errorf("unknown scheduler action: '%s'", r.Action)
+	}
+	if _, _, err := parseSchedulerTime(r.Time); err != nil {
+		return err
+	}
+	for _, wd := range r.Weekdays {
+		if wd < 0 || wd > 6 {
+			return fmt.Errorf("bad weekday value: %d (acceptable values: 0 - Sunday ... 6 - Saturday)", wd)
+		}
+	}
+	return nil
+}
+
+// parseSchedulerTime parses time of day in "HH:MM" format
+func parseSchedulerTime(value string) (hour int, minute int, err error) {
+	parts := strings.Split(strings.TrimSpace(value), ":")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("bad time value: '%s' (expected format \"HH:MM\")", value)
+	}
+	hour, err = strconv.Atoi(parts[0])
+	if err != nil || hour < 0 || hour > 23 {
+		return 0, 0, fmt.Errorf("bad time value: '%s' (expected format \"HH:MM\")", value)
+	}
+	minute, err = strconv.Atoi(parts[1])
+	if err != nil || minute < 0 || minute > 59 {
+		return 0, 0, fmt.Errorf("bad time value: '%s' (expected format \"HH:MM\")", value)
+	}
+	return hour, minute, nil
+}
+
+// schedulerRules returns scheduler rules saved in daemon preferences
+func (p *Protocol) schedulerRules() []types.SchedulerRule {
+	rulesJSON := p._service.SchedulerRules()
+	if len(rulesJSON) == 0 {
+		return nil
+	}
+
+	var rules []types.SchedulerRule
+	if err := json.Unmarshal([]byte(rulesJSON), &rules); err != nil {
+		log.Error("Failed to parse saved scheduler rules: ", err)
+		return nil
+	}
+	return rules
+}
+
+// schedulerLoop periodically checks for scheduler rules to be performed
+func (p *Protocol) schedulerLoop() {
+	lastCheck := time.Now()
+	for {
+		time.Sleep(_schedulerCheckInterval)
+
+		now := time.Now()
+		for _, rule := range p.schedulerRules() {
+			if isSchedulerRuleDue(rule, lastCheck, now) {
+				p.schedulerDoAction(rule)
+			}
+		}
+		lastCheck = now
+	}
+}
+
+// isSchedulerRuleDue checks if time of a rule occurred in interval (lastCheck, now]
+func isSchedulerRuleDue(rule types.SchedulerRule, lastCheck time.Time, now time.Time) bool {
+	hour, minute, err := parseSchedulerTime(rule.Time)
+	if err != nil {
+		return false
+	}
+
+	// check rule occurrences for both interval boundary days (interval can cross midnight)
+	for day := lastCheck; day.Before(now.AddDate(0, 0, 1)); day = day.AddDate(0, 0, 1) {
+		occurrence := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, now.Location())
+		if occurrence.After(lastCheck) == false || occurrence.After(now) {
+			continue
+		}
+		if len(rule.Weekdays) == 0 {
+			return true // rule is active every day
+		}
+		for _, wd := range rule.Weekdays {
+			if time.Weekday(wd) == occurrence.Weekday() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// schedulerDoAction performs scheduled action and notifies clients about it
+func (p *Protocol) schedulerDoAction(rule types.SchedulerRule) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error("PANIC on scheduled action: ", r)
+		}
+	}()
+
+	log.Info(fmt.Sprintf("Scheduler: performing '%s' action (scheduled on %s)", rule.Action, rule.Time))
+	p.notifyClients(&types.SchedulerActionResp{Action: rule.Action, Time: rule.Time})
+
+	switch rule.Action {
+	case SchedulerActionConnect:
+		// restore the last known connection (nothing to do when already connected)
+		p.autoConnect("scheduler")
+
+	case SchedulerActionDisconnect:
+		if p._service.Connected() == false {
+			return // already disconnected - nothing to do
+		}
+		p._disconnectRequested = true
+		if err := p._service.Disconnect(); err != nil {
+			log.Error("Scheduler: failed to disconnect: ", err)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package protocol
+
+import (
+	"net"
+)
+
+// Event classes of daemon notifications
+// A client can limit received notifications by subscribing only to required classes
+// (see 'SetEventsSubscription' request). By default client receives all notifications
+const (
+	// EventClassVpnState - VPN connection state changes
+	EventClassVpnState = "vpn_state"
+	// EventClassServers - servers list updates and ping results
+	EventClassServers = "servers"
+	// EventClassFirewall - kill-switch (firewall) state changes
+	EventClassFirewall = "firewall"
+	// EventClassSession - account session changes
+	EventClassSession = "session"
+	// EventClassWifi - WiFi network changes
+	EventClassWifi = "wifi"
+	// EventClassDns - DNS configuration changes
+	EventClassDns = "dns"
+	// EventClassProgress - progress of long operations (e.g. latency scan)
+	EventClassProgress = "progress"
+)
+
+// eventClassByTypeName maps notification type to the event class it belongs to
+// (notifications which are not mentioned here are always delivered: e.g. ErrorResp, ServiceExitingResp)
+var eventClassByTypeName = map[string]string{
+	"VpnStateResp":     EventClassVpnState,
+	"ConnectedResp":    EventClassVpnState,
+	"DisconnectedResp": EventClassVpnState,
+	"PortForwardResp":  EventClassVpnState,
+
+	"ServerListResp":  EventClassServers,
+	"PingServersResp": EventClassServers,
+
+	"KillSwitchStatusResp":          EventClassFirewall,
+	"KillSwitchGetIsPestistentResp": EventClassFirewall,
+
+	"HelloResp":         EventClassSession,
+	"SessionNewResp":    EventClassSession,
+	"AccountStatusResp": EventClassSession,
+
+	"WiFiCurrentNetworkResp":    EventClassWifi,
+	"WiFiAvailableNetworksResp": EventClassWifi,
+
+	"SetAlternateDNSResp": EventClassDns,
+
+	"OperationProgressResp": EventClassProgress,
+}
+
+// isKnownEventClass checks is the event class name known
+func isKnownEventClass(class string) bool {
+	switch class {
+	case EventClassVpnState, EventClassServers, EventClassFirewall, EventClassSession, EventClassWifi, EventClassDns, EventClassProgress:
+		return true
+	}
+	return false
+}
+
+// setConnectionEventsFilter limits notifications of a connection to the defined event classes
+// Empty list of classes erases the filter (client receives all notifications)
+func (p *Protocol) setConnectionEventsFilter(c net.Conn, eventClasses []string) {
+	p._connectionsMutex.Lock()
+	defer p._connectionsMutex.Unlock()
+
+	if len(eventClasses) == 0 {
+		delete(p._connEventFilters, c)
+		return
+	}
+
+	filter := make(map[string]struct{}, len(eventClasses))
+	for _, class := range eventClasses {
+		filter[class] = struct{}{}
+	}
+
+	if p._connEventFilters == nil {
+		p._connEventFilters = make(map[net.Conn]map[string]struct{})
+	}
+	p._connEventFilters[c] = filter
+}
+
+// isEventSubscribed checks is a client subscribed to a notification type
+// Must be called under '_connectionsMutex' lock (used by 'notifyClients')
+func (p *Protocol) isEventSubscribed(c net.Conn, notificationTypeName string) bool {
+	filter, hasFilter := p._connEventFilters[c]
+	if hasFilter == false {
+		return true // no filter defined - client receives all notifications
+	}
+
+	class, ok := eventClassByTypeName[notificationTypeName]
+	if ok == false {
+		return true // notifications without event class are always delivered
+	}
+
+	_, isSubscribed := filter[class]
+	return isSubscribed
+}
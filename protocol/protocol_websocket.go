@@ -0,0 +1,151 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/ivpn/desktop-app-daemon/protocol/types"
+)
+
+// WebSocket bridge
+// When enabled (preference 'websocket_port' > 0), daemon serves the protocol event stream
+// over a local WebSocket endpoint: every notification which is broadcasted to the TCP clients
+// is also sent to the WebSocket subscribers as a text frame (same JSON objects, one per frame).
+// It allows web-technology UIs to subscribe to state\statistics events
+// without implementing the raw TCP framing.
+// The bridge is read-only: incoming frames (except the initial 'Hello') are ignored,
+// requests can not be sent over it.
+// Subscriber must authenticate by the first frame: 'Hello' request with the connection secret.
+
+// WebSocket listener accepts local connections only
+const _webSocketListenHost = "127.0.0.1"
+
+// path of the events endpoint (e.g. 'ws://127.0.0.1:<port>/v1/events')
+const _webSocketEventsPath = "/v1/events"
+
+// applyWebSocketBridgeSettings starts\stops WebSocket listener according to 'WebSocketPort' preference
+func (p *Protocol) applyWebSocketBridgeSettings() {
+	p._webSocketMutex.Lock()
+	defer p._webSocketMutex.Unlock()
+
+	p.closeWebSocketBridgeNoLock()
+
+	port := p._service.Preferences().WebSocketPort
+	if port <= 0 {
+		return // WebSocket bridge disabled
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(_webSocketEventsPath, websocket.Handler(p.processWebSocketClient))
+	server := &http.Server{Addr: fmt.Sprintf("%s:%d", _webSocketListenHost, port), Handler: mux}
+	p._webSocketServer = server
+
+	go func() {
+		log.Info(fmt.Sprintf("WebSocket bridge started: ws://%s%s", server.Addr, _webSocketEventsPath))
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Error("WebSocket listener error: ", err)
+		}
+	}()
+}
+
+// closeWebSocketBridge stops WebSocket listener and erases subscribers connections
+func (p *Protocol) closeWebSocketBridge() {
+	p._webSocketMutex.Lock()
+	defer p._webSocketMutex.Unlock()
+	p.closeWebSocketBridgeNoLock()
+}
+
+func (p *Protocol) closeWebSocketBridgeNoLock() {
+	if p._webSocketServer != nil {
+		if err := p._webSocketServer.Close(); err != nil {
+			log.Error("Failed to stop WebSocket listener: ", err)
+		}
+		p._webSocketServer = nil
+	}
+
+	for conn := range p._webSocketConns {
+		conn.Close()
+	}
+	p._webSocketConns = nil
+}
+
+// processWebSocketClient processes a WebSocket subscriber connection
+// (authenticate, register for event notifications; blocks until client disconnects)
+func (p *Protocol) processWebSocketClient(ws *websocket.Conn) {
+	defer func() {
+		p._webSocketMutex.Lock()
+		delete(p._webSocketConns, ws)
+		p._webSocketMutex.Unlock()
+
+		ws.Close()
+		log.Info("WebSocket subscriber disconnected: ", ws.Request().RemoteAddr)
+	}()
+
+	// first frame must be 'Hello' request with the connection secret
+	var message string
+	if err := websocket.Message.Receive(ws, &message); err != nil {
+		log.Warning("WebSocket subscriber not authenticated: ", err)
+		return
+	}
+
+	var hello types.Hello
+	if err := json.Unmarshal([]byte(message), &hello); err != nil || hello.Secret != p._secret {
+		log.Warning(fmt.Sprintf("Refusing WebSocket subscriber (%s): secret verification error", ws.Request().RemoteAddr))
+		p.sendResponse(ws, &types.ErrorResp{ErrorMessage: "secret verification error"}, hello.Idx)
+		return
+	}
+
+	p.sendResponse(ws, p.createHelloResponse(), hello.Idx)
+
+	p._webSocketMutex.Lock()
+	if p._webSocketConns == nil {
+		p._webSocketConns = make(map[net.Conn]struct{})
+	}
+	p._webSocketConns[ws] = struct{}{}
+	p._webSocketMutex.Unlock()
+
+	log.Info("New WebSocket subscriber: ", ws.Request().RemoteAddr)
+
+	// ignore all the next incoming frames (read-only event stream); just wait for disconnection
+	for {
+		if err := websocket.Message.Receive(ws, &message); err != nil {
+			return
+		}
+	}
+}
+
+// notifyWebSocketClients sends notification to all WebSocket subscribers
+// ('*websocket.Conn' implements 'net.Conn': each message is sent as a single text frame)
+func (p *Protocol) notifyWebSocketClients(cmd interface{}) {
+	p._webSocketMutex.Lock()
+	defer p._webSocketMutex.Unlock()
+	for conn := range p._webSocketConns {
+		p.sendResponse(conn, cmd, 0)
+	}
+}
@@ -0,0 +1,98 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package types
+
+import (
+	"errors"
+	"strings"
+)
+
+// Daemon-wide error taxonomy: an enumerated code is attached to every error response
+// ('ErrorResp.ErrorCode') so clients can localize the error and react programmatically
+// instead of parsing the English error message.
+// An empty code means the error is not classified (only the message is available).
+const (
+	// ErrCodeApiUnreachable - IVPN API backend is not accessible (network problem or API blocked)
+	ErrCodeApiUnreachable = "API_UNREACHABLE"
+	// ErrCodeWgServiceTimeout - WireGuard tunnel service failed to install\start (Windows)
+	ErrCodeWgServiceTimeout = "WG_SERVICE_TIMEOUT"
+	// ErrCodeFirewallBackendMissing - required firewall functionality is not available on this platform
+	ErrCodeFirewallBackendMissing = "FIREWALL_BACKEND_MISSING"
+	// ErrCodeDnsApplyFailed - failed to apply DNS configuration
+	ErrCodeDnsApplyFailed = "DNS_APPLY_FAILED"
+	// ErrCodeNotLoggedIn - user is not logged in into the account
+	ErrCodeNotLoggedIn = "NOT_LOGGED_IN"
+	// ErrCodeNotAuthorized - client has no rights to execute the command (see capabilities)
+	ErrCodeNotAuthorized = "NOT_AUTHORIZED"
+	// ErrCodeAuthenticationFailed - client connection authentication failed
+	ErrCodeAuthenticationFailed = "AUTHENTICATION_FAILED"
+	// ErrCodePortBlocked - selected port is blocked by the local network (see 'connection warm-up')
+	ErrCodePortBlocked = "PORT_BLOCKED"
+	// ErrCodeServerNotReachable - destination VPN server is not reachable
+	ErrCodeServerNotReachable = "SERVER_NOT_REACHABLE"
+)
+
+// ErrCodeProvider - optional interface of error values which explicitly define their taxonomy code
+type ErrCodeProvider interface {
+	ErrCode() string
+}
+
+// GetErrCode returns the taxonomy code of an error
+// When the error value (or any error in its chain) does not provide the code explicitly -
+// classification is based on well-known error texts (empty string - error not classified)
+func GetErrCode(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var provider ErrCodeProvider
+	if errors.As(err, &provider) {
+		return provider.ErrCode()
+	}
+
+	text := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(text, "unable to access ivpn api server"):
+		return ErrCodeApiUnreachable
+	case strings.Contains(text, "tunnel service"),
+		strings.Contains(text, "service not installed (timeout"),
+		strings.Contains(text, "service not started (timeout"):
+		return ErrCodeWgServiceTimeout
+	case strings.Contains(text, "firewall") && strings.Contains(text, "not implemented"):
+		return ErrCodeFirewallBackendMissing
+	case strings.Contains(text, "dns") && (strings.Contains(text, "fail") || strings.Contains(text, "error")):
+		return ErrCodeDnsApplyFailed
+	case strings.Contains(text, "not logged in"):
+		return ErrCodeNotLoggedIn
+	case strings.Contains(text, "not enough rights"):
+		return ErrCodeNotAuthorized
+	case strings.Contains(text, "authentication"), strings.Contains(text, "secret verification"):
+		return ErrCodeAuthenticationFailed
+	case strings.Contains(text, "blocked on this network"):
+		return ErrCodePortBlocked
+	case strings.Contains(text, "not reachable on this network"):
+		return ErrCodeServerNotReachable
+	}
+
+	return ""
+}
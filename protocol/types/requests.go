@@ -22,7 +22,10 @@
 
 package types
 
-import "github.com/ivpn/desktop-app-daemon/vpn"
+import (
+	"github.com/ivpn/desktop-app-daemon/service/preferences"
+	"github.com/ivpn/desktop-app-daemon/vpn"
+)
 
 // Hello is an initial request
 type Hello struct {
@@ -31,6 +34,11 @@ type Hello struct {
 	Version string
 	Secret  uint64
 
+	// CapabilityToken - alternative authentication way (when Secret not defined)
+	// Client authenticated by a token has access only to commands covered by capabilities of this token
+	// (tokens can be created by a full-access client: see 'GenerateCapabilityToken')
+	CapabilityToken string
+
 	// GetServersList == true - client requests to send back info about all servers
 	GetServersList bool
 
@@ -43,6 +51,31 @@ type Hello struct {
 	//	KeepDaemonAlone == false (default) - VPN disconnects when client disconnects from a daemon
 	//	KeepDaemonAlone == true - do nothing when client disconnects from a daemon (if VPN is connected - do not disconnect)
 	KeepDaemonAlone bool
+
+	// ClientName - human-readable name of the client (e.g. "UI", "CLI"; used by multi-client arbitration:
+	// other clients are informed who initiated a state change)
+	ClientName string
+
+	// ObserverMode == true - client declares itself as a read-only observer:
+	// all state-changing commands of this connection will be rejected
+	ObserverMode bool
+
+	// ProtocolSchemaVersion - protocol schema version supported by the client
+	// (0 - client implemented before schema versioning:
+	// daemon will not send notifications of the newer schema versions to such connection)
+	ProtocolSchemaVersion int
+}
+
+// ControlLockAcquire request to acquire the exclusive control lock
+// (while the lock is held - state-changing commands of other clients are rejected;
+// the lock is automatically released when the client disconnects)
+type ControlLockAcquire struct {
+	CommandBase
+}
+
+// ControlLockRelease request to release the exclusive control lock (when held by this client)
+type ControlLockRelease struct {
+	CommandBase
 }
 
 // GetServers request servers list
@@ -57,6 +90,16 @@ type PingServers struct {
 	TimeOutMs  int
 }
 
+// PingServersEx request to ping servers in parallel
+// Still valid cached results are returned immediately; the rest of servers are probed in background
+// with incremental 'PingServersResp' notifications
+type PingServersEx struct {
+	CommandBase
+	TimeOutMs int
+	// ProbeType: "icmp" (default) or "udp" (for networks where ICMP is blocked)
+	ProbeType string
+}
+
 // KillSwitchSetAllowLANMulticast enable\disable LAN multicast acces for kill-switch
 type KillSwitchSetAllowLANMulticast struct {
 	CommandBase
@@ -77,12 +120,40 @@ type KillSwitchSetAllowLAN struct {
 	Synchronously bool
 }
 
+// KillSwitchSetAllowLANTrustedOnly - when enabled, the 'allow LAN' rules are applied only on trusted WiFi networks
+type KillSwitchSetAllowLANTrustedOnly struct {
+	CommandBase
+	AllowLANTrustedOnly bool
+
+	// When true - deamon returns empty response as confirmation
+	// Needed for supporting old UI clients which are don't require confirmation
+	Synchronously bool
+}
+
+// KillSwitchSetAllowLANSubnets - limit the 'allow LAN' functionality to the specific subnets only
+// (CIDR notation; empty list - all detected local subnets are allowed)
+type KillSwitchSetAllowLANSubnets struct {
+	CommandBase
+	Subnets []string
+
+	// When true - deamon returns empty response as confirmation
+	// Needed for supporting old UI clients which are don't require confirmation
+	Synchronously bool
+}
+
 // KillSwitchSetEnabled request to enable\disable kill-switch
 type KillSwitchSetEnabled struct {
 	CommandBase
 	IsEnabled bool
 }
 
+// CaptivePortalBypass request to temporarily disable kill switch to pass a captive portal
+// login page; full protection is restored automatically ('DurationSec == 0' - default duration)
+type CaptivePortalBypass struct {
+	CommandBase
+	DurationSec int
+}
+
 // KillSwitchGetStatus get full killswitch status
 type KillSwitchGetStatus struct {
 	CommandBase
@@ -101,6 +172,44 @@ type SetPreference struct {
 	Value string
 }
 
+// SetLoggerConfig request to change logger configuration at runtime
+type SetLoggerConfig struct {
+	CommandBase
+	// StructuredLogging - write log records as JSON objects (one object per line)
+	StructuredLogging bool
+	// ModuleLevels - minimal log level for modules (module "*" defines the default level)
+	// Acceptable level values: "debug", "info", "warning", "error"
+	ModuleLevels map[string]string
+	// DisableSampling - disable suppression of high-frequency messages
+	DisableSampling bool
+	// RotationMaxFileSize - rotate the active log file when its size exceeds this value, bytes
+	// (0 - keep current rotation configuration)
+	RotationMaxFileSize int64
+	// RotationMaxBackups - count of rotated log files to keep
+	RotationMaxBackups int
+	// RotationCompress - compress rotated log files (gzip)
+	RotationCompress bool
+}
+
+// SetModulesDebug request to enable\disable debug logging for specific subsystems
+// (logger names: e.g. "api", "wg", "frwl", "dns") without restarting the daemon
+type SetModulesDebug struct {
+	CommandBase
+	Modules  []string
+	IsEnable bool
+	// DurationMins - enable debug logging only for the defined interval, minutes
+	// (0 - until disabled by a next request; ignored when IsEnable==false)
+	DurationMins int
+}
+
+// GetRecentLogs request to get last daemon log records from the in-memory buffer
+// (does not require access to the log files)
+type GetRecentLogs struct {
+	CommandBase
+	// MaxLines - max count of records to return (0 - all buffered records)
+	MaxLines int
+}
+
 // SetAlternateDns request to set custom DNS
 type SetAlternateDns struct {
 	CommandBase
@@ -109,7 +218,9 @@ type SetAlternateDns struct {
 
 // WGHost is a WireGuard host description
 type WGHost struct {
-	Host      string
+	Host string
+	// HostIPv6 - IPv6 endpoint of same host (empty - IPv6 endpoint not available)
+	HostIPv6  string `json:"host_ipv6"`
 	PublicKey string `json:"public_key"`
 	LocalIP   string `json:"local_ip"`
 }
@@ -119,6 +230,9 @@ type Connect struct {
 	CommandBase
 	VpnType    vpn.Type
 	CurrentDNS string
+	// FastestServer - when true, daemon overrides requested entry server by its own choice
+	// (combining latency, server load and historical connection success rate)
+	FastestServer bool
 	// Enable firewall before connection
 	// (if true - the parameter 'firewallDuringConnection' will be ignored)
 	FirewallOn bool
@@ -134,13 +248,25 @@ type Connect struct {
 		EntryVpnServer struct {
 			Hosts []WGHost
 		}
+
+		// CustomConfig - raw content of a user-provided (non-IVPN) WireGuard configuration file (wg-quick format)
+		// When defined - 'EntryVpnServer' and 'Port' are ignored: connection parameters are taken from the config
+		// (connection is managed by the same daemon machinery: firewall, DNS, pause\resume, kill switch)
+		CustomConfig string
 	}
 
 	OpenVpnParameters struct {
 		EntryVpnServer struct {
 			IPAddresses []string `json:"ip_addresses"`
+			// IPv6Addresses - IPv6 endpoints of same server (empty - IPv6 endpoints not available)
+			IPv6Addresses []string `json:"ipv6_addresses"`
 		}
 
+		// ImportedProfileName - name of an imported third-party '.ovpn' profile (see 'OvpnProfileImport')
+		// When defined - other OpenVPN parameters are ignored: connection parameters are taken from the profile
+		// (connection is managed by the same daemon machinery: state machine, firewall, DNS, pause\resume, kill switch)
+		ImportedProfileName string
+
 		MultihopExitSrvID string
 		ProxyType         string
 		ProxyAddress      string
@@ -155,6 +281,13 @@ type Connect struct {
 	}
 }
 
+// SwitchServer establish a new VPN connection instead of the active one
+// (same parameters as 'Connect'; firewall protection stays continuously engaged
+// between the connections instead of the disconnect-then-connect cycle)
+type SwitchServer struct {
+	Connect
+}
+
 // Disconnect disconnect active VPN connection
 type Disconnect struct {
 	CommandBase
@@ -170,6 +303,8 @@ type GetVPNState struct {
 // When force is set to true - all active sessions will be deleted prior to creating a new one if user reached session limit.
 // Initial call to /sessin/new should always be performed with force set to false, to display special form, when sessions limit is reached.
 // IVPN client apps have to set force to true only when customer clicks Log all other clients button.
+// Empty AccountID continues the pending multi-step login: the daemon re-uses the account ID
+// (and the captcha ID requested by the API) of the previous SessionNew request.
 type SessionNew struct {
 	CommandBase
 	AccountID  string
@@ -185,6 +320,13 @@ type SessionDelete struct {
 	CommandBase
 }
 
+// AccountCreate - register new account (trial)
+type AccountCreate struct {
+	CommandBase
+	// ProductName - product to register the account for (empty - default product)
+	ProductName string
+}
+
 // AccountStatus get account status
 type AccountStatus struct {
 	CommandBase
@@ -217,3 +359,278 @@ type APIRequest struct {
 	CommandBase
 	APIPath string
 }
+
+// APIRequestStream do custom request to API with streaming of response body
+// (response forwarded to the client by chunks: see 'APIResponseChunk')
+type APIRequestStream struct {
+	CommandBase
+	APIPath string
+	// MaxResponseSize - maximum acceptable response size in bytes (0 - no limit)
+	MaxResponseSize int64
+}
+
+// SetAPIEndpoints request to override API\update hosts and their pinned key sets
+// (for staging or self-hosted environments; applicable only when enabled by administrator in daemon settings file)
+// Empty host values reset hosts to defaults
+type SetAPIEndpoints struct {
+	CommandBase
+	APIHost          string
+	UpdateHost       string
+	APIPinnedKeys    []string
+	UpdatePinnedKeys []string
+}
+
+// GeoLookup request geolocation info (real location vs VPN location)
+type GeoLookup struct {
+	CommandBase
+	TimeOutMs int
+}
+
+// RunSelfTest request to perform connectivity and leak checks of the active VPN connection:
+// default-route verification, DNS leak test, IPv6 leak test and kill-switch validation
+type RunSelfTest struct {
+	CommandBase
+}
+
+// GetDaemonHealth request daemon uptime and health of internal subsystems
+// (lightweight; intended for installers, scripts and 'daemon not responding' detection by UI)
+type GetDaemonHealth struct {
+	CommandBase
+}
+
+// GenerateDiagnosticBundle request to assemble a support bundle:
+// recent logs, sanitized connection parameters, routing table, DNS configuration, firewall rules and VPN status
+// (secrets are redacted)
+type GenerateDiagnosticBundle struct {
+	CommandBase
+}
+
+// PauseConnection request to pause active VPN connection
+type PauseConnection struct {
+	CommandBase
+	// Duration - automatically resume connection after the defined interval in seconds (0 - no auto-resume)
+	Duration int
+}
+
+// GetPauseState request to read current pause state (including auto-resume deadline)
+type GetPauseState struct {
+	CommandBase
+}
+
+// GetBandwidthStats request to read current bandwidth usage counters
+type GetBandwidthStats struct {
+	CommandBase
+}
+
+// ResetBandwidthStats request to erase persisted monthly bandwidth counters
+type ResetBandwidthStats struct {
+	CommandBase
+}
+
+// GetConnectionHistory request to read last sessions from local connection history
+type GetConnectionHistory struct {
+	CommandBase
+	// MaxRecords - maximum records to return (0 - use retention limit from preferences)
+	MaxRecords int
+}
+
+// ClearConnectionHistory request to erase local connection history
+type ClearConnectionHistory struct {
+	CommandBase
+}
+
+// GetConnectionDiagnostics request to read opt-in connection diagnostics report
+// (anonymized info about which transports\ports of connection attempts failed or succeeded)
+type GetConnectionDiagnostics struct {
+	CommandBase
+}
+
+// ClearConnectionDiagnostics request to erase recorded connection diagnostics
+type ClearConnectionDiagnostics struct {
+	CommandBase
+}
+
+// LanServicesDiscover request to discover background LAN services (printers, casting devices)
+// over mDNS\Bonjour (discovery must be enabled by preference 'lan_services_discovery')
+type LanServicesDiscover struct {
+	CommandBase
+}
+
+// LanServiceSetAllowed request to allow\disallow communication with a LAN device
+// while kill switch is on (narrowly scoped firewall exception: single IP)
+type LanServiceSetAllowed struct {
+	CommandBase
+	// IP - IPv4 address of the device
+	IP      string
+	Allowed bool
+}
+
+// CleanupNetworkArtifacts request to remove network artifacts leftover from crashed sessions
+// (orphaned VPN interfaces, leftover WG services and DNS settings)
+type CleanupNetworkArtifacts struct {
+	CommandBase
+}
+
+// ExportSettings request to export daemon settings as an encrypted bundle
+// (secrets are excluded unless 'IsIncludeSecrets' defined)
+type ExportSettings struct {
+	CommandBase
+	Passphrase string
+	// IsIncludeSecrets - export secrets too (session info, VPN credentials, WG private key);
+	// they stay protected by the passphrase
+	IsIncludeSecrets bool
+}
+
+// SetOpenVPNCredentials request to supply new credentials to the active OpenVPN connection attempt
+// (applicable when the server rejected current credentials: the connection attempt resumes
+// with the new credentials instead of tearing everything down)
+type SetOpenVPNCredentials struct {
+	CommandBase
+	Username string
+	Password string
+}
+
+// SetOpenVPNTlsCryptV2Key request to provision a per-client tls-crypt-v2 key
+// (PEM format; empty value erases the key - generated configurations fall back
+// to the static 'tls-auth' key)
+type SetOpenVPNTlsCryptV2Key struct {
+	CommandBase
+	Key string
+}
+
+// ImportSettings request to apply daemon settings from a bundle created by 'ExportSettings'
+type ImportSettings struct {
+	CommandBase
+	Bundle     string
+	Passphrase string
+}
+
+// ProfileSave request to save a named connection profile (named set of connection parameters)
+// (profile with the same name will be overwritten)
+type ProfileSave struct {
+	CommandBase
+	Name   string
+	Params Connect
+}
+
+// ProfileDelete request to delete a saved connection profile
+type ProfileDelete struct {
+	CommandBase
+	Name string
+}
+
+// ProfileList request names of all saved connection profiles
+type ProfileList struct {
+	CommandBase
+}
+
+// OvpnProfileImport request to import a third-party OpenVPN profile ('.ovpn') with its credentials
+// (profile content is validated against the whitelist of allowed directives;
+// profile with the same name will be overwritten; credentials are optional:
+// a profile can use certificate-only authentication)
+type OvpnProfileImport struct {
+	CommandBase
+	Name string
+	// Config - raw content of the '.ovpn' profile
+	Config   string
+	Username string
+	Password string
+}
+
+// OvpnProfileDelete request to delete an imported OpenVPN profile (with its credentials)
+type OvpnProfileDelete struct {
+	CommandBase
+	Name string
+}
+
+// OvpnProfileList request names of all imported OpenVPN profiles
+type OvpnProfileList struct {
+	CommandBase
+}
+
+// SetServerMetadata request to save user-defined metadata of a VPN server
+// (favorite mark, custom label, exclusion from the automatic server selection;
+// metadata with all fields empty erases the server entry)
+type SetServerMetadata struct {
+	CommandBase
+	// Gateway - server identifier (e.g. 'xx.wg.ivpn.net')
+	Gateway  string
+	Metadata preferences.ServerMetadata
+}
+
+// GetServersMetadata request to send back user-defined metadata of all VPN servers
+type GetServersMetadata struct {
+	CommandBase
+}
+
+// ConnectProfile request to establish VPN connection using parameters of a saved profile
+// (processed in same way as a 'Connect' request)
+type ConnectProfile struct {
+	CommandBase
+	Name string
+}
+
+// ConnectWithSettings request to atomically apply a set of settings and establish VPN connection
+// (intended for scripted clients: avoids racy sequences of separate setting\connect commands)
+// When applying any setting fails - already applied settings are rolled back and connection is not started;
+// when the connection attempt fails - the applied settings are rolled back too.
+type ConnectWithSettings struct {
+	CommandBase
+	// Firewall\Obfsproxy - desired state: "on", "off" or "" (empty - keep current state)
+	Firewall  string
+	Obfsproxy string
+	// ManualDNS - custom DNS to use ("" - keep current settings; "0.0.0.0" - reset manual DNS)
+	ManualDNS string
+	// Params - 'Connect' request to execute after the settings are applied (server, port, protocol ...)
+	Params Connect
+}
+
+// SchedulerRule - scheduled connect\disconnect rule
+type SchedulerRule struct {
+	// Action to perform: "connect" or "disconnect"
+	// (scheduled "connect" restores the last known connection)
+	Action string
+	// Time of day when action has to be performed ("HH:MM", daemon local time)
+	Time string
+	// Days of week when the rule is active (0 - Sunday ... 6 - Saturday); empty - every day
+	Weekdays []int
+}
+
+// SchedulerSetRules request to replace all scheduler rules
+// (empty list disables the scheduler)
+type SchedulerSetRules struct {
+	CommandBase
+	Rules []SchedulerRule
+}
+
+// SchedulerGetRules request currently configured scheduler rules
+type SchedulerGetRules struct {
+	CommandBase
+}
+
+// PortForwardRequest request to allocate forwarded port on currently connected server
+// (when port already allocated - prolongs its expiration)
+type PortForwardRequest struct {
+	CommandBase
+}
+
+// PortForwardRelease request to release forwarded port
+type PortForwardRelease struct {
+	CommandBase
+}
+
+// SetEventsSubscription request to limit notifications of current connection to specific event classes
+// (empty list erases the filter: client receives all notifications)
+type SetEventsSubscription struct {
+	CommandBase
+	// Known event classes: "vpn_state", "servers", "firewall", "session", "wifi", "dns"
+	EventClasses []string
+}
+
+// GenerateCapabilityToken request to create an authentication token restricted to the defined capabilities
+// (available only for clients authenticated by the daemon secret)
+type GenerateCapabilityToken struct {
+	CommandBase
+	// Known capabilities: "status", "connect", "settings", "account"
+	Capabilities []string
+}
@@ -39,6 +39,9 @@ func init() {
 type ErrorResp struct {
 	CommandBase
 	ErrorMessage string
+	// ErrorCode - enumerated code of the daemon-wide error taxonomy
+	// (see 'error_codes.go'; empty - error not classified)
+	ErrorCode string `json:",omitempty"`
 }
 
 // EmptyResp empty response on request
@@ -60,12 +63,26 @@ type DisabledFunctionality struct {
 	ObfsproxyError string
 }
 
+// DaemonFeaturesInfo feature flags of the daemon build (capability negotiation:
+// client enables only functionality which the daemon supports
+// instead of failing on unknown fields\commands)
+type DaemonFeaturesInfo struct {
+	IsSplitTunnelSupported bool
+	IsMultihopSupported    bool
+	// ObfuscationTypes - supported obfuscation transports (e.g. "obfsproxy", "v2ray")
+	ObfuscationTypes []string
+}
+
 // HelloResp response on initial request
 type HelloResp struct {
 	CommandBase
 	Version           string
 	Session           SessionResp
 	DisabledFunctions DisabledFunctionality
+	// ProtocolSchemaVersion - schema version supported by the daemon (see 'ProtocolSchemaVersion' constant)
+	ProtocolSchemaVersion int
+	// Features - feature flags of the daemon build
+	Features DaemonFeaturesInfo
 	//Account preferences.AccountStatus
 }
 
@@ -116,13 +133,31 @@ type AccountStatusResp struct {
 	Account         preferences.AccountStatus
 }
 
+// AccountCreateResp - information about created account (or error info)
+type AccountCreateResp struct {
+	CommandBase
+	APIStatus       int
+	APIErrorMessage string
+	AccountID       string
+	RawResponse     string
+}
+
 // KillSwitchStatusResp returns kill-switch status
 type KillSwitchStatusResp struct {
 	CommandBase
-	IsEnabled        bool
-	IsPersistent     bool
-	IsAllowLAN       bool
-	IsAllowMulticast bool
+	IsEnabled             bool
+	IsPersistent          bool
+	IsAllowLAN            bool
+	IsAllowMulticast      bool
+	IsAllowLANTrustedOnly bool
+	AllowLANSubnets       []string
+}
+
+// FirewallTamperedResp notification: firewall rules were erased by a third-party tool
+// ('RulesReApplied == true' when the daemon successfully restored the rules)
+type FirewallTamperedResp struct {
+	CommandBase
+	RulesReApplied bool
 }
 
 // KillSwitchGetIsPestistentResp returns kill-switch persistance status
@@ -131,6 +166,12 @@ type KillSwitchGetIsPestistentResp struct {
 	IsPersistent bool
 }
 
+// RecentLogsResp returns last daemon log records from the in-memory buffer
+type RecentLogsResp struct {
+	CommandBase
+	Lines []string
+}
+
 // DiagnosticsGeneratedResp returns info from daemon logs
 type DiagnosticsGeneratedResp struct {
 	CommandBase
@@ -193,10 +234,21 @@ type ServerListResp struct {
 	VpnServers types.ServersInfoResponse
 }
 
-//PingResultType represents information ping TTL for a host (is a part of 'PingServersResp')
+// ServerListDiffResp notifies about changes of the servers list since the previous update
+// (sent instead of the full 'ServerListResp' to clients supporting protocol schema version >= 2:
+// reduces IPC traffic for large server lists)
+type ServerListDiffResp struct {
+	CommandBase
+	Changes types.ServersListDiff
+}
+
+// PingResultType represents information ping TTL for a host (is a part of 'PingServersResp')
 type PingResultType struct {
 	Host string
 	Ping int
+	// country info of the host from the local GeoIP dataset (empty - no info available)
+	CountryCode string `json:",omitempty"`
+	Country     string `json:",omitempty"`
 }
 
 // PingServersResp returns average ping time for servers
@@ -217,12 +269,306 @@ type WiFiAvailableNetworksResp struct {
 }
 
 // WiFiCurrentNetworkResp contains the information about currently connected WIFI
+// (sent also as a notification on every SSID\BSSID\security change)
 type WiFiCurrentNetworkResp struct {
 	CommandBase
-	SSID              string
+	SSID string
+	// BSSID - MAC address of the access point (empty - not available;
+	// changed BSSID with same SSID means roaming to another access point)
+	BSSID             string
 	IsInsecureNetwork bool
 }
 
+// CaptivePortalResp - notification: current network intercepts traffic (captive portal)
+type CaptivePortalResp struct {
+	CommandBase
+	IsDetected bool
+}
+
+// CaptivePortalBypassResp - notification: captive portal bypass started\finished
+type CaptivePortalBypassResp struct {
+	CommandBase
+	IsActive bool
+	// ExpiresAt - unix time (seconds) when full protection will be restored (0 - bypass finished)
+	ExpiresAt int64
+}
+
+// ExportedSettingsResp contains encrypted bundle of exported daemon settings
+type ExportedSettingsResp struct {
+	CommandBase
+	Bundle string
+}
+
+// GeoLookupResp - geolocation info: location through the VPN tunnel and outside of it
+// (a nil lookup result means that the lookup failed: see corresponding error message)
+type GeoLookupResp struct {
+	CommandBase
+	InTunnel         *types.GeoLookupResponse
+	OutOfTunnel      *types.GeoLookupResponse
+	InTunnelError    string
+	OutOfTunnelError string
+}
+
+// PortForwardResp - info about forwarded port (port==0: no forwarded port)
+type PortForwardResp struct {
+	CommandBase
+	Port      int
+	ExpiresAt int64 // unix time (seconds)
+}
+
+// OperationProgressResp - notification about progress of a long operation
+// (e.g. latency scan, service install, diagnostics collection)
+type OperationProgressResp struct {
+	CommandBase
+	// OperationID - unique ID of operation instance (to correlate events of same operation)
+	OperationID string
+	// Operation - name of operation (e.g. "latency_scan")
+	Operation string
+	// Phase - current phase of operation
+	Phase string
+	// Progress - progress in percent [0-100] (-1 when progress is indeterminate)
+	Progress int
+}
+
+// CapabilityTokenResp contains an authentication token restricted to the defined capabilities
+type CapabilityTokenResp struct {
+	CommandBase
+	Token        string
+	Capabilities []string
+}
+
+// ControlLockResp - result of a control lock request
+// (when the lock is held by another client - 'IsAcquired'==false and 'OwnerClientName' informs who holds it)
+type ControlLockResp struct {
+	CommandBase
+	IsAcquired      bool
+	OwnerClientName string
+}
+
+// ClientActivityResp - notification to other clients about a state-changing command
+// initiated by one of the connected clients
+type ClientActivityResp struct {
+	CommandBase
+	// ClientName - name of the initiator ('Hello' request; address of the connection when name not defined)
+	ClientName string
+	// InitiatedCommand - name of the command initiated by the client (e.g. "Connect", "KillSwitchSetEnabled")
+	InitiatedCommand string
+}
+
+// ProfileListResp contains names of all saved connection profiles
+type ProfileListResp struct {
+	CommandBase
+	Profiles []string
+}
+
+// OvpnProfileInfo - info about an imported OpenVPN profile
+// (country info comes from the local GeoIP dataset; empty - no info available)
+type OvpnProfileInfo struct {
+	Name        string
+	CountryCode string `json:",omitempty"`
+	Country     string `json:",omitempty"`
+}
+
+// OvpnProfileListResp contains names of all imported OpenVPN profiles
+// (credentials are never sent back to clients)
+type OvpnProfileListResp struct {
+	CommandBase
+	Profiles []string
+	// ProfilesInfo - same profiles with country annotations (when available)
+	ProfilesInfo []OvpnProfileInfo `json:",omitempty"`
+}
+
+// ServersMetadataResp contains user-defined metadata of all VPN servers (server gateway -> metadata)
+// (also broadcasted to all clients when metadata changed: favorites follow the machine)
+type ServersMetadataResp struct {
+	CommandBase
+	ServersMetadata map[string]preferences.ServerMetadata
+}
+
+// SelfTestCheck - result of one self-test check
+type SelfTestCheck struct {
+	// Name of the check: "default_route", "dns_leak", "ipv6_leak", "kill_switch"
+	Name string
+	// Passed - true when no problem detected
+	Passed bool
+	// Skipped - true when the check is not applicable for current configuration
+	Skipped bool
+	// Details - human-readable description of the check result
+	Details string
+}
+
+// SelfTestResp contains results of connectivity and leak self-test
+type SelfTestResp struct {
+	CommandBase
+	Checks []SelfTestCheck
+}
+
+// DaemonHealthResp - daemon uptime and health of internal subsystems
+// (no network requests performed to collect this data)
+type DaemonHealthResp struct {
+	CommandBase
+	// UptimeSec - daemon process uptime (seconds)
+	UptimeSec int64
+
+	// IsFirewallOperational - firewall engine responds to state requests
+	IsFirewallOperational bool
+	FirewallError         string `json:",omitempty"`
+
+	// IsOpenVPNAvailable - OpenVPN binary is present and has correct access rights
+	IsOpenVPNAvailable bool
+	OpenVPNError       string `json:",omitempty"`
+	// IsWireGuardAvailable - WireGuard binary is present and has correct access rights
+	IsWireGuardAvailable bool
+	WireGuardError       string `json:",omitempty"`
+
+	// SecondsSinceAPISuccess - seconds since the last successful API request
+	// (-1 - no successful API requests performed yet)
+	SecondsSinceAPISuccess int64
+}
+
+// ConnectionHistoryRecord - info about one VPN session from local connection history
+type ConnectionHistoryRecord struct {
+	// StartedAt - unix time (seconds) when connection was initiated
+	StartedAt int64
+	// ConnectedAt - unix time (seconds) when connection was established (0 - connection failed)
+	ConnectedAt int64
+	// DisconnectedAt - unix time (seconds) when connection was closed
+	DisconnectedAt int64
+	// Server - IP address of the VPN server
+	Server string
+	// VpnType - "OpenVPN" or "WireGuard"
+	VpnType string
+	// BytesReceived\BytesSent - bytes transferred during the session (0 - no data available)
+	BytesReceived int64
+	BytesSent     int64
+	// FailureReason - empty for normally closed sessions
+	FailureReason string
+}
+
+// ConnectionHistoryResp contains last records of local connection history (newest first)
+type ConnectionHistoryResp struct {
+	CommandBase
+	Records []ConnectionHistoryRecord
+}
+
+// ConnectionDiagnosticsResp contains opt-in connection diagnostics report (JSON; newest first)
+type ConnectionDiagnosticsResp struct {
+	CommandBase
+	Report string
+}
+
+// LanServiceDeviceInfo - discovered background LAN service (is a part of 'LanServicesResp')
+type LanServiceDeviceInfo struct {
+	// Name - service instance name (e.g. "Office printer")
+	Name string
+	// Kind - human-readable device kind ("printer", "casting device")
+	Kind string
+	// IP - IPv4 address of the device
+	IP string
+	// IsAllowed - device is allowed by the user while kill switch is on
+	IsAllowed bool
+}
+
+// LanServicesResp contains LAN services discovered over mDNS\Bonjour
+type LanServicesResp struct {
+	CommandBase
+	Devices []LanServiceDeviceInfo
+}
+
+// PauseStateResp - current pause state of the connection
+// (sent also as a notification when connection paused\resumed)
+type PauseStateResp struct {
+	CommandBase
+	IsPaused bool
+	// AutoResumeAt - unix time (seconds) when connection will be automatically resumed (0 - no auto-resume)
+	AutoResumeAt int64
+}
+
+// BandwidthStats - counters of transferred bytes (per-session and per-calendar-month)
+type BandwidthStats struct {
+	// SessionBytesReceived\SessionBytesSent - bytes transferred during the current VPN session (0 - not connected)
+	SessionBytesReceived int64
+	SessionBytesSent     int64
+	// Month - identifier of the calendar month the counters belong to ("YYYY-MM")
+	Month string
+	// MonthBytesReceived\MonthBytesSent - cumulative bytes transferred during the month
+	MonthBytesReceived int64
+	MonthBytesSent     int64
+	// MonthlyCapBytes - user-defined monthly traffic cap (0 - no cap)
+	MonthlyCapBytes int64
+	// AlertThresholdPercent - percent of the cap the alert notification is sent at
+	AlertThresholdPercent int
+}
+
+// BandwidthStatsResp contains current bandwidth usage counters
+type BandwidthStatsResp struct {
+	CommandBase
+	Stats BandwidthStats
+}
+
+// ConnectionQualityResp - notification: measured latency\jitter through the active tunnel
+// (sent periodically when enabled by the 'connection_quality_interval_seconds' preference)
+type ConnectionQualityResp struct {
+	CommandBase
+	LatencyMs int
+	JitterMs  int
+}
+
+// BetterServerAvailableResp - notification: a clearly better server is available for the current connection
+// (advisory; 'IsAutoMigrate == true' when daemon is going to switch to the server by itself)
+type BetterServerAvailableResp struct {
+	CommandBase
+	Gateway        string
+	Score          float64
+	CurrentGateway string
+	CurrentScore   float64
+	IsAutoMigrate  bool
+}
+
+// BandwidthAlertResp - notification: consumed monthly traffic reached the configured percent of a cap
+type BandwidthAlertResp struct {
+	CommandBase
+	UsedBytes        int64
+	CapBytes         int64
+	ThresholdPercent int
+}
+
+// DiagnosticBundleResp contains assembled support bundle (base64 of a zip archive)
+type DiagnosticBundleResp struct {
+	CommandBase
+	Bundle string
+}
+
+// SchedulerRulesResp contains currently configured scheduler rules
+type SchedulerRulesResp struct {
+	CommandBase
+	Rules []SchedulerRule
+}
+
+// SchedulerActionResp notification about performed scheduled action
+type SchedulerActionResp struct {
+	CommandBase
+	// Action which was performed: "connect" or "disconnect"
+	Action string
+	// Time of day the action was scheduled on ("HH:MM")
+	Time string
+}
+
+// APIResponseChunk contains one chunk of response to streamed API request
+// The last chunk of a download has 'IsFinished'==true (and empty 'Data')
+type APIResponseChunk struct {
+	CommandBase
+	APIPath string
+	// Data - base64-encoded chunk of response body
+	Data string
+	// Received - total bytes received so far
+	Received int64
+	// ContentLength - full response size in bytes (-1 when not known)
+	ContentLength int64
+	IsFinished    bool
+	Error         string
+}
+
 // APIResponse contains the raw data of response to custom API request
 type APIResponse struct {
 	CommandBase
@@ -30,6 +30,18 @@ import (
 	"strings"
 )
 
+// ProtocolSchemaVersion - current version of the daemon protocol schema.
+// Daemon and client exchange supported schema versions by the 'Hello' request\response:
+// daemon does not send notifications of newer schema versions to older clients,
+// client does not use functionality of newer schema versions (see also 'HelloResp.Features').
+// Version history:
+//
+//	0 - initial schema (clients implemented before schema versioning)
+//	1 - multi-client arbitration notifications ('ClientActivityResp')
+//	2 - servers list delta notifications ('ServerListDiffResp')
+//	3 - servers metadata notifications ('ServersMetadataResp')
+const ProtocolSchemaVersion = 3
+
 // CommandBase is a base object for communication with daemon.
 // Contains fields required for all requests\responses.
 type CommandBase struct {
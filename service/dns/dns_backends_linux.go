@@ -0,0 +1,367 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package dns
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/ivpn/desktop-app-daemon/service/platform"
+	"github.com/ivpn/desktop-app-daemon/shell"
+)
+
+// dnsBackend - interface of a DNS configuration mechanism
+// The concrete implementation is auto-detected at daemon start (according to how '/etc/resolv.conf' is managed on the current system)
+type dnsBackend interface {
+	// name of the backend (for logging)
+	name() string
+	// isAvailable returns true when the backend can be used on the current system
+	isAvailable() bool
+	// setDNS applies the DNS server to the OS configuration
+	setDNS(addr net.IP) error
+	// restoreDNS reverts the OS DNS configuration to default
+	// ('isKeepBackup' == true when the configuration will be applied back soon: e.g. paused connection)
+	restoreDNS(isKeepBackup bool) error
+}
+
+// detectDNSBackend returns the DNS configuration backend applicable for the current system
+func detectDNSBackend() dnsBackend {
+	backends := []dnsBackend{
+		&systemdResolvedBackend{},
+		&resolvconfBackend{},
+		&networkManagerBackend{}}
+
+	for _, b := range backends {
+		if b.isAvailable() {
+			return b
+		}
+	}
+
+	// fallback: direct modification of '/etc/resolv.conf'
+	return &resolvFileBackend{}
+}
+
+// vpnLinkName returns the name of an active VPN network interface
+// (e.g. 'wgivpn' for WireGuard, 'tunX' for OpenVPN)
+func vpnLinkName() (string, error) {
+	// WireGuard interface name is defined by the configuration file name
+	wgName := filepath.Base(platform.WGConfigFilePath())
+	wgName = strings.TrimSuffix(wgName, filepath.Ext(wgName))
+
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return "", fmt.Errorf("failed to enumerate network interfaces: %w", err)
+	}
+
+	for _, ifc := range ifaces {
+		if ifc.Flags&net.FlagUp == 0 {
+			continue
+		}
+		if ifc.Name == wgName || strings.HasPrefix(ifc.Name, "tun") {
+			return ifc.Name, nil
+		}
+	}
+
+	return "", fmt.Errorf("VPN network interface not found")
+}
+
+// resolvFileBackend - direct modification of '/etc/resolv.conf'
+// The original file is backed up; file-change monitoring restores our configuration
+// when it was overwritten by a third-party tool
+type resolvFileBackend struct{}
+
+func (b *resolvFileBackend) name() string { return "resolv.conf" }
+
+// isAvailable - direct file modification is always possible (the last-resort backend)
+func (b *resolvFileBackend) isAvailable() bool { return true }
+
+func (b *resolvFileBackend) setDNS(addr net.IP) error {
+	stopDNSChangeMonitoring()
+
+	createBackupIfNotExists := func() (created bool, er error) {
+		isOwerwriteIfExists := false
+		return createBackup(resolvBackupFile, isOwerwriteIfExists)
+	}
+
+	saveNewConfig := func() error {
+		createBackupIfNotExists()
+
+		// create new configuration
+		out, err := os.OpenFile(resolvFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, defaultFilePermissions)
+		if err != nil {
+			return fmt.Errorf("failed to update DNS configuration (%w)", err)
+		}
+
+		if _, err := out.WriteString(fmt.Sprintln(fmt.Sprintf("# resolv.conf autogenerated by '%s'\n\nnameserver %s", os.Args[0], addr.String()))); err != nil {
+			return fmt.Errorf("failed to change DNS configuration: %w", err)
+		}
+
+		if err := out.Sync(); err != nil {
+			return fmt.Errorf("failed to change DNS configuration: %w", err)
+		}
+		return nil
+	}
+
+	_, err := createBackupIfNotExists()
+	if err != nil {
+		return err
+	}
+
+	// Save new configuration
+	if err := saveNewConfig(); err != nil {
+		return err
+	}
+
+	// enable file change monitoring
+	go func() {
+		w, err := fsnotify.NewWatcher()
+		if err != nil {
+			log.Error(fmt.Errorf("failed to start DNS-change monitoring (fsnotify error): %w", err))
+			return
+		}
+
+		log.Info("DNS-change monitoring started")
+		defer func() {
+			w.Close()
+			log.Info("DNS-change monitoring stopped")
+		}()
+
+		for {
+			// start watching file
+			err = w.Add(resolvFile)
+			if err != nil {
+				log.Error(fmt.Errorf("failed to start DNS-change monitoring (fsnotify error): %w", err))
+				return
+			}
+
+			// wait for changes
+			var evt fsnotify.Event
+			select {
+			case evt = <-w.Events:
+				break
+			case <-done:
+				// monitoring stopped
+				return
+			}
+
+			//stop watching file
+			if err := w.Remove(resolvFile); err != nil {
+				log.Error(fmt.Errorf("failed to remove warcher (fsnotify error): %w", err))
+			}
+
+			// wait 2 seconds for reaction (in case if we are stopping of when multiple consecutive file changes)
+			select {
+			case <-time.After(time.Second * 2):
+			case <-done:
+				// monitoring stopped
+				return
+			}
+
+			// restore DNS configuration
+			log.Info(fmt.Sprintf("DNS-change monitoring: DNS was changed outside [%s]. Restoring ...", evt.Op.String()))
+			if err := saveNewConfig(); err != nil {
+				log.Error(err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (b *resolvFileBackend) restoreDNS(isKeepBackup bool) error {
+	// stop file change monitoring
+	stopDNSChangeMonitoring()
+	return restoreBackup(resolvBackupFile, isKeepBackup == false)
+}
+
+// systemdResolvedBackend - DNS configuration via systemd-resolved
+// The 'resolvectl' tool is using D-Bus calls SetLinkDNS\SetLinkDomains of the resolved manager,
+// so the configuration is bound to the VPN link and is not affected by '/etc/resolv.conf' rewrites
+type systemdResolvedBackend struct {
+	// link which the DNS configuration was applied to (empty - nothing applied)
+	appliedLink string
+}
+
+func (b *systemdResolvedBackend) name() string { return "systemd-resolved" }
+
+func (b *systemdResolvedBackend) isAvailable() bool {
+	if _, err := exec.LookPath("resolvectl"); err != nil {
+		return false
+	}
+	// '/etc/resolv.conf' has to be under control of systemd-resolved
+	target, err := filepath.EvalSymlinks(resolvFile)
+	if err != nil {
+		return false
+	}
+	return strings.Contains(target, "systemd")
+}
+
+func (b *systemdResolvedBackend) setDNS(addr net.IP) error {
+	link, err := vpnLinkName()
+	if err != nil {
+		return fmt.Errorf("failed to apply DNS configuration: %w", err)
+	}
+
+	if err := shell.Exec(log, "resolvectl", "dns", link, addr.String()); err != nil {
+		return fmt.Errorf("failed to apply DNS configuration (resolvectl): %w", err)
+	}
+	// route all DNS requests over the VPN link (otherwise resolved can still use per-link DNS of a physical interface)
+	if err := shell.Exec(log, "resolvectl", "domain", link, "~."); err != nil {
+		return fmt.Errorf("failed to apply DNS routing domain (resolvectl): %w", err)
+	}
+
+	b.appliedLink = link
+	return nil
+}
+
+func (b *systemdResolvedBackend) restoreDNS(isKeepBackup bool) error {
+	if len(b.appliedLink) == 0 {
+		// nothing to restore
+		return nil
+	}
+
+	err := shell.Exec(log, "resolvectl", "revert", b.appliedLink)
+	if err != nil {
+		// the VPN link could be already removed (per-link configuration erased with it)
+		log.Warning(fmt.Errorf("failed to revert DNS configuration (resolvectl): %w", err))
+	}
+
+	if isKeepBackup == false {
+		b.appliedLink = ""
+	}
+	return nil
+}
+
+// resolvconfBackend - DNS configuration via the 'resolvconf' tool
+// (openresolv or Debian resolvconf implementations)
+type resolvconfBackend struct {
+	// record name the configuration was registered with (empty - nothing applied)
+	appliedRecord string
+}
+
+func (b *resolvconfBackend) name() string { return "resolvconf" }
+
+func (b *resolvconfBackend) isAvailable() bool {
+	if _, err := exec.LookPath("resolvconf"); err != nil {
+		return false
+	}
+	// '/etc/resolv.conf' has to be under control of resolvconf
+	target, err := filepath.EvalSymlinks(resolvFile)
+	if err != nil {
+		return false
+	}
+	return strings.Contains(target, "resolvconf")
+}
+
+func (b *resolvconfBackend) setDNS(addr net.IP) error {
+	link, err := vpnLinkName()
+	if err != nil {
+		return fmt.Errorf("failed to apply DNS configuration: %w", err)
+	}
+	record := link + ".ivpn"
+
+	// 'resolvconf -a' reads the configuration from stdin
+	cmd := exec.Command("resolvconf", "-a", record)
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("nameserver %s\n", addr.String()))
+	if outText, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to apply DNS configuration (resolvconf): %w (%s)", err, strings.TrimSpace(string(outText)))
+	}
+
+	b.appliedRecord = record
+	return nil
+}
+
+func (b *resolvconfBackend) restoreDNS(isKeepBackup bool) error {
+	if len(b.appliedRecord) == 0 {
+		// nothing to restore
+		return nil
+	}
+
+	if err := shell.Exec(log, "resolvconf", "-d", b.appliedRecord); err != nil {
+		return fmt.Errorf("failed to restore DNS configuration (resolvconf): %w", err)
+	}
+
+	if isKeepBackup == false {
+		b.appliedRecord = ""
+	}
+	return nil
+}
+
+// networkManagerBackend - direct modification of '/etc/resolv.conf' on systems where the file
+// is managed by NetworkManager (without systemd-resolved or resolvconf)
+// NetworkManager DNS processing is temporary disabled (by a config drop-in) while the manual DNS is active,
+// otherwise NM rewrites our configuration on every network event
+type networkManagerBackend struct {
+	resolvFileBackend
+}
+
+// path of the NetworkManager config drop-in which disables its DNS processing
+const nmConfFile = "/etc/NetworkManager/conf.d/ivpn-dns.conf"
+
+const nmConfData = "# autogenerated by the IVPN daemon (will be removed on disconnect)\n[main]\ndns=none\n"
+
+func (b *networkManagerBackend) name() string { return "NetworkManager" }
+
+func (b *networkManagerBackend) isAvailable() bool {
+	if _, err := exec.LookPath("nmcli"); err != nil {
+		return false
+	}
+	data, err := ioutil.ReadFile(resolvFile)
+	if err != nil {
+		return false
+	}
+	return strings.Contains(string(data), "NetworkManager")
+}
+
+func (b *networkManagerBackend) setDNS(addr net.IP) error {
+	// stop NetworkManager from overwriting resolv.conf while the manual DNS is active
+	if err := ioutil.WriteFile(nmConfFile, []byte(nmConfData), 0644); err != nil {
+		log.Warning(fmt.Errorf("failed to disable NetworkManager DNS processing: %w", err))
+	} else if err := shell.Exec(log, "nmcli", "general", "reload", "conf"); err != nil {
+		log.Warning(fmt.Errorf("failed to reload NetworkManager configuration: %w", err))
+	}
+
+	return b.resolvFileBackend.setDNS(addr)
+}
+
+func (b *networkManagerBackend) restoreDNS(isKeepBackup bool) error {
+	ret := b.resolvFileBackend.restoreDNS(isKeepBackup)
+
+	if isKeepBackup == false {
+		// give DNS processing back to NetworkManager
+		if err := os.Remove(nmConfFile); err == nil {
+			if err := shell.Exec(log, "nmcli", "general", "reload", "conf"); err != nil {
+				log.Warning(fmt.Errorf("failed to reload NetworkManager configuration: %w", err))
+			}
+		}
+	}
+
+	return ret
+}
@@ -23,8 +23,12 @@
 package dns
 
 import (
+	"bufio"
 	"fmt"
 	"net"
+	"os/exec"
+	"strings"
+	"time"
 
 	"github.com/ivpn/desktop-app-daemon/service/platform"
 	"github.com/ivpn/desktop-app-daemon/shell"
@@ -32,9 +36,80 @@ import (
 
 // implInitialize doing initialization stuff (called on application start)
 func implInitialize() error {
+	// start watching for DNS configuration changes
+	startDNSChangeMonitoring()
 	return nil
 }
 
+// startDNSChangeMonitoring - start watching for DNS configuration changes in the SystemConfiguration dynamic store.
+// Third-party software or the OS itself (on network changes) can overwrite the DNS configuration of the primary service;
+// in this case the IVPN DNS configuration will be re-applied
+// (has no effect when the IVPN DNS is not set: see '-update' command of the DNS script)
+func startDNSChangeMonitoring() {
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Error("PANIC in DNS-change monitoring (recovered): ", r)
+			}
+		}()
+
+		for {
+			if err := runDNSChangeWatcher(); err != nil {
+				log.Error(fmt.Errorf("DNS-change monitoring error: %w", err))
+			}
+			// the 'scutil' session finished unexpectedly - restart it after a delay
+			time.Sleep(time.Second * 5)
+		}
+	}()
+}
+
+// runDNSChangeWatcher - start interactive 'scutil' session subscribed for notifications
+// about changes of the DNS keys of network services (blocks until the session is closed)
+func runDNSChangeWatcher() error {
+	cmd := exec.Command("/usr/sbin/scutil")
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to start scutil: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to start scutil: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start scutil: %w", err)
+	}
+	defer func() {
+		stdin.Close()
+		cmd.Wait()
+	}()
+
+	// subscribe for notifications about DNS configuration changes
+	if _, err := fmt.Fprintln(stdin, "n.add State:/Network/Service/.*/DNS pattern\nn.watch"); err != nil {
+		return fmt.Errorf("failed to subscribe for DNS-change notifications: %w", err)
+	}
+
+	log.Info("DNS-change monitoring started")
+	defer log.Info("DNS-change monitoring stopped")
+
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		if strings.Contains(scanner.Text(), "changed key") == false {
+			continue
+		}
+
+		// DNS configuration of a network service was changed outside
+		// Re-apply the IVPN DNS configuration (the script does nothing when the DNS was not overwritten
+		// or when the IVPN DNS is not set)
+		if err := shell.Exec(log, platform.DNSScript(), "-update"); err != nil {
+			log.Error(fmt.Errorf("failed to re-apply DNS configuration: %w", err))
+		}
+	}
+
+	return scanner.Err()
+}
+
 func implPause() error {
 	err := shell.Exec(log, platform.DNSScript(), "-pause")
 	if err != nil {
@@ -26,9 +26,7 @@ import (
 	"fmt"
 	"net"
 	"os"
-	"time"
 
-	"github.com/fsnotify/fsnotify"
 	"github.com/ivpn/desktop-app-daemon/helpers"
 )
 
@@ -40,6 +38,9 @@ var (
 	isPaused  bool   = false
 	manualDNS net.IP = nil
 
+	// active DNS configuration backend (detected by 'implInitialize()'; see 'dns_backends_linux.go')
+	backend dnsBackend
+
 	done chan struct{}
 )
 
@@ -49,15 +50,25 @@ func init() {
 
 // implInitialize doing initialization stuff (called on application start)
 func implInitialize() error {
+	backend = detectDNSBackend()
+	log.Info("DNS configuration backend: ", backend.name())
+
+	// remove NetworkManager config drop-in which could be left by unexpected daemon stop
+	if err := os.Remove(nmConfFile); err == nil {
+		log.Info("Detected NetworkManager configuration from the previous VPN connection. Removed")
+	}
+
 	// check if backup DNS file exists
-	if _, err := os.Stat(resolvBackupFile); err != nil {
+	// (backup file is always file-based; it can be left only by the 'resolv.conf' backends)
+	if isBackupExists(resolvBackupFile) == false {
 		// nothing to restore
 		return nil
 	}
 
 	log.Info("Detected DNS configuration from the previous VPN connection. Restoring OS-default DNS values ...")
 	// restore it
-	if err := implDeleteManual(nil); err != nil {
+	isDeleteBackup := true // delete backup file
+	if err := restoreBackup(resolvBackupFile, isDeleteBackup); err != nil {
 		return fmt.Errorf("failed to restore DNS to default: %w", err)
 	}
 
@@ -65,19 +76,15 @@ func implInitialize() error {
 }
 
 func implPause() error {
-	if isBackupExists(resolvBackupFile) == false {
-		// The backup for the OS-defined configuration not exists.
+	if manualDNS == nil {
 		// It seems, we are not connected. Nothing to pause.
 		return nil
 	}
 
-	// stop file change monitoring
-	stopDNSChangeMonitoring()
-
 	// restore original OS-default DNS configuration
-	// (the backup file will not be deleted)
-	isDeleteBackup := false // do not delete backup file
-	ret := restoreBackup(resolvBackupFile, isDeleteBackup)
+	// (the configuration will be applied back on RESUME)
+	isKeepBackup := true
+	ret := backend.restoreDNS(isKeepBackup)
 
 	isPaused = true
 	return ret
@@ -109,101 +116,15 @@ func implSetManual(addr net.IP, localInterfaceIP net.IP) error {
 		return nil
 	}
 
-	stopDNSChangeMonitoring()
-
 	if addr == nil {
 		return implDeleteManual(nil)
 	}
 
-	createBackupIfNotExists := func() (created bool, er error) {
-		isOwerwriteIfExists := false
-		return createBackup(resolvBackupFile, isOwerwriteIfExists)
-	}
-
-	saveNewConfig := func() error {
-		createBackupIfNotExists()
-
-		// create new configuration
-		out, err := os.OpenFile(resolvFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, defaultFilePermissions)
-		if err != nil {
-			return fmt.Errorf("failed to update DNS configuration (%w)", err)
-		}
-
-		if _, err := out.WriteString(fmt.Sprintln(fmt.Sprintf("# resolv.conf autogenerated by '%s'\n\nnameserver %s", os.Args[0], addr.String()))); err != nil {
-			return fmt.Errorf("failed to change DNS configuration: %w", err)
-		}
-
-		if err := out.Sync(); err != nil {
-			return fmt.Errorf("failed to change DNS configuration: %w", err)
-		}
-		return nil
-	}
-
-	_, err := createBackupIfNotExists()
-	if err != nil {
-		return err
-	}
-
-	// Save new configuration
-	if err := saveNewConfig(); err != nil {
+	if err := backend.setDNS(addr); err != nil {
 		return err
 	}
 
 	manualDNS = addr
-
-	// enable file change monitoring
-	go func() {
-		w, err := fsnotify.NewWatcher()
-		if err != nil {
-			log.Error(fmt.Errorf("failed to start DNS-change monitoring (fsnotify error): %w", err))
-			return
-		}
-
-		log.Info("DNS-change monitoring started")
-		defer func() {
-			w.Close()
-			log.Info("DNS-change monitoring stopped")
-		}()
-
-		for {
-			// start watching file
-			err = w.Add(resolvFile)
-			if err != nil {
-				log.Error(fmt.Errorf("failed to start DNS-change monitoring (fsnotify error): %w", err))
-				return
-			}
-
-			// wait for changes
-			var evt fsnotify.Event
-			select {
-			case evt = <-w.Events:
-				break
-			case <-done:
-				// monitoring stopped
-				return
-			}
-
-			//stop watching file
-			if err := w.Remove(resolvFile); err != nil {
-				log.Error(fmt.Errorf("failed to remove warcher (fsnotify error): %w", err))
-			}
-
-			// wait 2 seconds for reaction (in case if we are stopping of when multiple consecutive file changes)
-			select {
-			case <-time.After(time.Second * 2):
-			case <-done:
-				// monitoring stopped
-				return
-			}
-
-			// restore DNS configuration
-			log.Info(fmt.Sprintf("DNS-change monitoring: DNS was changed outside [%s]. Restoring ...", evt.Op.String()))
-			if err := saveNewConfig(); err != nil {
-				log.Error(err)
-			}
-		}
-	}()
-
 	return nil
 }
 
@@ -216,10 +137,9 @@ func implDeleteManual(localInterfaceIP net.IP) error {
 		manualDNS = nil
 		return nil
 	}
-	// stop file change monitoring
-	stopDNSChangeMonitoring()
-	isDeleteBackup := true // delete backup file
-	return restoreBackup(resolvBackupFile, isDeleteBackup)
+
+	isKeepBackup := false
+	return backend.restoreDNS(isKeepBackup)
 }
 
 func stopDNSChangeMonitoring() {
@@ -0,0 +1,203 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package dns
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/ivpn/desktop-app-daemon/netinfo"
+)
+
+// Modern DNS configuration mechanism: 'SetInterfaceDnsSettings' (iphlpapi.dll; available since Windows 10 2004)
+// Supports multiple resolvers for an interface and DNS-over-HTTPS templates (Windows 11)
+var (
+	_fSetInterfaceDNSSettings     *syscall.LazyProc // NTSTATUS SetInterfaceDnsSettings(GUID Interface, const DNS_INTERFACE_SETTINGS* Settings)
+	_fConvertInterfaceIndexToLUID *syscall.LazyProc // NETIO_STATUS ConvertInterfaceIndexToLuid(NET_IFINDEX InterfaceIndex, PNET_LUID InterfaceLuid)
+	_fConvertInterfaceLUIDToGUID  *syscall.LazyProc // NETIO_STATUS ConvertInterfaceLuidToGuid(const NET_LUID* InterfaceLuid, GUID* InterfaceGuid)
+)
+
+// flags of DNS_INTERFACE_SETTINGS (only flags in use defined)
+const (
+	dnsSettingNameserver uint64 = 0x0002
+	dnsSettingDoh        uint64 = 0x1000
+)
+
+const (
+	dnsServerPropertyVersion1 uint32 = 1
+	dnsServerDohProperty      uint32 = 1 // DNS_SERVER_PROPERTY_TYPE
+
+	dnsDohServerSettingsEnable uint64 = 0x1 // DNS_DOH_SERVER_SETTINGS flags
+)
+
+type windowsGUID struct {
+	Data1 uint32
+	Data2 uint16
+	Data3 uint16
+	Data4 [8]byte
+}
+
+// DNS_DOH_SERVER_SETTINGS
+type dnsDohServerSettings struct {
+	Template *uint16
+	Flags    uint64
+}
+
+// DNS_SERVER_PROPERTY
+type dnsServerProperty struct {
+	Version     uint32
+	ServerIndex uint32
+	Type        uint32 // DNS_SERVER_PROPERTY_TYPE
+	_           uint32
+	Property    unsafe.Pointer // PDNS_DOH_SERVER_SETTINGS (for 'dnsServerDohProperty' type)
+}
+
+// DNS_INTERFACE_SETTINGS3
+type dnsInterfaceSettings struct {
+	Version                      uint32 // 1 - basic settings; 3 - required for per-server DoH properties
+	_                            uint32
+	Flags                        uint64
+	Domain                       *uint16
+	NameServer                   *uint16 // comma-separated list of DNS servers
+	SearchList                   *uint16
+	RegistrationEnabled          uint32
+	RegisterAdapterName          uint32
+	EnableLLMNR                  uint32
+	QueryAdapterName             uint32
+	ProfileNameServer            *uint16
+	DisableUnconstrainedQueries  uint32
+	_                            uint32
+	SupplementalSearchList       *uint16
+	ServerPropertiesCount        uint32
+	_                            uint32
+	ServerProperties             *dnsServerProperty
+	ProfileServerPropertiesCount uint32
+	_                            uint32
+	ProfileServerProperties      *dnsServerProperty
+}
+
+func initDNSSettingsAPI() {
+	dll := syscall.NewLazyDLL("iphlpapi.dll")
+	_fSetInterfaceDNSSettings = dll.NewProc("SetInterfaceDnsSettings")
+	_fConvertInterfaceIndexToLUID = dll.NewProc("ConvertInterfaceIndexToLuid")
+	_fConvertInterfaceLUIDToGUID = dll.NewProc("ConvertInterfaceLuidToGuid")
+}
+
+// isDNSSettingsAPIAvailable returns true when 'SetInterfaceDnsSettings' is supported by the current system
+// (Windows 10 2004+; when not available - the legacy helper-dll implementation have to be used)
+func isDNSSettingsAPIAvailable() bool {
+	if _fSetInterfaceDNSSettings == nil {
+		return false
+	}
+	return _fSetInterfaceDNSSettings.Find() == nil
+}
+
+// getInterfaceGUID returns GUID of the network interface defined by its local IP address
+func getInterfaceGUID(localAddr net.IP) (windowsGUID, error) {
+	var guid windowsGUID
+
+	ifc, err := netinfo.InterfaceByIPAddr(localAddr)
+	if err != nil {
+		return guid, fmt.Errorf("failed to get interface by IP address: %w", err)
+	}
+
+	var luid uint64
+	retval, _, err := _fConvertInterfaceIndexToLUID.Call(
+		uintptr(uint32(ifc.Index)),
+		uintptr(unsafe.Pointer(&luid)))
+	if e := checkDefaultAPIResp(retval, err); e != nil {
+		return guid, fmt.Errorf("failed to get interface LUID: %w", e)
+	}
+
+	retval, _, err = _fConvertInterfaceLUIDToGUID.Call(
+		uintptr(unsafe.Pointer(&luid)),
+		uintptr(unsafe.Pointer(&guid)))
+	if e := checkDefaultAPIResp(retval, err); e != nil {
+		return guid, fmt.Errorf("failed to get interface GUID: %w", e)
+	}
+
+	return guid, nil
+}
+
+// applyInterfaceDNSSettings defines DNS configuration for a network interface ('SetInterfaceDnsSettings' API)
+//
+//	localInterfaceIP - local IP of the interface to configure
+//	dnsServers       - DNS resolvers to set (empty list - reset interface DNS configuration to default)
+//	dohTemplate      - DNS-over-HTTPS template URI applicable to all defined resolvers
+//	                   (e.g. "https://dns.example.com/dns-query"; empty - plain DNS; requires Windows 11)
+func applyInterfaceDNSSettings(localInterfaceIP net.IP, dnsServers []net.IP, dohTemplate string) error {
+	guid, err := getInterfaceGUID(localInterfaceIP)
+	if err != nil {
+		return err
+	}
+
+	addrs := make([]string, 0, len(dnsServers))
+	for _, dns := range dnsServers {
+		addrs = append(addrs, dns.String())
+	}
+
+	nameServer, err := syscall.UTF16PtrFromString(strings.Join(addrs, ","))
+	if err != nil {
+		return fmt.Errorf("failed to apply DNS configuration: %w", err)
+	}
+
+	settings := dnsInterfaceSettings{
+		Version:    1,
+		Flags:      dnsSettingNameserver,
+		NameServer: nameServer}
+
+	// per-server DoH properties (DNS_INTERFACE_SETTINGS v3)
+	var serverProperties []dnsServerProperty
+	if len(dohTemplate) > 0 && len(dnsServers) > 0 {
+		template, err := syscall.UTF16PtrFromString(dohTemplate)
+		if err != nil {
+			return fmt.Errorf("failed to apply DoH configuration: %w", err)
+		}
+
+		dohSettings := dnsDohServerSettings{
+			Template: template,
+			Flags:    dnsDohServerSettingsEnable}
+
+		for i := range dnsServers {
+			serverProperties = append(serverProperties, dnsServerProperty{
+				Version:     dnsServerPropertyVersion1,
+				ServerIndex: uint32(i),
+				Type:        dnsServerDohProperty,
+				Property:    unsafe.Pointer(&dohSettings)})
+		}
+
+		settings.Version = 3
+		settings.Flags |= dnsSettingDoh
+		settings.ServerPropertiesCount = uint32(len(serverProperties))
+		settings.ServerProperties = &serverProperties[0]
+	}
+
+	retval, _, err := _fSetInterfaceDNSSettings.Call(
+		uintptr(unsafe.Pointer(&guid)),
+		uintptr(unsafe.Pointer(&settings)))
+
+	return checkDefaultAPIResp(retval, err)
+}
@@ -66,6 +66,15 @@ func implInitialize() error {
 	dll := syscall.NewLazyDLL(helpersDllPath)
 	_fSetDNSByMAC = dll.NewProc("SetDNSByMAC")         // DWORD _cdecl SetDNSByMAC(const char* interfaceMAC, const char* dnsIP, byte operation)
 	_fSetDNSByLocalIP = dll.NewProc("SetDNSByLocalIP") // DWORD _cdecl SetDNSByLocalIP(const char* interfaceLocalAddr, const char* dnsIP, byte operation)
+
+	// modern DNS configuration API (when supported by the current system)
+	initDNSSettingsAPI()
+	if isDNSSettingsAPIAvailable() {
+		log.Info("DNS configuration: 'SetInterfaceDnsSettings' API")
+	} else {
+		log.Info("DNS configuration: legacy implementation ('SetInterfaceDnsSettings' API not available)")
+	}
+
 	return nil
 }
 
@@ -183,7 +192,11 @@ func implSetManual(addr net.IP, localInterfaceIP net.IP) (err error) {
 
 	if localInterfaceIP != nil {
 		// SET DNS to VPN interface
-		if err := fSetDNSByLocalIP(localInterfaceIP, addr, OperationSet); err != nil {
+		if isDNSSettingsAPIAvailable() {
+			if err := applyInterfaceDNSSettings(localInterfaceIP, []net.IP{addr}, ""); err != nil {
+				return fmt.Errorf("failed to set DNS for local interface: %w", err)
+			}
+		} else if err := fSetDNSByLocalIP(localInterfaceIP, addr, OperationSet); err != nil {
 			return fmt.Errorf("failed to set DNS for local interface: %w", err)
 		}
 	}
@@ -226,7 +239,11 @@ func implDeleteManual(localInterfaceIP net.IP) (err error) {
 
 	if localInterfaceIP != nil {
 		// RESET DNS for VPN interface
-		if err := fSetDNSByLocalIP(localInterfaceIP, net.IPv4zero, OperationSet); err != nil {
+		if isDNSSettingsAPIAvailable() {
+			if err := applyInterfaceDNSSettings(localInterfaceIP, nil, ""); err != nil {
+				return fmt.Errorf("failed to reset DNS for local interface: %w", err)
+			}
+		} else if err := fSetDNSByLocalIP(localInterfaceIP, net.IPv4zero, OperationSet); err != nil {
 			return fmt.Errorf("failed to reset DNS for local interface: %w", err)
 		}
 	}
@@ -246,8 +263,9 @@ func implDeleteManual(localInterfaceIP net.IP) (err error) {
 }
 
 // getMACAddrByIPinNetwork - get hardware addresses (MAC) of the network interfaces to which belongs an IP address (MAC of interface which is in same network as 'addr')
-// 		addr - IP address from local network (which can be accessed by interface different to VPN interface)
-//		localAddrToSkip - local IP of interface which can be excluded from output (e.g. VPN interface)
+//
+//	addr - IP address from local network (which can be accessed by interface different to VPN interface)
+//	localAddrToSkip - local IP of interface which can be excluded from output (e.g. VPN interface)
 func getMACAddrByIPinNetwork(addr net.IP, localAddrToSkip net.IP) (ret []net.HardwareAddr, err error) {
 	if addr == nil {
 		return ret, nil
@@ -0,0 +1,91 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+)
+
+const (
+	// interval of periodical checks that the configured manual DNS is responding
+	dnsProbeInterval = time.Minute
+	dnsProbeTimeout  = time.Second * 5
+	// hostname to resolve during the check (any response from the resolver counts, even NXDOMAIN)
+	dnsProbeHostname = "api.ivpn.net"
+	// do not fall back after a single failure (resolver can be temporarily overloaded)
+	dnsProbeRetries = 2
+)
+
+// probeManualDNS verifies that the configured manual DNS is responding.
+// On failure - falls back to the default VPN DNS (clients are notified by OnDNSChanged() handler).
+// Fixes "connected but can't resolve" situation when a custom DNS dies.
+func (s *Service) probeManualDNS() {
+	manualDNS := s._manualDNS
+	if manualDNS == nil {
+		return // nothing to check (DNS pushed by VPN server in use)
+	}
+	if s.IsPaused() {
+		return
+	}
+
+	for i := 0; i < dnsProbeRetries; i++ {
+		if isDNSResponding(manualDNS) {
+			return // DNS is fine
+		}
+	}
+
+	// re-read value: manual DNS could be changed during the checks
+	if currentDNS := s._manualDNS; currentDNS == nil || currentDNS.Equal(manualDNS) == false {
+		return
+	}
+
+	log.Warning(fmt.Sprintf("Manual DNS %s is not responding. Falling back to default VPN DNS...", manualDNS))
+	if err := s.ResetManualDNS(); err != nil {
+		log.Error("Failed to fall back to default VPN DNS: ", err)
+	}
+}
+
+// isDNSResponding returns 'true' when DNS server answers requests
+// (negative responses e.g. NXDOMAIN are also OK - the resolver is alive)
+func isDNSResponding(dnsSrv net.IP) bool {
+	resolver := net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			d := net.Dialer{Timeout: dnsProbeTimeout}
+			return d.DialContext(ctx, "udp", net.JoinHostPort(dnsSrv.String(), "53"))
+		}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dnsProbeTimeout)
+	defer cancel()
+
+	if _, err := resolver.LookupHost(ctx, dnsProbeHostname); err != nil {
+		if dnsErr, ok := err.(*net.DNSError); ok && dnsErr.IsNotFound {
+			return true
+		}
+		return false
+	}
+	return true
+}
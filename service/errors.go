@@ -22,6 +22,10 @@
 
 package service
 
+import (
+	protocoltypes "github.com/ivpn/desktop-app-daemon/protocol/types"
+)
+
 // ErrorNotLoggedIn - error, user not logged in into account
 type ErrorNotLoggedIn struct {
 }
@@ -29,3 +33,8 @@ type ErrorNotLoggedIn struct {
 func (e ErrorNotLoggedIn) Error() string {
 	return "not logged in; please visit https://www.ivpn.net/ to Sign Up or Log In to get info about your Account ID"
 }
+
+// ErrCode returns code of the daemon-wide error taxonomy
+func (e ErrorNotLoggedIn) ErrCode() string {
+	return protocoltypes.ErrCodeNotLoggedIn
+}
@@ -44,8 +44,65 @@ var (
 	connectedIsTCP             bool
 	mutex                      sync.Mutex
 	isClientPaused             bool
+
+	// limitation for the 'allow LAN' functionality: when not empty - only this subnets are allowed
+	// (see 'SetAllowLANSubnets()')
+	allowLANSubnets []string
+
+	// the state which was requested by the daemon (used by the tampering detection; see 'RestoreIfTampered()')
+	expectedEnabled bool
+
+	// IPv6 egress policy (see 'SetIPv6Policy()')
+	ipv6Policy = IPv6PolicyBlock
 )
 
+// IPv6 egress policy values: tunnels are IPv4-only, so without blocking
+// all IPv6 traffic bypasses the VPN (IPv6 leak)
+const (
+	// IPv6PolicyBlock - block all IPv6 egress for the connection period (default;
+	// including router advertisements and DHCPv6, so the system can not (re)configure
+	// global IPv6 addresses while the block is active)
+	IPv6PolicyBlock = "block"
+	// IPv6PolicyAllow - do not touch IPv6 traffic (possible IPv6 leak!)
+	IPv6PolicyAllow = "allow"
+	// IPv6PolicyTunnel - route IPv6 into the tunnel; current tunnels are IPv4-only,
+	// therefore IPv6 egress stays blocked until an IPv6-capable tunnel is available
+	IPv6PolicyTunnel = "tunnel"
+)
+
+// SetIPv6Policy - define IPv6 egress policy ("block", "allow" or "tunnel")
+// The policy is re-applied immediately for an active connection
+func SetIPv6Policy(policy string) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	switch policy {
+	case IPv6PolicyBlock, IPv6PolicyAllow, IPv6PolicyTunnel:
+	default:
+		return fmt.Errorf("unsupported IPv6 policy value: '%s' (acceptable: 'block', 'allow', 'tunnel')", policy)
+	}
+
+	if ipv6Policy == policy {
+		return nil
+	}
+
+	log.Info("IPv6 policy: ", policy)
+	ipv6Policy = policy
+
+	err := implOnIPv6PolicyChanged()
+	if err != nil {
+		log.Error(err)
+	}
+	return err
+}
+
+// GetIPv6Policy returns currently defined IPv6 egress policy
+func GetIPv6Policy() string {
+	mutex.Lock()
+	defer mutex.Unlock()
+	return ipv6Policy
+}
+
 // Initialize is doing initialization stuff
 // Must be called on application start
 func Initialize() error {
@@ -69,6 +126,8 @@ func SetEnabled(enable bool) error {
 		return fmt.Errorf("failed to change firewall state : %w", err)
 	}
 
+	expectedEnabled = enable
+
 	if enable {
 		// To fulfill such flow (example): FWEnable -> Connected -> FWDisable -> FWEnable
 		// Here we should notify that client is still connected
@@ -94,10 +153,55 @@ func SetPersistant(persistant bool) error {
 	err := implSetPersistant(persistant)
 	if err != nil {
 		log.Error(err)
+	} else if persistant {
+		// persistent firewall is always enabled
+		expectedEnabled = true
 	}
 	return err
 }
 
+// RestoreIfTampered - verify that firewall rules are still applied
+// and restore them if they were erased (e.g. flushed by a third-party tool)
+// Returns 'wasTampered == true' when the actual firewall state did not match the expected one
+func RestoreIfTampered() (wasTampered bool, err error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if expectedEnabled == false {
+		return false, nil
+	}
+
+	enabled, err := implGetEnabled()
+	if err != nil {
+		return false, fmt.Errorf("failed to get firewall state: %w", err)
+	}
+	if enabled {
+		return false, nil
+	}
+
+	log.Warning("Firewall rules are not applied anymore (erased by a third-party tool?). Restoring...")
+
+	if err := implSetEnabled(true); err != nil {
+		log.Error(err)
+		return true, fmt.Errorf("failed to restore firewall state: %w", err)
+	}
+
+	// restore rules relevant to an active VPN connection (if any)
+	clientAddr := connectedClientInterfaceIP
+	if clientAddr != nil && isClientPaused == false {
+		if e := implClientConnected(clientAddr, connectedClientPort, connectedHostIP, connectedHostPort, connectedIsTCP); e != nil {
+			log.Error(e)
+		}
+	}
+	if isDNSBlockingActive {
+		if e := implEnableDNSBlocking(dnsBlockingAllowedIP); e != nil {
+			log.Error(e)
+		}
+	}
+
+	return true, nil
+}
+
 // GetEnabled - get firewall state
 func GetEnabled() (bool, error) {
 	mutex.Lock()
@@ -142,6 +246,13 @@ func ClientConnected(clientLocalIPAddress net.IP, clientPort int, serverIP net.I
 	if err != nil {
 		log.Error(err)
 	}
+
+	// block IPv6 egress for the connection period (tunnel is IPv4-only: prevent IPv6 leak)
+	if ipv6Policy != IPv6PolicyAllow {
+		if e := implSetIPv6Block(true); e != nil {
+			log.Error("Failed to block IPv6 egress: ", e)
+		}
+	}
 	return err
 }
 
@@ -151,10 +262,21 @@ func ClientDisconnected() error {
 	defer mutex.Unlock()
 	ClientResumed()
 
+	// DNS blocking rules are relevant only for an active connection
+	if err := doDisableDNSBlocking(); err != nil {
+		log.Error(err)
+	}
+
 	// Remove client interface from exceptions
 	if connectedClientInterfaceIP != nil {
 		connectedClientInterfaceIP = nil
 		log.Info("Client disconnected")
+
+		// erase IPv6 block applied for the connection period
+		if e := implSetIPv6Block(false); e != nil {
+			log.Error("Failed to unblock IPv6 egress: ", e)
+		}
+
 		err := implClientDisconnected()
 		if err != nil {
 			log.Error(err)
@@ -178,6 +300,30 @@ func AddHostsToExceptions(IPs []net.IP, onlyForICMP bool) error {
 	return err
 }
 
+// SetAllowLANSubnets - limit the 'allow LAN' functionality to the specific subnets only
+// (when the list is empty - all detected local subnets are allowed)
+// Note: the new configuration will be applied by the next AllowLAN() call
+func SetAllowLANSubnets(subnets []string) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if isStrSlicesEqual(allowLANSubnets, subnets) {
+		return nil
+	}
+
+	log.Info(fmt.Sprintf("Allow LAN subnets: %v", subnets))
+
+	// erase currently applied 'allow LAN' rules
+	// (they have to be re-created according to the new subnets list; see AllowLAN())
+	err := implAllowLAN(false, false)
+	if err != nil {
+		log.Error(err)
+	}
+
+	allowLANSubnets = subnets
+	return err
+}
+
 // AllowLAN - allow/forbid LAN communication
 func AllowLAN(allowLan bool, allowLanMulticast bool) error {
 	mutex.Lock()
@@ -192,6 +338,37 @@ func AllowLAN(allowLan bool, allowLanMulticast bool) error {
 	return err
 }
 
+// SetUserAppRules - define user per-application allow\block rules
+// ('allowedApps'\'blockedApps' are full paths to application binaries)
+// Applicable to Windows implementation (WFP is able to filter traffic by application)
+func SetUserAppRules(allowedApps []string, blockedApps []string) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	log.Info(fmt.Sprintf("User app rules: allowed=%d blocked=%d", len(allowedApps), len(blockedApps)))
+
+	err := implSetUserAppRules(allowedApps, blockedApps)
+	if err != nil {
+		log.Error(err)
+	}
+	return err
+}
+
+// SetHotspotSharing - allow/forbid traffic of Mobile Hotspot \ Internet Connection Sharing clients
+// Applicable to Windows implementation (hotspot clients are NAT-ed by the OS and routed through the VPN tunnel)
+func SetHotspotSharing(enable bool) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	log.Info(fmt.Sprintf("Hotspot sharing: %t", enable))
+
+	err := implSetHotspotSharing(enable)
+	if err != nil {
+		log.Error(err)
+	}
+	return err
+}
+
 // SetManualDNS - configure firewall to allow DNS which is out of VPN tunnel
 // Applicable to Windows implementation (to allow custom DNS from local network)
 func SetManualDNS(addr net.IP) error {
@@ -204,3 +381,15 @@ func SetManualDNS(addr net.IP) error {
 	}
 	return err
 }
+
+func isStrSlicesEqual(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,136 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package firewall
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Virtual bridge policy values: how traffic of local virtual bridges
+// (docker0, vEthernet, vmnet, virbr ...) is treated by the kill-switch
+const (
+	// VirtualBridgePolicyBlock - bridge traffic is blocked (default kill-switch behavior)
+	VirtualBridgePolicyBlock = "block"
+	// VirtualBridgePolicyAllowLAN - containers\VMs can communicate with the local machine only
+	VirtualBridgePolicyAllowLAN = "allow-lan"
+	// VirtualBridgePolicyTunnel - bridge traffic is permitted; it is NAT-ed by the
+	// virtualization software and leaves the machine through the VPN tunnel
+	VirtualBridgePolicyTunnel = "tunnel"
+)
+
+// name prefixes of known virtual bridge interfaces (Docker, libvirt, VMware, VirtualBox, Hyper-V)
+var virtualBridgeIfPrefixes = []string{"docker", "br-", "virbr", "vmnet", "vboxnet", "vEthernet"}
+
+var (
+	// default policy applied to all detected virtual bridges (see 'SetVirtualBridgePolicy()')
+	virtualBridgePolicy = VirtualBridgePolicyBlock
+	// per-bridge policy overrides (key: interface name)
+	virtualBridgePolicyOverrides map[string]string
+)
+
+// SetVirtualBridgePolicy - define how traffic of local virtual bridges (docker0, vEthernet, vmnet ...)
+// is treated by the firewall: "block", "allow-lan" or "tunnel"; 'overrides' defines per-bridge exceptions
+// (key: interface name). The policy is re-applied immediately when the firewall is enabled.
+func SetVirtualBridgePolicy(policy string, overrides map[string]string) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if err := checkVirtualBridgePolicy(policy); err != nil {
+		return err
+	}
+	for ifName, p := range overrides {
+		if err := checkVirtualBridgePolicy(p); err != nil {
+			return fmt.Errorf("bad policy for bridge '%s': %w", ifName, err)
+		}
+	}
+
+	log.Info(fmt.Sprintf("Virtual bridge policy: %s (overrides: %v)", policy, overrides))
+	virtualBridgePolicy = policy
+	virtualBridgePolicyOverrides = overrides
+
+	err := implOnVirtualBridgePolicyChanged()
+	if err != nil {
+		log.Error(err)
+	}
+	return err
+}
+
+func checkVirtualBridgePolicy(policy string) error {
+	switch policy {
+	case VirtualBridgePolicyBlock, VirtualBridgePolicyAllowLAN, VirtualBridgePolicyTunnel:
+		return nil
+	}
+	return fmt.Errorf("unsupported virtual bridge policy value: '%s' (acceptable: 'block', 'allow-lan', 'tunnel')", policy)
+}
+
+// virtualBridgePolicyFor returns the effective policy for a bridge interface
+// (the per-bridge override when defined, the default policy otherwise)
+func virtualBridgePolicyFor(ifName string) string {
+	if p, ok := virtualBridgePolicyOverrides[ifName]; ok {
+		return p
+	}
+	return virtualBridgePolicy
+}
+
+// isVirtualBridgeIfName returns true for interface names of known virtual bridges
+func isVirtualBridgeIfName(name string) bool {
+	for _, prefix := range virtualBridgeIfPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// getVirtualBridgeSubnets returns IPv4 subnets of detected virtual bridge interfaces
+// (key: interface name)
+func getVirtualBridgeSubnets() map[string][]net.IPNet {
+	ret := make(map[string][]net.IPNet)
+
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		log.Error("failed to get network interfaces: ", err)
+		return ret
+	}
+
+	for _, ifc := range ifaces {
+		if isVirtualBridgeIfName(ifc.Name) == false {
+			continue
+		}
+		addrs, err := ifc.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if ok == false || ipNet.IP.To4() == nil {
+				continue
+			}
+			ret[ifc.Name] = append(ret[ifc.Name], net.IPNet{IP: ipNet.IP.Mask(ipNet.Mask), Mask: ipNet.Mask})
+		}
+	}
+
+	return ret
+}
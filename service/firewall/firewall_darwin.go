@@ -29,6 +29,7 @@ import (
 	"time"
 
 	"github.com/ivpn/desktop-app-daemon/netinfo"
+	"github.com/ivpn/desktop-app-daemon/privhelper"
 	"github.com/ivpn/desktop-app-daemon/service/platform"
 	"github.com/ivpn/desktop-app-daemon/shell"
 )
@@ -46,6 +47,9 @@ var (
 
 	delayedAllowLanAllowed bool = true
 	delayedAllowLanStarted bool = false
+
+	// subnets of virtual bridges which are currently allowed (see 'SetVirtualBridgePolicy()')
+	allowedBridgeIPs []string
 )
 
 const (
@@ -64,11 +68,20 @@ func init() {
 
 func implInitialize() error { return nil }
 
+// runFirewallScript executes the firewall script
+// (over the privileged helper process when privilege separation is in use)
+func runFirewallScript(args ...string) error {
+	if executed, err := privhelper.ExecFirewallScript(args...); executed {
+		return err
+	}
+	return shell.Exec(nil, platform.FirewallScript(), args...)
+}
+
 func implGetEnabled() (bool, error) {
-	err := shell.Exec(nil, platform.FirewallScript(), "-status")
+	err := runFirewallScript("-status")
 
 	if err != nil {
-		exitCode, err := shell.GetCmdExitCode(err)
+		exitCode, err := privhelper.GetCmdExitCode(err)
 		if err != nil {
 			return false, fmt.Errorf("failed to get Cmd exit code: %w", err)
 		}
@@ -82,7 +95,7 @@ func implGetEnabled() (bool, error) {
 
 func implSetEnabled(isEnabled bool) error {
 	if isEnabled {
-		err := shell.Exec(nil, platform.FirewallScript(), "-enable")
+		err := runFirewallScript("-enable")
 		if err != nil {
 			return fmt.Errorf("failed to execute shell command: %w", err)
 		}
@@ -90,7 +103,16 @@ func implSetEnabled(isEnabled bool) error {
 		// Here we should restore all exceptions (all hosts which are allowed)
 		return reApplyExceptions()
 	}
-	return shell.Exec(nil, platform.FirewallScript(), "-disable")
+
+	if err := runFirewallScript("-disable"); err != nil {
+		return err
+	}
+	// DNS blocking is not bound to the firewall state
+	// ('-disable' command erases the main anchor ruleset, so the 'dns' sub-anchor link has to be restored)
+	if isDNSBlockingActive {
+		return implEnableDNSBlocking(dnsBlockingAllowedIP)
+	}
+	return nil
 }
 
 func implSetPersistant(persistant bool) error {
@@ -124,7 +146,7 @@ func implClientConnected(clientLocalIPAddress net.IP, clientPort int, serverIP n
 		serverIP,
 		serverPort,
 		protocol)
-	err = shell.Exec(nil, platform.FirewallScript(), scriptArgs)
+	err = runFirewallScript(scriptArgs)
 	if err != nil {
 		return fmt.Errorf("failed to add rule for current connection directions: %w", err)
 	}
@@ -142,7 +164,26 @@ func implClientDisconnected() error {
 		log.Error(err)
 	}
 
-	return shell.Exec(nil, platform.FirewallScript(), "-disconnected")
+	return runFirewallScript("-disconnected")
+}
+
+// implSetIPv6Block - block\unblock all IPv6 egress (tunnel is IPv4-only: prevents IPv6 leaks)
+// The script blocks also router advertisements and DHCPv6 (ICMPv6 type 134; UDP 546\547),
+// so the system can not (re)configure global IPv6 addresses while the block is active
+// (link-local IPv6 stays unblocked: required by Apple services like AirDrop or HandOff)
+func implSetIPv6Block(isBlock bool) error {
+	if isBlock {
+		return runFirewallScript("-ipv6_block")
+	}
+	return runFirewallScript("-ipv6_unblock")
+}
+
+// implOnIPv6PolicyChanged - re-apply IPv6 egress rules for an active connection
+func implOnIPv6PolicyChanged() error {
+	if connectedClientInterfaceIP == nil {
+		return nil // no active connection: the policy will be applied on next connection
+	}
+	return implSetIPv6Block(ipv6Policy != IPv6PolicyAllow)
 }
 
 func implAllowLAN(isAllowLAN bool, isAllowLanMulticast bool) error {
@@ -154,11 +195,16 @@ func implAllowLAN(isAllowLAN bool, isAllowLanMulticast bool) error {
 	if !isAllowLAN {
 		// LAN NOT ALLOWED
 		delayedAllowLanAllowed = false
-		// disallow everything (LAN + multicast)
-		return removeHostsFromExceptions(append(localIPs, multicastIP))
+		// disallow everything (LAN + multicast + user-defined subnets)
+		return removeHostsFromExceptions(append(append(localIPs, allowLANSubnets...), multicastIP))
 	}
 
 	// LAN ALLOWED
+	if len(allowLANSubnets) > 0 {
+		// 'allow LAN' functionality is limited to the user-defined subnets only
+		localIPs = allowLANSubnets
+	}
+
 	if len(localIPs) > 0 {
 		delayedAllowLanAllowed = false
 	} else {
@@ -233,6 +279,63 @@ func implSetManualDNS(addr net.IP) error {
 	return nil
 }
 
+// SetUserAppRules - define user per-application allow\block rules
+// Applicable to Windows implementation (WFP is able to filter traffic by application)
+func implSetUserAppRules(allowedApps []string, blockedApps []string) error {
+	if len(allowedApps) > 0 || len(blockedApps) > 0 {
+		return fmt.Errorf("per-application firewall rules are not implemented for macOS")
+	}
+	return nil
+}
+
+// SetHotspotSharing - allow/forbid traffic of Mobile Hotspot \ ICS clients
+// Applicable to Windows implementation only
+func implSetHotspotSharing(enable bool) error {
+	if enable {
+		return fmt.Errorf("hotspot sharing mode is not implemented for macOS")
+	}
+	return nil
+}
+
+// implOnVirtualBridgePolicyChanged - re-apply exceptions for virtual bridge subnets according to the new policy
+// Note: for the 'tunnel' policy forwarding\NAT of the bridge traffic is configured by the
+// virtualization software (e.g. VMware Fusion); the firewall only permits communication with the bridge subnet
+func implOnVirtualBridgePolicyChanged() error {
+	const persistant = true
+
+	if len(allowedBridgeIPs) > 0 {
+		toRemove := allowedBridgeIPs
+		allowedBridgeIPs = nil
+		if err := removeHostsFromExceptions(toRemove); err != nil {
+			log.Error(err)
+		}
+	}
+
+	for ifName, subnets := range getVirtualBridgeSubnets() {
+		if virtualBridgePolicyFor(ifName) == VirtualBridgePolicyBlock {
+			continue
+		}
+		for _, subnet := range subnets {
+			allowedBridgeIPs = append(allowedBridgeIPs, subnet.String())
+		}
+	}
+
+	return addHostsToExceptions(allowedBridgeIPs, persistant)
+}
+
+func implEnableDNSBlocking(allowedDNS net.IP) error {
+	dohIPs := make([]string, 0, len(dohResolversIPv4))
+	for _, ip := range dohResolversIPv4 {
+		dohIPs = append(dohIPs, ip.String())
+	}
+
+	return runFirewallScript("-dns_block_start", allowedDNS.String(), strings.Join(dohIPs, " "))
+}
+
+func implDisableDNSBlocking() error {
+	return runFirewallScript("-dns_block_stop")
+}
+
 //---------------------------------------------------------------------
 
 func applyAddHostsToExceptions(hostsIPs []string) error { //
@@ -241,7 +344,7 @@ func applyAddHostsToExceptions(hostsIPs []string) error { //
 
 	if len(ipList) > 0 {
 		log.Info("-add_exceptions ", ipList)
-		return shell.Exec(nil, platform.FirewallScript(), "-add_exceptions", ipList)
+		return runFirewallScript("-add_exceptions", ipList)
 	}
 	return nil
 }
@@ -252,7 +355,7 @@ func applyRemoveHostsFromExceptions(hostsIPs []string) error {
 
 	if len(ipList) > 0 {
 		log.Info("-remove_exceptions ", ipList)
-		return shell.Exec(nil, platform.FirewallScript(), "-remove_exceptions", ipList)
+		return runFirewallScript("-remove_exceptions", ipList)
 	}
 	return nil
 }
@@ -332,7 +435,8 @@ func removeHostsFromExceptions(IPs []string) error {
 
 // removeAllHostsFromExceptions - Remove hosts (which are related to a current connection) from exceptions
 // Note: some exceptions should stay without changes, they are marked as 'persistant'
-//		(has 'true' value in allowedHosts; eg.: LAN and Multicast connectivity)
+//
+//	(has 'true' value in allowedHosts; eg.: LAN and Multicast connectivity)
 func removeAllHostsFromExceptions() error {
 	toRemoveIPs := make([]string, 0, len(allowedHosts))
 	for ipStr, isPersistant := range allowedHosts {
@@ -0,0 +1,98 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package firewall
+
+import (
+	"fmt"
+	"net"
+)
+
+// DNS leak protection:
+// when active - outgoing DNS requests (plain DNS and DNS-over-TLS) are blocked
+// for all destinations except 'dnsBlockingAllowedIP' (the DNS server of a current VPN connection).
+// Additionally, communication with well-known public DNS-over-HTTPS resolvers is blocked
+// (DoH is a normal HTTPS traffic, so it can not be detected by port number).
+var (
+	isDNSBlockingActive  bool
+	dnsBlockingAllowedIP net.IP
+
+	// well-known public DoH resolvers (Google, Cloudflare, Quad9, OpenDNS)
+	dohResolversIPv4 = []net.IP{
+		net.IPv4(8, 8, 8, 8),
+		net.IPv4(8, 8, 4, 4),
+		net.IPv4(1, 1, 1, 1),
+		net.IPv4(1, 0, 0, 1),
+		net.IPv4(9, 9, 9, 9),
+		net.IPv4(149, 112, 112, 112),
+		net.IPv4(208, 67, 222, 222),
+		net.IPv4(208, 67, 220, 220),
+	}
+)
+
+// EnableDNSLeakProtection - block outgoing DNS requests to all destinations except 'allowedDNS'
+// Applicable only for an active VPN connection ('allowedDNS' is the DNS which is in use by the connection).
+// Note!: protection will be disabled on client disconnection (after call 'ClientDisconnected()')
+func EnableDNSLeakProtection(allowedDNS net.IP) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if allowedDNS == nil {
+		return fmt.Errorf("unable to enable DNS leak protection: allowed DNS server not defined")
+	}
+
+	log.Info("DNS leak protection: enabling (allowed DNS: ", allowedDNS.String(), ")")
+
+	isDNSBlockingActive = true
+	dnsBlockingAllowedIP = allowedDNS
+
+	err := implEnableDNSBlocking(allowedDNS)
+	if err != nil {
+		log.Error(err)
+	}
+	return err
+}
+
+// DisableDNSLeakProtection - remove DNS blocking rules
+func DisableDNSLeakProtection() error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	return doDisableDNSBlocking()
+}
+
+func doDisableDNSBlocking() error {
+	if isDNSBlockingActive == false {
+		return nil
+	}
+
+	log.Info("DNS leak protection: disabling")
+
+	isDNSBlockingActive = false
+	dnsBlockingAllowedIP = nil
+
+	err := implDisableDNSBlocking()
+	if err != nil {
+		log.Error(err)
+	}
+	return err
+}
@@ -29,6 +29,7 @@ import (
 	"time"
 
 	"github.com/ivpn/desktop-app-daemon/netinfo"
+	"github.com/ivpn/desktop-app-daemon/privhelper"
 	"github.com/ivpn/desktop-app-daemon/service/platform"
 	"github.com/ivpn/desktop-app-daemon/shell"
 )
@@ -38,7 +39,9 @@ var (
 	// value: true - if exception rule is persistant (persistant, means will stay available even client is disconnected)
 	allowedHosts map[string]bool
 	// IP addresses of local interfaces (using for 'allow LAN' functionality)
-	allowedLanIPs       []string
+	allowedLanIPs []string
+	// subnets of virtual bridges which are currently allowed (see 'SetVirtualBridgePolicy()')
+	allowedBridgeIPs    []string
 	allowedForICMP      map[string]struct{}
 	connectedVpnLocalIP string
 
@@ -58,11 +61,20 @@ func init() {
 
 func implInitialize() error { return nil }
 
+// runFirewallScript executes the firewall script
+// (over the privileged helper process when privilege separation is in use)
+func runFirewallScript(args ...string) error {
+	if executed, err := privhelper.ExecFirewallScript(args...); executed {
+		return err
+	}
+	return shell.Exec(nil, platform.FirewallScript(), args...)
+}
+
 func implGetEnabled() (bool, error) {
-	err := shell.Exec(nil, platform.FirewallScript(), "-status")
+	err := runFirewallScript("-status")
 
 	if err != nil {
-		exitCode, err := shell.GetCmdExitCode(err)
+		exitCode, err := privhelper.GetCmdExitCode(err)
 		if err != nil {
 			return false, fmt.Errorf("failed to get Cmd exit code: %w", err)
 		}
@@ -76,7 +88,7 @@ func implGetEnabled() (bool, error) {
 
 func implSetEnabled(isEnabled bool) error {
 	if isEnabled {
-		err := shell.Exec(nil, platform.FirewallScript(), "-enable")
+		err := runFirewallScript("-enable")
 		if err != nil {
 			return fmt.Errorf("failed to execute shell command: %w", err)
 		}
@@ -87,7 +99,7 @@ func implSetEnabled(isEnabled bool) error {
 
 	isPersistant = false
 	allowedForICMP = nil
-	return shell.Exec(nil, platform.FirewallScript(), "-disable")
+	return runFirewallScript("-disable")
 }
 
 func implSetPersistant(persistant bool) error {
@@ -154,7 +166,7 @@ func implClientConnected(clientLocalIPAddress net.IP, clientPort int, serverIP n
 		serverIP,
 		serverPort,
 		protocol)
-	err = shell.Exec(nil, platform.FirewallScript(), scriptArgs)
+	err = runFirewallScript(scriptArgs)
 	if err != nil {
 		return fmt.Errorf("failed to add rule for current connection directions: %w", err)
 	}
@@ -173,7 +185,25 @@ func implClientDisconnected() error {
 		log.Error(err)
 	}
 
-	return shell.Exec(nil, platform.FirewallScript(), "-disconnected")
+	return runFirewallScript("-disconnected")
+}
+
+// implSetIPv6Block - block\unblock all IPv6 egress (tunnel is IPv4-only: prevents IPv6 leaks)
+// The script blocks also router advertisements and DHCPv6 (ICMPv6 type 134; UDP 546\547),
+// so the system can not (re)configure global IPv6 addresses while the block is active
+func implSetIPv6Block(isBlock bool) error {
+	if isBlock {
+		return runFirewallScript("-ipv6_block")
+	}
+	return runFirewallScript("-ipv6_unblock")
+}
+
+// implOnIPv6PolicyChanged - re-apply IPv6 egress rules for an active connection
+func implOnIPv6PolicyChanged() error {
+	if len(connectedVpnLocalIP) == 0 {
+		return nil // no active connection: the policy will be applied on next connection
+	}
+	return implSetIPv6Block(ipv6Policy != IPv6PolicyAllow)
 }
 
 func implAllowLAN(isAllowLAN bool, isAllowLanMulticast bool) error {
@@ -198,6 +228,11 @@ func implAllowLAN(isAllowLAN bool, isAllowLanMulticast bool) error {
 		return fmt.Errorf("failed to get local IPs: %w", err)
 	}
 
+	if len(allowLANSubnets) > 0 {
+		// 'allow LAN' functionality is limited to the user-defined subnets only
+		localIPs = allowLANSubnets
+	}
+
 	if len(localIPs) > 0 {
 		delayedAllowLanAllowed = false
 	} else {
@@ -280,11 +315,92 @@ func implSetManualDNS(addr net.IP) error {
 	return nil
 }
 
+// SetUserAppRules - define user per-application allow\block rules
+// Applicable to Windows implementation (WFP is able to filter traffic by application)
+func implSetUserAppRules(allowedApps []string, blockedApps []string) error {
+	if len(allowedApps) > 0 || len(blockedApps) > 0 {
+		return fmt.Errorf("per-application firewall rules are not implemented for Linux")
+	}
+	return nil
+}
+
+// SetHotspotSharing - allow/forbid traffic of Mobile Hotspot \ ICS clients
+// Applicable to Windows implementation only
+func implSetHotspotSharing(enable bool) error {
+	if enable {
+		return fmt.Errorf("hotspot sharing mode is not implemented for Linux")
+	}
+	return nil
+}
+
+// implOnVirtualBridgePolicyChanged - re-apply exceptions for virtual bridge subnets according to the new policy
+// Note: for the 'tunnel' policy forwarding\NAT of the bridge traffic is configured by the
+// virtualization software (e.g. Docker); the firewall only permits communication with the bridge subnet
+func implOnVirtualBridgePolicyChanged() error {
+	const persistant = true
+	const notOnlyForICMP = false
+
+	if len(allowedBridgeIPs) > 0 {
+		toRemove := allowedBridgeIPs
+		allowedBridgeIPs = nil
+		if err := removeHostsFromExceptions(toRemove, persistant); err != nil {
+			log.Error(err)
+		}
+	}
+
+	for ifName, subnets := range getVirtualBridgeSubnets() {
+		if virtualBridgePolicyFor(ifName) == VirtualBridgePolicyBlock {
+			continue
+		}
+		for _, subnet := range subnets {
+			allowedBridgeIPs = append(allowedBridgeIPs, subnet.String())
+		}
+	}
+
+	return addHostsToExceptions(allowedBridgeIPs, persistant, notOnlyForICMP)
+}
+
+func implEnableDNSBlocking(allowedDNS net.IP) error {
+	dohIPs := make([]string, 0, len(dohResolversIPv4))
+	for _, ip := range dohResolversIPv4 {
+		dohIPs = append(dohIPs, ip.String())
+	}
+
+	return runFirewallScript("-dns_block_start", allowedDNS.String(), strings.Join(dohIPs, " "))
+}
+
+func implDisableDNSBlocking() error {
+	return runFirewallScript("-dns_block_stop")
+}
+
 //---------------------------------------------------------------------
 
+// splitIPListByVersion separates IP addresses list into IPv4 and IPv6 lists
+func splitIPListByVersion(hostsIPs []string) (ipv4 []string, ipv6 []string) {
+	for _, ip := range hostsIPs {
+		if strings.Contains(ip, ":") {
+			ipv6 = append(ipv6, ip)
+		} else {
+			ipv4 = append(ipv4, ip)
+		}
+	}
+	return ipv4, ipv6
+}
+
 func applyAddHostsToExceptions(hostsIPs []string, isPersistant bool, onlyForICMP bool) error {
-	var ipList string
-	ipList = strings.Join(hostsIPs, ",")
+	hostsIPv4, hostsIPv6 := splitIPListByVersion(hostsIPs)
+
+	// IPv6 exceptions (e.g. an IPv6 endpoint of the VPN server)
+	// applicable only for VPN-depended exceptions (static and ICMP rules are IPv4-only)
+	if len(hostsIPv6) > 0 && onlyForICMP == false && isPersistant == false {
+		ipList := strings.Join(hostsIPv6, ",")
+		log.Info("-add_exceptions_ipv6 ", ipList)
+		if err := runFirewallScript("-add_exceptions_ipv6", ipList); err != nil {
+			return err
+		}
+	}
+
+	ipList := strings.Join(hostsIPv4, ",")
 
 	if len(ipList) > 0 {
 		scriptCommand := "-add_exceptions"
@@ -296,14 +412,23 @@ func applyAddHostsToExceptions(hostsIPs []string, isPersistant bool, onlyForICMP
 		}
 
 		log.Info(scriptCommand, " ", ipList)
-		return shell.Exec(nil, platform.FirewallScript(), scriptCommand, ipList)
+		return runFirewallScript(scriptCommand, ipList)
 	}
 	return nil
 }
 
 func applyRemoveHostsFromExceptions(hostsIPs []string, isPersistant bool) error {
-	var ipList string
-	ipList = strings.Join(hostsIPs, ",")
+	hostsIPv4, hostsIPv6 := splitIPListByVersion(hostsIPs)
+
+	if len(hostsIPv6) > 0 && isPersistant == false {
+		ipList := strings.Join(hostsIPv6, ",")
+		log.Info("-remove_exceptions_ipv6 ", ipList)
+		if err := runFirewallScript("-remove_exceptions_ipv6", ipList); err != nil {
+			return err
+		}
+	}
+
+	ipList := strings.Join(hostsIPv4, ",")
 
 	if len(ipList) > 0 {
 		scriptCommand := "-remove_exceptions"
@@ -313,7 +438,7 @@ func applyRemoveHostsFromExceptions(hostsIPs []string, isPersistant bool) error
 		}
 
 		log.Info(scriptCommand, " ", ipList)
-		return shell.Exec(nil, platform.FirewallScript(), scriptCommand, ipList)
+		return runFirewallScript(scriptCommand, ipList)
 	}
 	return nil
 }
@@ -433,7 +558,8 @@ func removeHostsFromExceptions(IPs []string, isPersistant bool) error {
 
 // removeAllHostsFromExceptions - Remove hosts (which are related to a current connection) from exceptions
 // Note: some exceptions should stay without changes, they are marked as 'persistant'
-//		(has 'true' value in allowedHosts; eg.: LAN and Multicast connectivity)
+//
+//	(has 'true' value in allowedHosts; eg.: LAN and Multicast connectivity)
 func removeAllHostsFromExceptions() error {
 	toRemoveIPs := make([]string, 0, len(allowedHosts))
 	for ipStr, isPersistant := range allowedHosts {
@@ -45,9 +45,21 @@ var (
 	clientLocalIPFilterIDs []uint64
 	customDNS              net.IP
 
+	// user-defined per-application rules (full paths to application binaries)
+	userAllowedApps []string
+	userBlockedApps []string
+
 	isPersistant        bool
 	isAllowLAN          bool
 	isAllowLANMulticast bool
+	isHotspotSharing    bool
+
+	// default subnets used by Windows Mobile Hotspot \ Internet Connection Sharing for hotspot clients
+	// (their traffic is NAT-ed by the OS; allowing these subnets lets it leave the machine through the VPN tunnel)
+	hotspotSubnetsV4 = []net.IPNet{
+		{IP: net.IPv4(192, 168, 137, 0), Mask: net.IPv4Mask(255, 255, 255, 0)}, // Mobile Hotspot
+		{IP: net.IPv4(192, 168, 0, 0), Mask: net.IPv4Mask(255, 255, 255, 0)},   // classic ICS
+	}
 )
 
 const (
@@ -175,6 +187,25 @@ func implAddHostsToExceptions(IPs []net.IP, onlyForICMP bool) error {
 	return nil
 }
 
+// implSetIPv6Block - nothing to do for windows implementation:
+// IPv6 filters (including blocking of router advertisements and DHCPv6 on the ALE layers)
+// are part of the kill-switch rules and stay active for the whole enabled period (see 'doEnable()')
+func implSetIPv6Block(isBlock bool) error {
+	return nil
+}
+
+// implOnIPv6PolicyChanged - re-apply kill-switch rules according to the new IPv6 policy
+func implOnIPv6PolicyChanged() error {
+	enabled, err := implGetEnabled()
+	if err != nil {
+		return fmt.Errorf("failed to get info if firewall is on: %w", err)
+	}
+	if enabled == false {
+		return nil // the policy will be applied when the firewall become enabled
+	}
+	return reEnable()
+}
+
 // AllowLAN - allow/forbid LAN communication
 func implAllowLAN(allowLan bool, allowLanMulticast bool) error {
 
@@ -196,6 +227,39 @@ func implAllowLAN(allowLan bool, allowLanMulticast bool) error {
 	return reEnable()
 }
 
+// implSetHotspotSharing - allow/forbid traffic of Mobile Hotspot \ ICS clients
+// The rules are woven into the common filter set (see doEnable()) so they are effective only when the firewall is enabled
+func implSetHotspotSharing(enable bool) error {
+	if isHotspotSharing == enable {
+		return nil
+	}
+
+	isHotspotSharing = enable
+
+	enabled, err := implGetEnabled()
+	if err != nil {
+		return fmt.Errorf("failed to get info if firewall is on: %w", err)
+	}
+	if enabled == false {
+		return nil
+	}
+
+	return reEnable()
+}
+
+// implOnVirtualBridgePolicyChanged - re-apply kill-switch rules according to the new virtual bridge policy
+// The rules are woven into the common filter set (see doEnable()) so they are effective only when the firewall is enabled
+func implOnVirtualBridgePolicyChanged() error {
+	enabled, err := implGetEnabled()
+	if err != nil {
+		return fmt.Errorf("failed to get info if firewall is on: %w", err)
+	}
+	if enabled == false {
+		return nil // the policy will be applied when the firewall become enabled
+	}
+	return reEnable()
+}
+
 // SetManualDNS - configure firewall to allow DNS which is out of VPN tunnel
 // Applicable to Windows implementation (to allow custom DNS from local network)
 func implSetManualDNS(addr net.IP) error {
@@ -216,6 +280,51 @@ func implSetManualDNS(addr net.IP) error {
 	return reEnable()
 }
 
+// implSetUserAppRules - define user per-application allow\block rules (WFP filters by application binary)
+// The rules are woven into the common filter set (see doEnable()) so they are effective only when the firewall is enabled
+func implSetUserAppRules(allowedApps []string, blockedApps []string) error {
+	userAllowedApps = allowedApps
+	userBlockedApps = blockedApps
+
+	enabled, err := implGetEnabled()
+	if err != nil {
+		return fmt.Errorf("failed to get info if firewall is on: %w", err)
+	}
+	if enabled == false {
+		// rules will be applied when the firewall become enabled
+		return nil
+	}
+
+	return reEnable()
+}
+
+// DNS blocking rules are woven into the common filter set (see doEnable())
+// so they are effective only when the firewall is enabled
+func implEnableDNSBlocking(allowedDNS net.IP) error {
+	enabled, err := implGetEnabled()
+	if err != nil {
+		return fmt.Errorf("failed to get info if firewall is on: %w", err)
+	}
+	if enabled == false {
+		// rules will be applied when the firewall become enabled
+		return nil
+	}
+
+	return reEnable()
+}
+
+func implDisableDNSBlocking() error {
+	enabled, err := implGetEnabled()
+	if err != nil {
+		return fmt.Errorf("failed to get info if firewall is on: %w", err)
+	}
+	if enabled == false {
+		return nil
+	}
+
+	return reEnable()
+}
+
 func reEnable() (retErr error) {
 	// start / commit transaction
 	if err := manager.TransactionStart(); err != nil {
@@ -288,20 +397,34 @@ func doEnable() (retErr error) {
 
 	// IPv6 filters
 	for _, layer := range v6Layers {
-		_, err := manager.AddFilter(winlib.NewFilterBlockAll(providerKey, layer, sublayerKey, filterDName, "", true, isPersistant))
-		if err != nil {
-			return fmt.Errorf("failed to add filter 'block all IPv6': %w", err)
-		}
+		// IPv6 egress is blocked unless the 'allow' IPv6 policy is defined
+		// (tunnels are IPv4-only: without the block all IPv6 traffic bypasses the VPN)
+		if ipv6Policy != IPv6PolicyAllow {
+			_, err := manager.AddFilter(winlib.NewFilterBlockAll(providerKey, layer, sublayerKey, filterDName, "", true, isPersistant))
+			if err != nil {
+				return fmt.Errorf("failed to add filter 'block all IPv6': %w", err)
+			}
 
-		if isAllowLAN {
-			for _, ip := range addressesV6 {
-				prefixLen, _ := ip.Mask.Size()
-				_, err = manager.AddFilter(winlib.NewFilterAllowRemoteIPV6(providerKey, layer, sublayerKey, filterDName, "", ip.IP, byte(prefixLen), isPersistant))
-				if err != nil {
-					return fmt.Errorf("failed to add filter 'allow lan IPv6': %w", err)
+			// Note: when 'allow LAN' is limited to the user-defined subnets - IPv6 LAN stays blocked
+			// (the subnets limitation is IPv4-only)
+			if isAllowLAN && len(allowLANSubnets) == 0 {
+				for _, ip := range addressesV6 {
+					prefixLen, _ := ip.Mask.Size()
+					_, err = manager.AddFilter(winlib.NewFilterAllowRemoteIPV6(providerKey, layer, sublayerKey, filterDName, "", ip.IP, byte(prefixLen), isPersistant))
+					if err != nil {
+						return fmt.Errorf("failed to add filter 'allow lan IPv6': %w", err)
+					}
 				}
 			}
 		}
+
+		// user-defined 'block application' rules (has to block also IPv6 communication; e.g. LAN)
+		for _, app := range userBlockedApps {
+			_, err = manager.AddFilter(winlib.NewFilterBlockApplication(providerKey, layer, sublayerKey, filterDName, "", app, isPersistant))
+			if err != nil {
+				return fmt.Errorf("failed to add filter 'block application (user)': %w", err)
+			}
+		}
 	}
 
 	// IPv4 filters
@@ -318,6 +441,25 @@ func doEnable() (retErr error) {
 			return fmt.Errorf("failed to add filter 'block dns': %w", err)
 		}
 
+		// DNS leak protection: hard-block DNS requests to all destinations except allowed DNS
+		// (the 'block dns' filter above is not enough: it does not affect traffic which is going through the VPN interface)
+		if isDNSBlockingActive {
+			_, err = manager.AddFilter(winlib.NewFilterBlockRemotePort(providerKey, layer, sublayerKey, sublayerDName, "", 53, dnsBlockingAllowedIP, isPersistant))
+			if err != nil {
+				return fmt.Errorf("failed to add filter 'block dns leak': %w", err)
+			}
+			_, err = manager.AddFilter(winlib.NewFilterBlockRemotePort(providerKey, layer, sublayerKey, sublayerDName, "", 853, dnsBlockingAllowedIP, isPersistant))
+			if err != nil {
+				return fmt.Errorf("failed to add filter 'block dns-over-tls': %w", err)
+			}
+			for _, dohIP := range dohResolversIPv4 {
+				_, err = manager.AddFilter(winlib.NewFilterBlockRemoteIPPort(providerKey, layer, sublayerKey, sublayerDName, "", dohIP, 443, isPersistant))
+				if err != nil {
+					return fmt.Errorf("failed to add filter 'block doh resolver': %w", err)
+				}
+			}
+		}
+
 		// allow DHCP port
 		_, err = manager.AddFilter(winlib.NewFilterAllowLocalPort(providerKey, layer, sublayerKey, sublayerDName, "", 68, isPersistant))
 		if err != nil {
@@ -350,13 +492,27 @@ func doEnable() (retErr error) {
 			return fmt.Errorf("failed to add filter 'allow application - obfsproxy': %w", err)
 		}
 
+		// user-defined per-application rules
+		for _, app := range userAllowedApps {
+			_, err = manager.AddFilter(winlib.NewFilterAllowApplication(providerKey, layer, sublayerKey, sublayerDName, "", app, isPersistant))
+			if err != nil {
+				return fmt.Errorf("failed to add filter 'allow application (user)': %w", err)
+			}
+		}
+		for _, app := range userBlockedApps {
+			_, err = manager.AddFilter(winlib.NewFilterBlockApplication(providerKey, layer, sublayerKey, sublayerDName, "", app, isPersistant))
+			if err != nil {
+				return fmt.Errorf("failed to add filter 'block application (user)': %w", err)
+			}
+		}
+
 		for _, ip := range addressesV4 {
 			_, err = manager.AddFilter(winlib.NewFilterAllowRemoteIP(providerKey, layer, sublayerKey, filterDName, "", ip.IP, net.IPv4(255, 255, 255, 255), isPersistant))
 			if err != nil {
 				return fmt.Errorf("failed to add filter 'allow remote IP': %w", err)
 			}
 
-			if isAllowLAN {
+			if isAllowLAN && len(allowLANSubnets) == 0 {
 				_, err = manager.AddFilter(winlib.NewFilterAllowRemoteIP(providerKey, layer, sublayerKey, filterDName, "", ip.IP, net.IP(ip.Mask), isPersistant))
 				if err != nil {
 					return fmt.Errorf("failed to add filter 'allow LAN': %w", err)
@@ -364,6 +520,21 @@ func doEnable() (retErr error) {
 			}
 		}
 
+		// 'allow LAN' is limited to the user-defined subnets only
+		if isAllowLAN && len(allowLANSubnets) > 0 {
+			for _, subnet := range allowLANSubnets {
+				_, ipNet, err := net.ParseCIDR(subnet)
+				if err != nil {
+					log.Warning(fmt.Sprintf("failed to parse 'allow LAN' subnet '%s': %v", subnet, err))
+					continue
+				}
+				_, err = manager.AddFilter(winlib.NewFilterAllowRemoteIP(providerKey, layer, sublayerKey, filterDName, "", ipNet.IP, net.IP(ipNet.Mask), isPersistant))
+				if err != nil {
+					return fmt.Errorf("failed to add filter 'allow LAN subnet': %w", err)
+				}
+			}
+		}
+
 		if isAllowLANMulticast {
 			_, err = manager.AddFilter(winlib.NewFilterAllowRemoteIP(providerKey, layer, sublayerKey, filterDName, "",
 				net.IPv4(224, 0, 0, 0), net.IPv4(240, 0, 0, 0), isPersistant))
@@ -372,6 +543,32 @@ func doEnable() (retErr error) {
 			}
 		}
 
+		// Mobile Hotspot \ ICS compatibility: allow communication with hotspot clients
+		// (without these rules the kill-switch silently drops all traffic of hotspot clients)
+		if isHotspotSharing {
+			for _, subnet := range hotspotSubnetsV4 {
+				_, err = manager.AddFilter(winlib.NewFilterAllowRemoteIP(providerKey, layer, sublayerKey, filterDName, "", subnet.IP, net.IP(subnet.Mask), isPersistant))
+				if err != nil {
+					return fmt.Errorf("failed to add filter 'allow hotspot subnet': %w", err)
+				}
+			}
+		}
+
+		// virtual bridge traffic (docker0, vEthernet, vmnet ...; see 'SetVirtualBridgePolicy()')
+		// 'allow-lan' permits communication with the bridge subnet only;
+		// 'tunnel' additionally relies on the virtualization NAT to forward the bridge traffic into the VPN tunnel
+		for ifName, subnets := range getVirtualBridgeSubnets() {
+			if virtualBridgePolicyFor(ifName) == VirtualBridgePolicyBlock {
+				continue
+			}
+			for _, subnet := range subnets {
+				_, err = manager.AddFilter(winlib.NewFilterAllowRemoteIP(providerKey, layer, sublayerKey, filterDName, "", subnet.IP, net.IP(subnet.Mask), isPersistant))
+				if err != nil {
+					return fmt.Errorf("failed to add filter 'allow virtual bridge subnet': %w", err)
+				}
+			}
+		}
+
 		/*
 			for ipStrKey := range allowedHosts {
 				ip := net.ParseIP(ipStrKey)
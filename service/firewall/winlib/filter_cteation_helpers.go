@@ -20,6 +20,7 @@
 //  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
 //
 
+//go:build windows
 // +build windows
 
 package winlib
@@ -38,6 +39,8 @@ const (
 	weightAllowLocalIP     = 10
 	weightBlockAll         = 2
 	weightBlockDNS         = 4
+	weightBlockApplication = 11
+	weightBlockDNSLeak     = 12
 )
 
 // NewFilterAllowLocalPort creates a filter to allow local port
@@ -86,6 +89,30 @@ func NewFilterAllowApplication(
 	return f
 }
 
+// NewFilterBlockApplication creates a filter to block application
+// (high weight: overrides any 'allow' filter; e.g. traffic of a VPN interface)
+func NewFilterBlockApplication(
+	keyProvider syscall.GUID,
+	keyLayer syscall.GUID,
+	keySublayer syscall.GUID,
+	dispName string,
+	dispDescription string,
+	binaryPath string,
+	isPersistent bool) Filter {
+
+	f := NewFilter(keyProvider, keyLayer, keySublayer, dispName, dispDescription)
+	f.Weight = weightBlockApplication
+	f.Action = FwpActionBlock
+
+	f.Flags = FwpmFilterFlagClearActionRight
+	if isPersistent {
+		f.Flags = f.Flags | FwpmFilterFlagPersistent
+	}
+
+	f.AddCondition(&ConditionAleAppID{Match: FwpMatchEqual, FullPathTobinary: binaryPath})
+	return f
+}
+
 // NewFilterAllowRemoteIP creates a filter to allow remote IP
 func NewFilterAllowRemoteIP(
 	keyProvider syscall.GUID,
@@ -216,3 +243,57 @@ func NewFilterBlockDNS(
 	}
 	return f
 }
+
+// NewFilterBlockRemotePort creates a hard-block filter for a remote port
+// (high weight: overrides any 'allow' filter; e.g. traffic of a VPN interface)
+func NewFilterBlockRemotePort(
+	keyProvider syscall.GUID,
+	keyLayer syscall.GUID,
+	keySublayer syscall.GUID,
+	dispName string,
+	dispDescription string,
+	port uint16,
+	exceptioIP net.IP,
+	isPersistent bool) Filter {
+
+	f := NewFilter(keyProvider, keyLayer, keySublayer, dispName, dispDescription)
+	f.Weight = weightBlockDNSLeak
+	f.Action = FwpActionBlock
+
+	f.Flags = FwpmFilterFlagClearActionRight
+	if isPersistent {
+		f.Flags = f.Flags | FwpmFilterFlagPersistent
+	}
+
+	f.AddCondition(&ConditionIPRemotePort{Match: FwpMatchEqual, Port: port})
+	if exceptioIP != nil && len(exceptioIP) > 0 {
+		f.AddCondition(&ConditionIPRemoteAddressV4{Match: FwpMatchNotEqual, IP: exceptioIP, Mask: net.IPv4(255, 255, 255, 255)})
+	}
+	return f
+}
+
+// NewFilterBlockRemoteIPPort creates a hard-block filter for a specific remote IP and port
+// (high weight: overrides any 'allow' filter; e.g. traffic of a VPN interface)
+func NewFilterBlockRemoteIPPort(
+	keyProvider syscall.GUID,
+	keyLayer syscall.GUID,
+	keySublayer syscall.GUID,
+	dispName string,
+	dispDescription string,
+	ip net.IP,
+	port uint16,
+	isPersistent bool) Filter {
+
+	f := NewFilter(keyProvider, keyLayer, keySublayer, dispName, dispDescription)
+	f.Weight = weightBlockDNSLeak
+	f.Action = FwpActionBlock
+
+	f.Flags = FwpmFilterFlagClearActionRight
+	if isPersistent {
+		f.Flags = f.Flags | FwpmFilterFlagPersistent
+	}
+
+	f.AddCondition(&ConditionIPRemoteAddressV4{Match: FwpMatchEqual, IP: ip, Mask: net.IPv4(255, 255, 255, 255)})
+	f.AddCondition(&ConditionIPRemotePort{Match: FwpMatchEqual, Port: port})
+	return f
+}
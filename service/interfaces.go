@@ -36,6 +36,14 @@ type IServersUpdater interface {
 	GetServers() (*types.ServersInfoResponse, error)
 	// UpdateNotifierChannel returns channel which is notifying when servers was updated
 	UpdateNotifierChannel() chan struct{}
+	// UpdateNotifierChannelInternal returns servers-updated notification channel
+	// for daemon-internal consumers ('UpdateNotifierChannel' is consumed by the protocol)
+	UpdateNotifierChannelInternal() chan struct{}
+	// LastUpdateDiff returns changes of the servers list detected by the last update
+	// (nil - no background update happened yet)
+	LastUpdateDiff() *types.ServersListDiff
+	// SetUpdateInterval changes interval of the background servers-list update
+	SetUpdateInterval(interval time.Duration)
 }
 
 // INetChangeDetector - object is detecting routing changes on a PC
@@ -48,6 +56,15 @@ type INetChangeDetector interface {
 	DelayBeforeNotify() time.Duration
 }
 
+// IPowerEventsDetector - object is detecting system power events (system sleep\resume)
+type IPowerEventsDetector interface {
+	// Start - start power events detector (asynchronous)
+	//    'suspendChan' is the channel for notifying when the system is going to sleep
+	//    'resumeChan' is the channel for notifying when the system resumed after sleep
+	Start(suspendChan chan<- struct{}, resumeChan chan<- struct{})
+	Stop()
+}
+
 // IWgKeysManager - WireGuard keys manager
 type IWgKeysManager interface {
 	Init(receiver wgkeys.IWgKeysChangeReceiver) error
@@ -63,6 +80,27 @@ type IServiceEventsReceiver interface {
 	OnAccountStatus(sessionToken string, account preferences.AccountStatus)
 	OnDNSChanged(dns net.IP)
 	OnKillSwitchStateChanged()
-	OnWiFiChanged(ssid string, isInsecureNetwork bool)
+	// OnFirewallTampered - notify that firewall rules were erased by a third-party tool
+	// ('rulesReApplied == true' when the rules were successfully restored)
+	OnFirewallTampered(rulesReApplied bool)
+	OnWiFiChanged(ssid string, bssid string, isInsecureNetwork bool)
 	OnPingStatus(retMap map[string]int)
+	// OnOperationProgress - notify about progress of a long operation (progress in percent; -1 when indeterminate)
+	OnOperationProgress(operationID string, operation string, phase string, progress int)
+	// OnPortForwardChanged - notify about changed port-forwarding state (port==0: no forwarded port)
+	OnPortForwardChanged(port int, expiresAt int64)
+	// OnBandwidthAlert - notify that consumed monthly traffic reached the configured percent of a cap
+	OnBandwidthAlert(usedBytes int64, capBytes int64, thresholdPercent int)
+	// OnPauseStateChanged - notify about paused\resumed connection (autoResumeAt==0: no auto-resume scheduled)
+	OnPauseStateChanged(isPaused bool, autoResumeAt int64)
+	// OnConnectionQuality - notify about measured latency\jitter through the active tunnel
+	OnConnectionQuality(latencyMs int, jitterMs int)
+	// OnBetterServerAvailable - notify that a clearly better server is available for the current connection
+	// ('isAutoMigrate == true' when daemon is going to migrate to the server by itself)
+	OnBetterServerAvailable(gateway string, score float64, currentGateway string, currentScore float64, isAutoMigrate bool)
+	// OnCaptivePortalDetected - notify that current network intercepts traffic (captive portal)
+	OnCaptivePortalDetected()
+	// OnCaptivePortalBypass - notify about started\finished captive portal bypass
+	// ('expiresAt' - unix time (seconds) when full protection will be restored; 0 - bypass finished)
+	OnCaptivePortalBypass(isActive bool, expiresAt int64)
 }
@@ -1,3 +1,4 @@
+//go:build (darwin && debug) || (linux && debug)
 // +build darwin,debug linux,debug
 
 package filerights
@@ -57,6 +57,9 @@ var (
 	obfsproxyStartScript string
 	obfsproxyHostPort    int
 
+	v2rayBinaryPath string
+	v2rayConfigFile string
+
 	routeCommand string // Example: "/sbin/route" - for macOS, "/sbin/ip route" - for Linux, "C:\\Windows\\System32\\ROUTE.EXE" - for Windows
 
 	wgBinaryPath     string
@@ -139,6 +142,13 @@ func Init() (warnings []string, errors []error) {
 	if err := checkFileAccessRightsExecutable("obfsproxyStartScript", obfsproxyStartScript); err != nil {
 		warnings = append(warnings, fmt.Errorf("obfsproxy functionality not accessible: %w", err).Error())
 	}
+	// checking availability of V2Ray binaries
+	if len(v2rayConfigFile) <= 0 {
+		v2rayConfigFile = filepath.Join(filepath.Dir(settingsFile), "v2ray.json")
+	}
+	if err := checkFileAccessRightsExecutable("v2rayBinaryPath", v2rayBinaryPath); err != nil {
+		warnings = append(warnings, fmt.Errorf("V2Ray obfuscation functionality not accessible: %w", err).Error())
+	}
 	// checling availability of WireGuard binaries
 	if err := checkFileAccessRightsExecutable("wgBinaryPath", wgBinaryPath); err != nil {
 		warnings = append(warnings, fmt.Errorf("WireGuard functionality not accessible: %w", err).Error())
@@ -308,6 +318,16 @@ func ObfsproxyStartScript() string {
 	return obfsproxyStartScript
 }
 
+// V2RayBinaryPath path to V2Ray binary
+func V2RayBinaryPath() string {
+	return v2rayBinaryPath
+}
+
+// V2RayConfigFile path to V2Ray configuration file
+func V2RayConfigFile() string {
+	return v2rayConfigFile
+}
+
 // ObfsproxyHostPort is an port of obfsproxy host
 func ObfsproxyHostPort() int {
 	return obfsproxyHostPort
@@ -20,6 +20,7 @@
 //  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
 //
 
+//go:build darwin && debug
 // +build darwin,debug
 
 package platform
@@ -66,6 +67,9 @@ func doOsInitForBuild() (warnings []string, errors []error) {
 
 	obfsproxyStartScript = path.Join(installDir, "References/macOS/obfsproxy/obfsproxy.sh")
 
+	v2rayBinaryPath = path.Join(installDir, "References/macOS/_deps/v2ray/v2ray")
+	v2rayConfigFile = path.Join(settingsDir, "v2ray.json")
+
 	wgBinaryPath = path.Join(installDir, "References/macOS/_deps/wg_inst/wireguard-go")
 	wgToolBinaryPath = path.Join(installDir, "References/macOS/_deps/wg_inst/wg")
 
@@ -20,6 +20,7 @@
 //  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
 //
 
+//go:build darwin && !debug
 // +build darwin,!debug
 
 package platform
@@ -55,6 +56,9 @@ func doOsInitForBuild() (warnings []string, errors []error) {
 
 	obfsproxyStartScript = "/Applications/IVPN.app/Contents/Resources/obfsproxy/obfsproxy.sh"
 
+	v2rayBinaryPath = "/Applications/IVPN.app/Contents/MacOS/v2ray/v2ray"
+	v2rayConfigFile = path.Join(settingsDir, "v2ray.json")
+
 	wgBinaryPath = "/Applications/IVPN.app/Contents/MacOS/WireGuard/wireguard-go"
 	wgToolBinaryPath = "/Applications/IVPN.app/Contents/MacOS/WireGuard/wg"
 
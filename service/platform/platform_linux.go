@@ -45,6 +45,7 @@ func doInitConstants() {
 func doOsInit() (warnings []string, errors []error) {
 	openVpnBinaryPath = path.Join("/usr/sbin", "openvpn")
 	obfsproxyStartScript = "/usr/bin/obfsproxy"
+	v2rayBinaryPath = path.Join("/opt/ivpn/v2ray", "v2ray")
 	wgBinaryPath = path.Join("/usr/bin", "wg-quick")
 	wgToolBinaryPath = path.Join("/usr/bin", "wg")
 	routeCommand = "/sbin/ip route"
@@ -86,6 +86,9 @@ func doOsInit() (warnings []string, errors []error) {
 
 	obfsproxyStartScript = path.Join(_installDir, "OpenVPN", "obfsproxy", "obfsproxy.exe")
 
+	v2rayBinaryPath = path.Join(_installDir, "v2ray", "v2ray.exe")
+	v2rayConfigFile = path.Join(settingsDir, "v2ray.json")
+
 	_wgArchDir := "x86_64"
 	if _, err := os.Stat(path.Join(_installDir, "WireGuard", _wgArchDir, "wireguard.exe")); err != nil {
 		_wgArchDir = "x86"
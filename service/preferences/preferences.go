@@ -32,6 +32,7 @@ import (
 	"github.com/ivpn/desktop-app-daemon/logger"
 	"github.com/ivpn/desktop-app-daemon/service/platform"
 	"github.com/ivpn/desktop-app-daemon/service/platform/filerights"
+	"github.com/ivpn/desktop-app-daemon/service/secrets"
 )
 
 var log *logger.Logger
@@ -45,6 +46,41 @@ const (
 	DefaultWGKeysInterval = time.Hour * 24 * 1
 )
 
+// keys of sensitive values in the protected secrets storage (see 'secrets' package)
+const (
+	_secretKeySessionToken        = "session_token"
+	_secretKeyOvpnUser            = "ovpn_user"
+	_secretKeyOvpnPass            = "ovpn_pass"
+	_secretKeyWgPrivateKey        = "wg_private_key"
+	_secretKeyWgPendingPrivateKey = "wg_pending_private_key"
+	_secretKeyOvpnProfilesCreds   = "ovpn_profiles_credentials" // JSON: profile name -> credentials
+)
+
+// ovpnProfileCredentials - credentials of an imported OpenVPN profile
+// (stored in the protected secrets storage; see '_secretKeyOvpnProfilesCreds')
+type ovpnProfileCredentials struct {
+	Username string
+	Password string
+}
+
+// ServerMetadata - user-defined metadata of a VPN server (see 'Preferences.ServersMetadata')
+type ServerMetadata struct {
+	IsFavorite bool
+	// Label - user-defined display label of the server (empty - no label)
+	Label string
+	// IsExcludedFromAutoSelect == true - server is never chosen by the automatic server selection
+	// (e.g. fastest-server or random-server selection)
+	IsExcludedFromAutoSelect bool
+}
+
+// OpenvpnImportedProfile - imported third-party OpenVPN profile ('.ovpn') with its credentials
+// (credentials are optional: a profile can use certificate-only authentication)
+type OpenvpnImportedProfile struct {
+	Config   string
+	Username string
+	Password string
+}
+
 // Preferences - IVPN service preferences
 type Preferences struct {
 	IsLogging                bool
@@ -54,6 +90,169 @@ type Preferences struct {
 	IsStopOnClientDisconnect bool
 	IsObfsproxy              bool
 
+	// IsFwDNSLeakProtection - when true, firewall blocks DNS requests (plain DNS, DoT and well-known public DoH resolvers)
+	// to all destinations except the DNS server of a current VPN connection
+	IsFwDNSLeakProtection bool
+
+	// FwUserAllowedApps\FwUserBlockedApps - user per-application firewall rules
+	// (semicolon-separated lists of full paths to application binaries; applicable only for Windows)
+	FwUserAllowedApps string
+	FwUserBlockedApps string
+
+	// IsFwHotspotSharing - when true, firewall permits traffic of Mobile Hotspot \ Internet Connection Sharing clients
+	// (so they can route through the VPN tunnel; applicable only for Windows)
+	IsFwHotspotSharing bool
+
+	// VirtualBridgePolicy - how traffic of local virtual bridges (docker0, vEthernet, vmnet ...)
+	// is treated by the firewall: 'block', 'allow-lan' or 'tunnel' (empty - 'block')
+	VirtualBridgePolicy string
+	// VirtualBridgeOverrides - per-bridge policy overrides
+	// (semicolon-separated list of 'interfaceName=policy' pairs; e.g. 'docker0=tunnel;vmnet8=block')
+	VirtualBridgeOverrides string
+
+	// IsFwAllowLANTrustedOnly - when true, the 'allow LAN' rules are applied only on trusted WiFi networks
+	IsFwAllowLANTrustedOnly bool
+	// FwAllowLANSubnets - limit the 'allow LAN' functionality to the specific subnets only
+	// (CIDR notation; when empty - all detected local subnets are allowed)
+	FwAllowLANSubnets []string
+
+	// IsAutoconnectOnLaunch - when true, daemon restores the last connection on start (before any client connected)
+	IsAutoconnectOnLaunch bool
+	// IsAutoconnectOnUntrustedNetwork - when true, daemon automatically connects when an insecure WiFi network detected
+	IsAutoconnectOnUntrustedNetwork bool
+	// LastConnectionParams - raw JSON of the last 'Connect' request (used by autoconnect functionality)
+	LastConnectionParams string
+
+	// IsRestoreStateOnStart - when true, daemon restores state saved on previous graceful stop
+	// (reconnects and re-enables firewall before any client connected; e.g. after OS restart)
+	IsRestoreStateOnStart bool
+	// LastStopStateVpnConnected\LastStopStateFirewallEnabled - daemon state at the moment of last graceful stop
+	// (used by the state restoration functionality; see 'IsRestoreStateOnStart')
+	LastStopStateVpnConnected    bool
+	LastStopStateFirewallEnabled bool
+
+	// IsRandomPort - when true, daemon picks a random port (from applicable ports of servers configuration) on each connect
+	IsRandomPort bool
+
+	// IsIPv6Endpoints - when true, daemon prefers connecting to an IPv6 endpoint of the server
+	// when the local network has IPv6 connectivity (with automatic fallback to IPv4 on failure)
+	IsIPv6Endpoints bool
+
+	// IsPauseKeepFirewall - when true, firewall stays engaged for the pause period allowing LAN traffic only
+	// (false - paused traffic bypasses the VPN without restrictions)
+	IsPauseKeepFirewall bool
+
+	// PauseResumeDeadline - unix time (seconds) when paused connection will be automatically resumed
+	// (0 - no auto-resume defined; persisted to survive daemon restart)
+	PauseResumeDeadline int64
+
+	// V2Ray obfuscation transport parameters (see 'v2ray' package)
+	V2RayTransport     string // "tls", "ws" or "" (obfuscation disabled)
+	V2RayEndpoint      string // obfuscation endpoint ("host:port")
+	V2RayVMessID       string // user ID (UUID) of the VMess protocol
+	V2RayTLSServerName string // SNI to use for the TLS connection (optional)
+
+	// Stealth obfuscation transport parameters (see 'stealth' package)
+	StealthEndpoint      string // obfuscation endpoint ("host:port"; empty - stealth mode disabled)
+	StealthTLSServerName string // SNI to use for the TLS connection (optional)
+
+	// IsPortRotationOnFailure - when true, failed connection attempt is automatically retried
+	// using the next allowed port\protocol combination from the servers configuration
+	IsPortRotationOnFailure bool
+
+	// IsWgToOpenvpnFallback - when true, failed WireGuard connection (e.g. UDP is blocked by the network)
+	// is automatically retried with OpenVPN over TCP 443 on the same location
+	IsWgToOpenvpnFallback bool
+
+	// IsWGTcpFallback - when true, failed WireGuard connection is automatically retried
+	// with datagrams encapsulated into a TCP connection to the same server
+	// (for networks where all UDP traffic is blocked; see 'udp2tcp' package)
+	IsWGTcpFallback bool
+
+	// IsConnectWarmup - when true, reachability of the destination server on the selected port
+	// is checked before the VPN process is started (fail fast with a precise error
+	// instead of waiting for a connection timeout; see 'service_warmup.go')
+	IsConnectWarmup bool
+
+	// WGServiceInstallTimeoutSec\WGServiceStartTimeoutSec - limit wait timeouts of the
+	// WireGuard tunnel service setup (applicable only for Windows; 0 - use the built-in defaults)
+	WGServiceInstallTimeoutSec int
+	WGServiceStartTimeoutSec   int
+
+	// ConnectionProfiles - named sets of connection parameters (profile name -> raw JSON of a 'Connect' request)
+	ConnectionProfiles map[string]string
+
+	// ServersMetadata - user-defined metadata of VPN servers (server gateway -> metadata)
+	// Stored daemon-side: favorites\labels follow the machine rather than each UI's local storage
+	ServersMetadata map[string]ServerMetadata
+
+	// OpenvpnImportedProfiles - imported third-party OpenVPN profiles (profile name -> profile)
+	OpenvpnImportedProfiles map[string]OpenvpnImportedProfile
+
+	// SchedulerRules - scheduled connect\disconnect rules (raw JSON of a 'SchedulerSetRules' rules list)
+	SchedulerRules string
+
+	// MetricsPort - local TCP port of the metrics endpoint (0 - metrics functionality disabled)
+	MetricsPort int
+
+	// WebSocketPort - local TCP port of the WebSocket bridge of the protocol event stream (0 - disabled)
+	WebSocketPort int
+
+	// ServersUpdateIntervalMinutes - interval of the background servers-list update (0 - default: 60 minutes)
+	ServersUpdateIntervalMinutes int
+
+	// ConnQualityIntervalSec - interval of the periodic latency\jitter measurement
+	// through the active tunnel (0 - measurement disabled)
+	ConnQualityIntervalSec int
+
+	// IsBetterServerAutoMigrate - when true, daemon migrates to a clearly better server by itself
+	// (only while the connection is idle; see 'service_betterserver.go')
+	IsBetterServerAutoMigrate bool
+
+	// OpenVpnExtraParameters - user-defined extra directives of OpenVPN configuration
+	// (only whitelisted directives are acceptable; see 'openvpn.ValidateUserParameters()')
+	OpenVpnExtraParameters string
+
+	// BandwidthMonthlyCapBytes - user-defined monthly traffic cap in bytes (0 - no cap, alerts disabled)
+	BandwidthMonthlyCapBytes int64
+	// BandwidthAlertThresholdPercent - percent of the monthly cap to notify clients at (0 - default: 80)
+	BandwidthAlertThresholdPercent int
+
+	// IsConnectionHistoryEnabled - when true, daemon saves info about VPN sessions into a local history file
+	IsConnectionHistoryEnabled bool
+	// ConnectionHistoryMaxRecords - retention limit of the connection history (0 - default limit)
+	ConnectionHistoryMaxRecords int
+
+	// IsConnectionDiagnostics - when true, daemon records anonymized info about which
+	// transports\ports of connection attempts failed or succeeded (see 'service_conndiag.go')
+	IsConnectionDiagnostics bool
+
+	// FwIPv6Policy - IPv6 egress policy: "block" (default), "allow" or "tunnel"
+	// (tunnels are IPv4-only: without blocking all IPv6 traffic bypasses the VPN)
+	FwIPv6Policy string
+
+	// IsLanServicesDiscovery - when true, daemon is allowed to discover background LAN services
+	// (printers, casting devices) over mDNS to create firewall exceptions for them (see 'service_lanservices.go')
+	IsLanServicesDiscovery bool
+	// LanAllowedDevices - IP addresses of LAN devices allowed by the user while kill switch is on
+	LanAllowedDevices []string
+
+	// user-defined proxy for all API traffic (useful when API servers are blocked)
+	APIProxyType     string // "socks5", "http" or "" (no proxy)
+	APIProxyAddress  string
+	APIProxyPort     int
+	APIProxyUsername string
+	APIProxyPassword string
+
+	// IsCustomAPIEndpointsAllowed - admin-only gate for overriding API\update hosts
+	// NOT settable over the protocol: can be enabled only by editing the settings file manually (file is accessible to admins only)
+	IsCustomAPIEndpointsAllowed bool
+	// custom API\update hosts and their pinned key sets (for staging or self-hosted environments)
+	CustomAPIHost          string
+	CustomUpdateHost       string
+	CustomAPIPinnedKeys    []string
+	CustomUpdatePinnedKeys []string
+
 	// last known account status
 	Session SessionStatus
 }
@@ -77,9 +276,26 @@ func (p *Preferences) UpdateWgCredentials(wgPublicKey string, wgPrivateKey strin
 	p.SavePreferences()
 }
 
+// UpdateWgPendingKeys save WG key pair which is not yet confirmed by the API (empty values - erase)
+func (p *Preferences) UpdateWgPendingKeys(wgPublicKey string, wgPrivateKey string) {
+	p.Session.WGPendingPublicKey = strings.TrimSpace(wgPublicKey)
+	p.Session.WGPendingPrivateKey = strings.TrimSpace(wgPrivateKey)
+	p.SavePreferences()
+}
+
 // SavePreferences saves preferences
 func (p *Preferences) SavePreferences() error {
-	data, err := json.Marshal(p)
+	toSave := *p
+
+	// sensitive values are kept in the protected secrets storage (see 'secrets' package);
+	// when the storage is not functional - values stay in the settings file (better than losing the session)
+	if err := p.storeSecrets(); err != nil {
+		log.Warning("failed to save sensitive values into the protected storage: ", err)
+	} else {
+		redactSecrets(&toSave)
+	}
+
+	data, err := json.Marshal(&toSave)
 	if err != nil {
 		return fmt.Errorf("failed to save preferences file (json marshal error): %w", err)
 	}
@@ -97,6 +313,114 @@ func (p *Preferences) SavePreferences() error {
 	return nil
 }
 
+// storeSecrets saves sensitive values into the protected secrets storage
+// (empty values erase corresponding secrets; e.g. on logout)
+func (p *Preferences) storeSecrets() error {
+	profilesCreds := make(map[string]ovpnProfileCredentials)
+	for name, profile := range p.OpenvpnImportedProfiles {
+		if len(profile.Username) > 0 || len(profile.Password) > 0 {
+			profilesCreds[name] = ovpnProfileCredentials{Username: profile.Username, Password: profile.Password}
+		}
+	}
+	profilesCredsJSON := ""
+	if len(profilesCreds) > 0 {
+		data, err := json.Marshal(profilesCreds)
+		if err != nil {
+			return err
+		}
+		profilesCredsJSON = string(data)
+	}
+
+	toStore := map[string]string{
+		_secretKeySessionToken:        p.Session.Session,
+		_secretKeyOvpnUser:            p.Session.OpenVPNUser,
+		_secretKeyOvpnPass:            p.Session.OpenVPNPass,
+		_secretKeyWgPrivateKey:        p.Session.WGPrivateKey,
+		_secretKeyWgPendingPrivateKey: p.Session.WGPendingPrivateKey,
+		_secretKeyOvpnProfilesCreds:   profilesCredsJSON}
+
+	for key, value := range toStore {
+		if err := secrets.Store(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// redactSecrets removes sensitive values from a preferences copy prepared for saving
+// (the values are kept in the protected secrets storage; see 'storeSecrets()')
+func redactSecrets(p *Preferences) {
+	p.Session.Session = ""
+	p.Session.OpenVPNUser = ""
+	p.Session.OpenVPNPass = ""
+	p.Session.WGPrivateKey = ""
+	p.Session.WGPendingPrivateKey = ""
+
+	if len(p.OpenvpnImportedProfiles) > 0 {
+		profiles := make(map[string]OpenvpnImportedProfile, len(p.OpenvpnImportedProfiles))
+		for name, profile := range p.OpenvpnImportedProfiles {
+			profile.Username = ""
+			profile.Password = ""
+			profiles[name] = profile
+		}
+		p.OpenvpnImportedProfiles = profiles
+	}
+}
+
+// hasPlaintextSecrets returns true when loaded preferences contain sensitive values
+// in plain text (settings file of an older daemon version)
+func (p *Preferences) hasPlaintextSecrets() bool {
+	if len(p.Session.Session) > 0 || len(p.Session.OpenVPNUser) > 0 || len(p.Session.OpenVPNPass) > 0 ||
+		len(p.Session.WGPrivateKey) > 0 || len(p.Session.WGPendingPrivateKey) > 0 {
+		return true
+	}
+	for _, profile := range p.OpenvpnImportedProfiles {
+		if len(profile.Username) > 0 || len(profile.Password) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// loadSecrets fills sensitive fields from the protected secrets storage
+func (p *Preferences) loadSecrets() error {
+	var err error
+	if p.Session.Session, err = secrets.Retrieve(_secretKeySessionToken); err != nil {
+		return err
+	}
+	if p.Session.OpenVPNUser, err = secrets.Retrieve(_secretKeyOvpnUser); err != nil {
+		return err
+	}
+	if p.Session.OpenVPNPass, err = secrets.Retrieve(_secretKeyOvpnPass); err != nil {
+		return err
+	}
+	if p.Session.WGPrivateKey, err = secrets.Retrieve(_secretKeyWgPrivateKey); err != nil {
+		return err
+	}
+	if p.Session.WGPendingPrivateKey, err = secrets.Retrieve(_secretKeyWgPendingPrivateKey); err != nil {
+		return err
+	}
+
+	profilesCredsJSON, err := secrets.Retrieve(_secretKeyOvpnProfilesCreds)
+	if err != nil {
+		return err
+	}
+	if len(profilesCredsJSON) > 0 && len(p.OpenvpnImportedProfiles) > 0 {
+		profilesCreds := make(map[string]ovpnProfileCredentials)
+		if err := json.Unmarshal([]byte(profilesCredsJSON), &profilesCreds); err != nil {
+			return err
+		}
+		for name, creds := range profilesCreds {
+			if profile, ok := p.OpenvpnImportedProfiles[name]; ok {
+				profile.Username = creds.Username
+				profile.Password = creds.Password
+				p.OpenvpnImportedProfiles[name] = profile
+			}
+		}
+	}
+	return nil
+}
+
 // LoadPreferences loads preferences
 func (p *Preferences) LoadPreferences() error {
 	data, err := ioutil.ReadFile(platform.SettingsFile())
@@ -138,6 +462,17 @@ func (p *Preferences) LoadPreferences() error {
 		return err
 	}
 
+	// non-empty sensitive values in the settings file - install updated from an older version:
+	// move them into the protected secrets storage (see 'secrets' package)
+	if p.hasPlaintextSecrets() {
+		log.Info("moving sensitive values from the settings file into the protected storage...")
+		if err := p.SavePreferences(); err != nil {
+			log.Error("failed to move sensitive values into the protected storage: ", err)
+		}
+	} else if err := p.loadSecrets(); err != nil {
+		return fmt.Errorf("failed to read the protected secrets storage: %w", err)
+	}
+
 	if len(p.Session.WGPublicKey) == 0 || len(p.Session.WGPrivateKey) == 0 || len(p.Session.WGLocalIP) == 0 {
 		p.Session.WGKeyGenerated = time.Time{}
 	}
@@ -39,6 +39,11 @@ type SessionStatus struct {
 	WGLocalIP          string
 	WGKeyGenerated     time.Time
 	WGKeysRegenInerval time.Duration // syntax error in variable name. Keeping it as is for compatibility with previous versions
+
+	// pending WG key pair: generated but not yet confirmed by the API
+	// (key rotation will be retried with the same pair until the API confirms it)
+	WGPendingPublicKey  string `json:",omitempty"`
+	WGPendingPrivateKey string `json:",omitempty"`
 }
 
 // IsLoggedIn returns 'true' when user logged-in
@@ -0,0 +1,115 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package secrets
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/ivpn/desktop-app-daemon/logger"
+	"github.com/ivpn/desktop-app-daemon/service/platform"
+	"github.com/ivpn/desktop-app-daemon/service/platform/filerights"
+)
+
+// Protected storage of sensitive material of the daemon
+// (session token, WireGuard private keys, OpenVPN credentials).
+// Values are kept out of the plain settings file:
+//	Windows - encrypted by DPAPI (machine scope) and saved into a separate file
+//	macOS   - saved into the system keychain (via the 'security' utility)
+//	Linux   - saved into a separate file accessible only to the privileged user
+//	          (libsecret\Secret Service requires a user session bus which is not
+//	          available for a system daemon)
+
+var log *logger.Logger
+
+func init() {
+	log = logger.NewLogger("secrt")
+}
+
+// Store saves a secret value into the protected storage (empty value erases the secret)
+func Store(key string, value string) error {
+	if len(value) == 0 {
+		return Erase(key)
+	}
+	return implStore(key, value)
+}
+
+// Retrieve returns a secret value from the protected storage
+// (empty string when the secret is not defined)
+func Retrieve(key string) (string, error) {
+	return implRetrieve(key)
+}
+
+// Erase removes a secret value from the protected storage
+func Erase(key string) error {
+	return implErase(key)
+}
+
+// secretsFilePath returns location of the secrets file
+// (same folder as the settings file; not applicable for macOS: system keychain is in use)
+func secretsFilePath() string {
+	return filepath.Join(filepath.Dir(platform.SettingsFile()), "secrets.dat")
+}
+
+// readSecretsFile reads the secrets file (empty map when file does not exist)
+func readSecretsFile() (map[string]string, error) {
+	data, err := ioutil.ReadFile(secretsFilePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]string{}, nil
+		}
+		return nil, err
+	}
+
+	ret := map[string]string{}
+	if err := json.Unmarshal(data, &ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// writeSecretsFile saves the secrets file (file is removed when there is nothing to keep)
+func writeSecretsFile(secrets map[string]string) error {
+	file := secretsFilePath()
+
+	if len(secrets) == 0 {
+		if err := os.Remove(file); err != nil && os.IsNotExist(err) == false {
+			return err
+		}
+		return nil
+	}
+
+	data, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(file, data, 0600); err != nil { // read\write only for privileged user
+		return err
+	}
+
+	// only for Windows: Golang is not able to change file permissins in Windows style
+	return filerights.WindowsChmod(file, 0600) // read\write only for privileged user
+}
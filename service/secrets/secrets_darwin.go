@@ -0,0 +1,93 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package secrets
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/ivpn/desktop-app-daemon/shell"
+)
+
+// macOS: secrets are kept in the system keychain (accessible only to the privileged user)
+
+const (
+	_securityBinaryPath = "/usr/bin/security"
+	_keychainService    = "net.ivpn.daemon"
+	_systemKeychainPath = "/Library/Keychains/System.keychain"
+
+	// exit code of the 'security' utility when an item is not found (errSecItemNotFound)
+	_errSecItemNotFound = 44
+)
+
+func implStore(key string, value string) error {
+	// The secret must not appear in the process argument list (visible to all local users via 'ps');
+	// the command is passed to the 'security' utility over stdin ('-i' - read commands from stdin)
+	// '-U' - update the item when it already exists
+	cmd := exec.Command(_securityBinaryPath, "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s %s\n",
+		quoteSecurityArg(_keychainService), quoteSecurityArg(key), quoteSecurityArg(value), _systemKeychainPath))
+
+	log.Info("Shell exec: ", _securityBinaryPath, " -i (add-generic-password)")
+	if err := cmd.Run(); err != nil {
+		if code, e := shell.GetCmdExitCode(err); e == nil {
+			return fmt.Errorf("failed to store secret: 'security' exited with code %d", code)
+		}
+		return fmt.Errorf("failed to store secret: %w", err)
+	}
+	return nil
+}
+
+// quoteSecurityArg quotes an argument of a 'security' interactive command
+func quoteSecurityArg(arg string) string {
+	arg = strings.ReplaceAll(arg, `\`, `\\`)
+	arg = strings.ReplaceAll(arg, `"`, `\"`)
+	return `"` + arg + `"`
+}
+
+func implRetrieve(key string) (string, error) {
+	// '-w' - print only the password
+	outText, _, _, err := shell.ExecAndGetOutput(log, 1024, "",
+		_securityBinaryPath, "find-generic-password",
+		"-s", _keychainService, "-a", key, "-w", _systemKeychainPath)
+	if err != nil {
+		if code, e := shell.GetCmdExitCode(err); e == nil && code == _errSecItemNotFound {
+			return "", nil
+		}
+		return "", err
+	}
+	return strings.TrimRight(outText, "\n"), nil
+}
+
+func implErase(key string) error {
+	err := shell.Exec(log, _securityBinaryPath, "delete-generic-password",
+		"-s", _keychainService, "-a", key, _systemKeychainPath)
+	if err != nil {
+		if code, e := shell.GetCmdExitCode(err); e == nil && code == _errSecItemNotFound {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
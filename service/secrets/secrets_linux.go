@@ -0,0 +1,70 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package secrets
+
+import "encoding/base64"
+
+// Linux: there is no protected system storage applicable for a system daemon
+// (libsecret\Secret Service belongs to a user session).
+// Secrets are kept in a separate file accessible only to the privileged user.
+
+func implStore(key string, value string) error {
+	secrets, err := readSecretsFile()
+	if err != nil {
+		return err
+	}
+	secrets[key] = base64.StdEncoding.EncodeToString([]byte(value))
+	return writeSecretsFile(secrets)
+}
+
+func implRetrieve(key string) (string, error) {
+	secrets, err := readSecretsFile()
+	if err != nil {
+		return "", err
+	}
+
+	encoded, ok := secrets[key]
+	if ok == false {
+		return "", nil
+	}
+
+	value, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
+
+func implErase(key string) error {
+	secrets, err := readSecretsFile()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := secrets[key]; ok == false {
+		return nil
+	}
+
+	delete(secrets, key)
+	return writeSecretsFile(secrets)
+}
@@ -0,0 +1,135 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package secrets
+
+import (
+	"encoding/base64"
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// Windows: secrets are encrypted by DPAPI and saved into the secrets file.
+// Machine scope is in use: the daemon runs under the SYSTEM account and
+// encrypted values must not depend on a user profile.
+
+var (
+	_crypt32  = syscall.NewLazyDLL("crypt32.dll")
+	_kernel32 = syscall.NewLazyDLL("kernel32.dll")
+
+	_fCryptProtectData   = _crypt32.NewProc("CryptProtectData")
+	_fCryptUnprotectData = _crypt32.NewProc("CryptUnprotectData")
+	_fLocalFree          = _kernel32.NewProc("LocalFree")
+)
+
+const (
+	_cryptprotectUIForbidden  = 0x1 // CRYPTPROTECT_UI_FORBIDDEN
+	_cryptprotectLocalMachine = 0x4 // CRYPTPROTECT_LOCAL_MACHINE
+)
+
+// dataBlob - DATA_BLOB structure of the Windows API
+type dataBlob struct {
+	cbData uint32
+	pbData *byte
+}
+
+func newDataBlob(data []byte) *dataBlob {
+	if len(data) == 0 {
+		return &dataBlob{}
+	}
+	return &dataBlob{cbData: uint32(len(data)), pbData: &data[0]}
+}
+
+func (b *dataBlob) bytes() []byte {
+	ret := make([]byte, b.cbData)
+	copy(ret, (*[1 << 30]byte)(unsafe.Pointer(b.pbData))[:b.cbData:b.cbData])
+	return ret
+}
+
+func dpapiCrypt(proc *syscall.LazyProc, flags uintptr, data []byte) ([]byte, error) {
+	var out dataBlob
+	ret, _, err := proc.Call(
+		uintptr(unsafe.Pointer(newDataBlob(data))),
+		0, // data description
+		0, // optional entropy
+		0, // reserved
+		0, // prompt structure
+		flags,
+		uintptr(unsafe.Pointer(&out)))
+	if ret == 0 {
+		return nil, fmt.Errorf("DPAPI error: %w", err)
+	}
+	defer _fLocalFree.Call(uintptr(unsafe.Pointer(out.pbData)))
+	return out.bytes(), nil
+}
+
+func implStore(key string, value string) error {
+	encrypted, err := dpapiCrypt(_fCryptProtectData, _cryptprotectUIForbidden|_cryptprotectLocalMachine, []byte(value))
+	if err != nil {
+		return err
+	}
+
+	secrets, err := readSecretsFile()
+	if err != nil {
+		return err
+	}
+	secrets[key] = base64.StdEncoding.EncodeToString(encrypted)
+	return writeSecretsFile(secrets)
+}
+
+func implRetrieve(key string) (string, error) {
+	secrets, err := readSecretsFile()
+	if err != nil {
+		return "", err
+	}
+
+	encoded, ok := secrets[key]
+	if ok == false {
+		return "", nil
+	}
+
+	encrypted, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+
+	value, err := dpapiCrypt(_fCryptUnprotectData, _cryptprotectUIForbidden, encrypted)
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
+
+func implErase(key string) error {
+	secrets, err := readSecretsFile()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := secrets[key]; ok == false {
+		return nil
+	}
+
+	delete(secrets, key)
+	return writeSecretsFile(secrets)
+}
@@ -28,6 +28,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/ivpn/desktop-app-daemon/api"
@@ -36,17 +37,30 @@ import (
 	"github.com/ivpn/desktop-app-daemon/service/platform/filerights"
 )
 
+// default interval of the background servers-list update
+const _defaultServersUpdateInterval = time.Hour
+
 type serversUpdater struct {
 	servers           *types.ServersInfoResponse
 	api               *api.API
 	updatedNotifyChan chan struct{}
+	// separate notification channel for daemon-internal consumers
+	// ('updatedNotifyChan' is consumed by the protocol: clients notification)
+	updatedNotifyChanInternal chan struct{}
+
+	mutex sync.Mutex
+	// interval of the background servers-list update (see 'SetUpdateInterval()')
+	updateInterval time.Duration
+	// changes of the servers list detected by the last update (nil - no update happened yet)
+	lastDiff *types.ServersListDiff
 }
 
 // CreateServersUpdater - constructor for serversUpdater object
 func CreateServersUpdater(apiObj *api.API) (IServersUpdater, error) {
-	updater := &serversUpdater{api: apiObj}
+	updater := &serversUpdater{api: apiObj, updateInterval: _defaultServersUpdateInterval}
 
 	updater.updatedNotifyChan = make(chan struct{}, 1)
+	updater.updatedNotifyChanInternal = make(chan struct{}, 1)
 
 	servers, err := updater.GetServers()
 	if err == nil && servers != nil {
@@ -95,7 +109,7 @@ func (s *serversUpdater) startUpdater() error {
 	go func(s *serversUpdater) {
 		isFirstIteration := true
 		for {
-			updateDelay := time.Hour
+			updateDelay := s.getUpdateInterval()
 			if _, err := s.updateServers(); err != nil {
 				log.Error(err)
 				if isFirstIteration {
@@ -121,6 +135,15 @@ func (s *serversUpdater) updateServers() (*types.ServersInfoResponse, error) {
 	}
 	log.Info(fmt.Sprintf("Updated servers info (%d OpenVPN; %d WireGuard)\n", len(servers.OpenvpnServers), len(servers.WireguardServers)))
 
+	// save changes of the servers list since the previous update
+	// (allows to notify clients only about changed servers; see 'LastUpdateDiff()')
+	if s.servers != nil {
+		diff := computeServersListDiff(s.servers, servers)
+		s.mutex.Lock()
+		s.lastDiff = &diff
+		s.mutex.Unlock()
+	}
+
 	s.servers = servers
 	if err := writeServersToCache(servers); err != nil {
 		log.Error("failed to save servers cache file: ", err)
@@ -132,6 +155,12 @@ func (s *serversUpdater) updateServers() (*types.ServersInfoResponse, error) {
 	default:
 		// channel is full
 	}
+	select {
+	case s.updatedNotifyChanInternal <- struct{}{}:
+		// notified
+	default:
+		// channel is full
+	}
 
 	return servers, nil
 }
@@ -141,6 +170,84 @@ func (s *serversUpdater) UpdateNotifierChannel() chan struct{} {
 	return s.updatedNotifyChan
 }
 
+// UpdateNotifierChannelInternal returns servers-updated notification channel
+// for daemon-internal consumers (see 'updatedNotifyChanInternal')
+func (s *serversUpdater) UpdateNotifierChannelInternal() chan struct{} {
+	return s.updatedNotifyChanInternal
+}
+
+// LastUpdateDiff returns changes of the servers list detected by the last update
+// (nil - no background update happened yet)
+func (s *serversUpdater) LastUpdateDiff() *types.ServersListDiff {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.lastDiff
+}
+
+// SetUpdateInterval changes interval of the background servers-list update
+// (applied after the currently scheduled update)
+func (s *serversUpdater) SetUpdateInterval(interval time.Duration) {
+	if interval <= 0 {
+		interval = _defaultServersUpdateInterval
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.updateInterval = interval
+}
+
+func (s *serversUpdater) getUpdateInterval() time.Duration {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.updateInterval
+}
+
+// computeServersListDiff returns changes of the servers list since the previous update
+// (added\removed servers and load changes of servers which stayed in the list)
+func computeServersListDiff(oldServers *types.ServersInfoResponse, newServers *types.ServersInfoResponse) types.ServersListDiff {
+	diff := types.ServersListDiff{}
+
+	oldWgServers := make(map[string]types.WireGuardServerInfo, len(oldServers.WireguardServers))
+	for _, svr := range oldServers.WireguardServers {
+		oldWgServers[svr.Gateway] = svr
+	}
+	for _, svr := range newServers.WireguardServers {
+		oldSvr, isKnown := oldWgServers[svr.Gateway]
+		if isKnown == false {
+			diff.AddedWireguardServers = append(diff.AddedWireguardServers, svr)
+			continue
+		}
+		if oldSvr.Load != svr.Load {
+			diff.LoadChanges = append(diff.LoadChanges, types.ServerLoadChange{Gateway: svr.Gateway, Load: svr.Load})
+		}
+		delete(oldWgServers, svr.Gateway)
+	}
+	for gateway := range oldWgServers {
+		diff.RemovedWireguardServers = append(diff.RemovedWireguardServers, gateway)
+	}
+
+	oldOvpnServers := make(map[string]types.OpenvpnServerInfo, len(oldServers.OpenvpnServers))
+	for _, svr := range oldServers.OpenvpnServers {
+		oldOvpnServers[svr.Gateway] = svr
+	}
+	for _, svr := range newServers.OpenvpnServers {
+		oldSvr, isKnown := oldOvpnServers[svr.Gateway]
+		if isKnown == false {
+			diff.AddedOpenvpnServers = append(diff.AddedOpenvpnServers, svr)
+			continue
+		}
+		if oldSvr.Load != svr.Load {
+			diff.LoadChanges = append(diff.LoadChanges, types.ServerLoadChange{Gateway: svr.Gateway, Load: svr.Load})
+		}
+		delete(oldOvpnServers, svr.Gateway)
+	}
+	for gateway := range oldOvpnServers {
+		diff.RemovedOpenvpnServers = append(diff.RemovedOpenvpnServers, gateway)
+	}
+
+	return diff
+}
+
 func readServersFromCache() (svrs *types.ServersInfoResponse, apiIPs []string, e error) {
 
 	serversFile := platform.ServersFile()
@@ -27,7 +27,9 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
+	"reflect"
 	"sort"
 	"strconv"
 	"strings"
@@ -36,14 +38,19 @@ import (
 
 	"github.com/ivpn/desktop-app-daemon/api"
 	"github.com/ivpn/desktop-app-daemon/api/types"
+	"github.com/ivpn/desktop-app-daemon/geoip"
 	"github.com/ivpn/desktop-app-daemon/helpers"
 	"github.com/ivpn/desktop-app-daemon/logger"
 	"github.com/ivpn/desktop-app-daemon/netinfo"
+	protocoltypes "github.com/ivpn/desktop-app-daemon/protocol/types"
 	"github.com/ivpn/desktop-app-daemon/service/dns"
 	"github.com/ivpn/desktop-app-daemon/service/firewall"
 	"github.com/ivpn/desktop-app-daemon/service/platform"
 	"github.com/ivpn/desktop-app-daemon/service/platform/filerights"
 	"github.com/ivpn/desktop-app-daemon/service/preferences"
+	"github.com/ivpn/desktop-app-daemon/stealth"
+	"github.com/ivpn/desktop-app-daemon/udp2tcp"
+	"github.com/ivpn/desktop-app-daemon/v2ray"
 	"github.com/ivpn/desktop-app-daemon/vpn"
 	"github.com/ivpn/desktop-app-daemon/vpn/openvpn"
 	"github.com/ivpn/desktop-app-daemon/vpn/wireguard"
@@ -58,6 +65,21 @@ func init() {
 // RequiredState VPN state which service is going to reach
 type RequiredState int
 
+// connectionHistory - statistics of connections to one host
+type connectionHistory struct {
+	attempts  int
+	successes int
+}
+
+// loginTransaction - state of a pending multi-step login
+// The API can require captcha and/or 2FA confirmation; the client continues such login
+// by sending only the requested data (account ID is taken from the transaction)
+type loginTransaction struct {
+	accountID  string
+	forceLogin bool
+	captchaID  string // ID of the captcha requested by the API
+}
+
 // Requested VPN states
 const (
 	Disconnect     RequiredState = 0
@@ -68,6 +90,12 @@ const (
 const (
 	// SessionCheckInterval - the interval for periodical check session status
 	SessionCheckInterval time.Duration = time.Hour * 6
+	// SessionCheckIntervalExpiration - the interval for periodical check session status
+	// when the subscription is close to expiration (to detect the expiration moment in a reasonable time)
+	SessionCheckIntervalExpiration time.Duration = time.Hour
+	// sessionCheckExpirationThreshold - when subscription expires sooner than this threshold,
+	// the session checker switches to the 'SessionCheckIntervalExpiration' interval
+	sessionCheckExpirationThreshold time.Duration = time.Hour * 24
 )
 
 // Service - IVPN service
@@ -76,10 +104,13 @@ type Service struct {
 	_api               *api.API
 	_serversUpdater    IServersUpdater
 	_netChangeDetector INetChangeDetector
-	_wgKeysMgr         IWgKeysManager
-	_vpn               vpn.Process
-	_preferences       preferences.Preferences
-	_connectMutex      sync.Mutex
+
+	// system power events detector (nil - power events detection not available)
+	_powerEventsDetector IPowerEventsDetector
+	_wgKeysMgr           IWgKeysManager
+	_vpn                 vpn.Process
+	_preferences         preferences.Preferences
+	_connectMutex        sync.Mutex
 
 	// manual DNS value (if not defined - nil)
 	_manualDNS net.IP
@@ -91,25 +122,133 @@ type Service struct {
 	// Note: Disconnect() function will wait until VPN fully disconnects
 	_done chan struct{}
 
+	// reason of reconnection initiated by daemon itself (e.g. by tunnel watchdog)
+	// (used to notify clients with correct RECONNECTING description)
+	_reconnectReason string
+
+	// server-switch window: firewall protection stays engaged between the connections
+	// (see 'service_serverswitch.go')
+	_serverSwitchMutex sync.Mutex
+	_isServerSwitch    bool
+	_serverSwitchTimer *time.Timer
+
+	// captive portal bypass timer (nil - no bypass active; see 'service_captiveportal.go')
+	_captivePortalMutex sync.Mutex
+	_captivePortalTimer *time.Timer
+
 	_isServersPingInProgress bool
 
+	// info about forwarded port (0 - no forwarded port)
+	_portForwardMutex     sync.Mutex
+	_portForwardPort      int
+	_portForwardExpiresAt int64 // unix time (seconds)
+
 	// nil - when session checker stopped
 	// to stop -> write to channel (it is synchronous channel)
 	_sessionCheckerStopChn chan struct{}
+
+	// pending multi-step login transaction (empty accountID - no login in progress)
+	_loginMutex sync.Mutex
+	_login      loginTransaction
+
+	// last known account status received by the session checker
+	// (pushed to new clients on connect; empty '_accountStatusSession' - no status received yet)
+	_accountStatusMutex   sync.Mutex
+	_accountStatus        preferences.AccountStatus
+	_accountStatusSession string
+
+	// historical connection success rate per host (used by fastest-server selection)
+	_connHistoryMutex sync.Mutex
+	_connHistory      map[string]*connectionHistory
+
+	// better-server advisory state (see 'service_betterserver.go')
+	_betterSrvLastNotify time.Time // time of the last advisory (rate limiting)
+	_betterSrvIdleBytes  int64     // session bytes counter at the moment of the last check (idle detection)
+	_betterSrvIdleTime   time.Time // time of the last check (zero - no baseline yet)
+
+	// metrics listener (nil - when metrics functionality disabled)
+	_metricsMutex          sync.Mutex
+	_metricsServer         *http.Server
+	_metricsConnectedSince time.Time // zero - not connected
+	_metricsConnectsTotal  int64     // count of established VPN connections (including reconnections)
+
+	// local connection history (nil - no active session record)
+	_historyMutex        sync.Mutex
+	_historyActiveRecord *protocoltypes.ConnectionHistoryRecord
+
+	// bandwidth accounting (_bwStopChan != nil - accounting in progress)
+	_bwMutex           sync.Mutex
+	_bwTunnelInterface *net.Interface
+	_bwSessionReceived int64
+	_bwSessionSent     int64
+	_bwLastRawReceived int64 // raw counters of the tunnel interface (from last poll)
+	_bwLastRawSent     int64
+	_bwMonthStats      bandwidthMonthStats
+	_bwAlertNotified   bool
+	_bwStopChan        chan struct{}
+
+	// auto-resume timer of paused connection (nil - no auto-resume scheduled)
+	_pauseTimerMutex sync.Mutex
+	_pauseTimer      *time.Timer
+
+	// firewall configuration changed for the pause period (have to be restored on resume)
+	_pauseFwEnabled    bool
+	_pauseFwLanAllowed bool
+
+	// V2Ray obfuscation transport instance (nil - no active instance)
+	_v2rayMutex sync.Mutex
+	_v2ray      *v2ray.V2Ray
+
+	// stealth (TLS-wrapping) obfuscation transport instance (nil - no active instance)
+	_stealthMutex sync.Mutex
+	_stealth      *stealth.Stealth
+
+	// WireGuard UDP-over-TCP fallback transport (see 'service_wgtcp.go')
+	_wgTcpMutex   sync.Mutex
+	_wgTcpAllowed bool
+	_wgTcpActive  bool
+	_wgTcp        *udp2tcp.Udp2Tcp // nil - no active instance
+
+	// automatic port rotation on connection failure
+	_portRotationMutex    sync.Mutex
+	_portRotationVpnType  vpn.Type
+	_portRotationInitial  portCombination
+	_portRotationCombos   []portCombination // nil - not initialized yet
+	_portRotationAttempt  int
+	_portRotationOverride *portCombination // nil - no rotation in progress
+
+	// automatic fallback to the IPv4 endpoint when connection over an IPv6 endpoint failed
+	_ipv6FallbackMutex    sync.Mutex
+	_ipv6FallbackHost     net.IP // nil - no fallback host defined (connecting over IPv4)
+	_ipv6FallbackOverride net.IP // nil - no fallback in progress
+
+	// unified retry state of a connection request (see 'service_connmanager.go')
+	_connRetry connRetryState
+
+	// opt-in connection diagnostics (see 'service_conndiag.go')
+	_connDiagMutex   sync.Mutex
+	_connDiagRecords []connDiagRecord
+	_connDiagPending *connDiagRecord // nil - no attempt in progress
+
+	// port\protocol of the current connection attempt (see 'service_warmup.go')
+	_warmupMutex sync.Mutex
+	_warmupPort  int
+	_warmupIsTCP bool
 }
 
 // CreateService - service constructor
-func CreateService(evtReceiver IServiceEventsReceiver, api *api.API, updater IServersUpdater, netChDetector INetChangeDetector, wgKeysMgr IWgKeysManager) (*Service, error) {
+func CreateService(evtReceiver IServiceEventsReceiver, api *api.API, updater IServersUpdater, netChDetector INetChangeDetector, powerEvtDetector IPowerEventsDetector, wgKeysMgr IWgKeysManager) (*Service, error) {
 	if updater == nil {
 		return &Service{}, fmt.Errorf("ServersUpdater is not defined")
 	}
 
 	serv := &Service{
-		_evtReceiver:       evtReceiver,
-		_api:               api,
-		_serversUpdater:    updater,
-		_netChangeDetector: netChDetector,
-		_wgKeysMgr:         wgKeysMgr}
+		_evtReceiver:         evtReceiver,
+		_api:                 api,
+		_serversUpdater:      updater,
+		_netChangeDetector:   netChDetector,
+		_powerEventsDetector: powerEvtDetector,
+		_wgKeysMgr:           wgKeysMgr}
 
 	if err := serv.init(); err != nil {
 		return nil, fmt.Errorf("service initialization error : %w", err)
@@ -126,6 +265,26 @@ func (s *Service) init() error {
 		s._preferences.SavePreferences()
 	}
 
+	// apply user-defined proxy configuration for API traffic (if defined)
+	if len(s._preferences.APIProxyType) > 0 {
+		s.applyAPIProxySettings()
+	}
+
+	// apply user-defined interval of the background servers-list update (if defined)
+	if s._preferences.ServersUpdateIntervalMinutes > 0 {
+		s._serversUpdater.SetUpdateInterval(time.Duration(s._preferences.ServersUpdateIntervalMinutes) * time.Minute)
+	}
+
+	// apply custom API\update hosts (if defined and allowed)
+	if s._preferences.IsCustomAPIEndpointsAllowed {
+		p := s._preferences
+		if len(p.CustomAPIHost) > 0 || len(p.CustomUpdateHost) > 0 {
+			if err := s._api.SetCustomHosts(p.CustomAPIHost, p.CustomUpdateHost, p.CustomAPIPinnedKeys, p.CustomUpdatePinnedKeys); err != nil {
+				log.Error("Failed to apply custom API hosts: ", err)
+			}
+		}
+	}
+
 	if err := dns.Initialize(); err != nil {
 		log.Error(fmt.Sprintf("failed to initialize DNS : %s", err))
 	}
@@ -139,10 +298,36 @@ func (s *Service) init() error {
 	//logger.Enable(s._preferences.IsLogging)
 
 	// Init firewall
-	if err := firewall.AllowLAN(s._preferences.IsFwAllowLAN, s._preferences.IsFwAllowLANMulticast); err != nil {
+	if err := s.applyKillSwitchAllowLAN(); err != nil {
 		log.Error("Failed to initialize firewall with AllowLAN preference value: ", err)
 	}
 
+	// restore user per-application rules (must be defined before the firewall become enabled)
+	if err := s.applyUserAppRules(); err != nil {
+		log.Error("Failed to initialize firewall with user app rules: ", err)
+	}
+
+	// restore hotspot sharing mode (Windows Mobile Hotspot \ ICS compatibility)
+	if s._preferences.IsFwHotspotSharing {
+		if err := firewall.SetHotspotSharing(true); err != nil {
+			log.Error("Failed to initialize firewall with hotspot sharing mode: ", err)
+		}
+	}
+
+	// restore virtual bridge (Docker\VM) traffic policy
+	if len(s._preferences.VirtualBridgePolicy) > 0 || len(s._preferences.VirtualBridgeOverrides) > 0 {
+		if err := s.applyVirtualBridgePolicy(); err != nil {
+			log.Error("Failed to initialize firewall with virtual bridge policy: ", err)
+		}
+	}
+
+	// restore IPv6 egress policy (must be defined before the firewall become enabled)
+	if len(s._preferences.FwIPv6Policy) > 0 {
+		if err := firewall.SetIPv6Policy(s._preferences.FwIPv6Policy); err != nil {
+			log.Error("Failed to initialize firewall with IPv6 policy: ", err)
+		}
+	}
+
 	if s._preferences.IsFwPersistant {
 		log.Info("Enabling firewal (persistant configuration)")
 		if err := firewall.SetPersistant(true); err != nil {
@@ -150,6 +335,22 @@ func (s *Service) init() error {
 		}
 	}
 
+	// load admin-provisioned policy (if defined; see 'service_policy.go')
+	s.policyInit()
+
+	// detect a session which crashed mid-connection and reconcile firewall\DNS state
+	// (must be performed before the network cleanup; see 'service_crashguard.go')
+	s.crashGuardStartupCheck()
+
+	// garbage-collect network artifacts left by a crashed session
+	// (orphaned VPN interfaces, stale WG tunnel services; prevents 'adapter already exists' connect failures)
+	if err := s.CleanupNetworkArtifacts(); err != nil {
+		log.Warning("Startup cleanup of network artifacts failed: ", err)
+	}
+
+	// load local GeoIP dataset and start its background updater (see 'service_geoip.go')
+	s.geoipInit()
+
 	// start WireGuard keys rotation
 	if err := s._wgKeysMgr.Init(s); err != nil {
 		log.Error("Failed to initialize WG keys rotation:", err)
@@ -168,6 +369,29 @@ func (s *Service) init() error {
 	// Start session status checker
 	s.startSessionChecker()
 
+	// start periodic update of certificate pin sets (pin rotation without shipping a new daemon build)
+	go s.startPinManifestUpdater()
+
+	// start metrics endpoint (if enabled by preferences)
+	s.applyMetricsSettings()
+
+	// restore auto-resume timer of paused connection (if deadline persisted by previous daemon session)
+	if deadline := s._preferences.PauseResumeDeadline; deadline > 0 {
+		s.setPauseResumeDeadline(deadline)
+	}
+
+	// start receiving system power events (system sleep\resume)
+	s.startPowerEventsDetector()
+
+	// start verification of the firewall rules (tampering detection)
+	s.startFirewallWatchdog()
+
+	// start watching the settings file for external modifications (hot-reload; see 'service_prefsreload.go')
+	s.prefsReloadInit()
+
+	// start watching servers-list updates for endpoint changes of the connected WG server (see 'service_wgroaming.go')
+	s.wgRoamingInit()
+
 	return nil
 }
 
@@ -179,8 +403,8 @@ func (s *Service) init() error {
 // - err: error
 func (s *Service) OnControlConnectionClosed() (isServiceMustBeClosed bool, err error) {
 	isServiceMustBeClosed = s._preferences.IsStopOnClientDisconnect
-	// disable firewall if it not persistant
-	if !s._preferences.IsFwPersistant {
+	// disable firewall if it not persistant (and not forced by the admin policy)
+	if !s._preferences.IsFwPersistant && s.policyCheckKillSwitchDisable() == nil {
 		log.Info("Control connection was closed. Disabling firewall.")
 		err = firewall.SetEnabled(false)
 	}
@@ -197,11 +421,22 @@ func (s *Service) ServersUpdateNotifierChannel() chan struct{} {
 	return s._serversUpdater.UpdateNotifierChannel()
 }
 
+// ServersUpdateDiff returns changes of the servers list detected by the last update
+// (nil - no background update happened yet)
+func (s *Service) ServersUpdateDiff() *types.ServersListDiff {
+	return s._serversUpdater.LastUpdateDiff()
+}
+
 // APIRequest do custom request to API
 func (s *Service) APIRequest(apiAlias string) (responseData []byte, err error) {
 	return s._api.DoRequestByAlias(apiAlias)
 }
 
+// APIRequestStream do custom request to API, streaming response body by chunks
+func (s *Service) APIRequestStream(apiAlias string, maxSizeBytes int64, onChunk func(chunk []byte, received int64, contentLength int64) error) error {
+	return s._api.DoRequestByAliasStream(apiAlias, maxSizeBytes, onChunk)
+}
+
 // GetDisabledFunctions returns info about functions which are disabled
 // Some functionality can be not accessible
 // It can happen, for example, if some external binaries not installed
@@ -238,8 +473,60 @@ func (s *Service) GetDisabledFunctions() (wgErr, ovpnErr, obfspErr error) {
 
 // ConnectOpenVPN start OpenVPN connection
 func (s *Service) ConnectOpenVPN(connectionParams openvpn.ConnectionParams, manualDNS net.IP, firewallOn bool, firewallDuringConnection bool, stateChan chan<- vpn.StateInfo) error {
+	initialPort, initialIsTCP := connectionParams.Port()
+	s.portRotationInit(vpn.OpenVPN, initialPort, initialIsTCP)
+	s.wgTcpFallbackInit(false) // UDP-over-TCP fallback is not applicable to OpenVPN
 
 	createVpnObjfunc := func() (vpn.Process, error) {
+		// apply rotated port (when previous connection attempt failed; see 'portRotationNext()')
+		if port, isTCP, ok := s.portRotationOverride(); ok {
+			connectionParams.SetPort(port, isTCP)
+		}
+
+		// fall back to the IPv4 endpoint (when connection over the IPv6 endpoint failed; see 'ipv6FallbackNext()')
+		if host, ok := s.ipv6FallbackOverride(); ok {
+			connectionParams.SetHost(host)
+		}
+
+		// opt-in connection diagnostics: remember parameters of this attempt (see 'service_conndiag.go')
+		attemptPort, attemptIsTCP := connectionParams.Port()
+		s.connDiagOnAttempt(vpn.OpenVPN, attemptPort, attemptIsTCP)
+		s.warmupSetTarget(attemptPort, attemptIsTCP)
+
+		// V2Ray obfuscation transport: route the connection via local V2Ray listener
+		if v2rayParams, ok := s.v2rayParams(); ok {
+			if s.Preferences().IsObfsproxy {
+				log.Warning("V2Ray obfuscation is ignored: obfsproxy is enabled")
+			} else {
+				port, isTCP := connectionParams.Port()
+				localPort, err := s.v2rayStart(v2rayParams, isTCP, connectionParams.Host(), port)
+				if err != nil {
+					return nil, fmt.Errorf("failed to start V2Ray obfuscation transport: %w", err)
+				}
+				if isTCP {
+					connectionParams.SetLocalSocksProxy(localPort)
+				} else {
+					connectionParams.SetHost(net.IPv4(127, 0, 0, 1))
+					connectionParams.SetPort(localPort, false)
+				}
+			}
+		}
+
+		// stealth obfuscation transport: wrap the connection into TLS to port 443 of the obfuscation endpoint
+		if _, _, _, ok := s.stealthParams(); ok {
+			if s.Preferences().IsObfsproxy {
+				log.Warning("Stealth obfuscation is ignored: obfsproxy is enabled")
+			} else if _, v2rayEnabled := s.v2rayParams(); v2rayEnabled {
+				log.Warning("Stealth obfuscation is ignored: V2Ray obfuscation is enabled")
+			} else {
+				localPort, err := s.stealthStart()
+				if err != nil {
+					return nil, fmt.Errorf("failed to start stealth obfuscation transport: %w", err)
+				}
+				connectionParams.SetHost(net.IPv4(127, 0, 0, 1))
+				connectionParams.SetPort(localPort, true)
+			}
+		}
 		prefs := s.Preferences()
 
 		// checking if functionality accessible
@@ -302,6 +589,17 @@ func (s *Service) ConnectOpenVPN(connectionParams openvpn.ConnectionParams, manu
 			}
 		}
 
+		// user-defined extra parameters from preferences (validated when preference was set)
+		if len(prefs.OpenVpnExtraParameters) > 0 {
+			openVpnExtraParameters += "\n" + prefs.OpenVpnExtraParameters
+		}
+
+		// only whitelisted OpenVPN directives are acceptable (daemon is running with root privileges)
+		if err := openvpn.ValidateUserParameters(openVpnExtraParameters); err != nil {
+			log.Warning("NOTE! User-defined OpenVPN parameters are ignored! ", err)
+			openVpnExtraParameters = ""
+		}
+
 		// creating OpenVPN object
 		vpnObj, err := openvpn.NewOpenVpnObject(
 			platform.OpenVpnBinaryPath(),
@@ -349,9 +647,46 @@ func (s *Service) ConnectWireGuard(connectionParams wireguard.ConnectionParams,
 		}
 	}
 
+	s.portRotationInit(vpn.WireGuard, connectionParams.Port(), false)
+	s.wgTcpFallbackInit(true)
+	s.applyWGServiceTimeouts() // user-defined timeout caps of the tunnel service setup (Windows)
+
 	createVpnObjfunc := func() (vpn.Process, error) {
 		session := s.Preferences().Session
 
+		// apply rotated port (when previous connection attempt failed; see 'portRotationNext()')
+		if port, _, ok := s.portRotationOverride(); ok {
+			connectionParams.SetPort(port)
+		}
+
+		// fall back to the IPv4 endpoint (when connection over the IPv6 endpoint failed; see 'ipv6FallbackNext()')
+		if host, ok := s.ipv6FallbackOverride(); ok {
+			connectionParams.SetHost(host)
+		}
+
+		// opt-in connection diagnostics: remember parameters of this attempt (see 'service_conndiag.go')
+		s.connDiagOnAttempt(vpn.WireGuard, connectionParams.Port(), false)
+		s.warmupSetTarget(connectionParams.Port(), false)
+
+		// V2Ray obfuscation transport: route the connection via local V2Ray listener
+		if v2rayParams, ok := s.v2rayParams(); ok {
+			localPort, err := s.v2rayStart(v2rayParams, false, connectionParams.Host(), connectionParams.Port())
+			if err != nil {
+				return nil, fmt.Errorf("failed to start V2Ray obfuscation transport: %w", err)
+			}
+			connectionParams.SetHost(net.IPv4(127, 0, 0, 1))
+			connectionParams.SetPort(localPort)
+		} else if s.wgTcpFallbackActive() {
+			// UDP-over-TCP fallback: route the connection via local UDP listener
+			// (previous UDP attempts failed - UDP can be blocked by a local network; see 'service_wgtcp.go')
+			localPort, err := s.wgTcpStart(connectionParams.Host(), connectionParams.Port())
+			if err != nil {
+				return nil, fmt.Errorf("failed to start UDP-over-TCP transport: %w", err)
+			}
+			connectionParams.SetHost(net.IPv4(127, 0, 0, 1))
+			connectionParams.SetPort(localPort)
+		}
+
 		if session.IsWGCredentialsOk() == false {
 			return nil, fmt.Errorf("WireGuard credentials are not defined (please, regenerate WG credentials or re-login)")
 		}
@@ -377,6 +712,61 @@ func (s *Service) ConnectWireGuard(connectionParams wireguard.ConnectionParams,
 	return s.keepConnection(createVpnObjfunc, manualDNS, firewallOn, firewallDuringConnection, stateChan)
 }
 
+// ConnectCustomWireGuard starts WireGuard connection using parameters of a user-provided
+// (non-IVPN) configuration file (e.g. a self-hosted WG server).
+// Connection is managed by the same machinery as for IVPN servers:
+// firewall, DNS, pause\resume, kill switch.
+func (s *Service) ConnectCustomWireGuard(cfg *wireguard.CustomConfig, manualDNS net.IP, firewallOn bool, firewallDuringConnection bool, stateChan chan<- vpn.StateInfo) error {
+	// stop active connection (if exists)
+	if err := s.Disconnect(); err != nil {
+		return fmt.Errorf("failed to connect. Unable to stop active connection: %w", err)
+	}
+
+	// checking if functionality accessible
+	wgErr, _, _ := s.GetDisabledFunctions()
+	if wgErr != nil {
+		return wgErr
+	}
+
+	// in-tunnel DNS of the config is used when manual DNS not requested
+	if manualDNS == nil && cfg.DNS != nil {
+		manualDNS = cfg.DNS
+	}
+
+	// in-tunnel gateway IP of a custom server is not known
+	// (IVPN servers report it as the host 'local_ip'):
+	// using the DNS server of the config (when defined) or the client tunnel IP (point-to-point routing)
+	hostLocalIP := cfg.DNS
+	if hostLocalIP == nil {
+		hostLocalIP = cfg.ClientLocalIP
+	}
+
+	connectionParams := wireguard.CreateConnectionParams(
+		cfg.PeerPort,
+		cfg.PeerEndpoint,
+		cfg.PeerPublicKey,
+		hostLocalIP)
+	connectionParams.SetCredentials(cfg.ClientPrivateKey, cfg.ClientLocalIP)
+
+	// exact user-provided parameters must be used:
+	// no port rotation, IPv6 fallback or obfuscation transports for custom configurations
+	s.wgTcpFallbackInit(false)
+	createVpnObjfunc := func() (vpn.Process, error) {
+		vpnObj, err := wireguard.NewWireGuardObject(
+			platform.WgBinaryPath(),
+			platform.WgToolBinaryPath(),
+			platform.WGConfigFilePath(),
+			connectionParams)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to create new WireGuard object: %w", err)
+		}
+		return vpnObj, nil
+	}
+
+	return s.keepConnection(createVpnObjfunc, manualDNS, firewallOn, firewallDuringConnection, stateChan)
+}
+
 func (s *Service) keepConnection(createVpnObj func() (vpn.Process, error), manualDNS net.IP, firewallOn bool, firewallDuringConnection bool, stateChan chan<- vpn.StateInfo) error {
 	prefs := s.Preferences()
 	if prefs.Session.IsLoggedIn() == false {
@@ -389,9 +779,23 @@ func (s *Service) keepConnection(createVpnObj func() (vpn.Process, error), manua
 	// So just 'Connect' required for now
 	s._requiredVpnState = Connect
 
+	// stop V2Ray obfuscation transport (if it was started for this connection)
+	defer s.v2rayStop()
+	// stop stealth obfuscation transport (if it was started for this connection)
+	defer s.stealthStop()
+	// stop WireGuard UDP-over-TCP fallback transport (if it was started for this connection)
+	defer s.wgTcpStop()
+
+	// keep 'connection is active' marker on disk while connection is alive
+	// (detection of a daemon crash mid-connection; see 'service_crashguard.go')
+	s.crashGuardMarkStarted(firewallOn || firewallDuringConnection)
+	defer s.crashGuardMarkStopped()
+
 	// no delay before first reconnection
 	delayBeforeReconnect := 0 * time.Second
 
+	s.connRetryInit()
+
 	stateChan <- vpn.NewStateInfo(vpn.CONNECTING, "Connecting")
 	for {
 		// create new VPN object
@@ -400,13 +804,53 @@ func (s *Service) keepConnection(createVpnObj func() (vpn.Process, error), manua
 			return fmt.Errorf("failed to create VPN object: %w", err)
 		}
 
+		// check that destination server is allowed by the admin policy (if defined)
+		if err := s.policyCheckExitServer(vpnObj.DestinationIP()); err != nil {
+			return err
+		}
+
 		lastConnectionTryTime := time.Now()
 
+		// optional pre-connect warm-up: check reachability of the destination on the selected port
+		// and fail fast with a precise error instead of waiting for a connection timeout (see 'service_warmup.go')
+		connErr := s.connectWarmup(vpnObj.DestinationIP())
+
 		// start connection
-		connErr := s.connect(vpnObj, s._manualDNS, firewallOn, firewallDuringConnection, stateChan)
+		if connErr == nil {
+			connErr = s.connect(vpnObj, s._manualDNS, firewallOn, firewallDuringConnection, stateChan)
+		}
 		if connErr != nil {
 			log.Error(fmt.Sprintf("Connection error: %s", connErr))
+			// save attempt result into connection diagnostics (if enabled)
+			s.connDiagOnResult(false, connErr)
 			if s._requiredVpnState == Connect {
+				// connection parameters for a retry:
+				// when connection over an IPv6 endpoint failed - IPv4 endpoint (if known);
+				// otherwise - the next allowed port\protocol combination (if enabled)
+				attemptInfo, isRetryPossible := s.ipv6FallbackNext()
+				if isRetryPossible == false {
+					attemptInfo, isRetryPossible = s.portRotationNext()
+				}
+				if isRetryPossible == false {
+					// all UDP retry options exhausted: WireGuard UDP-over-TCP fallback (if enabled)
+					attemptInfo, isRetryPossible = s.wgTcpFallbackNext()
+				}
+
+				if isRetryPossible {
+					// unified retry narrative: attempt counter + exponential backoff
+					// (common for all VPN types; see 'service_connmanager.go')
+					if description, delay, ok := s.connRetryNext(attemptInfo); ok {
+						log.Info("Retry: ", description)
+						stateChan <- vpn.StateInfo{State: vpn.RECONNECTING, StateAdditionalInfo: description}
+						s.connRetryWait(delay)
+						if s._requiredVpnState == Connect {
+							continue
+						}
+					} else {
+						log.Info("Connection retry attempts exhausted")
+					}
+				}
+
 				// throw error only on first try to connect
 				// if we were already connected (_requiredVpnState==KeepConnection) - ignore error and try to reconnect
 				return connErr
@@ -416,7 +860,7 @@ func (s *Service) keepConnection(createVpnObj func() (vpn.Process, error), manua
 		// retry, if reconnection requested
 		if s._requiredVpnState == KeepConnection {
 			// notifying clients about reconnection
-			stateChan <- vpn.NewStateInfo(vpn.RECONNECTING, "Reconnecting due to disconnection")
+			stateChan <- vpn.NewStateInfo(vpn.RECONNECTING, s.tunnelWatchdogReconnectReason())
 
 			// no delay before reconnection (if last connection was long time ago)
 			if time.Now().After(lastConnectionTryTime.Add(time.Second * 30)) {
@@ -487,6 +931,12 @@ func (s *Service) connect(vpnProc vpn.Process, manualDNS net.IP, firewallOn bool
 	}()
 
 	log.Info("Connecting...")
+
+	// new connection arrived: close the server-switch window (if it was opened)
+	if s.serverSwitchAcknowledge() {
+		log.Info("Server switch: establishing new connection")
+	}
+
 	// save vpn object
 	s._vpn = vpnProc
 
@@ -506,17 +956,30 @@ func (s *Service) connect(vpnProc vpn.Process, manualDNS net.IP, firewallOn bool
 		// Ensure that routing-change detector is stopped (we do not need it when VPN disconnected)
 		s._netChangeDetector.Stop()
 
-		// notify firewall that client is disconnected
-		err := firewall.ClientDisconnected()
-		if err != nil {
-			log.Error("(stopping) error on notifying FW about disconnected client:", err)
-		}
+		// stop binding API requests to the tunnel interface
+		s._api.SetBindLocalIP(nil)
+
+		// OS-specific operations (if required)
+		// (e.g. Windows: restore automatic metric of the tunnel interface)
+		s.implOnVpnDisconnected()
+
+		// server switch: firewall protection must stay continuously engaged
+		// until the new connection is established (see 'service_serverswitch.go')
+		if s.isServerSwitch() {
+			log.Info("Server switch in progress: keeping firewall protection engaged")
+		} else {
+			// notify firewall that client is disconnected
+			err := firewall.ClientDisconnected()
+			if err != nil {
+				log.Error("(stopping) error on notifying FW about disconnected client:", err)
+			}
 
-		// when we were requested to enable firewall for this connection
-		// And initial FW state was disabled - we have to disable it back
-		if firewallDuringConnection == true && fwInitState == false {
-			if err = s.SetKillSwitchState(false); err != nil {
-				log.Error("(stopping) failed to disable firewall:", err)
+			// when we were requested to enable firewall for this connection
+			// And initial FW state was disabled - we have to disable it back
+			if firewallDuringConnection == true && fwInitState == false {
+				if err = s.SetKillSwitchState(false); err != nil {
+					log.Error("(stopping) failed to disable firewall:", err)
+				}
 			}
 		}
 
@@ -524,8 +987,7 @@ func (s *Service) connect(vpnProc vpn.Process, manualDNS net.IP, firewallOn bool
 		close(stopChannel)
 
 		// resetting manual DNS (if it is necessary)
-		err = vpnProc.ResetManualDNS()
-		if err != nil {
+		if err := vpnProc.ResetManualDNS(); err != nil {
 			log.Error("(stopping) error resetting manual DNS: ", err)
 		}
 
@@ -534,6 +996,13 @@ func (s *Service) connect(vpnProc vpn.Process, manualDNS net.IP, firewallOn bool
 		// Forget VPN object
 		s._vpn = nil
 
+		s.metricsOnDisconnected()
+
+		// stop bandwidth accounting and save transferred bytes into connection history record
+		received, sent := s.bandwidthOnDisconnected()
+		s.historySetSessionBytes(received, sent)
+		s.historyOnStopped()
+
 		log.Info("VPN process stopped")
 	}()
 
@@ -544,6 +1013,11 @@ func (s *Service) connect(vpnProc vpn.Process, manualDNS net.IP, firewallOn bool
 
 	destinationHostIP := vpnProc.DestinationIP()
 
+	// update connection history (used by fastest-server selection)
+	s.recordConnectionAttempt(destinationHostIP)
+	// open local connection history record (if history enabled)
+	s.historyOnConnectStarted(destinationHostIP, vpnProc.Type())
+
 	// goroutine: process + forward VPN state change
 	connectRoutinesWaiter.Add(1)
 	go func() {
@@ -563,6 +1037,30 @@ func (s *Service) connect(vpnProc vpn.Process, manualDNS net.IP, firewallOn bool
 				// store info about VPN connection type
 				state.VpnType = vpnProc.Type()
 
+				// verify that traffic actually passes through the tunnel before reporting CONNECTED
+				// (see 'tunnel_verify.go')
+				if state.State == vpn.CONNECTED {
+					if err := s.verifyTunnelConnectivity(vpnProc); err != nil {
+						log.Warning("In-tunnel connectivity verification failed: ", err)
+
+						// reconnect (performed by 'keepConnection(...)' loop according to '_requiredVpnState')
+						if s._requiredVpnState == Connect {
+							s._requiredVpnState = KeepConnection
+						}
+						s._reconnectReason = "Reconnecting: tunnel established but no traffic passes through it"
+						go func() {
+							defer func() {
+								if r := recover(); r != nil {
+									log.Error("PANIC: ", r)
+								}
+							}()
+							s.reconnect()
+						}()
+
+						break // do not report CONNECTED state to clients
+					}
+				}
+
 				// forward state to 'stateChan'
 				stateChan <- state
 
@@ -589,6 +1087,15 @@ func (s *Service) connect(vpnProc vpn.Process, manualDNS net.IP, firewallOn bool
 						s._requiredVpnState = KeepConnection
 					}
 
+					s.metricsOnConnected()
+					// update connection history (used by fastest-server selection)
+					s.recordConnectionSuccess(destinationHostIP)
+					s.historyOnConnected()
+					// save attempt result into connection diagnostics (if enabled)
+					s.connDiagOnResult(true, nil)
+					// apply firewall exceptions for user-allowed LAN devices (printers, casting devices)
+					s.lanServicesApplyAllowed()
+
 					// start routing change detection
 					if netInterface, err := netinfo.InterfaceByIPAddr(state.ClientIP); err != nil {
 						log.Error(fmt.Sprintf("Unable to initialize routing change detection. Failed to get interface '%s'", state.ClientIP.String()))
@@ -596,13 +1103,27 @@ func (s *Service) connect(vpnProc vpn.Process, manualDNS net.IP, firewallOn bool
 
 						log.Info("Starting route change detection")
 						s._netChangeDetector.Start(routingChangeChan, routingUpdateChan, netInterface)
+
+						// start accounting of transferred bytes (tunnel interface statistics)
+						s.bandwidthOnConnected(netInterface)
+
+						// OS-specific operations (if required)
+						// (e.g. Windows: set the lowest metric for the tunnel interface)
+						s.implOnVpnConnected(netInterface)
 					}
 
+					// bind API requests to the tunnel interface
+					// (prevents API traffic from escaping via a secondary adapter)
+					s._api.SetBindLocalIP(state.ClientIP)
+
 					// Inform firewall about client local IP
 					firewall.ClientConnected(
 						state.ClientIP, state.ClientPort,
 						state.ServerIP, state.ServerPort,
 						state.IsTCP)
+
+					// block DNS requests which are trying to bypass the VPN DNS (if enabled in preferences)
+					s.applyDNSLeakProtection()
 				default:
 				}
 
@@ -643,13 +1164,49 @@ func (s *Service) connect(vpnProc vpn.Process, manualDNS net.IP, firewallOn bool
 					isRuning = false
 				}
 			case <-routingUpdateChan: // there were some routing changes but 'interfaceToProtect' is still is the default route
-				s._vpn.OnRoutingChanged()
+				if err := s._vpn.OnRoutingChanged(); err != nil {
+					log.Warning(fmt.Sprintf("Failed to reassert VPN routes: %v", err))
+				}
 			case <-stopChannel: // triggered when the stopChannel is closed
 				isRuning = false
 			}
 		}
 	}()
 
+	// tunnel liveness watchdog (reconnects when tunnel is dead)
+	connectRoutinesWaiter.Add(1)
+	go func() {
+		defer connectRoutinesWaiter.Done()
+		s.startTunnelWatchdog(stopChannel)
+	}()
+
+	// periodical latency\jitter measurement through the tunnel (disabled by default)
+	connectRoutinesWaiter.Add(1)
+	go func() {
+		defer connectRoutinesWaiter.Done()
+		s.startConnQualityMonitor(stopChannel)
+	}()
+
+	// periodical check that the configured manual DNS is responding
+	// (fall back to default VPN DNS when it is dead)
+	connectRoutinesWaiter.Add(1)
+	go func() {
+		log.Info("DNS probe started")
+		defer func() {
+			log.Info("DNS probe stopped")
+			connectRoutinesWaiter.Done()
+		}()
+
+		for {
+			select {
+			case <-time.After(dnsProbeInterval):
+				s.probeManualDNS()
+			case <-stopChannel: // triggered when the stopChannel is closed
+				return
+			}
+		}
+	}()
+
 	log.Info("Initializing...")
 	if err := vpnProc.Init(); err != nil {
 		return fmt.Errorf("failed to initialize VPN object: %w", err)
@@ -763,9 +1320,33 @@ func (s *Service) disconnect() error {
 		<-done
 	}
 
+	// garbage-collect network artifacts which could be left by the stopped connection
+	// (not applicable for reconnections and server switch: new connection will reuse the interface)
+	if s._requiredVpnState == Disconnect && s.isServerSwitch() == false {
+		if err := s.CleanupNetworkArtifacts(); err != nil {
+			log.Warning("Post-disconnect cleanup of network artifacts failed: ", err)
+		}
+	}
+
 	return nil
 }
 
+// SaveDaemonStopState persists current daemon state (is VPN connected, is firewall enabled)
+// It has to be called on graceful daemon stop (e.g. OS shutdown\restart);
+// saved state can be restored on next daemon start (see 'IsRestoreStateOnStart' preference)
+func (s *Service) SaveDaemonStopState() {
+	fwEnabled, err := firewall.GetEnabled()
+	if err != nil {
+		log.Error("failed to save daemon stop state (unable to get firewall status): ", err)
+		fwEnabled = false
+	}
+
+	prefs := s._preferences
+	prefs.LastStopStateVpnConnected = s.Connected()
+	prefs.LastStopStateFirewallEnabled = fwEnabled
+	s.setPreferences(prefs)
+}
+
 // Connected returns 'true' if VPN connected
 func (s *Service) Connected() bool {
 	if s._vpn == nil {
@@ -790,7 +1371,8 @@ func (s *Service) FirewallEnabled() (bool, error) {
 }
 
 // Pause pause vpn connection
-func (s *Service) Pause() error {
+// Param 'durationSeconds' - automatically resume connection after the defined interval (0 - no auto-resume)
+func (s *Service) Pause(durationSeconds int) error {
 	vpn := s._vpn
 	if vpn == nil {
 		return nil
@@ -798,11 +1380,49 @@ func (s *Service) Pause() error {
 
 	log.Info("Pausing...")
 	firewall.ClientPaused()
-	return vpn.Pause()
+	// tunnel is not available during pause - do not bind API requests to it
+	s._api.SetBindLocalIP(nil)
+	if err := vpn.Pause(); err != nil {
+		return err
+	}
+
+	// when enabled - keep firewall engaged for the pause period (only LAN traffic is allowed)
+	// instead of the plain bypass of all traffic
+	if s._preferences.IsPauseKeepFirewall {
+		if enabled, err := firewall.GetEnabled(); err != nil {
+			log.Error("Failed to get firewall state: ", err)
+		} else if enabled == false {
+			if err := firewall.SetEnabled(true); err != nil {
+				log.Error("Failed to enable firewall for pause period: ", err)
+			} else {
+				s._pauseFwEnabled = true
+			}
+		}
+		if s._preferences.IsFwAllowLAN == false {
+			if err := firewall.AllowLAN(true, s._preferences.IsFwAllowLANMulticast); err != nil {
+				log.Error("Failed to allow LAN for pause period: ", err)
+			} else {
+				s._pauseFwLanAllowed = true
+			}
+		}
+	}
+
+	// initialize auto-resume timer (the deadline is persisted: timer survives daemon restart)
+	resumeAt := int64(0)
+	if durationSeconds > 0 {
+		resumeAt = time.Now().Add(time.Second * time.Duration(durationSeconds)).Unix()
+	}
+	s.setPauseResumeDeadline(resumeAt)
+
+	s._evtReceiver.OnPauseStateChanged(true, resumeAt)
+	return nil
 }
 
 // Resume resume vpn connection
 func (s *Service) Resume() error {
+	// erase persisted auto-resume deadline (if defined)
+	s.setPauseResumeDeadline(0)
+
 	vpn := s._vpn
 	if vpn == nil {
 		return nil
@@ -813,7 +1433,88 @@ func (s *Service) Resume() error {
 
 	log.Info("Resuming...")
 	firewall.ClientResumed()
-	return vpn.Resume()
+
+	// restore firewall configuration changed for the pause period (see 'Pause()')
+	if s._pauseFwLanAllowed {
+		s._pauseFwLanAllowed = false
+		if err := s.applyKillSwitchAllowLAN(); err != nil {
+			log.Error("Failed to restore AllowLAN configuration: ", err)
+		}
+	}
+	if s._pauseFwEnabled {
+		s._pauseFwEnabled = false
+		if err := firewall.SetEnabled(false); err != nil {
+			log.Error("Failed to restore firewall state: ", err)
+		}
+	}
+
+	if err := vpn.Resume(); err != nil {
+		return err
+	}
+
+	s._evtReceiver.OnPauseStateChanged(false, 0)
+	return nil
+}
+
+// SetOpenVPNCredentials supplies new credentials to the active OpenVPN connection attempt.
+// Applicable when the server rejected current credentials ('auth-failure'):
+// OpenVPN is waiting for new credentials and resumes the same connection attempt
+func (s *Service) SetOpenVPNCredentials(username string, password string) error {
+	vpnObj := s._vpn
+	if vpnObj == nil {
+		return fmt.Errorf("VPN is not connected")
+	}
+
+	openVpnObj, ok := vpnObj.(*openvpn.OpenVPN)
+	if ok == false {
+		return fmt.Errorf("applicable only for OpenVPN connection")
+	}
+	return openVpnObj.SetCredentials(username, password)
+}
+
+// SetOpenVPNTlsCryptV2Key provisions a per-client tls-crypt-v2 key (empty value erases the key).
+// The key is kept in the protected secrets storage and included into generated OpenVPN
+// configurations instead of the static 'tls-auth' key (requires OpenVPN 2.5+)
+func (s *Service) SetOpenVPNTlsCryptV2Key(keyPem string) error {
+	if err := openvpn.SetTLSCryptV2Key(keyPem); err != nil {
+		return err
+	}
+	if len(keyPem) > 0 {
+		log.Info("OpenVPN tls-crypt-v2 key provisioned")
+	} else {
+		log.Info("OpenVPN tls-crypt-v2 key erased")
+	}
+	return nil
+}
+
+// PauseResumeDeadline returns unix time (seconds) when paused connection will be automatically resumed
+// (0 - no auto-resume defined)
+func (s *Service) PauseResumeDeadline() int64 {
+	return s._preferences.PauseResumeDeadline
+}
+
+// setPauseResumeDeadline persists auto-resume deadline and (re)starts the auto-resume timer
+func (s *Service) setPauseResumeDeadline(resumeAt int64) {
+	if s._preferences.PauseResumeDeadline != resumeAt {
+		prefs := s._preferences
+		prefs.PauseResumeDeadline = resumeAt
+		s.setPreferences(prefs)
+	}
+
+	s._pauseTimerMutex.Lock()
+	defer s._pauseTimerMutex.Unlock()
+	if s._pauseTimer != nil {
+		s._pauseTimer.Stop()
+		s._pauseTimer = nil
+	}
+	if resumeAt > 0 {
+		s._pauseTimer = time.AfterFunc(time.Until(time.Unix(resumeAt, 0)), func() {
+			log.Info("Auto-resume timer expired")
+			if err := s.Resume(); err != nil {
+				log.Error("Auto-resume failed:", err)
+			}
+		})
+	}
 }
 
 // IsPaused returns 'true' if current vpn connection is in paused state
@@ -840,6 +1541,7 @@ func (s *Service) SetManualDNS(dns net.IP) error {
 	err := vpn.SetManualDNS(dns)
 	if err == nil {
 		s._evtReceiver.OnDNSChanged(dns)
+		s.applyDNSLeakProtection()
 	}
 	return err
 }
@@ -859,33 +1561,120 @@ func (s *Service) ResetManualDNS() error {
 	err := vpn.ResetManualDNS()
 	if err == nil {
 		s._evtReceiver.OnDNSChanged(nil)
+		s.applyDNSLeakProtection()
 	}
 	return err
 }
 
-// if 'currentLocation' defined - the output hosts list will be sorted by distance to current location
-func (s *Service) getHostsToPing(currentLocation *types.GeoLookupResponse) ([]net.IP, error) {
-	// get servers info
-	servers, err := s._serversUpdater.GetServers()
-	if err != nil {
-		return nil, fmt.Errorf("unable to get servers list: %w", err)
-	}
+// applyUserAppRules - push user per-application firewall rules from preferences to the firewall implementation
+func (s *Service) applyUserAppRules() error {
+	return firewall.SetUserAppRules(
+		splitAppRulesList(s._preferences.FwUserAllowedApps),
+		splitAppRulesList(s._preferences.FwUserBlockedApps))
+}
 
-	type hostInfo struct {
-		Latitude  float32
-		Longitude float32
-		host      net.IP
-	}
+// applyWGServiceTimeouts - push user-defined timeout caps of the WireGuard tunnel service setup
+// to the wireguard implementation (applicable only for Windows)
+func (s *Service) applyWGServiceTimeouts() {
+	prefs := s._preferences
+	wireguard.SetServiceWaitTimeouts(
+		time.Duration(prefs.WGServiceInstallTimeoutSec)*time.Second,
+		time.Duration(prefs.WGServiceStartTimeoutSec)*time.Second)
+}
 
-	hosts := make([]hostInfo, 0, len(servers.OpenvpnServers)+len(servers.WireguardServers))
+// applyVirtualBridgePolicy - push the virtual bridge (docker0, vEthernet, vmnet ...) traffic policy
+// from preferences to the firewall implementation
+func (s *Service) applyVirtualBridgePolicy() error {
+	policy := s._preferences.VirtualBridgePolicy
+	if len(policy) == 0 {
+		policy = firewall.VirtualBridgePolicyBlock
+	}
+	return firewall.SetVirtualBridgePolicy(policy, parseVirtualBridgeOverrides(s._preferences.VirtualBridgeOverrides))
+}
 
-	// OpenVPN servers
-	for _, s := range servers.OpenvpnServers {
-		if len(s.IPAddresses) <= 0 {
+// parseVirtualBridgeOverrides parses a semicolon-separated list of 'interfaceName=policy' pairs
+func parseVirtualBridgeOverrides(overridesList string) map[string]string {
+	overrides := make(map[string]string)
+	for _, item := range strings.Split(overridesList, ";") {
+		item = strings.TrimSpace(item)
+		if len(item) == 0 {
 			continue
 		}
-		ip := net.ParseIP(s.IPAddresses[0])
-		if ip != nil {
+		fields := strings.SplitN(item, "=", 2)
+		if len(fields) != 2 {
+			log.Warning(fmt.Sprintf("bad virtual bridge override item (expected 'interfaceName=policy'): '%s'", item))
+			continue
+		}
+		overrides[strings.TrimSpace(fields[0])] = strings.TrimSpace(fields[1])
+	}
+	return overrides
+}
+
+// splitAppRulesList parses a semicolon-separated list of application binary paths
+func splitAppRulesList(appsList string) []string {
+	apps := make([]string, 0)
+	for _, app := range strings.Split(appsList, ";") {
+		app = strings.TrimSpace(app)
+		if len(app) > 0 {
+			apps = append(apps, app)
+		}
+	}
+	return apps
+}
+
+// applyDNSLeakProtection - apply current 'DNS leak protection' preference to the firewall
+// Applicable only for an active VPN connection: allowed DNS is the manual DNS (if defined)
+// or the default DNS of a current connection
+func (s *Service) applyDNSLeakProtection() {
+	vpn := s._vpn
+	if vpn == nil {
+		return
+	}
+
+	if s._preferences.IsFwDNSLeakProtection == false {
+		if err := firewall.DisableDNSLeakProtection(); err != nil {
+			log.Error("failed to disable DNS leak protection: ", err)
+		}
+		return
+	}
+
+	dnsIP := s._manualDNS
+	if dnsIP == nil {
+		dnsIP = vpn.InTunnelGatewayIP()
+	}
+	if dnsIP == nil {
+		log.Warning("DNS leak protection not applied: DNS server of a current connection is not defined")
+		return
+	}
+
+	if err := firewall.EnableDNSLeakProtection(dnsIP); err != nil {
+		log.Error("failed to enable DNS leak protection: ", err)
+	}
+}
+
+// if 'currentLocation' defined - the output hosts list will be sorted by distance to current location
+func (s *Service) getHostsToPing(currentLocation *types.GeoLookupResponse) ([]net.IP, error) {
+	// get servers info
+	servers, err := s._serversUpdater.GetServers()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get servers list: %w", err)
+	}
+
+	type hostInfo struct {
+		Latitude  float32
+		Longitude float32
+		host      net.IP
+	}
+
+	hosts := make([]hostInfo, 0, len(servers.OpenvpnServers)+len(servers.WireguardServers))
+
+	// OpenVPN servers
+	for _, s := range servers.OpenvpnServers {
+		if len(s.IPAddresses) <= 0 {
+			continue
+		}
+		ip := net.ParseIP(s.IPAddresses[0])
+		if ip != nil {
 			hosts = append(hosts, hostInfo{Latitude: s.Latitude, Longitude: s.Longitude, host: ip})
 		}
 	}
@@ -920,6 +1709,12 @@ func (s *Service) getHostsToPing(currentLocation *types.GeoLookupResponse) ([]ne
 
 // SetKillSwitchState enable\disable killswitch
 func (s *Service) SetKillSwitchState(isEnabled bool) error {
+	if isEnabled == false {
+		if err := s.policyCheckKillSwitchDisable(); err != nil {
+			return err
+		}
+	}
+
 	err := firewall.SetEnabled(isEnabled)
 	if err == nil {
 		s._evtReceiver.OnKillSwitchStateChanged()
@@ -936,6 +1731,12 @@ func (s *Service) KillSwitchState() (isEnabled, isPersistant, isAllowLAN, isAllo
 
 // SetKillSwitchIsPersistent change kill-switch value
 func (s *Service) SetKillSwitchIsPersistent(isPersistant bool) error {
+	if isPersistant == false {
+		if err := s.policyCheckKillSwitchDisable(); err != nil {
+			return err
+		}
+	}
+
 	prefs := s._preferences
 	prefs.IsFwPersistant = isPersistant
 	s.setPreferences(prefs)
@@ -963,15 +1764,71 @@ func (s *Service) setKillSwitchAllowLAN(isAllowLan bool, isAllowLanMulticast boo
 	prefs.IsFwAllowLANMulticast = isAllowLanMulticast
 	s.setPreferences(prefs)
 
-	err := firewall.AllowLAN(prefs.IsFwAllowLAN, prefs.IsFwAllowLANMulticast)
+	err := s.applyKillSwitchAllowLAN()
+	if err == nil {
+		s._evtReceiver.OnKillSwitchStateChanged()
+	}
+	return err
+}
+
+// SetKillSwitchAllowLANTrustedOnly change kill-switch value
+// (when enabled - the 'allow LAN' rules are applied only on trusted WiFi networks)
+func (s *Service) SetKillSwitchAllowLANTrustedOnly(isTrustedOnly bool) error {
+	prefs := s._preferences
+	prefs.IsFwAllowLANTrustedOnly = isTrustedOnly
+	s.setPreferences(prefs)
+
+	err := s.applyKillSwitchAllowLAN()
+	if err == nil {
+		s._evtReceiver.OnKillSwitchStateChanged()
+	}
+	return err
+}
+
+// SetKillSwitchAllowLANSubnets change kill-switch value
+// (limit the 'allow LAN' functionality to the specific subnets only; empty list - all local subnets allowed)
+func (s *Service) SetKillSwitchAllowLANSubnets(subnets []string) error {
+	for _, subnet := range subnets {
+		if _, _, err := net.ParseCIDR(subnet); err != nil {
+			return fmt.Errorf("invalid subnet '%s': %w", subnet, err)
+		}
+	}
+
+	prefs := s._preferences
+	prefs.FwAllowLANSubnets = subnets
+	s.setPreferences(prefs)
+
+	err := s.applyKillSwitchAllowLAN()
 	if err == nil {
 		s._evtReceiver.OnKillSwitchStateChanged()
 	}
 	return err
 }
 
+// applyKillSwitchAllowLAN - push the current 'allow LAN' configuration to the firewall
+// (takes into account granular LAN access options: subnets limitation, trusted networks)
+func (s *Service) applyKillSwitchAllowLAN() error {
+	prefs := s._preferences
+
+	isAllowLAN := prefs.IsFwAllowLAN
+	if isAllowLAN && prefs.IsFwAllowLANTrustedOnly && s.isCurrentNetworkInsecure() {
+		log.Info("LAN access blocked: current WiFi network is not trusted")
+		isAllowLAN = false
+	}
+
+	if err := firewall.SetAllowLANSubnets(prefs.FwAllowLANSubnets); err != nil {
+		log.Error("failed to apply 'allow LAN' subnets limitation: ", err)
+	}
+
+	return firewall.AllowLAN(isAllowLAN, prefs.IsFwAllowLANMulticast)
+}
+
 // SetPreference set preference value
 func (s *Service) SetPreference(key string, val string) error {
+	if err := s.policyCheckPreference(key); err != nil {
+		return err
+	}
+
 	prefs := s._preferences
 
 	switch key {
@@ -991,6 +1848,195 @@ func (s *Service) SetPreference(key string, val string) error {
 			prefs.IsObfsproxy = val
 		}
 		break
+	case "autoconnect_on_launch":
+		if val, err := strconv.ParseBool(val); err == nil {
+			prefs.IsAutoconnectOnLaunch = val
+		}
+		break
+	case "restore_state_on_start":
+		if val, err := strconv.ParseBool(val); err == nil {
+			prefs.IsRestoreStateOnStart = val
+		}
+		break
+	case "autoconnect_on_untrusted_network":
+		if val, err := strconv.ParseBool(val); err == nil {
+			prefs.IsAutoconnectOnUntrustedNetwork = val
+		}
+		break
+	case "connection_use_random_port":
+		if val, err := strconv.ParseBool(val); err == nil {
+			prefs.IsRandomPort = val
+		}
+		break
+	case "enable_ipv6_endpoints":
+		if val, err := strconv.ParseBool(val); err == nil {
+			prefs.IsIPv6Endpoints = val
+		}
+		break
+	case "api_proxy_type":
+		prefs.APIProxyType = val
+		break
+	case "api_proxy_address":
+		prefs.APIProxyAddress = val
+		break
+	case "api_proxy_port":
+		if val, err := strconv.Atoi(val); err == nil {
+			prefs.APIProxyPort = val
+		}
+		break
+	case "api_proxy_username":
+		prefs.APIProxyUsername = val
+		break
+	case "api_proxy_password":
+		prefs.APIProxyPassword = val
+		break
+	case "metrics_port":
+		if val, err := strconv.Atoi(val); err == nil {
+			prefs.MetricsPort = val
+		}
+		break
+	case "websocket_port":
+		if val, err := strconv.Atoi(val); err == nil {
+			prefs.WebSocketPort = val
+		}
+		break
+	case "servers_update_interval_minutes":
+		if val, err := strconv.Atoi(val); err == nil {
+			prefs.ServersUpdateIntervalMinutes = val
+			s._serversUpdater.SetUpdateInterval(time.Duration(val) * time.Minute)
+		}
+		break
+	case "connection_quality_interval_seconds":
+		if val, err := strconv.Atoi(val); err == nil {
+			prefs.ConnQualityIntervalSec = val
+		}
+		break
+	case "better_server_auto_migrate":
+		if val, err := strconv.ParseBool(val); err == nil {
+			prefs.IsBetterServerAutoMigrate = val
+		}
+		break
+	case "wg_to_openvpn_fallback":
+		if val, err := strconv.ParseBool(val); err == nil {
+			prefs.IsWgToOpenvpnFallback = val
+		}
+		break
+	case "pause_keep_firewall":
+		if val, err := strconv.ParseBool(val); err == nil {
+			prefs.IsPauseKeepFirewall = val
+		}
+		break
+	case "v2ray_transport":
+		if err := validateV2RayTransport(val); err != nil {
+			return err
+		}
+		prefs.V2RayTransport = strings.ToLower(val)
+		break
+	case "v2ray_endpoint":
+		prefs.V2RayEndpoint = val
+		break
+	case "v2ray_vmess_id":
+		prefs.V2RayVMessID = val
+		break
+	case "v2ray_tls_server_name":
+		prefs.V2RayTLSServerName = val
+		break
+	case "stealth_endpoint":
+		prefs.StealthEndpoint = val
+		break
+	case "stealth_tls_server_name":
+		prefs.StealthTLSServerName = val
+		break
+	case "port_rotation_on_failure":
+		if val, err := strconv.ParseBool(val); err == nil {
+			prefs.IsPortRotationOnFailure = val
+		}
+		break
+	case "wg_tcp_fallback":
+		if val, err := strconv.ParseBool(val); err == nil {
+			prefs.IsWGTcpFallback = val
+		}
+		break
+	case "connect_warmup":
+		if val, err := strconv.ParseBool(val); err == nil {
+			prefs.IsConnectWarmup = val
+		}
+		break
+	case "wg_service_install_timeout_seconds":
+		if val, err := strconv.Atoi(val); err == nil && val >= 0 {
+			prefs.WGServiceInstallTimeoutSec = val
+		}
+		break
+	case "wg_service_start_timeout_seconds":
+		if val, err := strconv.Atoi(val); err == nil && val >= 0 {
+			prefs.WGServiceStartTimeoutSec = val
+		}
+		break
+	case "openvpn_extra_parameters":
+		// only whitelisted OpenVPN directives are acceptable (daemon is running with root privileges)
+		if err := openvpn.ValidateUserParameters(val); err != nil {
+			return err
+		}
+		prefs.OpenVpnExtraParameters = val
+		break
+	case "bandwidth_monthly_cap_bytes":
+		if val, err := strconv.ParseInt(val, 10, 64); err == nil {
+			prefs.BandwidthMonthlyCapBytes = val
+		}
+		break
+	case "bandwidth_alert_threshold_percent":
+		if val, err := strconv.Atoi(val); err == nil {
+			prefs.BandwidthAlertThresholdPercent = val
+		}
+		break
+	case "connection_history_enabled":
+		if val, err := strconv.ParseBool(val); err == nil {
+			prefs.IsConnectionHistoryEnabled = val
+		}
+		break
+	case "connection_history_max_records":
+		if val, err := strconv.Atoi(val); err == nil {
+			prefs.ConnectionHistoryMaxRecords = val
+		}
+		break
+	case "connection_diagnostics":
+		if val, err := strconv.ParseBool(val); err == nil {
+			prefs.IsConnectionDiagnostics = val
+		}
+		break
+	case "lan_services_discovery":
+		if val, err := strconv.ParseBool(val); err == nil {
+			prefs.IsLanServicesDiscovery = val
+		}
+		break
+	case "firewall_ipv6_policy":
+		if err := firewall.SetIPv6Policy(val); err != nil {
+			return err
+		}
+		prefs.FwIPv6Policy = val
+		break
+	case "firewall_dns_leak_protection":
+		if val, err := strconv.ParseBool(val); err == nil {
+			prefs.IsFwDNSLeakProtection = val
+		}
+		break
+	case "firewall_hotspot_sharing":
+		if val, err := strconv.ParseBool(val); err == nil {
+			prefs.IsFwHotspotSharing = val
+		}
+		break
+	case "firewall_vbridge_policy":
+		prefs.VirtualBridgePolicy = val
+		break
+	case "firewall_vbridge_overrides":
+		prefs.VirtualBridgeOverrides = val
+		break
+	case "firewall_apps_allowed":
+		prefs.FwUserAllowedApps = val
+		break
+	case "firewall_apps_blocked":
+		prefs.FwUserBlockedApps = val
+		break
 	case "firewall_is_persistent":
 		log.Debug("Skipping 'firewall_is_persistent' value. IVPNKillSwitchSetIsPersistentRequest should be used")
 		break
@@ -999,16 +2045,339 @@ func (s *Service) SetPreference(key string, val string) error {
 	}
 
 	s.setPreferences(prefs)
+
+	if strings.HasPrefix(key, "api_proxy_") {
+		s.applyAPIProxySettings()
+		// do not log proxy credentials
+		if key == "api_proxy_password" || key == "api_proxy_username" {
+			val = "***"
+		}
+	}
+
+	if key == "metrics_port" {
+		s.applyMetricsSettings()
+	}
+
+	if key == "firewall_dns_leak_protection" {
+		s.applyDNSLeakProtection()
+	}
+
+	if key == "firewall_hotspot_sharing" {
+		if err := firewall.SetHotspotSharing(s._preferences.IsFwHotspotSharing); err != nil {
+			return err
+		}
+	}
+
+	if key == "firewall_vbridge_policy" || key == "firewall_vbridge_overrides" {
+		if err := s.applyVirtualBridgePolicy(); err != nil {
+			return err
+		}
+	}
+
+	if key == "wg_service_install_timeout_seconds" || key == "wg_service_start_timeout_seconds" {
+		s.applyWGServiceTimeouts()
+	}
+
+	if key == "firewall_apps_allowed" || key == "firewall_apps_blocked" {
+		if err := s.applyUserAppRules(); err != nil {
+			return err
+		}
+	}
+
 	log.Info(fmt.Sprintf("preferences %s='%s'", key, val))
 
 	return nil
 }
 
+// SetAPIEndpoints - override API\update hosts and their pinned key sets (for staging or self-hosted environments)
+// Applicable only when 'IsCustomAPIEndpointsAllowed' enabled in settings file (admin-only gate)
+// Empty host values reset hosts to defaults
+func (s *Service) SetAPIEndpoints(apiHost string, updateHost string, apiPinnedKeys []string, updatePinnedKeys []string) error {
+	if s._preferences.IsCustomAPIEndpointsAllowed == false {
+		return fmt.Errorf("custom API endpoints not allowed (can be enabled by administrator in daemon settings file)")
+	}
+
+	if err := s._api.SetCustomHosts(apiHost, updateHost, apiPinnedKeys, updatePinnedKeys); err != nil {
+		return err
+	}
+
+	prefs := s._preferences
+	prefs.CustomAPIHost = apiHost
+	prefs.CustomUpdateHost = updateHost
+	prefs.CustomAPIPinnedKeys = apiPinnedKeys
+	prefs.CustomUpdatePinnedKeys = updatePinnedKeys
+	s.setPreferences(prefs)
+
+	return nil
+}
+
+// startPinManifestUpdater periodically fetches signed pin manifest from the update host
+// and applies new certificate pin sets (see 'api.UpdatePinsFromManifest')
+func (s *Service) startPinManifestUpdater() {
+	if len(api.PinManifestSigningKeyBase64) == 0 {
+		return // pin hot-update functionality disabled (signing key not defined in this build)
+	}
+
+	for {
+		if err := s._api.UpdatePinsFromManifest(); err != nil {
+			log.Warning("Failed to update certificate pin sets: ", err)
+		}
+		time.Sleep(time.Hour * 24)
+	}
+}
+
+// applyAPIProxySettings applies user-defined proxy configuration (from preferences) to the API object
+func (s *Service) applyAPIProxySettings() {
+	p := s._preferences
+	if err := s._api.SetProxy(p.APIProxyType, p.APIProxyAddress, p.APIProxyPort, p.APIProxyUsername, p.APIProxyPassword); err != nil {
+		log.Error("Failed to apply API proxy settings: ", err)
+	}
+}
+
 // Preferences returns preferences
 func (s *Service) Preferences() preferences.Preferences {
 	return s._preferences
 }
 
+// SaveLastConnectionParams saves raw data of the last 'Connect' request
+// (it will be used to restore connection by autoconnect functionality)
+func (s *Service) SaveLastConnectionParams(params string) {
+	prefs := s._preferences
+	prefs.LastConnectionParams = params
+	s.setPreferences(prefs)
+}
+
+// SetSchedulerRules saves scheduler rules (raw JSON of a rules list; empty string disables the scheduler)
+func (s *Service) SetSchedulerRules(rulesJSON string) error {
+	prefs := s._preferences
+	prefs.SchedulerRules = rulesJSON
+	s.setPreferences(prefs)
+	return nil
+}
+
+// SchedulerRules returns saved scheduler rules (raw JSON of a rules list)
+func (s *Service) SchedulerRules() string {
+	return s._preferences.SchedulerRules
+}
+
+// SaveConnectionProfile saves a named set of connection parameters (raw JSON of a 'Connect' request)
+// Profile with the same name will be overwritten
+func (s *Service) SaveConnectionProfile(name string, params string) error {
+	name = strings.TrimSpace(name)
+	if len(name) == 0 {
+		return fmt.Errorf("profile name not defined")
+	}
+
+	prefs := s._preferences
+	profiles := make(map[string]string, len(prefs.ConnectionProfiles)+1)
+	for n, p := range prefs.ConnectionProfiles {
+		profiles[n] = p
+	}
+	profiles[name] = params
+	prefs.ConnectionProfiles = profiles
+	s.setPreferences(prefs)
+
+	log.Info(fmt.Sprintf("Connection profile '%s' saved", name))
+	return nil
+}
+
+// DeleteConnectionProfile removes a saved connection profile
+func (s *Service) DeleteConnectionProfile(name string) error {
+	prefs := s._preferences
+	if _, ok := prefs.ConnectionProfiles[name]; ok == false {
+		return fmt.Errorf("profile '%s' does not exist", name)
+	}
+
+	profiles := make(map[string]string, len(prefs.ConnectionProfiles))
+	for n, p := range prefs.ConnectionProfiles {
+		if n != name {
+			profiles[n] = p
+		}
+	}
+	prefs.ConnectionProfiles = profiles
+	s.setPreferences(prefs)
+
+	log.Info(fmt.Sprintf("Connection profile '%s' deleted", name))
+	return nil
+}
+
+// ConnectionProfile returns saved connection parameters (raw JSON of a 'Connect' request) of a named profile
+func (s *Service) ConnectionProfile(name string) (params string, err error) {
+	params, ok := s._preferences.ConnectionProfiles[name]
+	if ok == false {
+		return "", fmt.Errorf("profile '%s' does not exist", name)
+	}
+	return params, nil
+}
+
+// ConnectionProfiles returns names of all saved connection profiles
+func (s *Service) ConnectionProfiles() []string {
+	names := make([]string, 0, len(s._preferences.ConnectionProfiles))
+	for n := range s._preferences.ConnectionProfiles {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetServerMetadata saves user-defined metadata of a VPN server (gateway is the server identifier)
+// Metadata with all fields empty erases the server entry
+func (s *Service) SetServerMetadata(gateway string, metadata preferences.ServerMetadata) error {
+	gateway = strings.TrimSpace(gateway)
+	if len(gateway) == 0 {
+		return fmt.Errorf("server gateway not defined")
+	}
+
+	prefs := s._preferences
+	servers := make(map[string]preferences.ServerMetadata, len(prefs.ServersMetadata)+1)
+	for g, m := range prefs.ServersMetadata {
+		servers[g] = m
+	}
+	if metadata == (preferences.ServerMetadata{}) {
+		delete(servers, gateway)
+	} else {
+		servers[gateway] = metadata
+	}
+	prefs.ServersMetadata = servers
+	s.setPreferences(prefs)
+
+	log.Info(fmt.Sprintf("Server metadata of '%s' saved", gateway))
+	return nil
+}
+
+// ServersMetadata returns user-defined metadata of all VPN servers (server gateway -> metadata)
+func (s *Service) ServersMetadata() map[string]preferences.ServerMetadata {
+	return s._preferences.ServersMetadata
+}
+
+// ImportOpenvpnProfile saves an imported third-party OpenVPN profile ('.ovpn') with its credentials
+// (profile content is validated against the whitelist of allowed directives;
+// profile with the same name will be overwritten)
+func (s *Service) ImportOpenvpnProfile(name string, config string, username string, password string) error {
+	name = strings.TrimSpace(name)
+	if len(name) == 0 {
+		return fmt.Errorf("profile name not defined")
+	}
+
+	if err := openvpn.ValidateImportedProfile(config); err != nil {
+		return err
+	}
+
+	prefs := s._preferences
+	profiles := make(map[string]preferences.OpenvpnImportedProfile, len(prefs.OpenvpnImportedProfiles)+1)
+	for n, p := range prefs.OpenvpnImportedProfiles {
+		profiles[n] = p
+	}
+	profiles[name] = preferences.OpenvpnImportedProfile{Config: config, Username: username, Password: password}
+	prefs.OpenvpnImportedProfiles = profiles
+	s.setPreferences(prefs)
+
+	log.Info(fmt.Sprintf("OpenVPN profile '%s' imported", name))
+	return nil
+}
+
+// DeleteOpenvpnProfile removes an imported OpenVPN profile (with its credentials)
+func (s *Service) DeleteOpenvpnProfile(name string) error {
+	prefs := s._preferences
+	if _, ok := prefs.OpenvpnImportedProfiles[name]; ok == false {
+		return fmt.Errorf("imported OpenVPN profile '%s' does not exist", name)
+	}
+
+	profiles := make(map[string]preferences.OpenvpnImportedProfile, len(prefs.OpenvpnImportedProfiles))
+	for n, p := range prefs.OpenvpnImportedProfiles {
+		if n != name {
+			profiles[n] = p
+		}
+	}
+	prefs.OpenvpnImportedProfiles = profiles
+	s.setPreferences(prefs)
+
+	log.Info(fmt.Sprintf("OpenVPN profile '%s' deleted", name))
+	return nil
+}
+
+// OpenvpnProfiles returns names of all imported OpenVPN profiles (credentials are not exposed)
+func (s *Service) OpenvpnProfiles() []string {
+	names := make([]string, 0, len(s._preferences.OpenvpnImportedProfiles))
+	for n := range s._preferences.OpenvpnImportedProfiles {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// OpenvpnProfilesInfo returns info about all imported OpenVPN profiles with country annotations
+// from the local GeoIP dataset (credentials are not exposed)
+func (s *Service) OpenvpnProfilesInfo() []protocoltypes.OvpnProfileInfo {
+	infos := make([]protocoltypes.OvpnProfileInfo, 0, len(s._preferences.OpenvpnImportedProfiles))
+	for _, name := range s.OpenvpnProfiles() {
+		info := protocoltypes.OvpnProfileInfo{Name: name}
+		// annotate by the first 'remote' host which is covered by the GeoIP dataset
+		// (only IP addresses: no DNS resolution performed)
+		for _, host := range openvpn.GetImportedProfileRemoteHosts(s._preferences.OpenvpnImportedProfiles[name].Config) {
+			if location, ok := geoip.LookupHost(host); ok {
+				info.CountryCode = location.CountryCode
+				info.Country = location.Country
+				break
+			}
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// ConnectImportedOpenvpnProfile starts OpenVPN connection using an imported third-party profile.
+// Connection is managed by the same machinery as for IVPN servers:
+// state machine, firewall, DNS, pause\resume, kill switch.
+func (s *Service) ConnectImportedOpenvpnProfile(name string, manualDNS net.IP, firewallOn bool, firewallDuringConnection bool, stateChan chan<- vpn.StateInfo) error {
+	profile, ok := s._preferences.OpenvpnImportedProfiles[name]
+	if ok == false {
+		return fmt.Errorf("imported OpenVPN profile '%s' does not exist", name)
+	}
+
+	// stop active connection (if exists)
+	if err := s.Disconnect(); err != nil {
+		return fmt.Errorf("failed to connect. Unable to stop active connection: %w", err)
+	}
+
+	// checking if functionality accessible
+	_, ovpnErr, _ := s.GetDisabledFunctions()
+	if ovpnErr != nil {
+		return ovpnErr
+	}
+
+	connectionParams := openvpn.ConnectionParams{}
+	connectionParams.SetImportedProfile(profile.Config)
+	if len(profile.Username) > 0 {
+		connectionParams.SetCredentials(profile.Username, profile.Password)
+	}
+
+	// exact profile parameters must be used: no obfuscation transports or extra parameters
+	createVpnObjfunc := func() (vpn.Process, error) {
+		vpnObj, err := openvpn.NewOpenVpnObject(
+			platform.OpenVpnBinaryPath(),
+			platform.OpenvpnConfigFile(),
+			platform.OpenvpnLogFile(),
+			false,
+			"",
+			connectionParams)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to create new openVPN object: %w", err)
+		}
+		return vpnObj, nil
+	}
+
+	return s.keepConnection(createVpnObjfunc, manualDNS, firewallOn, firewallDuringConnection, stateChan)
+}
+
+// isServerExcludedFromAutoSelect returns true when the server was marked by user
+// as not applicable for the automatic server selection
+func (s *Service) isServerExcludedFromAutoSelect(gateway string) bool {
+	metadata, ok := s._preferences.ServersMetadata[gateway]
+	return ok && metadata.IsExcludedFromAutoSelect
+}
+
 //////////////////////////////////////////////////////////
 // SESSIONS
 //////////////////////////////////////////////////////////
@@ -1045,6 +2414,25 @@ func (s *Service) SessionNew(accountID string, forceLogin bool, captchaID string
 	rawResponse string,
 	err error) {
 
+	// Resumable login transaction:
+	// when account ID is not defined - continue the pending login
+	// (the client sends only the data requested by the API: captcha value and/or 2FA token)
+	s._loginMutex.Lock()
+	if len(accountID) == 0 {
+		if len(s._login.accountID) == 0 {
+			s._loginMutex.Unlock()
+			return 0, "", accountInfo, "", fmt.Errorf("unable to continue login: no login request in progress")
+		}
+		accountID = s._login.accountID
+		if forceLogin == false {
+			forceLogin = s._login.forceLogin
+		}
+		if len(captchaID) == 0 {
+			captchaID = s._login.captchaID
+		}
+	}
+	s._loginMutex.Unlock()
+
 	// delete current session (if exists)
 	if err := s.SessionDelete(); err != nil {
 		log.Error("Creating new session -> Failed to delete active session: ", err)
@@ -1073,6 +2461,21 @@ func (s *Service) SessionNew(accountID string, forceLogin bool, captchaID string
 		apiCode = apiErr.Status
 	}
 
+	// save or reset the login transaction state
+	// (login can be continued when the API requested captcha or 2FA confirmation)
+	switch apiCode {
+	case types.CodeCaptchaRequired, types.CodeCaptchaInvalid:
+		newCaptchaID := ""
+		if successResp != nil {
+			newCaptchaID = successResp.CaptchaID
+		}
+		s.saveLoginTransaction(accountID, forceLogin, newCaptchaID)
+	case types.Code2FARequired, types.Code2FAInvalidToken, types.CodeSessionsLimitReached:
+		s.saveLoginTransaction(accountID, forceLogin, captchaID)
+	default:
+		s.saveLoginTransaction("", false, "")
+	}
+
 	if err != nil {
 		// if SessionsLimit response
 		if errorLimitResp != nil {
@@ -1109,6 +2512,9 @@ func (s *Service) SessionNew(accountID string, forceLogin bool, captchaID string
 
 // SessionDelete removes session info
 func (s *Service) SessionDelete() error {
+	if err := s.policyCheckLogout(); err != nil {
+		return err
+	}
 	return s.logOut(true)
 }
 
@@ -1132,6 +2538,7 @@ func (s *Service) logOut(needToDeleteOnBackend bool) error {
 	}
 
 	s._preferences.SetSession("", "", "", "", "", "", "")
+	s.saveAccountStatus("", preferences.AccountStatus{})
 
 	// Disable firewall
 	s.SetKillSwitchState(false)
@@ -1183,6 +2590,7 @@ func (s *Service) RequestSessionStatus() (
 		// notify clients that account not active
 		if apiCode == types.AccountNotActive {
 			// notify about account status
+			s.saveAccountStatus(session.Session, preferences.AccountStatus{Active: false})
 			s._evtReceiver.OnAccountStatus(session.Session, accountInfo)
 			return apiCode, apiErr.Message, session.Session, preferences.AccountStatus{Active: false}, err
 		}
@@ -1204,6 +2612,7 @@ func (s *Service) RequestSessionStatus() (
 
 	// get account status info
 	accountInfo = s.createAccountStatus(*stat)
+	s.saveAccountStatus(session.Session, accountInfo)
 	// notify about account status
 	s._evtReceiver.OnAccountStatus(session.Session, accountInfo)
 
@@ -1211,6 +2620,77 @@ func (s *Service) RequestSessionStatus() (
 	return apiCode, "", session.Session, accountInfo, nil
 }
 
+// AccountCreate - register new account (trial)
+// 'productName' - empty for the default product
+func (s *Service) AccountCreate(productName string) (
+	apiCode int,
+	apiErrorMsg string,
+	accountID string,
+	rawResponse string,
+	err error) {
+
+	log.Info("Creating new account...")
+	defer func() {
+		if err != nil {
+			log.Info("Creating new account - FAILED: ", err)
+		} else {
+			log.Info("Creating new account - SUCCESS")
+		}
+	}()
+
+	successResp, apiErr, rawResponse, err := s._api.AccountNew(productName)
+
+	apiCode = 0
+	if apiErr != nil {
+		apiCode = apiErr.Status
+	}
+
+	if err != nil {
+		// in case of API error
+		if apiErr != nil {
+			return apiCode, apiErr.Message, "", rawResponse, err
+		}
+		// not API error
+		return apiCode, "", "", rawResponse, err
+	}
+
+	if successResp == nil {
+		return apiCode, "", "", rawResponse, fmt.Errorf("unexpected error when creating a new account")
+	}
+
+	return apiCode, "", successResp.AccountID, rawResponse, nil
+}
+
+// saveLoginTransaction keeps state of a pending multi-step login
+// (empty 'accountID' - reset the transaction)
+func (s *Service) saveLoginTransaction(accountID string, forceLogin bool, captchaID string) {
+	s._loginMutex.Lock()
+	defer s._loginMutex.Unlock()
+	s._login = loginTransaction{accountID: accountID, forceLogin: forceLogin, captchaID: captchaID}
+}
+
+// saveAccountStatus keeps the last known account status
+// (used to push the status to new clients and to detect subscription expiration)
+func (s *Service) saveAccountStatus(sessionToken string, accountInfo preferences.AccountStatus) {
+	s._accountStatusMutex.Lock()
+	defer s._accountStatusMutex.Unlock()
+
+	if s._accountStatusSession == sessionToken && s._accountStatus.Active && accountInfo.Active == false {
+		log.Info("Account status changed: subscription is not active anymore")
+	}
+
+	s._accountStatusSession = sessionToken
+	s._accountStatus = accountInfo
+}
+
+// AccountStatus returns the last known account status received by the session checker
+// (empty 'sessionToken' - no status received yet)
+func (s *Service) AccountStatus() (sessionToken string, stat preferences.AccountStatus) {
+	s._accountStatusMutex.Lock()
+	defer s._accountStatusMutex.Unlock()
+	return s._accountStatusSession, s._accountStatus
+}
+
 func (s *Service) createAccountStatus(apiResp types.ServiceStatusAPIResp) preferences.AccountStatus {
 	return preferences.AccountStatus{
 		Active:         apiResp.Active,
@@ -1243,11 +2723,19 @@ func (s *Service) startSessionChecker() {
 
 		stopChn := s._sessionCheckerStopChn
 		for {
+			interval := SessionCheckInterval
+			// check the account status more frequently when the subscription is close to expiration
+			if _, accStat := s.AccountStatus(); accStat.Active && accStat.ActiveUntil > 0 {
+				if time.Until(time.Unix(accStat.ActiveUntil, 0)) <= sessionCheckExpirationThreshold {
+					interval = SessionCheckIntervalExpiration
+				}
+			}
+
 			// wait for timeout or stop request
 			select {
 			case <-stopChn:
 				return
-			case <-time.After(SessionCheckInterval):
+			case <-time.After(interval):
 				break
 			}
 
@@ -1299,6 +2787,18 @@ func (s *Service) WireGuardSaveNewKeys(wgPublicKey string, wgPrivateKey string,
 	}()
 }
 
+// WireGuardSavePendingKeys saves WG key pair which was not confirmed by the API yet
+// (empty values - erase pending keys)
+func (s *Service) WireGuardSavePendingKeys(wgPublicKey string, wgPrivateKey string) {
+	s._preferences.UpdateWgPendingKeys(wgPublicKey, wgPrivateKey)
+}
+
+// WireGuardGetPendingKeys returns WG key pair which was not confirmed by the API yet
+func (s *Service) WireGuardGetPendingKeys() (wgPublicKey, wgPrivateKey string) {
+	p := s._preferences
+	return p.Session.WGPendingPublicKey, p.Session.WGPendingPrivateKey
+}
+
 // WireGuardSetKeysRotationInterval change WG key rotation interval
 func (s *Service) WireGuardSetKeysRotationInterval(interval int64) {
 	s._preferences.Session.WGKeysRegenInerval = time.Second * time.Duration(interval)
@@ -1359,8 +2859,8 @@ func (s *Service) WireGuardGenerateKeys(updateIfNecessary bool) error {
 //////////////////////////////////////////////////////////
 
 func (s *Service) setPreferences(p preferences.Preferences) {
-	//if reflect.DeepEqual(s._preferences, p) == false {
-	if s._preferences != p {
+	// NOTE: Preferences contains slice fields, therefore not comparable by '!=' operator
+	if reflect.DeepEqual(s._preferences, p) == false {
 		s._preferences = p
 		s._preferences.SavePreferences()
 	}
@@ -0,0 +1,278 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	protocoltypes "github.com/ivpn/desktop-app-daemon/protocol/types"
+	"github.com/ivpn/desktop-app-daemon/service/platform"
+	"github.com/ivpn/desktop-app-daemon/service/platform/filerights"
+)
+
+// Bandwidth usage accounting
+// While connected, daemon periodically reads statistics of the tunnel network interface
+// and maintains per-session and per-calendar-month counters of transferred bytes.
+// Monthly counters are persisted into a local file and survive daemon restarts.
+// When a monthly cap is defined (preference 'bandwidth_monthly_cap_bytes'), clients are notified
+// after the configured percent of the cap is consumed (useful on metered connections).
+
+const (
+	_bandwidthFileName            = "bandwidth-stats.json"
+	_bandwidthPollInterval        = time.Second * 30
+	_bandwidthDefaultAlertPercent = 80
+)
+
+// bandwidthMonthStats - persisted cumulative counters of a calendar month
+type bandwidthMonthStats struct {
+	Month         string // "YYYY-MM"
+	BytesReceived int64
+	BytesSent     int64
+}
+
+// bandwidthFilePath returns path to the bandwidth statistics file (next to the settings file)
+func bandwidthFilePath() string {
+	return filepath.Join(filepath.Dir(platform.SettingsFile()), _bandwidthFileName)
+}
+
+// bandwidthCurrentMonth returns identifier of the current calendar month
+func bandwidthCurrentMonth() string {
+	return time.Now().Format("2006-01")
+}
+
+// bandwidthLoadMonthStats reads persisted monthly counters (empty stats for current month - when no file or month changed)
+func bandwidthLoadMonthStats() bandwidthMonthStats {
+	stats := bandwidthMonthStats{Month: bandwidthCurrentMonth()}
+
+	data, err := ioutil.ReadFile(bandwidthFilePath())
+	if err != nil {
+		return stats
+	}
+
+	var saved bandwidthMonthStats
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return stats
+	}
+	if saved.Month == stats.Month {
+		return saved
+	}
+	return stats // month changed - counters start from zero
+}
+
+// bandwidthSaveMonthStats persists monthly counters
+func bandwidthSaveMonthStats(stats bandwidthMonthStats) {
+	data, err := json.Marshal(stats)
+	if err != nil {
+		return
+	}
+	filePath := bandwidthFilePath()
+	if err := ioutil.WriteFile(filePath, data, 0600); err != nil { // read\write only for privileged user
+		log.Warning("Failed to save bandwidth statistics: ", err)
+		return
+	}
+	// only for Windows: Golang is not able to change file permissins in Windows style
+	if err := filerights.WindowsChmod(filePath, 0600); err != nil {
+		log.Warning("Failed to change bandwidth statistics file permissions: ", err)
+	}
+}
+
+// bandwidthOnConnected starts accounting of transferred bytes (called when VPN connection established)
+func (s *Service) bandwidthOnConnected(tunnelInterface *net.Interface) {
+	if tunnelInterface == nil {
+		return
+	}
+
+	s._bwMutex.Lock()
+	defer s._bwMutex.Unlock()
+
+	if s._bwStopChan != nil {
+		close(s._bwStopChan)
+	}
+
+	s._bwTunnelInterface = tunnelInterface
+	s._bwSessionReceived = 0
+	s._bwSessionSent = 0
+	s._bwAlertNotified = false
+	s._bwMonthStats = bandwidthLoadMonthStats()
+	s._bwStopChan = make(chan struct{})
+
+	// raw counters of the interface at the moment of connection (baseline for delta calculation)
+	received, sent, err := s.implGetTunnelStatistics(tunnelInterface)
+	if err != nil {
+		log.Warning("Bandwidth accounting not available: ", err)
+		s._bwStopChan = nil
+		return
+	}
+	s._bwLastRawReceived = received
+	s._bwLastRawSent = sent
+
+	stopChan := s._bwStopChan
+	go func() {
+		for {
+			select {
+			case <-stopChan:
+				return
+			case <-time.After(_bandwidthPollInterval):
+				s.bandwidthPoll()
+			}
+		}
+	}()
+}
+
+// bandwidthOnDisconnected stops accounting and returns bytes transferred during the session
+func (s *Service) bandwidthOnDisconnected() (received int64, sent int64) {
+	s._bwMutex.Lock()
+	if s._bwStopChan == nil {
+		s._bwMutex.Unlock()
+		return 0, 0
+	}
+	close(s._bwStopChan)
+	s._bwStopChan = nil
+	s._bwMutex.Unlock()
+
+	s.bandwidthPoll() // read final counters values
+
+	s._bwMutex.Lock()
+	defer s._bwMutex.Unlock()
+	s._bwTunnelInterface = nil
+	return s._bwSessionReceived, s._bwSessionSent
+}
+
+// bandwidthPoll reads current interface counters and updates session and monthly statistics
+func (s *Service) bandwidthPoll() {
+	s._bwMutex.Lock()
+	tunnelInterface := s._bwTunnelInterface
+	s._bwMutex.Unlock()
+
+	if tunnelInterface == nil {
+		return
+	}
+
+	received, sent, err := s.implGetTunnelStatistics(tunnelInterface)
+	if err != nil {
+		return
+	}
+
+	s._bwMutex.Lock()
+
+	deltaReceived := received - s._bwLastRawReceived
+	deltaSent := sent - s._bwLastRawSent
+	if deltaReceived < 0 {
+		deltaReceived = received // counter was reset
+	}
+	if deltaSent < 0 {
+		deltaSent = sent // counter was reset
+	}
+	s._bwLastRawReceived = received
+	s._bwLastRawSent = sent
+
+	s._bwSessionReceived += deltaReceived
+	s._bwSessionSent += deltaSent
+
+	if s._bwMonthStats.Month != bandwidthCurrentMonth() {
+		// month changed during the session - counters start from zero
+		s._bwMonthStats = bandwidthMonthStats{Month: bandwidthCurrentMonth()}
+		s._bwAlertNotified = false
+	}
+	s._bwMonthStats.BytesReceived += deltaReceived
+	s._bwMonthStats.BytesSent += deltaSent
+	monthStats := s._bwMonthStats
+
+	s._bwMutex.Unlock()
+
+	bandwidthSaveMonthStats(monthStats)
+	s.bandwidthCheckAlert(monthStats)
+}
+
+// bandwidthCheckAlert notifies clients when consumed monthly traffic reached the configured percent of a cap
+func (s *Service) bandwidthCheckAlert(monthStats bandwidthMonthStats) {
+	capBytes := s._preferences.BandwidthMonthlyCapBytes
+	if capBytes <= 0 {
+		return
+	}
+	thresholdPercent := s._preferences.BandwidthAlertThresholdPercent
+	if thresholdPercent <= 0 || thresholdPercent > 100 {
+		thresholdPercent = _bandwidthDefaultAlertPercent
+	}
+
+	monthBytes := monthStats.BytesReceived + monthStats.BytesSent
+	if monthBytes < capBytes*int64(thresholdPercent)/100 {
+		return
+	}
+
+	s._bwMutex.Lock()
+	alreadyNotified := s._bwAlertNotified
+	s._bwAlertNotified = true
+	s._bwMutex.Unlock()
+	if alreadyNotified {
+		return
+	}
+
+	log.Info("Monthly bandwidth usage alert threshold reached")
+	s._evtReceiver.OnBandwidthAlert(monthBytes, capBytes, thresholdPercent)
+}
+
+// BandwidthStats returns current per-session and per-month counters of transferred bytes
+func (s *Service) BandwidthStats() protocoltypes.BandwidthStats {
+	s._bwMutex.Lock()
+	defer s._bwMutex.Unlock()
+
+	monthStats := s._bwMonthStats
+	if s._bwStopChan == nil {
+		// not connected - read persisted monthly counters
+		monthStats = bandwidthLoadMonthStats()
+	}
+
+	thresholdPercent := s._preferences.BandwidthAlertThresholdPercent
+	if thresholdPercent <= 0 || thresholdPercent > 100 {
+		thresholdPercent = _bandwidthDefaultAlertPercent
+	}
+
+	return protocoltypes.BandwidthStats{
+		SessionBytesReceived:  s._bwSessionReceived,
+		SessionBytesSent:      s._bwSessionSent,
+		Month:                 monthStats.Month,
+		MonthBytesReceived:    monthStats.BytesReceived,
+		MonthBytesSent:        monthStats.BytesSent,
+		MonthlyCapBytes:       s._preferences.BandwidthMonthlyCapBytes,
+		AlertThresholdPercent: thresholdPercent}
+}
+
+// ResetBandwidthStats erases persisted monthly counters
+func (s *Service) ResetBandwidthStats() error {
+	s._bwMutex.Lock()
+	defer s._bwMutex.Unlock()
+
+	s._bwMonthStats = bandwidthMonthStats{Month: bandwidthCurrentMonth()}
+	s._bwAlertNotified = false
+
+	if err := os.Remove(bandwidthFilePath()); err != nil && os.IsNotExist(err) == false {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ivpn/desktop-app-daemon/vpn"
+)
+
+// Better-server advisory
+// On every background servers-list update the connected server is scored against all servers
+// (same score as the fastest-server selection: latency + load + success rate; measured
+// in-tunnel latency of the connected host feeds the same cache - see 'service_connquality.go').
+// When a clearly better server is available - clients are notified with an advisory event.
+// Optionally ('better_server_auto_migrate' preference) daemon migrates to the better server
+// by itself, but only while the connection is idle (not to interrupt active transfers).
+
+const (
+	// candidate score must be below this fraction of the current server score to be advised
+	_betterServerScoreRatio = 0.7
+	// minimal interval between advisories (rate limiting)
+	_betterServerNotifyInterval = time.Minute * 30
+	// connection treated as idle when transfer rate is below this value
+	_betterServerIdleRateBytesPerSec = 2048
+)
+
+// betterServerCheck compares the connected server with the rest of the servers list
+// and notifies clients when a clearly better server is available
+func (s *Service) betterServerCheck() {
+	vpnObj := s._vpn
+	if vpnObj == nil || vpnObj.IsPaused() {
+		return
+	}
+	// checking only fully established connections
+	if s._requiredVpnState != KeepConnection {
+		return
+	}
+
+	curHostIP := vpnObj.DestinationIP()
+	if curHostIP == nil || curHostIP.IsLoopback() {
+		return // an obfuscation transport is in use (local listener): real host not comparable
+	}
+
+	isIdle := s.betterServerIsConnectionIdle()
+
+	servers, err := s._serversUpdater.GetServers()
+	if err != nil || servers == nil {
+		return
+	}
+
+	latencyCache := pingCacheGet()
+	curHost := curHostIP.String()
+
+	currentGateway := ""
+	currentScore := 0.0
+	bestGateway := ""
+	bestScore := 0.0
+
+	if vpnObj.Type() == vpn.WireGuard {
+		for i := range servers.WireguardServers {
+			srv := &servers.WireguardServers[i]
+			for _, host := range srv.Hosts {
+				score := s.fastestHostScore(host.Host, srv.Load, latencyCache)
+				if host.Host == curHost {
+					currentGateway = srv.Gateway
+					currentScore = score
+				}
+				if s.isServerExcludedFromAutoSelect(srv.Gateway) {
+					continue
+				}
+				if len(bestGateway) == 0 || score < bestScore {
+					bestGateway = srv.Gateway
+					bestScore = score
+				}
+			}
+		}
+	} else {
+		for i := range servers.OpenvpnServers {
+			srv := &servers.OpenvpnServers[i]
+			for _, host := range srv.IPAddresses {
+				score := s.fastestHostScore(host, srv.Load, latencyCache)
+				if host == curHost {
+					currentGateway = srv.Gateway
+					currentScore = score
+				}
+				if s.isServerExcludedFromAutoSelect(srv.Gateway) {
+					continue
+				}
+				if len(bestGateway) == 0 || score < bestScore {
+					bestGateway = srv.Gateway
+					bestScore = score
+				}
+			}
+		}
+	}
+
+	if len(currentGateway) == 0 || len(bestGateway) == 0 || bestGateway == currentGateway {
+		return // connected server not found in the list or is already the best one
+	}
+	if bestScore >= currentScore*_betterServerScoreRatio {
+		return // candidate is not better enough to bother
+	}
+
+	// rate limiting: do not spam clients with advisories
+	if time.Since(s._betterSrvLastNotify) < _betterServerNotifyInterval {
+		return
+	}
+	s._betterSrvLastNotify = time.Now()
+
+	isAutoMigrate := s._preferences.IsBetterServerAutoMigrate
+	if isAutoMigrate && isIdle == false {
+		log.Info(fmt.Sprintf("Better server available: %s (score %.1f vs %.1f). Migration postponed: connection is not idle", bestGateway, bestScore, currentScore))
+		isAutoMigrate = false
+	} else {
+		log.Info(fmt.Sprintf("Better server available: %s (score %.1f vs %.1f)", bestGateway, bestScore, currentScore))
+	}
+
+	s._evtReceiver.OnBetterServerAvailable(bestGateway, bestScore, currentGateway, currentScore, isAutoMigrate)
+}
+
+// betterServerIsConnectionIdle returns true when the transfer rate of the current session
+// is below '_betterServerIdleRateBytesPerSec' (false - when there is no baseline to compare yet)
+func (s *Service) betterServerIsConnectionIdle() bool {
+	s._bwMutex.Lock()
+	transferredBytes := s._bwSessionReceived + s._bwSessionSent
+	s._bwMutex.Unlock()
+
+	lastBytes := s._betterSrvIdleBytes
+	lastTime := s._betterSrvIdleTime
+	s._betterSrvIdleBytes = transferredBytes
+	s._betterSrvIdleTime = time.Now()
+
+	if lastTime.IsZero() || transferredBytes < lastBytes {
+		return false // no baseline (first check of this session)
+	}
+
+	elapsedSec := time.Since(lastTime).Seconds()
+	if elapsedSec <= 0 {
+		return false
+	}
+
+	rate := float64(transferredBytes-lastBytes) / elapsedSec
+	return rate < _betterServerIdleRateBytesPerSec
+}
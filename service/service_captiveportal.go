@@ -0,0 +1,149 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Captive portal detection
+// On a network change daemon probes a well-known URL which always returns a fixed plain-text
+// response. A redirect or an unexpected response body means the network intercepts traffic
+// (captive portal): clients are notified so the UI can offer a time-boxed bypass.
+// The bypass temporarily disables kill switch to let the user pass the portal login page;
+// full protection is restored automatically when the bypass time is over
+// (clients are notified and can re-establish the VPN connection).
+
+const (
+	// probe target: plain HTTP URL with a known fixed response (no TLS: portals can not intercept it)
+	_captivePortalProbeURL = "http://detectportal.firefox.com/success.txt"
+	// expected response body of the probe URL
+	_captivePortalProbeExpected = "success"
+	// timeout of the probe request
+	_captivePortalProbeTimeout = time.Second * 5
+	// bypass duration when not defined by the request
+	_captivePortalDefaultBypassDuration = time.Minute * 5
+	// hard limit of the bypass duration
+	_captivePortalMaxBypassDuration = time.Minute * 15
+)
+
+// captivePortalCheck probes for a captive portal on the current network
+// and notifies clients when one is detected
+func (s *Service) captivePortalCheck() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error("captivePortalCheck PANIC (recovered): ", r)
+		}
+	}()
+
+	if s.Connected() {
+		return // traffic goes through the tunnel: probe result is meaningless
+	}
+
+	client := &http.Client{
+		Timeout: _captivePortalProbeTimeout,
+		// a redirect IS the portal indication: do not follow it
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		}}
+
+	resp, err := client.Get(_captivePortalProbeURL)
+	if err != nil {
+		return // network is down or traffic is blocked (e.g. by kill switch): not a detection
+	}
+	defer resp.Body.Close()
+
+	isPortal := false
+	if resp.StatusCode != http.StatusOK {
+		isPortal = true
+	} else {
+		body, err := ioutil.ReadAll(&io.LimitedReader{R: resp.Body, N: 1024})
+		if err != nil || strings.TrimSpace(string(body)) != _captivePortalProbeExpected {
+			isPortal = true
+		}
+	}
+
+	if isPortal == false {
+		return
+	}
+
+	log.Info("Captive portal detected on the current network")
+	s._evtReceiver.OnCaptivePortalDetected()
+}
+
+// CaptivePortalBypassStart temporarily disables kill switch to let the user pass
+// the captive portal login page ('durationSec == 0' - default duration).
+// Full protection is restored automatically when the bypass time is over.
+func (s *Service) CaptivePortalBypassStart(durationSec int) error {
+	duration := _captivePortalDefaultBypassDuration
+	if durationSec > 0 {
+		duration = time.Second * time.Duration(durationSec)
+	}
+	if duration > _captivePortalMaxBypassDuration {
+		duration = _captivePortalMaxBypassDuration
+	}
+
+	fwEnabled, err := s.FirewallEnabled()
+	if err != nil {
+		return fmt.Errorf("failed to get firewall status: %w", err)
+	}
+	if fwEnabled == false {
+		return fmt.Errorf("firewall is not enabled (nothing to bypass)")
+	}
+
+	s._captivePortalMutex.Lock()
+	defer s._captivePortalMutex.Unlock()
+
+	if s._captivePortalTimer != nil {
+		return fmt.Errorf("captive portal bypass is already active")
+	}
+
+	if err := s.SetKillSwitchState(false); err != nil {
+		return fmt.Errorf("failed to disable firewall: %w", err)
+	}
+
+	log.Warning(fmt.Sprintf("Captive portal bypass: kill switch temporarily disabled for %s", duration))
+	s._captivePortalTimer = time.AfterFunc(duration, s.onCaptivePortalBypassEnd)
+
+	s._evtReceiver.OnCaptivePortalBypass(true, time.Now().Add(duration).Unix())
+	return nil
+}
+
+// onCaptivePortalBypassEnd restores full protection when the bypass time is over
+func (s *Service) onCaptivePortalBypassEnd() {
+	s._captivePortalMutex.Lock()
+	s._captivePortalTimer = nil
+	s._captivePortalMutex.Unlock()
+
+	log.Info("Captive portal bypass finished: restoring kill switch")
+	if err := s.SetKillSwitchState(true); err != nil {
+		log.Error("Failed to restore firewall after captive portal bypass: ", err)
+	}
+
+	s._evtReceiver.OnCaptivePortalBypass(false, 0)
+}
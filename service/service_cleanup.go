@@ -0,0 +1,47 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"fmt"
+
+	"github.com/ivpn/desktop-app-daemon/service/dns"
+)
+
+// CleanupNetworkArtifacts removes network artifacts leftover from crashed sessions:
+// orphaned VPN interfaces, leftover WG services and DNS settings
+// (e.g. when daemon process was terminated unexpectedly during an active connection)
+func (s *Service) CleanupNetworkArtifacts() error {
+	if s.Connected() {
+		return fmt.Errorf("unable to cleanup network artifacts: VPN is connected")
+	}
+
+	log.Info("Cleaning up network artifacts...")
+
+	// restore DNS settings (can be left modified by a crashed session)
+	if err := dns.DeleteManual(nil); err != nil {
+		log.Warning("Failed to restore DNS configuration: ", err)
+	}
+
+	return s.implCleanupNetworkArtifacts()
+}
@@ -0,0 +1,145 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/ivpn/desktop-app-daemon/vpn"
+)
+
+// Opt-in connection diagnostics (preference 'connection_diagnostics')
+// When enabled, daemon records which transports\ports of connection attempts failed or succeeded.
+// Intended to help diagnosing ISP-level blocking: the report shows, for example, that UDP attempts
+// time out while TCP 443 succeeds.
+// Records are anonymized (no server addresses, no account info) and kept in memory only;
+// the report leaves the device only by explicit user request ('GetConnectionDiagnostics').
+
+// retention limit of connection diagnostics records
+const _connDiagMaxRecords = 200
+
+// connDiagRecord - info about one connection attempt
+type connDiagRecord struct {
+	At        int64  // unix time the attempt started
+	VpnType   string // "OpenVPN" or "WireGuard"
+	Transport string // "UDP" or "TCP"
+	Port      int    // requested destination port (before obfuscation transport applied)
+	Obfs      string `json:",omitempty"` // obfuscation in use: "obfsproxy", "v2ray", "stealth", "udp2tcp"
+	Success   bool
+	Error     string `json:",omitempty"` // failure reason (IP addresses stripped)
+}
+
+// matches IPv4 addresses in error messages (to keep records anonymized)
+var connDiagIPRegexp = regexp.MustCompile(`([0-9]{1,3}\.){3}[0-9]{1,3}`)
+
+// connDiagObfuscation returns name of the obfuscation transport in use (empty - no obfuscation)
+func (s *Service) connDiagObfuscation() string {
+	if s.Preferences().IsObfsproxy {
+		return "obfsproxy"
+	}
+	if _, ok := s.v2rayParams(); ok {
+		return "v2ray"
+	}
+	if _, _, _, ok := s.stealthParams(); ok {
+		return "stealth"
+	}
+	if s.wgTcpFallbackActive() {
+		return "udp2tcp"
+	}
+	return ""
+}
+
+// connDiagOnAttempt opens a new diagnostics record (connection attempt started)
+func (s *Service) connDiagOnAttempt(vpnType vpn.Type, port int, isTCP bool) {
+	if s._preferences.IsConnectionDiagnostics == false {
+		return
+	}
+
+	transport := "UDP"
+	if isTCP {
+		transport = "TCP"
+	}
+
+	s._connDiagMutex.Lock()
+	defer s._connDiagMutex.Unlock()
+	s._connDiagPending = &connDiagRecord{
+		At:        time.Now().Unix(),
+		VpnType:   vpnType.String(),
+		Transport: transport,
+		Port:      port,
+		Obfs:      s.connDiagObfuscation()}
+}
+
+// connDiagOnResult closes pending diagnostics record with attempt result
+func (s *Service) connDiagOnResult(isSuccess bool, connErr error) {
+	s._connDiagMutex.Lock()
+	defer s._connDiagMutex.Unlock()
+
+	record := s._connDiagPending
+	s._connDiagPending = nil
+	if record == nil {
+		return
+	}
+
+	record.Success = isSuccess
+	if isSuccess == false && connErr != nil {
+		record.Error = connDiagIPRegexp.ReplaceAllString(connErr.Error(), "x.x.x.x")
+	}
+
+	s._connDiagRecords = append(s._connDiagRecords, *record)
+	if len(s._connDiagRecords) > _connDiagMaxRecords {
+		s._connDiagRecords = s._connDiagRecords[len(s._connDiagRecords)-_connDiagMaxRecords:]
+	}
+}
+
+// ConnectionDiagnosticsReport returns recorded connection diagnostics (JSON; newest first)
+func (s *Service) ConnectionDiagnosticsReport() (reportJSON string, err error) {
+	if s._preferences.IsConnectionDiagnostics == false {
+		return "", fmt.Errorf("connection diagnostics disabled (see preference 'connection_diagnostics')")
+	}
+
+	s._connDiagMutex.Lock()
+	records := make([]connDiagRecord, 0, len(s._connDiagRecords))
+	for i := len(s._connDiagRecords) - 1; i >= 0; i-- {
+		records = append(records, s._connDiagRecords[i])
+	}
+	s._connDiagMutex.Unlock()
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize connection diagnostics: %w", err)
+	}
+	return string(data), nil
+}
+
+// ClearConnectionDiagnostics erases recorded connection diagnostics
+func (s *Service) ClearConnectionDiagnostics() error {
+	s._connDiagMutex.Lock()
+	defer s._connDiagMutex.Unlock()
+	s._connDiagRecords = nil
+	s._connDiagPending = nil
+	return nil
+}
@@ -0,0 +1,94 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"fmt"
+	"time"
+)
+
+// Unified retry manager of a connection sequence (common for all VPN types).
+//
+// keepConnection() owns the connection loop; this object owns the retry narrative:
+// attempt counting, exponential backoff between failed attempts and single description
+// of the current attempt for clients (e.g. "attempt 3 of 10: Retrying with the next port ...").
+// Changes of connection parameters between attempts are provided by the dual-stack fallback
+// and the port rotation functionality (see 'service_dualstack.go', 'service_portrotation.go').
+
+const (
+	// limit of connection retry attempts within one connection request
+	// (safety valve: sources of retry parameters have own limits)
+	_connRetryMaxAttempts = 10
+	// exponential backoff between failed connection attempts
+	_connRetryInitialDelay = time.Second * 1
+	_connRetryMaxDelay     = time.Second * 30
+)
+
+type connRetryState struct {
+	attempt int
+	delay   time.Duration
+}
+
+// connRetryInit resets the retry state (must be called on start of each connection request)
+func (s *Service) connRetryInit() {
+	s._connRetry = connRetryState{}
+}
+
+// connRetryNext registers a failed connection attempt
+// 'attemptInfo' - description of parameter changes for the next attempt (e.g. from port rotation)
+// ok == false - retry attempts exhausted: the failure must be surfaced to the client
+func (s *Service) connRetryNext(attemptInfo string) (description string, delay time.Duration, ok bool) {
+	st := &s._connRetry
+
+	st.attempt++
+	if st.attempt >= _connRetryMaxAttempts {
+		return "", 0, false
+	}
+
+	// first retry - immediately; consequent retries - with exponential backoff
+	delay = st.delay
+	if st.delay == 0 {
+		st.delay = _connRetryInitialDelay
+	} else {
+		st.delay *= 2
+		if st.delay > _connRetryMaxDelay {
+			st.delay = _connRetryMaxDelay
+		}
+	}
+
+	description = fmt.Sprintf("attempt %d of %d: %s", st.attempt, _connRetryMaxAttempts, attemptInfo)
+	return description, delay, true
+}
+
+// connRetryWait waits for the backoff delay before next connection attempt
+// (interrupted by a disconnection request)
+func (s *Service) connRetryWait(delay time.Duration) {
+	if delay <= 0 {
+		return
+	}
+	log.Info(fmt.Sprintf("Waiting %s before next connection attempt...", delay))
+	waitTill := time.Now().Add(delay)
+	for time.Now().Before(waitTill) && s._requiredVpnState == Connect {
+		time.Sleep(time.Millisecond * 10)
+	}
+}
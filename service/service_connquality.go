@@ -0,0 +1,117 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ivpn/desktop-app-daemon/ping"
+)
+
+// Connection quality monitor
+// When enabled (preference 'connection_quality_interval_seconds' > 0), daemon periodically
+// measures RTT and jitter to the VPN gateway inside the active tunnel and notifies clients
+// (lets UIs show live connection quality). Measured latency is also saved into the latency
+// cache in use by the fastest-server selection.
+
+const (
+	// interval of re-checking the preference value while measurement is disabled
+	_connQualityRecheckInterval = time.Second * 5
+	// amount of pings of a single measurement (jitter requires a series)
+	_connQualityPingsCount = 5
+	// interval between pings of a single measurement
+	_connQualityPingsInterval = time.Millisecond * 200
+	// timeout of a single measurement
+	_connQualityTimeout = time.Second * 5
+)
+
+// startConnQualityMonitor starts periodical latency\jitter measurements for the active connection
+// (stops when 'stopChannel' closed; interval changes are applied without reconnection)
+func (s *Service) startConnQualityMonitor(stopChannel <-chan bool) {
+	log.Info("Connection quality monitor started")
+	defer log.Info("Connection quality monitor stopped")
+
+	for {
+		interval := _connQualityRecheckInterval
+		intervalSec := s._preferences.ConnQualityIntervalSec
+		if intervalSec > 0 {
+			interval = time.Second * time.Duration(intervalSec)
+		}
+
+		select {
+		case <-time.After(interval):
+			if intervalSec > 0 {
+				s.measureConnQuality()
+			}
+		case <-stopChannel: // triggered when the stopChannel is closed
+			return
+		}
+	}
+}
+
+// measureConnQuality measures RTT and jitter to the in-tunnel VPN gateway and notifies clients
+func (s *Service) measureConnQuality() {
+	vpnObj := s._vpn
+	if vpnObj == nil || vpnObj.IsPaused() {
+		return
+	}
+	// measuring only fully established connections
+	if s._requiredVpnState != KeepConnection {
+		return
+	}
+
+	gatewayIP := vpnObj.InTunnelGatewayIP()
+	if gatewayIP == nil {
+		return // no information about in-tunnel gateway (nothing to measure)
+	}
+
+	pinger, err := ping.NewPinger(gatewayIP.String())
+	if err != nil {
+		log.Error("Connection quality: pinger creation error: " + err.Error())
+		return
+	}
+	pinger.SetPrivileged(true)
+	pinger.Count = _connQualityPingsCount
+	pinger.Interval = _connQualityPingsInterval
+	pinger.Timeout = _connQualityTimeout
+	pinger.Run()
+
+	stat := pinger.Statistics()
+	if stat.PacketsRecv == 0 {
+		return // tunnel liveness problems are the responsibility of the tunnel watchdog
+	}
+
+	latencyMs := int(stat.AvgRtt / time.Millisecond)
+	jitterMs := int(stat.StdDevRtt / time.Millisecond)
+
+	log.Debug(fmt.Sprintf("Connection quality: latency=%dms jitter=%dms (%d/%d pings)", latencyMs, jitterMs, stat.PacketsRecv, _connQualityPingsCount))
+
+	// update the latency cache in use by the fastest-server selection
+	// (in-tunnel latency of the connected host is at least as accurate as the probed one)
+	if destIP := vpnObj.DestinationIP(); destIP != nil && destIP.IsLoopback() == false {
+		pingCacheSave(destIP.String(), latencyMs)
+	}
+
+	s._evtReceiver.OnConnectionQuality(latencyMs, jitterMs)
+}
@@ -0,0 +1,138 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ivpn/desktop-app-daemon/service/dns"
+	"github.com/ivpn/desktop-app-daemon/service/firewall"
+	"github.com/ivpn/desktop-app-daemon/service/platform"
+	"github.com/ivpn/desktop-app-daemon/service/platform/filerights"
+)
+
+// Crash-safe firewall handoff.
+//
+// While a VPN connection is active the daemon keeps a marker file on disk.
+// The marker is removed on clean teardown. When the daemon starts and the marker
+// still exists - the previous daemon session ended unexpectedly (crash, kill, power loss)
+// while a connection was active. In this case the daemon:
+//   - engages the firewall BEFORE any network cleanup (to not open a leak window);
+//   - reverts leftover DNS configuration;
+//   - relies on 'CleanupNetworkArtifacts()' (called right after the check)
+//     to remove orphaned tunnel interfaces and routes.
+//
+// The connection itself is not restored here: auto-connect on launch (when enabled)
+// or the client application performs a new connection request.
+
+// crashGuardMarker - data persisted while a VPN connection is active
+type crashGuardMarker struct {
+	At time.Time
+	// expected firewall state for the active connection
+	// (used to re-engage the firewall after a crash even when it was not persistent)
+	IsFirewallExpected bool
+}
+
+func crashGuardFilePath() string {
+	// marker is stored in the same folder as the daemon settings file
+	return filepath.Join(filepath.Dir(platform.SettingsFile()), "active-connection.marker")
+}
+
+// crashGuardMarkStarted persists the 'connection is active' marker
+// (must be called when connection establishing starts; see 'keepConnection()')
+func (s *Service) crashGuardMarkStarted(isFirewallExpected bool) {
+	if isFirewallExpected == false {
+		// connection can be requested with already enabled firewall
+		if enabled, err := firewall.GetEnabled(); err == nil {
+			isFirewallExpected = enabled
+		}
+	}
+
+	marker := crashGuardMarker{At: time.Now(), IsFirewallExpected: isFirewallExpected}
+	data, err := json.Marshal(marker)
+	if err != nil {
+		log.Warning("Failed to save connection marker: ", err)
+		return
+	}
+
+	filePath := crashGuardFilePath()
+	if err := ioutil.WriteFile(filePath, data, 0600); err != nil { // read\write only for privileged user
+		log.Warning("Failed to save connection marker: ", err)
+		return
+	}
+	// only for Windows: Golang is not able to change file permissins in Windows style
+	if err := filerights.WindowsChmod(filePath, 0600); err != nil { // read\write only for privileged user
+		log.Warning("Failed to change connection marker file permissions: ", err)
+	}
+}
+
+// crashGuardMarkStopped removes the 'connection is active' marker (clean teardown)
+func (s *Service) crashGuardMarkStopped() {
+	if err := os.Remove(crashGuardFilePath()); err != nil && os.IsNotExist(err) == false {
+		log.Warning("Failed to remove connection marker: ", err)
+	}
+}
+
+// crashGuardStartupCheck detects a daemon session which ended unexpectedly mid-connection
+// and reconciles firewall\DNS state
+// (must be called on daemon initialization BEFORE 'CleanupNetworkArtifacts()')
+func (s *Service) crashGuardStartupCheck() {
+	data, err := ioutil.ReadFile(crashGuardFilePath())
+	if err != nil {
+		if os.IsNotExist(err) == false {
+			log.Warning("Failed to read connection marker: ", err)
+		}
+		return // no marker - previous session finished correctly
+	}
+
+	log.Warning("Previous daemon session ended unexpectedly while a VPN connection was active. Reconciling...")
+
+	var marker crashGuardMarker
+	if err := json.Unmarshal(data, &marker); err != nil {
+		log.Warning("Failed to parse connection marker: ", err)
+		// even when the marker is not readable - the crashed state itself is a fact
+		marker = crashGuardMarker{IsFirewallExpected: s._preferences.IsFwPersistant}
+	}
+
+	// engage the firewall first: network cleanup (route\interface removal) must not
+	// create a time window when traffic goes out unprotected
+	if marker.IsFirewallExpected {
+		if enabled, err := firewall.GetEnabled(); err == nil && enabled == false {
+			log.Info("Enabling firewall (crashed session had it enabled)")
+			if err := firewall.SetEnabled(true); err != nil {
+				log.Error("Failed to enable firewall: ", err)
+			}
+		}
+	}
+
+	// revert DNS configuration leftover from the crashed connection
+	if err := dns.DeleteManual(nil); err != nil {
+		log.Warning("Failed to revert DNS configuration: ", err)
+	}
+
+	s.crashGuardMarkStopped()
+}
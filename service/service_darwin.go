@@ -23,12 +23,78 @@
 package service
 
 import (
+	"fmt"
 	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/ivpn/desktop-app-daemon/service/firewall"
+	"github.com/ivpn/desktop-app-daemon/service/platform"
+	"github.com/ivpn/desktop-app-daemon/shell"
 )
 
 func (s *Service) implIsGoingToPingServers(hosts []net.IP) error {
 	const onlyForICMP = true
 	return firewall.AddHostsToExceptions(hosts, onlyForICMP)
 }
+
+func (s *Service) implDiagnosticNetworkInfo() string {
+	info := diagnosticExec("/usr/sbin/netstat", "-rn")
+	info += diagnosticExec("/usr/sbin/scutil", "--dns")
+	return info
+}
+
+func (s *Service) implDiagnosticFirewallInfo() string {
+	return diagnosticExec("/sbin/pfctl", "-s", "rules")
+}
+
+// implGetTunnelStatistics returns raw byte counters of a network interface (parsed from 'netstat -ibn' output)
+func (s *Service) implGetTunnelStatistics(tunnelInterface *net.Interface) (received int64, sent int64, err error) {
+	const maxOutSize = 1024 * 64
+	outText, _, _, err := shell.ExecAndGetOutput(nil, maxOutSize, "", "/usr/sbin/netstat", "-ibn", "-I", tunnelInterface.Name)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// columns: Name Mtu Network Address Ipkts Ierrs Ibytes Opkts Oerrs Obytes Coll
+	// (counters are taken from the '<Link#X>' line; 'Address' column can be empty - parsing from the end)
+	for _, line := range strings.Split(outText, "\n") {
+		if strings.Contains(line, "<Link#") == false {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 8 || fields[0] != tunnelInterface.Name {
+			continue
+		}
+		received, err = strconv.ParseInt(fields[len(fields)-5], 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to parse netstat output: %w", err)
+		}
+		sent, err = strconv.ParseInt(fields[len(fields)-2], 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to parse netstat output: %w", err)
+		}
+		return received, sent, nil
+	}
+	return 0, 0, fmt.Errorf("interface '%s' not found in netstat output", tunnelInterface.Name)
+}
+
+func (s *Service) implCleanupNetworkArtifacts() error {
+	// kill orphaned 'wireguard-go' processes leftover from a crashed session
+	// (utun interface disappears with the owning process)
+	wgBinaryName := filepath.Base(platform.WgBinaryPath())
+	if err := shell.Exec(log, "/usr/bin/killall", wgBinaryName); err != nil {
+		// non-zero exit code here normally means 'no processes found' (nothing to cleanup)
+		log.Debug("killall ", wgBinaryName, ": ", err)
+	}
+	return nil
+}
+
+func (s *Service) implOnVpnConnected(tunnelInterface *net.Interface) {
+	// nothing to do for macOS implementation
+}
+
+func (s *Service) implOnVpnDisconnected() {
+	// nothing to do for macOS implementation
+}
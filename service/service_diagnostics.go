@@ -0,0 +1,146 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ivpn/desktop-app-daemon/logger"
+	"github.com/ivpn/desktop-app-daemon/shell"
+	"github.com/ivpn/desktop-app-daemon/version"
+)
+
+// Diagnostic bundle generator
+// Assembles a support bundle: recent logs, sanitized connection parameters, routing table,
+// DNS configuration, firewall rules and VPN status - packaged as a single zip archive.
+// Secrets (passwords, session tokens, keys) are redacted.
+
+// maximum size of logs to include into the bundle
+const _diagnosticLogMaxSize = 1024 * 256
+
+// GenerateDiagnosticBundle assembles a support bundle
+// Returns base64 of a zip archive
+func (s *Service) GenerateDiagnosticBundle() (bundleBase64 string, err error) {
+	log.Info("Generating diagnostic bundle...")
+
+	var buff bytes.Buffer
+	archive := zip.NewWriter(&buff)
+
+	addFile := func(name string, content string) {
+		f, err := archive.Create(name)
+		if err != nil {
+			log.Warning(fmt.Sprintf("Failed to add '%s' into diagnostic bundle: %s", name, err))
+			return
+		}
+		f.Write([]byte(content))
+	}
+
+	// daemon info
+	isConnected, vpnType := s.ConnectedType()
+	daemonInfo := fmt.Sprintf("version: %s\ntime: %s\nconnected: %v\n", version.Version(), time.Now().Format(time.RFC3339), isConnected)
+	if isConnected {
+		daemonInfo += fmt.Sprintf("vpn type: %v\npaused: %v\n", vpnType, s.IsPaused())
+	}
+	addFile("daemon_info.txt", daemonInfo)
+
+	// recent logs
+	logText, logText0, _ := logger.GetLogText(_diagnosticLogMaxSize)
+	addFile("service.log", logText)
+	addFile("service.log.0", logText0)
+
+	// sanitized parameters of the last connection
+	addFile("connection_params.json", redactSecrets(s._preferences.LastConnectionParams))
+
+	// connection diagnostics report (present only when the opt-in preference enabled)
+	if report, err := s.ConnectionDiagnosticsReport(); err == nil {
+		addFile("connection_diagnostics.json", report)
+	}
+
+	// routing table + DNS configuration (OS-specific)
+	addFile("network.txt", s.implDiagnosticNetworkInfo())
+
+	// firewall rules dump (OS-specific)
+	addFile("firewall.txt", s.implDiagnosticFirewallInfo())
+
+	if err := archive.Close(); err != nil {
+		return "", fmt.Errorf("failed to create diagnostic bundle: %w", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(buff.Bytes()), nil
+}
+
+// redactSecrets replaces values of sensitive fields of a JSON document by '***'
+func redactSecrets(dataJSON string) string {
+	if len(dataJSON) == 0 {
+		return ""
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(dataJSON), &data); err != nil {
+		// unable to parse document - do not risk exposing secrets
+		return "<<< unable to parse data >>>"
+	}
+
+	redactObject(data)
+
+	redacted, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return "<<< unable to serialize data >>>"
+	}
+	return string(redacted)
+}
+
+// redactObject recursively replaces values of sensitive fields by '***'
+func redactObject(data map[string]interface{}) {
+	for key, value := range data {
+		if child, ok := value.(map[string]interface{}); ok {
+			redactObject(child)
+			continue
+		}
+
+		keyLower := strings.ToLower(key)
+		for _, sensitive := range []string{"password", "username", "session", "secret", "token", "key"} {
+			if strings.Contains(keyLower, sensitive) {
+				data[key] = "***"
+				break
+			}
+		}
+	}
+}
+
+// diagnosticExec executes a command and returns its output (for diagnostic bundle)
+func diagnosticExec(name string, args ...string) string {
+	header := fmt.Sprintf("$ %s %s\n", name, strings.Join(args, " "))
+
+	outText, outErrText, _, err := shell.ExecAndGetOutput(log, _diagnosticLogMaxSize, "", name, args...)
+	if err != nil {
+		return fmt.Sprintf("%s<<< failed to execute: %s >>>\n%s%s\n", header, err, outText, outErrText)
+	}
+	return fmt.Sprintf("%s%s%s\n", header, outText, outErrText)
+}
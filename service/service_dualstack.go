@@ -0,0 +1,72 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"fmt"
+	"net"
+)
+
+// Dual-stack endpoint support
+// When connection is requested over an IPv6 endpoint of the server (see 'IsIPv6Endpoints' preference),
+// the IPv4 endpoint of same server is remembered as a fallback: if the first connection attempt
+// over IPv6 fails - the connection is retried over IPv4 before surfacing failure to a client.
+
+// SetConnectionIPv4FallbackHost defines the IPv4 endpoint to fall back to when connection
+// over an IPv6 endpoint fails (nil - connecting over IPv4, no fallback applicable).
+// Must be called on start of each new connection request (before connection establishing)
+func (s *Service) SetConnectionIPv4FallbackHost(host net.IP) {
+	s._ipv6FallbackMutex.Lock()
+	defer s._ipv6FallbackMutex.Unlock()
+
+	s._ipv6FallbackHost = host
+	s._ipv6FallbackOverride = nil
+}
+
+// ipv6FallbackNext activates fallback to the IPv4 endpoint (when a fallback host is defined).
+// Returns description of the retry (to notify clients) and 'ok==true' when fallback activated
+func (s *Service) ipv6FallbackNext() (description string, ok bool) {
+	s._ipv6FallbackMutex.Lock()
+	defer s._ipv6FallbackMutex.Unlock()
+
+	if s._ipv6FallbackHost == nil {
+		return "", false
+	}
+
+	s._ipv6FallbackOverride = s._ipv6FallbackHost
+	s._ipv6FallbackHost = nil // fallback can be performed only once per connection request
+
+	return fmt.Sprintf("IPv6 endpoint is not reachable. Retrying with the IPv4 endpoint: %s", s._ipv6FallbackOverride), true
+}
+
+// ipv6FallbackOverride returns the IPv4 endpoint to connect to
+// ('ok==false' when no fallback in progress)
+func (s *Service) ipv6FallbackOverride() (host net.IP, ok bool) {
+	s._ipv6FallbackMutex.Lock()
+	defer s._ipv6FallbackMutex.Unlock()
+
+	if s._ipv6FallbackOverride == nil {
+		return nil, false
+	}
+	return s._ipv6FallbackOverride, true
+}
@@ -0,0 +1,166 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"fmt"
+	"net"
+
+	apitypes "github.com/ivpn/desktop-app-daemon/api/types"
+)
+
+// Fastest server auto-selection
+// Daemon-side logic of choosing the best server for a connection ('Connect' request with 'FastestServer'==true).
+// Score of a server combines:
+//	- measured latency (cached results of latency probing; see 'PingServersParallel')
+//	- current server load (from servers.json)
+//	- historical connection success rate of this daemon instance
+
+// latency value used for hosts which have no measured latency
+const _fastestDefaultLatencyMs = 500
+
+// recordConnectionAttempt updates connection history: connection to a host started
+func (s *Service) recordConnectionAttempt(hostIP net.IP) {
+	if hostIP == nil {
+		return
+	}
+	s._connHistoryMutex.Lock()
+	defer s._connHistoryMutex.Unlock()
+	if s._connHistory == nil {
+		s._connHistory = make(map[string]*connectionHistory)
+	}
+	h, ok := s._connHistory[hostIP.String()]
+	if ok == false {
+		h = &connectionHistory{}
+		s._connHistory[hostIP.String()] = h
+	}
+	h.attempts++
+}
+
+// recordConnectionSuccess updates connection history: connection to a host established
+func (s *Service) recordConnectionSuccess(hostIP net.IP) {
+	if hostIP == nil {
+		return
+	}
+	s._connHistoryMutex.Lock()
+	defer s._connHistoryMutex.Unlock()
+	h, ok := s._connHistory[hostIP.String()]
+	if ok == false {
+		return
+	}
+	h.successes++
+}
+
+// connectionSuccessRate returns success rate [0.1 - 1.0] of connections to a host
+// (1.0 - when no history available for the host)
+func (s *Service) connectionSuccessRate(host string) float64 {
+	s._connHistoryMutex.Lock()
+	defer s._connHistoryMutex.Unlock()
+
+	h, ok := s._connHistory[host]
+	if ok == false || h.attempts == 0 {
+		return 1.0
+	}
+
+	rate := float64(h.successes) / float64(h.attempts)
+	if rate < 0.1 {
+		// do not exclude host completely: it still has a chance to be selected
+		rate = 0.1
+	}
+	return rate
+}
+
+// fastestHostScore calculates score of a host (lower - better)
+func (s *Service) fastestHostScore(host string, load float32, latencyCache map[string]int) float64 {
+	latencyMs := _fastestDefaultLatencyMs
+	if l, ok := latencyCache[host]; ok && l > 0 {
+		latencyMs = l
+	}
+	return float64(latencyMs) * (1.0 + float64(load)/100.0) / s.connectionSuccessRate(host)
+}
+
+// FastestWireGuardServer returns the best WireGuard server for a connection
+// (nil - when selection is not possible)
+func (s *Service) FastestWireGuardServer() *apitypes.WireGuardServerInfo {
+	servers, err := s.ServersList()
+	if err != nil || servers == nil {
+		log.Warning("Fastest server selection failed: servers list not available")
+		return nil
+	}
+
+	latencyCache := pingCacheGet()
+
+	var best *apitypes.WireGuardServerInfo
+	bestScore := 0.0
+	for i := range servers.WireguardServers {
+		srv := &servers.WireguardServers[i]
+		if s.isServerExcludedFromAutoSelect(srv.Gateway) {
+			continue
+		}
+		for _, host := range srv.Hosts {
+			score := s.fastestHostScore(host.Host, srv.Load, latencyCache)
+			if best == nil || score < bestScore {
+				best = srv
+				bestScore = score
+			}
+		}
+	}
+
+	if best != nil {
+		log.Info(fmt.Sprintf("Fastest server: %s (score %.1f)", best.Gateway, bestScore))
+	}
+	return best
+}
+
+// FastestOpenvpnServer returns the best OpenVPN server for a connection
+// (nil - when selection is not possible)
+func (s *Service) FastestOpenvpnServer() *apitypes.OpenvpnServerInfo {
+	servers, err := s.ServersList()
+	if err != nil || servers == nil {
+		log.Warning("Fastest server selection failed: servers list not available")
+		return nil
+	}
+
+	latencyCache := pingCacheGet()
+
+	var best *apitypes.OpenvpnServerInfo
+	bestScore := 0.0
+	for i := range servers.OpenvpnServers {
+		srv := &servers.OpenvpnServers[i]
+		if s.isServerExcludedFromAutoSelect(srv.Gateway) {
+			continue
+		}
+		for _, host := range srv.IPAddresses {
+			score := s.fastestHostScore(host, srv.Load, latencyCache)
+			if best == nil || score < bestScore {
+				best = srv
+				bestScore = score
+			}
+		}
+	}
+
+	if best != nil {
+		log.Info(fmt.Sprintf("Fastest server: %s (score %.1f)", best.Gateway, bestScore))
+	}
+	return best
+}
@@ -0,0 +1,66 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"time"
+
+	"github.com/ivpn/desktop-app-daemon/service/firewall"
+)
+
+// interval of the firewall rules verification
+// (how often to check that the rules were not erased by a third-party tool)
+const firewallWatchdogCheckInterval = time.Second * 30
+
+// startFirewallWatchdog starts background verification of the firewall rules:
+// when the rules are not applied anymore (e.g. flushed by a third-party tool) -
+// they will be restored and all clients notified about tampering detection
+func (s *Service) startFirewallWatchdog() {
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Error("PANIC in firewall watchdog (recovered): ", r)
+			}
+		}()
+
+		for {
+			time.Sleep(firewallWatchdogCheckInterval)
+
+			wasTampered, err := firewall.RestoreIfTampered()
+			if wasTampered == false {
+				if err != nil {
+					log.Warning("Firewall watchdog: ", err)
+				}
+				continue
+			}
+
+			if err != nil {
+				log.Error("Firewall tampering detected. Failed to restore firewall rules: ", err)
+			} else {
+				log.Info("Firewall tampering detected. Firewall rules were restored")
+			}
+
+			s._evtReceiver.OnFirewallTampered(err == nil)
+		}
+	}()
+}
@@ -0,0 +1,95 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ivpn/desktop-app-daemon/geoip"
+	"github.com/ivpn/desktop-app-daemon/service/platform"
+	"github.com/ivpn/desktop-app-daemon/service/platform/filerights"
+)
+
+// Local GeoIP dataset (see 'geoip' package)
+// Dataset is distributed through the update host and cached next to the settings file;
+// it allows to annotate ping results and custom imported servers with country\region
+// locally, without extra geo-lookup API calls.
+
+const (
+	_geoipFileName = "geoip.json"
+	// interval of the background GeoIP dataset update
+	_geoipUpdateInterval = time.Hour * 24
+)
+
+// geoipFilePath returns path to the cached GeoIP dataset file (next to the settings file)
+func geoipFilePath() string {
+	return filepath.Join(filepath.Dir(platform.SettingsFile()), _geoipFileName)
+}
+
+// geoipInit loads cached GeoIP dataset and starts its background updater
+func (s *Service) geoipInit() {
+	if err := geoip.LoadFromFile(geoipFilePath()); err != nil {
+		if errors.Is(err, os.ErrNotExist) == false {
+			log.Warning("Failed to load cached GeoIP dataset: ", err)
+		}
+	}
+
+	go func() {
+		isFirstIteration := true
+		for {
+			updateDelay := _geoipUpdateInterval
+			if err := s.geoipUpdate(); err != nil {
+				log.Warning("Failed to update GeoIP dataset: ", err)
+				if isFirstIteration {
+					// the first try can fail when daemon is starting on OS boot
+					// (connectivity may be not initialized yet); retrying in 5 minutes
+					updateDelay = time.Minute * 5
+				}
+			}
+			isFirstIteration = false
+			time.Sleep(updateDelay)
+		}
+	}()
+}
+
+// geoipUpdate downloads GeoIP dataset from the update host and saves it into the cache file
+func (s *Service) geoipUpdate() error {
+	data, err := s._api.DownloadGeoIPDataset()
+	if err != nil {
+		return err
+	}
+
+	// parse before saving: corrupted data must not replace a good cache
+	if err := geoip.Load(data); err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(geoipFilePath(), data, filerights.DefaultFilePermissionsForConfig()); err != nil {
+		log.Warning("Failed to save GeoIP dataset cache: ", err)
+	}
+	return nil
+}
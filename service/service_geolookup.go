@@ -0,0 +1,76 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"fmt"
+	"net"
+
+	apitypes "github.com/ivpn/desktop-app-daemon/api/types"
+)
+
+// GeoLookupCompare performs two geo-lookups: through the VPN tunnel and outside of it
+// (e.g. enables 'real vs VPN location' functionality and traffic leak detection on client side)
+// When VPN not connected - only the out-of-tunnel lookup is performed
+func (s *Service) GeoLookupCompare(timeoutMs int) (inTunnel, outOfTunnel *apitypes.GeoLookupResponse, inTunnelErr, outOfTunnelErr error) {
+	vpnObj := s._vpn
+	if vpnObj == nil || s.Connected() == false {
+		inTunnelErr = fmt.Errorf("VPN not connected")
+		outOfTunnel, outOfTunnelErr = s._api.GeoLookup(timeoutMs)
+		return inTunnel, outOfTunnel, inTunnelErr, outOfTunnelErr
+	}
+
+	// when connected - default routing goes through the tunnel
+	inTunnel, inTunnelErr = s._api.GeoLookup(timeoutMs)
+
+	// out-of-tunnel lookup: bind socket to IP address of the physical interface
+	physicalIP, err := physicalInterfaceIP(vpnObj.DestinationIP())
+	if err != nil {
+		outOfTunnelErr = fmt.Errorf("unable to determine IP of physical interface: %w", err)
+		return inTunnel, outOfTunnel, inTunnelErr, outOfTunnelErr
+	}
+	outOfTunnel, outOfTunnelErr = s._api.GeoLookupBound(timeoutMs, physicalIP)
+
+	return inTunnel, outOfTunnel, inTunnelErr, outOfTunnelErr
+}
+
+// physicalInterfaceIP returns local IP address of the interface which is in use to communicate with the VPN server
+// (when connected, the route to the VPN server itself always goes through the physical interface)
+func physicalInterfaceIP(vpnServerIP net.IP) (net.IP, error) {
+	if vpnServerIP == nil {
+		return nil, fmt.Errorf("VPN server IP not defined")
+	}
+
+	// no real traffic sent here: UDP 'connect' only selects the route
+	conn, err := net.Dial("udp", vpnServerIP.String()+":53")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	localAddr, ok := conn.LocalAddr().(*net.UDPAddr)
+	if ok == false {
+		return nil, fmt.Errorf("unexpected local address type")
+	}
+	return localAddr.IP, nil
+}
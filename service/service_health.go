@@ -0,0 +1,72 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"time"
+
+	protocoltypes "github.com/ivpn/desktop-app-daemon/protocol/types"
+	"github.com/ivpn/desktop-app-daemon/service/firewall"
+	"github.com/ivpn/desktop-app-daemon/service/platform"
+	"github.com/ivpn/desktop-app-daemon/service/platform/filerights"
+)
+
+// time when the daemon process started (used by health-check functionality)
+var daemonStartTime = time.Now()
+
+// DaemonHealth returns daemon uptime and health of internal subsystems
+// (lightweight liveness probe: no network requests performed;
+// intended for installers, scripts and 'daemon not responding' detection by UI)
+func (s *Service) DaemonHealth() protocoltypes.DaemonHealthResp {
+	health := protocoltypes.DaemonHealthResp{
+		UptimeSec:              int64(time.Since(daemonStartTime).Seconds()),
+		SecondsSinceAPISuccess: -1,
+	}
+
+	// firewall engine: operational when it responds to a state request
+	if _, err := firewall.GetEnabled(); err != nil {
+		health.FirewallError = err.Error()
+	} else {
+		health.IsFirewallOperational = true
+	}
+
+	// VPN backends: binaries are present and have correct access rights
+	if err := filerights.CheckFileAccessRightsExecutable(platform.OpenVpnBinaryPath()); err != nil {
+		health.OpenVPNError = err.Error()
+	} else {
+		health.IsOpenVPNAvailable = true
+	}
+	if err := filerights.CheckFileAccessRightsExecutable(platform.WgBinaryPath()); err != nil {
+		health.WireGuardError = err.Error()
+	} else {
+		health.IsWireGuardAvailable = true
+	}
+
+	// API reachability: based on the last successful API request
+	// (no request performed here to keep the command lightweight)
+	if lastSuccess := s._api.LastSuccessfulRequestTime(); lastSuccess.IsZero() == false {
+		health.SecondsSinceAPISuccess = int64(time.Since(lastSuccess).Seconds())
+	}
+
+	return health
+}
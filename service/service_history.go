@@ -0,0 +1,232 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	protocoltypes "github.com/ivpn/desktop-app-daemon/protocol/types"
+	"github.com/ivpn/desktop-app-daemon/service/platform"
+	"github.com/ivpn/desktop-app-daemon/service/platform/filerights"
+	"github.com/ivpn/desktop-app-daemon/vpn"
+)
+
+// Local connection history
+// When enabled (preference 'connection_history_enabled'), daemon records info about VPN sessions
+// into an append-only file (one JSON record per line): connect\disconnect times, server, transferred
+// bytes and failure reason. History never leaves the device and is disabled by default.
+// Retention is limited by 'connection_history_max_records' preference.
+
+const (
+	_historyFileName          = "connection-history.json"
+	_historyDefaultMaxRecords = 100
+)
+
+// historyFilePath returns path to the connection history file (next to the settings file)
+func historyFilePath() string {
+	return filepath.Join(filepath.Dir(platform.SettingsFile()), _historyFileName)
+}
+
+// historyMaxRecords returns retention limit of the connection history
+func (s *Service) historyMaxRecords() int {
+	if s._preferences.ConnectionHistoryMaxRecords > 0 {
+		return s._preferences.ConnectionHistoryMaxRecords
+	}
+	return _historyDefaultMaxRecords
+}
+
+// historyOnConnectStarted opens a new history record (connection to a server started)
+func (s *Service) historyOnConnectStarted(serverIP net.IP, vpnType vpn.Type) {
+	if s._preferences.IsConnectionHistoryEnabled == false {
+		return
+	}
+
+	server := ""
+	if serverIP != nil {
+		server = serverIP.String()
+	}
+
+	s._historyMutex.Lock()
+	defer s._historyMutex.Unlock()
+	s._historyActiveRecord = &protocoltypes.ConnectionHistoryRecord{
+		StartedAt: time.Now().Unix(),
+		Server:    server,
+		VpnType:   vpnType.String()}
+}
+
+// historyOnConnected updates active history record (connection established)
+func (s *Service) historyOnConnected() {
+	s._historyMutex.Lock()
+	defer s._historyMutex.Unlock()
+	if s._historyActiveRecord != nil {
+		s._historyActiveRecord.ConnectedAt = time.Now().Unix()
+	}
+}
+
+// historySetSessionBytes saves bytes transferred during the session into active history record
+func (s *Service) historySetSessionBytes(received int64, sent int64) {
+	s._historyMutex.Lock()
+	defer s._historyMutex.Unlock()
+	if s._historyActiveRecord != nil {
+		s._historyActiveRecord.BytesReceived = received
+		s._historyActiveRecord.BytesSent = sent
+	}
+}
+
+// historyOnStopped closes active history record and saves it into the history file
+func (s *Service) historyOnStopped() {
+	s._historyMutex.Lock()
+	record := s._historyActiveRecord
+	s._historyActiveRecord = nil
+	s._historyMutex.Unlock()
+
+	if record == nil {
+		return
+	}
+
+	record.DisconnectedAt = time.Now().Unix()
+	if record.ConnectedAt == 0 {
+		record.FailureReason = "connection failed"
+	}
+
+	if err := s.historyAppendRecord(record); err != nil {
+		log.Warning("Failed to save connection history record: ", err)
+	}
+}
+
+// historyAppendRecord appends a record into the history file (with retention applied)
+func (s *Service) historyAppendRecord(record *protocoltypes.ConnectionHistoryRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	s._historyMutex.Lock()
+	defer s._historyMutex.Unlock()
+
+	filePath := historyFilePath()
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600) // read\write only for privileged user
+	if err != nil {
+		return err
+	}
+	if _, err := file.WriteString(string(data) + "\n"); err != nil {
+		file.Close()
+		return err
+	}
+	file.Close()
+
+	// only for Windows: Golang is not able to change file permissins in Windows style
+	if err := filerights.WindowsChmod(filePath, 0600); err != nil { // read\write only for privileged user
+		return fmt.Errorf("failed to change history-file permissions: %w", err)
+	}
+
+	// apply retention limit
+	records, err := historyLoadRecords()
+	if err != nil {
+		return err
+	}
+	maxRecords := s.historyMaxRecords()
+	if len(records) > maxRecords {
+		records = records[len(records)-maxRecords:]
+		lines := make([]string, 0, len(records))
+		for _, r := range records {
+			data, err := json.Marshal(r)
+			if err != nil {
+				continue
+			}
+			lines = append(lines, string(data))
+		}
+		if err := ioutil.WriteFile(filePath, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// historyLoadRecords reads all records from the history file (oldest first)
+func historyLoadRecords() ([]protocoltypes.ConnectionHistoryRecord, error) {
+	data, err := ioutil.ReadFile(historyFilePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var records []protocoltypes.ConnectionHistoryRecord
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		var record protocoltypes.ConnectionHistoryRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			continue // skip corrupted lines
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// ConnectionHistory returns last 'maxRecords' records of the connection history (newest first)
+func (s *Service) ConnectionHistory(maxRecords int) ([]protocoltypes.ConnectionHistoryRecord, error) {
+	s._historyMutex.Lock()
+	defer s._historyMutex.Unlock()
+
+	records, err := historyLoadRecords()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read connection history: %w", err)
+	}
+
+	if maxRecords <= 0 || maxRecords > s.historyMaxRecords() {
+		maxRecords = s.historyMaxRecords()
+	}
+	if len(records) > maxRecords {
+		records = records[len(records)-maxRecords:]
+	}
+
+	// newest first
+	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+		records[i], records[j] = records[j], records[i]
+	}
+	return records, nil
+}
+
+// ClearConnectionHistory removes all saved connection history records
+func (s *Service) ClearConnectionHistory() error {
+	s._historyMutex.Lock()
+	defer s._historyMutex.Unlock()
+
+	if err := os.Remove(historyFilePath()); err != nil && os.IsNotExist(err) == false {
+		return fmt.Errorf("failed to remove connection history: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/ivpn/desktop-app-daemon/mdns"
+	protocoltypes "github.com/ivpn/desktop-app-daemon/protocol/types"
+	"github.com/ivpn/desktop-app-daemon/service/firewall"
+)
+
+// Background LAN services allowlist (printers, casting devices)
+// When discovery is enabled (preference 'lan_services_discovery'), daemon is able to identify
+// LAN services over mDNS\Bonjour (see 'mdns' package); devices allowed by the user get
+// narrowly scoped firewall exceptions (single IP) while the kill switch is on.
+// Exceptions are applied on each connection (they are erased by the firewall on disconnect).
+
+// timeout of one mDNS discovery session
+const _lanDiscoveryTimeout = time.Second * 3
+
+// LanServicesDiscover discovers background LAN services over mDNS
+// (returns devices with their current allowed status)
+func (s *Service) LanServicesDiscover() ([]protocoltypes.LanServiceDeviceInfo, error) {
+	if s._preferences.IsLanServicesDiscovery == false {
+		return nil, fmt.Errorf("LAN services discovery disabled (see preference 'lan_services_discovery')")
+	}
+
+	devices, err := mdns.Discover(_lanDiscoveryTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("LAN services discovery failed: %w", err)
+	}
+
+	allowed := map[string]struct{}{}
+	for _, ip := range s._preferences.LanAllowedDevices {
+		allowed[ip] = struct{}{}
+	}
+
+	result := make([]protocoltypes.LanServiceDeviceInfo, 0, len(devices))
+	for _, device := range devices {
+		_, isAllowed := allowed[device.IP.String()]
+		result = append(result, protocoltypes.LanServiceDeviceInfo{
+			Name:      device.Name,
+			Kind:      device.Kind,
+			IP:        device.IP.String(),
+			IsAllowed: isAllowed})
+	}
+	return result, nil
+}
+
+// LanServiceSetAllowed allows\disallows communication with a LAN device while kill switch is on
+// Note: disallowing takes effect on the next connection (applied exceptions are erased on disconnect)
+func (s *Service) LanServiceSetAllowed(ipStr string, allowed bool) error {
+	ip := net.ParseIP(ipStr)
+	if ip == nil || ip.To4() == nil || isLanIP(ip) == false {
+		return fmt.Errorf("acceptable value is an IPv4 address of a LAN device")
+	}
+	ipStr = ip.String()
+
+	prefs := s._preferences
+	devices := make([]string, 0, len(prefs.LanAllowedDevices)+1)
+	for _, known := range prefs.LanAllowedDevices {
+		if known != ipStr {
+			devices = append(devices, known)
+		}
+	}
+	if allowed {
+		devices = append(devices, ipStr)
+	}
+	prefs.LanAllowedDevices = devices
+	s.setPreferences(prefs)
+
+	log.Info(fmt.Sprintf("LAN device %s: allowed=%v", ipStr, allowed))
+
+	// apply immediately for active connection
+	if allowed {
+		if enabled, err := firewall.GetEnabled(); err == nil && enabled {
+			return firewall.AddHostsToExceptions([]net.IP{ip}, false)
+		}
+	}
+	return nil
+}
+
+// lanServicesApplyAllowed applies firewall exceptions for user-allowed LAN devices
+// (called when a connection established)
+func (s *Service) lanServicesApplyAllowed() {
+	if s._preferences.IsLanServicesDiscovery == false || len(s._preferences.LanAllowedDevices) == 0 {
+		return
+	}
+
+	ips := make([]net.IP, 0, len(s._preferences.LanAllowedDevices))
+	for _, ipStr := range s._preferences.LanAllowedDevices {
+		if ip := net.ParseIP(ipStr); ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+	if len(ips) == 0 {
+		return
+	}
+
+	if err := firewall.AddHostsToExceptions(ips, false); err != nil {
+		log.Warning("Failed to apply exceptions for allowed LAN devices: ", err)
+	}
+}
+
+// isLanIP returns true for private-range IPv4 addresses (RFC 1918 + link-local)
+func isLanIP(ip net.IP) bool {
+	for _, cidr := range []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "169.254.0.0/16"} {
+		_, subnet, _ := net.ParseCIDR(cidr)
+		if subnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
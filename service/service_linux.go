@@ -23,12 +23,81 @@
 package service
 
 import (
+	"fmt"
+	"io/ioutil"
 	"net"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/ivpn/desktop-app-daemon/service/firewall"
+	"github.com/ivpn/desktop-app-daemon/service/platform"
+	"github.com/ivpn/desktop-app-daemon/shell"
 )
 
 func (s *Service) implIsGoingToPingServers(hosts []net.IP) error {
 	const onlyForICMP = true
 	return firewall.AddHostsToExceptions(hosts, onlyForICMP)
 }
+
+func (s *Service) implDiagnosticNetworkInfo() string {
+	info := diagnosticExec("ip", "route")
+	info += diagnosticExec("ip", "addr")
+
+	resolvConf, err := ioutil.ReadFile("/etc/resolv.conf")
+	if err != nil {
+		info += fmt.Sprintf("<<< failed to read /etc/resolv.conf: %s >>>\n", err)
+	} else {
+		info += "/etc/resolv.conf:\n" + string(resolvConf) + "\n"
+	}
+	return info
+}
+
+func (s *Service) implDiagnosticFirewallInfo() string {
+	return diagnosticExec("iptables", "-S")
+}
+
+// implGetTunnelStatistics returns raw byte counters of a network interface (from /sys/class/net)
+func (s *Service) implGetTunnelStatistics(tunnelInterface *net.Interface) (received int64, sent int64, err error) {
+	readCounter := func(counterName string) (int64, error) {
+		data, err := ioutil.ReadFile(filepath.Join("/sys/class/net", tunnelInterface.Name, "statistics", counterName))
+		if err != nil {
+			return 0, err
+		}
+		return strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	}
+
+	if received, err = readCounter("rx_bytes"); err != nil {
+		return 0, 0, err
+	}
+	if sent, err = readCounter("tx_bytes"); err != nil {
+		return 0, 0, err
+	}
+	return received, sent, nil
+}
+
+func (s *Service) implCleanupNetworkArtifacts() error {
+	// remove orphaned WireGuard interface leftover from a crashed session (e.g. 'wgivpn')
+	wgInterfaceName := filepath.Base(platform.WGConfigFilePath())
+	wgInterfaceName = strings.TrimSuffix(wgInterfaceName, path.Ext(wgInterfaceName))
+
+	if i, _ := net.InterfaceByName(wgInterfaceName); i != nil {
+		log.Info(fmt.Sprintf("Removing orphaned WireGuard interface '%s'...", wgInterfaceName))
+		if err := shell.Exec(log, "ip", "link", "set", "down", wgInterfaceName); err != nil {
+			log.Warning(err)
+		}
+		if err := shell.Exec(log, "ip", "link", "delete", wgInterfaceName); err != nil {
+			return fmt.Errorf("failed to remove orphaned WireGuard interface: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *Service) implOnVpnConnected(tunnelInterface *net.Interface) {
+	// nothing to do for Linux implementation
+}
+
+func (s *Service) implOnVpnDisconnected() {
+	// nothing to do for Linux implementation
+}
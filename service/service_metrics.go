@@ -0,0 +1,144 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Metrics endpoint
+// When enabled (preference 'metrics_port' > 0), daemon listens on localhost and serves
+// daemon health info in Prometheus text format (path '/metrics'):
+// connection state, uptime, count of established connections, API latency, firewall status.
+// Listener is bound to the loopback interface only (metrics are not exposed to the network).
+
+// metrics listener accepts local connections only
+const _metricsListenHost = "127.0.0.1"
+
+// time of the daemon start (used to report daemon uptime)
+var _daemonStartTime = time.Now()
+
+// applyMetricsSettings starts\stops metrics listener according to 'MetricsPort' preference
+func (s *Service) applyMetricsSettings() {
+	s._metricsMutex.Lock()
+	defer s._metricsMutex.Unlock()
+
+	// stop currently running listener (if exists)
+	if s._metricsServer != nil {
+		if err := s._metricsServer.Close(); err != nil {
+			log.Error("Failed to stop metrics listener: ", err)
+		}
+		s._metricsServer = nil
+	}
+
+	port := s._preferences.MetricsPort
+	if port <= 0 {
+		return // metrics functionality disabled
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", s.serveMetrics)
+	server := &http.Server{Addr: fmt.Sprintf("%s:%d", _metricsListenHost, port), Handler: mux}
+	s._metricsServer = server
+
+	go func() {
+		log.Info(fmt.Sprintf("Metrics endpoint started: http://%s/metrics", server.Addr))
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Error("Metrics listener error: ", err)
+		}
+	}()
+}
+
+// serveMetrics writes current daemon health info in Prometheus text format
+func (s *Service) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	s._metricsMutex.Lock()
+	connectedSince := s._metricsConnectedSince
+	connectsTotal := s._metricsConnectsTotal
+	s._metricsMutex.Unlock()
+
+	isConnected := 0
+	connectionUptime := float64(0)
+	if connectedSince.IsZero() == false {
+		isConnected = 1
+		connectionUptime = time.Since(connectedSince).Seconds()
+	}
+
+	isFirewallEnabled := 0
+	if enabled, err := s.FirewallEnabled(); err == nil && enabled {
+		isFirewallEnabled = 1
+	}
+
+	isPaused := 0
+	if s.IsPaused() {
+		isPaused = 1
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	fmt.Fprintln(w, "# HELP ivpn_daemon_uptime_seconds Time since the daemon start")
+	fmt.Fprintln(w, "# TYPE ivpn_daemon_uptime_seconds gauge")
+	fmt.Fprintf(w, "ivpn_daemon_uptime_seconds %f\n", time.Since(_daemonStartTime).Seconds())
+
+	fmt.Fprintln(w, "# HELP ivpn_connected VPN connection state (1 - connected)")
+	fmt.Fprintln(w, "# TYPE ivpn_connected gauge")
+	fmt.Fprintf(w, "ivpn_connected %d\n", isConnected)
+
+	fmt.Fprintln(w, "# HELP ivpn_connection_uptime_seconds Time since the VPN connection was established (0 - not connected)")
+	fmt.Fprintln(w, "# TYPE ivpn_connection_uptime_seconds gauge")
+	fmt.Fprintf(w, "ivpn_connection_uptime_seconds %f\n", connectionUptime)
+
+	fmt.Fprintln(w, "# HELP ivpn_connections_total Count of established VPN connections since the daemon start (including reconnections)")
+	fmt.Fprintln(w, "# TYPE ivpn_connections_total counter")
+	fmt.Fprintf(w, "ivpn_connections_total %d\n", connectsTotal)
+
+	fmt.Fprintln(w, "# HELP ivpn_paused VPN connection paused state (1 - paused)")
+	fmt.Fprintln(w, "# TYPE ivpn_paused gauge")
+	fmt.Fprintf(w, "ivpn_paused %d\n", isPaused)
+
+	fmt.Fprintln(w, "# HELP ivpn_firewall_enabled Kill-switch state (1 - enabled)")
+	fmt.Fprintln(w, "# TYPE ivpn_firewall_enabled gauge")
+	fmt.Fprintf(w, "ivpn_firewall_enabled %d\n", isFirewallEnabled)
+
+	fmt.Fprintln(w, "# HELP ivpn_api_last_request_duration_seconds Duration of the last successful API request (0 - no requests performed yet)")
+	fmt.Fprintln(w, "# TYPE ivpn_api_last_request_duration_seconds gauge")
+	fmt.Fprintf(w, "ivpn_api_last_request_duration_seconds %f\n", s._api.LastRequestDuration().Seconds())
+}
+
+// metricsOnConnected has to be called when VPN connection established
+func (s *Service) metricsOnConnected() {
+	s._metricsMutex.Lock()
+	defer s._metricsMutex.Unlock()
+	if s._metricsConnectedSince.IsZero() {
+		s._metricsConnectedSince = time.Now()
+	}
+	s._metricsConnectsTotal++
+}
+
+// metricsOnDisconnected has to be called when VPN process stopped
+func (s *Service) metricsOnDisconnected() {
+	s._metricsMutex.Lock()
+	defer s._metricsMutex.Unlock()
+	s._metricsConnectedSince = time.Time{}
+}
@@ -20,6 +20,7 @@
 //  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
 //
 
+//go:build !fastping
 // +build !fastping
 
 package service
@@ -77,6 +78,9 @@ func (s *Service) PingServers(retryCount int, timeoutMs int) (map[string]int, er
 
 	result := make(map[string]int)
 
+	// unique ID of this scan instance (to correlate progress events)
+	progressOpID := fmt.Sprintf("latency_scan-%x", time.Now().UnixNano())
+
 	funcPingIteration := func(onePingTimeoutMs int, timeout *time.Time) map[string]int {
 
 		retMap := make(map[string]int)
@@ -121,6 +125,11 @@ func (s *Service) PingServers(retryCount int, timeoutMs int) (map[string]int, er
 				// periodically notify ping results when pinging in background
 				s._evtReceiver.OnPingStatus(retMap)
 			}
+
+			if len(hosts) > 0 && i%10 == 0 {
+				// periodically notify progress of the scan
+				s._evtReceiver.OnOperationProgress(progressOpID, "latency_scan", "pinging", i*100/len(hosts))
+			}
 		}
 
 		log.Info(fmt.Sprintf("Pinged %d of %d servers (%d successfully, timeout=%d)", i, len(hosts), len(retMap), onePingTimeoutMs))
@@ -165,6 +174,7 @@ func (s *Service) PingServers(retryCount int, timeoutMs int) (map[string]int, er
 			}
 		}
 		s._evtReceiver.OnPingStatus(result)
+		s._evtReceiver.OnOperationProgress(progressOpID, "latency_scan", "done", 100)
 	}()
 
 	// Return first ping result
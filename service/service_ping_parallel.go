@@ -0,0 +1,281 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/ivpn/desktop-app-daemon/ping"
+)
+
+// Parallel latency probing
+// Measures latency to all servers concurrently (with limited count of parallel probes).
+// Supports ICMP probes and UDP-port probes (for networks where ICMP is blocked).
+// Results are cached and streamed to clients incrementally (by 'PingServersResp' notifications)
+// instead of a single blocking bulk response.
+
+const (
+	// maximum count of parallel probes
+	_pingParallelism = 16
+	// validity period of cached latency results
+	_pingCacheTTL = time.Minute * 5
+	// count of finished probes to notify clients about intermediate results
+	_pingStreamBatchSize = 10
+	// timeout of one UDP probe
+	_pingUDPProbeTimeout = time.Second * 2
+
+	// probe types
+	PingProbeICMP = "icmp"
+	PingProbeUDP  = "udp"
+)
+
+type pingCacheEntry struct {
+	latencyMs int
+	updated   time.Time
+}
+
+var (
+	_pingCacheMutex sync.Mutex
+	_pingCache      = map[string]pingCacheEntry{}
+)
+
+// pingCacheGet returns all cached results which are still valid
+func pingCacheGet() map[string]int {
+	_pingCacheMutex.Lock()
+	defer _pingCacheMutex.Unlock()
+
+	ret := make(map[string]int, len(_pingCache))
+	for host, entry := range _pingCache {
+		if time.Since(entry.updated) <= _pingCacheTTL {
+			ret[host] = entry.latencyMs
+		}
+	}
+	return ret
+}
+
+func pingCacheSave(host string, latencyMs int) {
+	_pingCacheMutex.Lock()
+	defer _pingCacheMutex.Unlock()
+	_pingCache[host] = pingCacheEntry{latencyMs: latencyMs, updated: time.Now()}
+}
+
+// PingServersParallel measures latency to all servers concurrently
+// Still valid cached results are returned immediately; the rest of hosts are probed in background
+// with incremental notifications to clients
+//
+//	probeType: "icmp" (default) or "udp" (for networks where ICMP is blocked)
+func (s *Service) PingServersParallel(timeoutMs int, probeType string) (map[string]int, error) {
+	if len(probeType) == 0 {
+		probeType = PingProbeICMP
+	}
+	if probeType != PingProbeICMP && probeType != PingProbeUDP {
+		return nil, fmt.Errorf("unknown probe type: '%s' (acceptable values: \"%s\", \"%s\")", probeType, PingProbeICMP, PingProbeUDP)
+	}
+
+	if s._vpn != nil {
+		log.Info("Servers pinging skipped due to connected state")
+		return pingCacheGet(), nil
+	}
+	if timeoutMs <= 0 {
+		log.Debug("Servers pinging skipped: timeout argument value is 0")
+		return pingCacheGet(), nil
+	}
+
+	// do not allow multiple ping request simultaneously
+	if s._isServersPingInProgress {
+		log.Info("Servers pinging skipped. Ping already in progress")
+		return pingCacheGet(), nil
+	}
+
+	// get servers IP
+	hosts, err := s.getHostsToPing(nil)
+	if err != nil {
+		return nil, fmt.Errorf("servers ping failed: %w", err)
+	}
+
+	// OS-specific preparations (e.g. we need to add servers IPs to firewall exceptions list)
+	if err := s.implIsGoingToPingServers(hosts); err != nil {
+		return nil, fmt.Errorf("servers ping failed: %w", err)
+	}
+
+	// hosts with still valid cached result are not probed again
+	cached := pingCacheGet()
+	var hostsToProbe []string
+	for _, h := range hosts {
+		if h == nil {
+			continue
+		}
+		ipStr := h.String()
+		if len(ipStr) == 0 {
+			continue
+		}
+		if _, ok := cached[ipStr]; ok {
+			continue
+		}
+		hostsToProbe = append(hostsToProbe, ipStr)
+	}
+
+	if len(hostsToProbe) == 0 {
+		log.Info("Servers pinging skipped: all results are in cache")
+		return cached, nil
+	}
+
+	s._isServersPingInProgress = true
+
+	// probe the rest of hosts in background (results are streamed to clients incrementally)
+	go s.pingHostsParallel(hostsToProbe, timeoutMs, probeType)
+
+	// immediately return cached results (not blocking client by waiting for all probes)
+	return cached, nil
+}
+
+func (s *Service) pingHostsParallel(hosts []string, timeoutMs int, probeType string) {
+	defer func() {
+		s._isServersPingInProgress = false
+
+		if r := recover(); r != nil {
+			log.Error("Panic in parallel ping: ", r)
+			if err, ok := r.(error); ok {
+				log.ErrorTrace(err)
+			}
+		}
+	}()
+
+	log.Info(fmt.Sprintf("Pinging %d servers (%s probes, %d in parallel)...", len(hosts), probeType, _pingParallelism))
+
+	// unique ID of this scan instance (to correlate progress events)
+	progressOpID := fmt.Sprintf("latency_scan-%x", time.Now().UnixNano())
+
+	var waiter sync.WaitGroup
+	// semaphore: rate limiting of parallel probes
+	semaphore := make(chan struct{}, _pingParallelism)
+
+	var resultsMutex sync.Mutex
+	results := make(map[string]int)
+	finished := 0
+	lastNotified := 0
+
+	for _, host := range hosts {
+		waiter.Add(1)
+		semaphore <- struct{}{}
+
+		go func(host string) {
+			defer func() {
+				<-semaphore
+				waiter.Done()
+			}()
+
+			var latencyMs int
+			switch probeType {
+			case PingProbeUDP:
+				latencyMs = probeHostUDP(host)
+			default:
+				latencyMs = probeHostICMP(host, timeoutMs)
+			}
+
+			resultsMutex.Lock()
+			defer resultsMutex.Unlock()
+
+			finished++
+			if latencyMs > 0 {
+				results[host] = latencyMs
+				pingCacheSave(host, latencyMs)
+			}
+
+			// stream intermediate results to clients
+			if len(results) > 0 && finished-lastNotified >= _pingStreamBatchSize {
+				lastNotified = finished
+				s._evtReceiver.OnPingStatus(results)
+				s._evtReceiver.OnOperationProgress(progressOpID, "latency_scan", "pinging", finished*100/len(hosts))
+			}
+		}(host)
+	}
+
+	waiter.Wait()
+
+	log.Info(fmt.Sprintf("Pinged %d servers (%d successfully)", len(hosts), len(results)))
+
+	// final notification contains all results (probed + still valid cached)
+	finalResults := pingCacheGet()
+	for host, latencyMs := range results {
+		finalResults[host] = latencyMs
+	}
+	s._evtReceiver.OnPingStatus(finalResults)
+	s._evtReceiver.OnOperationProgress(progressOpID, "latency_scan", "done", 100)
+}
+
+// probeHostICMP measures latency by ICMP echo request
+func probeHostICMP(host string, timeoutMs int) int {
+	pinger, err := ping.NewPinger(host)
+	if err != nil {
+		log.Error("Pinger creation error: " + err.Error())
+		return 0
+	}
+
+	pinger.SetPrivileged(true)
+	pinger.Count = 1
+	pinger.Timeout = time.Millisecond * time.Duration(timeoutMs)
+	pinger.Run()
+
+	stat := pinger.Statistics()
+	if stat.AvgRtt <= 0 {
+		return 0
+	}
+	return int(stat.AvgRtt / time.Millisecond)
+}
+
+// probeHostUDP measures latency by a DNS request over UDP (applicable for networks where ICMP is blocked)
+// Returns 0 when server did not respond within timeout
+func probeHostUDP(host string) int {
+	conn, err := net.DialTimeout("udp", host+":53", _pingUDPProbeTimeout)
+	if err != nil {
+		return 0
+	}
+	defer conn.Close()
+
+	// minimal DNS request: query of root NS record
+	request := []byte{
+		0xab, 0xcd, // transaction ID
+		0x01, 0x00, // flags: standard query, recursion desired
+		0x00, 0x01, // questions: 1
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // answer\authority\additional RRs: 0
+		0x00,       // name: <root>
+		0x00, 0x02, // type: NS
+		0x00, 0x01} // class: IN
+
+	started := time.Now()
+	if _, err := conn.Write(request); err != nil {
+		return 0
+	}
+
+	conn.SetReadDeadline(time.Now().Add(_pingUDPProbeTimeout))
+	buff := make([]byte, 512)
+	if _, err := conn.Read(buff); err != nil {
+		return 0 // no response (or port unreachable)
+	}
+
+	return int(time.Since(started) / time.Millisecond)
+}
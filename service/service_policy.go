@@ -0,0 +1,151 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ivpn/desktop-app-daemon/geoip"
+	"github.com/ivpn/desktop-app-daemon/service/firewall"
+	"github.com/ivpn/desktop-app-daemon/service/platform"
+)
+
+// Admin-provisioned policy (enterprise managed configuration).
+//
+// An administrator can create a policy file 'policy.json' next to the daemon
+// settings file (e.g. by configuration management tools). The file is read-only
+// for the daemon: it is never created or modified here. Restrictions defined in
+// the policy are enforced daemon-side regardless of client requests.
+//
+// Example:
+//	{
+//		"IsFirewallAlwaysOn": true,
+//		"IsLogoutDisabled": true,
+//		"AllowedExitCountries": ["CH", "IS"],
+//		"LockedPreferences": ["enable_logging"]
+//	}
+
+// servicePolicy - admin-provisioned restrictions (see the package comment above)
+type servicePolicy struct {
+	// IsFirewallAlwaysOn - kill-switch is forced to be persistent;
+	// requests to disable it are rejected
+	IsFirewallAlwaysOn bool
+	// IsLogoutDisabled - logout requests are rejected
+	// (e.g. machines with a centrally managed account)
+	IsLogoutDisabled bool
+	// AllowedExitCountries - country codes (ISO 3166-1 alpha-2) allowed for connection
+	// (empty - no limitation; verified by the local GeoIP dataset)
+	AllowedExitCountries []string
+	// LockedPreferences - preference keys which cannot be modified by clients
+	LockedPreferences []string
+}
+
+// active policy (empty policy - no restrictions)
+var policy servicePolicy
+
+func policyFilePath() string {
+	// policy file is expected in the same folder as the daemon settings file
+	return filepath.Join(filepath.Dir(platform.SettingsFile()), "policy.json")
+}
+
+// policyInit loads the admin-provisioned policy (if defined)
+// (must be called on daemon initialization before clients can connect)
+func (s *Service) policyInit() {
+	data, err := ioutil.ReadFile(policyFilePath())
+	if err != nil {
+		if os.IsNotExist(err) == false {
+			log.Error("Failed to read policy file: ", err)
+		}
+		return // no policy defined
+	}
+
+	p := servicePolicy{}
+	if err := json.Unmarshal(data, &p); err != nil {
+		log.Error("Failed to parse policy file (policy NOT applied): ", err)
+		return
+	}
+	policy = p
+
+	log.Info(fmt.Sprintf("Admin policy loaded: firewallAlwaysOn=%t logoutDisabled=%t allowedExitCountries=%v lockedPreferences=%v",
+		policy.IsFirewallAlwaysOn, policy.IsLogoutDisabled, policy.AllowedExitCountries, policy.LockedPreferences))
+
+	if policy.IsFirewallAlwaysOn {
+		if err := firewall.SetPersistant(true); err != nil {
+			log.Error("Failed to enable firewall (policy 'IsFirewallAlwaysOn'): ", err)
+		}
+	}
+}
+
+// policyCheckKillSwitchDisable returns error when the policy does not allow to disable the kill-switch
+func (s *Service) policyCheckKillSwitchDisable() error {
+	if policy.IsFirewallAlwaysOn {
+		return fmt.Errorf("disabling the firewall is blocked by the administrator policy")
+	}
+	return nil
+}
+
+// policyCheckLogout returns error when the policy does not allow to logout
+func (s *Service) policyCheckLogout() error {
+	if policy.IsLogoutDisabled {
+		return fmt.Errorf("logout is blocked by the administrator policy")
+	}
+	return nil
+}
+
+// policyCheckPreference returns error when the preference key is locked by the policy
+func (s *Service) policyCheckPreference(key string) error {
+	for _, locked := range policy.LockedPreferences {
+		if strings.EqualFold(locked, key) {
+			return fmt.Errorf("preference '%s' is locked by the administrator policy", key)
+		}
+	}
+	return nil
+}
+
+// policyCheckExitServer returns error when the policy does not allow to connect to the destination server
+// NOTE: when the server location cannot be determined (e.g. local GeoIP dataset not downloaded yet) -
+// the connection is allowed with a warning (to not brick fresh installations without connectivity)
+func (s *Service) policyCheckExitServer(hostIP net.IP) error {
+	if len(policy.AllowedExitCountries) == 0 {
+		return nil
+	}
+
+	location, ok := geoip.Lookup(hostIP)
+	if ok == false {
+		log.Warning(fmt.Sprintf("Unable to verify exit country of '%s' (policy 'AllowedExitCountries' not applied)", hostIP))
+		return nil
+	}
+
+	for _, countryCode := range policy.AllowedExitCountries {
+		if strings.EqualFold(countryCode, location.CountryCode) {
+			return nil
+		}
+	}
+	return fmt.Errorf("connection blocked by the administrator policy (exit country '%s' is not allowed)", location.CountryCode)
+}
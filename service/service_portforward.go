@@ -0,0 +1,145 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"fmt"
+	"time"
+)
+
+// Port-forwarding management
+// Forwarded port is allocated on currently connected server (applicable only for servers which support it).
+// While connected, the daemon periodically prolongs expiration of the allocated port.
+// No additional firewall rules required: incoming traffic of forwarded port
+// arrives through the VPN tunnel interface (which is not blocked by the firewall implementations)
+
+const (
+	// interval of checking expiration time of the forwarded port
+	_portForwardCheckInterval = time.Minute
+	// port will be renewed when less time left till expiration
+	_portForwardRenewBeforeExpiration = time.Minute * 5
+)
+
+// PortForwardRequest - allocate forwarded port on currently connected server
+// (when port already allocated - prolongs its expiration)
+func (s *Service) PortForwardRequest() (port int, expiresAt int64, err error) {
+	session := s._preferences.Session
+	if session.IsLoggedIn() == false {
+		return 0, 0, ErrorNotLoggedIn{}
+	}
+	if s.Connected() == false {
+		return 0, 0, fmt.Errorf("unable to request port forwarding: VPN not connected")
+	}
+
+	resp, err := s._api.PortForwardAdd(session.Session)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to request forwarded port: %w", err)
+	}
+
+	s._portForwardMutex.Lock()
+	isRenewerRunning := s._portForwardPort > 0
+	s._portForwardPort = resp.Port
+	s._portForwardExpiresAt = resp.ExpiresAt
+	s._portForwardMutex.Unlock()
+
+	log.Info(fmt.Sprintf("Forwarded port allocated: %d (expires: %v)", resp.Port, time.Unix(resp.ExpiresAt, 0)))
+
+	// notify clients about changed port-forwarding state
+	s._evtReceiver.OnPortForwardChanged(resp.Port, resp.ExpiresAt)
+
+	// start port renewal routine (if not started yet)
+	if isRenewerRunning == false {
+		go s.portForwardRenewLoop()
+	}
+
+	return resp.Port, resp.ExpiresAt, nil
+}
+
+// PortForwardRelease - release forwarded port
+func (s *Service) PortForwardRelease() error {
+	session := s._preferences.Session
+	if session.IsLoggedIn() == false {
+		return ErrorNotLoggedIn{}
+	}
+
+	s._portForwardMutex.Lock()
+	port := s._portForwardPort
+	s._portForwardPort = 0
+	s._portForwardExpiresAt = 0
+	s._portForwardMutex.Unlock()
+
+	if port <= 0 {
+		return nil // nothing to release
+	}
+
+	// notify clients about changed port-forwarding state (port==0: no forwarded port)
+	s._evtReceiver.OnPortForwardChanged(0, 0)
+
+	if err := s._api.PortForwardDelete(session.Session, port); err != nil {
+		return fmt.Errorf("failed to release forwarded port: %w", err)
+	}
+
+	log.Info(fmt.Sprintf("Forwarded port released: %d", port))
+	return nil
+}
+
+// PortForwardState returns info about currently forwarded port (port==0: no forwarded port)
+func (s *Service) PortForwardState() (port int, expiresAt int64) {
+	s._portForwardMutex.Lock()
+	defer s._portForwardMutex.Unlock()
+	return s._portForwardPort, s._portForwardExpiresAt
+}
+
+// portForwardRenewLoop periodically prolongs expiration of the forwarded port (while connected)
+func (s *Service) portForwardRenewLoop() {
+	defer log.Info("Port-forwarding renewal stopped")
+	log.Info("Port-forwarding renewal started")
+
+	for {
+		time.Sleep(_portForwardCheckInterval)
+
+		port, expiresAt := s.PortForwardState()
+		if port <= 0 {
+			return // port was released
+		}
+
+		if s.Connected() == false {
+			// forwarded port is not applicable anymore (server allocation is lost on disconnect)
+			s._portForwardMutex.Lock()
+			s._portForwardPort = 0
+			s._portForwardExpiresAt = 0
+			s._portForwardMutex.Unlock()
+
+			s._evtReceiver.OnPortForwardChanged(0, 0)
+			return
+		}
+
+		if time.Until(time.Unix(expiresAt, 0)) > _portForwardRenewBeforeExpiration {
+			continue // too early to renew
+		}
+
+		if _, _, err := s.PortForwardRequest(); err != nil {
+			log.Error("Failed to renew forwarded port: ", err)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/ivpn/desktop-app-daemon/vpn"
+)
+
+// Automatic port rotation on connection failure
+// When enabled (preference 'port_rotation_on_failure') and the first connection attempt fails
+// (e.g. TLS error, handshake timeout - current port can be blocked by a local network),
+// the daemon retries using the next allowed port\protocol combination from the servers configuration
+// ('config'->'ports' section of servers.json) before surfacing failure to a client.
+// Each retry is reported to clients via 'StateAdditionalInfo' of the RECONNECTING state.
+
+// maximum count of additional connection attempts with a rotated port
+const _portRotationMaxAttempts = 5
+
+// portCombination - one allowed port\protocol combination
+type portCombination struct {
+	port  int
+	isTCP bool
+}
+
+func (pc portCombination) String() string {
+	protocol := "UDP"
+	if pc.isTCP {
+		protocol = "TCP"
+	}
+	return fmt.Sprintf("%s %d", protocol, pc.port)
+}
+
+// portRotationInit resets port rotation state (called on start of each new connection request)
+func (s *Service) portRotationInit(vpnType vpn.Type, initialPort int, initialIsTCP bool) {
+	s._portRotationMutex.Lock()
+	defer s._portRotationMutex.Unlock()
+
+	s._portRotationVpnType = vpnType
+	s._portRotationInitial = portCombination{port: initialPort, isTCP: initialIsTCP}
+	s._portRotationCombos = nil
+	s._portRotationAttempt = 0
+	s._portRotationOverride = nil
+}
+
+// portRotationCombinations returns allowed port\protocol combinations from the servers configuration
+func (s *Service) portRotationCombinations(vpnType vpn.Type) []portCombination {
+	servers, err := s.ServersList()
+	if err != nil || servers == nil {
+		return nil
+	}
+
+	portsInfo := servers.Config.Ports.OpenVPN
+	if vpnType == vpn.WireGuard {
+		portsInfo = servers.Config.Ports.WireGuard
+	}
+
+	var combos []portCombination
+	for _, pi := range portsInfo {
+		if vpnType == vpn.WireGuard && pi.IsTCP() {
+			continue // WireGuard uses UDP only
+		}
+		port := pi.Port
+		if pi.IsRange() {
+			port = pi.Range.Min + rand.Intn(pi.Range.Max-pi.Range.Min+1)
+		}
+		if port <= 0 {
+			continue
+		}
+		combos = append(combos, portCombination{port: port, isTCP: pi.IsTCP()})
+	}
+	return combos
+}
+
+// portRotationNext switches connection parameters to the next allowed port\protocol combination
+// (returns false - when rotation disabled, no more combinations to try or attempts limit reached)
+func (s *Service) portRotationNext() (description string, ok bool) {
+	if s._preferences.IsPortRotationOnFailure == false {
+		return "", false
+	}
+
+	s._portRotationMutex.Lock()
+	defer s._portRotationMutex.Unlock()
+
+	if s._portRotationCombos == nil {
+		allCombos := s.portRotationCombinations(s._portRotationVpnType)
+		// exclude combination which just failed
+		combos := make([]portCombination, 0, len(allCombos))
+		for _, c := range allCombos {
+			if c == s._portRotationInitial {
+				continue
+			}
+			combos = append(combos, c)
+		}
+		s._portRotationCombos = combos
+	}
+
+	if s._portRotationAttempt >= len(s._portRotationCombos) || s._portRotationAttempt >= _portRotationMaxAttempts {
+		return "", false
+	}
+
+	combo := s._portRotationCombos[s._portRotationAttempt]
+	s._portRotationAttempt++
+	s._portRotationOverride = &combo
+
+	maxAttempts := len(s._portRotationCombos)
+	if maxAttempts > _portRotationMaxAttempts {
+		maxAttempts = _portRotationMaxAttempts
+	}
+	return fmt.Sprintf("Retrying with the next port: %s (attempt %d of %d)", combo, s._portRotationAttempt, maxAttempts), true
+}
+
+// portRotationOverride returns port\protocol combination to use instead of the requested one
+// (false - when no rotation in progress)
+func (s *Service) portRotationOverride() (port int, isTCP bool, ok bool) {
+	s._portRotationMutex.Lock()
+	defer s._portRotationMutex.Unlock()
+
+	if s._portRotationOverride == nil {
+		return 0, false, false
+	}
+	return s._portRotationOverride.port, s._portRotationOverride.isTCP, true
+}
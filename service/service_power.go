@@ -0,0 +1,75 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+// startPowerEventsDetector starts receiving system power events (system sleep\resume)
+func (s *Service) startPowerEventsDetector() {
+	if s._powerEventsDetector == nil {
+		return
+	}
+
+	suspendChan := make(chan struct{}, 1)
+	resumeChan := make(chan struct{}, 1)
+
+	go func() {
+		for {
+			select {
+			case <-suspendChan:
+				s.onSystemSuspend()
+			case <-resumeChan:
+				s.onSystemResume()
+			}
+		}
+	}()
+
+	s._powerEventsDetector.Start(suspendChan, resumeChan)
+}
+
+// onSystemSuspend proactively closes the tunnel when the system is going to sleep
+// (reconnection is performed by 'keepConnection(...)' loop according to '_requiredVpnState';
+// reconnection attempts will fail while the system sleeps, the tunnel will be re-established right after wake)
+func (s *Service) onSystemSuspend() {
+	if s.Connected() == false {
+		return
+	}
+
+	log.Info("System is going to sleep: closing the tunnel (will be re-established on wake)")
+	s._reconnectReason = "Reconnecting after system sleep"
+	s.reconnect()
+}
+
+// onSystemResume re-establishes the tunnel immediately after system wake:
+// the old tunnel is most likely dead, reconnecting immediately instead of waiting
+// for the tunnel watchdog or VPN-protocol timeouts
+func (s *Service) onSystemResume() {
+	if s.Connected() == false {
+		return
+	}
+	if s.IsPaused() {
+		return // paused connection: no active tunnel to re-establish
+	}
+
+	log.Info("System resumed after sleep: re-establishing the tunnel")
+	s._reconnectReason = "Reconnecting after system sleep"
+	s.reconnect()
+}
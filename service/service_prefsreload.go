@@ -0,0 +1,166 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+	"time"
+
+	"github.com/ivpn/desktop-app-daemon/logger"
+	"github.com/ivpn/desktop-app-daemon/service/firewall"
+	"github.com/ivpn/desktop-app-daemon/service/platform"
+	"github.com/ivpn/desktop-app-daemon/service/preferences"
+)
+
+// Hot-reload of the settings file.
+// The daemon re-reads its settings file when SIGHUP received or when the file
+// modification detected (allows to manage fleet configuration by configuration
+// management tools without restarting the daemon).
+// The file is validated first (loaded into a temporary object): when it is not
+// readable - the current configuration stays untouched.
+
+// interval of checking the settings file for modifications
+const _prefsReloadCheckInterval = time.Second * 30
+
+// prefsReloadInit starts watching the settings file for external modifications
+func (s *Service) prefsReloadInit() {
+	sigChan := make(chan os.Signal, 1)
+	// SIGHUP is never delivered on Windows; file-modification detection still works there
+	signal.Notify(sigChan, syscall.SIGHUP)
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Error("PANIC in settings file watcher: ", r)
+			}
+		}()
+
+		lastModTime := time.Time{}
+		if fi, err := os.Stat(platform.SettingsFile()); err == nil {
+			lastModTime = fi.ModTime()
+		}
+
+		for {
+			isHaveToReload := false
+
+			select {
+			case <-sigChan:
+				log.Info("SIGHUP received. Reloading preferences...")
+				isHaveToReload = true
+
+			case <-time.After(_prefsReloadCheckInterval):
+				fi, err := os.Stat(platform.SettingsFile())
+				if err != nil {
+					break
+				}
+				if fi.ModTime().Equal(lastModTime) == false {
+					lastModTime = fi.ModTime()
+					// the file is also re-written by the daemon itself on every
+					// preferences change; 'reloadPreferences()' ignores a file
+					// which content equals to the active configuration
+					isHaveToReload = true
+				}
+			}
+
+			if isHaveToReload {
+				s.reloadPreferences()
+			}
+		}
+	}()
+}
+
+// reloadPreferences re-reads the settings file and applies the new configuration
+// to all dependent subsystems (only the changed values are re-applied)
+func (s *Service) reloadPreferences() {
+	// validation: load into a temporary object first
+	// (the active configuration stays untouched when the file is corrupted)
+	newPrefs := preferences.Preferences{}
+	if err := newPrefs.LoadPreferences(); err != nil {
+		log.Error("Preferences not reloaded (settings file validation failed): ", err)
+		return
+	}
+
+	oldPrefs := s._preferences
+	if reflect.DeepEqual(oldPrefs, newPrefs) {
+		return // no changes (e.g. the file was re-written by the daemon itself)
+	}
+
+	log.Info("Settings file changed. Applying new preferences...")
+	s._preferences = newPrefs
+
+	if oldPrefs.IsLogging != newPrefs.IsLogging {
+		logger.Enable(newPrefs.IsLogging)
+	}
+
+	if oldPrefs.APIProxyType != newPrefs.APIProxyType ||
+		oldPrefs.APIProxyAddress != newPrefs.APIProxyAddress ||
+		oldPrefs.APIProxyPort != newPrefs.APIProxyPort ||
+		oldPrefs.APIProxyUsername != newPrefs.APIProxyUsername ||
+		oldPrefs.APIProxyPassword != newPrefs.APIProxyPassword {
+		s.applyAPIProxySettings()
+	}
+
+	if oldPrefs.ServersUpdateIntervalMinutes != newPrefs.ServersUpdateIntervalMinutes && newPrefs.ServersUpdateIntervalMinutes > 0 {
+		s._serversUpdater.SetUpdateInterval(time.Duration(newPrefs.ServersUpdateIntervalMinutes) * time.Minute)
+	}
+
+	if oldPrefs.IsFwPersistant != newPrefs.IsFwPersistant {
+		if err := firewall.SetPersistant(newPrefs.IsFwPersistant); err != nil {
+			log.Error("Failed to apply firewall persistence: ", err)
+		}
+	}
+
+	if oldPrefs.FwIPv6Policy != newPrefs.FwIPv6Policy && len(newPrefs.FwIPv6Policy) > 0 {
+		if err := firewall.SetIPv6Policy(newPrefs.FwIPv6Policy); err != nil {
+			log.Error("Failed to apply IPv6 policy: ", err)
+		}
+	}
+
+	if oldPrefs.IsFwAllowLAN != newPrefs.IsFwAllowLAN ||
+		oldPrefs.IsFwAllowLANMulticast != newPrefs.IsFwAllowLANMulticast ||
+		oldPrefs.IsFwAllowLANTrustedOnly != newPrefs.IsFwAllowLANTrustedOnly ||
+		reflect.DeepEqual(oldPrefs.FwAllowLANSubnets, newPrefs.FwAllowLANSubnets) == false {
+		if err := s.applyKillSwitchAllowLAN(); err != nil {
+			log.Error("Failed to apply 'allow LAN' configuration: ", err)
+		}
+		s._evtReceiver.OnKillSwitchStateChanged()
+	}
+
+	if oldPrefs.FwUserAllowedApps != newPrefs.FwUserAllowedApps ||
+		oldPrefs.FwUserBlockedApps != newPrefs.FwUserBlockedApps {
+		if err := s.applyUserAppRules(); err != nil {
+			log.Error("Failed to apply user per-application firewall rules: ", err)
+		}
+	}
+
+	if oldPrefs.IsFwDNSLeakProtection != newPrefs.IsFwDNSLeakProtection {
+		s.applyDNSLeakProtection()
+	}
+
+	if oldPrefs.MetricsPort != newPrefs.MetricsPort {
+		s.applyMetricsSettings()
+	}
+}
@@ -0,0 +1,208 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	protocoltypes "github.com/ivpn/desktop-app-daemon/protocol/types"
+	"github.com/ivpn/desktop-app-daemon/service/dns"
+)
+
+// Connectivity and leak self-test suite
+// On-demand checks of an active VPN connection:
+//	- default-route verification (traffic goes through the tunnel)
+//	- DNS leak test (DNS requests are routed through the tunnel)
+//	- IPv6 leak test (no IPv6 connectivity outside the tunnel)
+//	- kill-switch validation (traffic outside the tunnel is blocked when firewall enabled)
+
+const _selfTestDialTimeout = time.Second * 3
+
+// RunSelfTest performs connectivity and leak checks of the active VPN connection
+func (s *Service) RunSelfTest() (checks []protocoltypes.SelfTestCheck, err error) {
+	vpnObj := s._vpn
+	if vpnObj == nil || s.Connected() == false {
+		return nil, fmt.Errorf("unable to run self-test: VPN not connected")
+	}
+	if s.IsPaused() {
+		return nil, fmt.Errorf("unable to run self-test: VPN connection is paused")
+	}
+
+	log.Info("Running connectivity and leak self-test...")
+
+	physicalIP, err := physicalInterfaceIP(vpnObj.DestinationIP())
+	if err != nil {
+		return nil, fmt.Errorf("unable to determine IP of physical interface: %w", err)
+	}
+
+	checks = append(checks, s.selfTestDefaultRoute(physicalIP))
+	checks = append(checks, s.selfTestDNSLeak(physicalIP, vpnObj.InTunnelGatewayIP()))
+	checks = append(checks, s.selfTestIPv6Leak())
+	checks = append(checks, s.selfTestKillSwitch(physicalIP))
+
+	for _, c := range checks {
+		status := "PASSED"
+		if c.Skipped {
+			status = "SKIPPED"
+		} else if c.Passed == false {
+			status = "FAILED"
+		}
+		log.Info(fmt.Sprintf("Self-test '%s': %s (%s)", c.Name, status, c.Details))
+	}
+
+	return checks, nil
+}
+
+// selfTestDefaultRoute verifies that the default route goes through the VPN tunnel
+func (s *Service) selfTestDefaultRoute(physicalIP net.IP) protocoltypes.SelfTestCheck {
+	check := protocoltypes.SelfTestCheck{Name: "default_route"}
+
+	// no real traffic sent here: UDP 'connect' only selects the route
+	conn, err := net.Dial("udp", "8.8.8.8:53")
+	if err != nil {
+		check.Details = fmt.Sprintf("unable to determine default route: %s", err)
+		return check
+	}
+	defer conn.Close()
+
+	localAddr, ok := conn.LocalAddr().(*net.UDPAddr)
+	if ok == false {
+		check.Details = "unexpected local address type"
+		return check
+	}
+
+	if localAddr.IP.Equal(physicalIP) {
+		check.Details = fmt.Sprintf("default route goes through the physical interface (%s), not through the tunnel", physicalIP)
+		return check
+	}
+
+	check.Passed = true
+	check.Details = fmt.Sprintf("default route goes through the tunnel (%s)", localAddr.IP)
+	return check
+}
+
+// selfTestDNSLeak verifies that DNS requests are routed through the VPN tunnel
+func (s *Service) selfTestDNSLeak(physicalIP net.IP, inTunnelGatewayIP net.IP) protocoltypes.SelfTestCheck {
+	check := protocoltypes.SelfTestCheck{Name: "dns_leak"}
+
+	// DNS server in use: manually configured DNS or default DNS of the VPN connection
+	dnsServer := net.ParseIP(dns.GetLastManualDNS())
+	if dnsServer == nil {
+		dnsServer = inTunnelGatewayIP
+	}
+	if dnsServer == nil {
+		check.Skipped = true
+		check.Passed = true
+		check.Details = "unable to determine DNS server in use - check skipped"
+		return check
+	}
+
+	// no real traffic sent here: UDP 'connect' only selects the route
+	conn, err := net.Dial("udp", dnsServer.String()+":53")
+	if err != nil {
+		check.Details = fmt.Sprintf("unable to determine route to DNS server %s: %s", dnsServer, err)
+		return check
+	}
+	defer conn.Close()
+
+	localAddr, ok := conn.LocalAddr().(*net.UDPAddr)
+	if ok == false {
+		check.Details = "unexpected local address type"
+		return check
+	}
+
+	if localAddr.IP.Equal(physicalIP) {
+		check.Details = fmt.Sprintf("DNS requests to %s are routed through the physical interface (%s), not through the tunnel", dnsServer, physicalIP)
+		return check
+	}
+
+	check.Passed = true
+	check.Details = fmt.Sprintf("DNS requests to %s are routed through the tunnel", dnsServer)
+	return check
+}
+
+// selfTestIPv6Leak verifies that there is no IPv6 connectivity outside the VPN tunnel
+func (s *Service) selfTestIPv6Leak() protocoltypes.SelfTestCheck {
+	check := protocoltypes.SelfTestCheck{Name: "ipv6_leak"}
+
+	// no real traffic sent here: UDP 'connect' only selects the route
+	conn, err := net.Dial("udp6", "[2001:4860:4860::8888]:53")
+	if err != nil {
+		// no route to global IPv6 addresses - IPv6 leak not possible
+		check.Passed = true
+		check.Details = "no IPv6 connectivity detected"
+		return check
+	}
+	defer conn.Close()
+
+	localAddr, ok := conn.LocalAddr().(*net.UDPAddr)
+	if ok == false {
+		check.Details = "unexpected local address type"
+		return check
+	}
+
+	if localAddr.IP.IsGlobalUnicast() {
+		check.Details = fmt.Sprintf("IPv6 traffic can bypass the tunnel (global IPv6 address in use: %s)", localAddr.IP)
+		return check
+	}
+
+	check.Passed = true
+	check.Details = "no routable IPv6 connectivity detected"
+	return check
+}
+
+// selfTestKillSwitch verifies that traffic outside the tunnel is blocked when firewall enabled
+func (s *Service) selfTestKillSwitch(physicalIP net.IP) protocoltypes.SelfTestCheck {
+	check := protocoltypes.SelfTestCheck{Name: "kill_switch"}
+
+	isFirewallEnabled, err := s.FirewallEnabled()
+	if err != nil {
+		check.Details = fmt.Sprintf("unable to get firewall status: %s", err)
+		return check
+	}
+	if isFirewallEnabled == false {
+		check.Skipped = true
+		check.Passed = true
+		check.Details = "kill-switch is disabled - check skipped"
+		return check
+	}
+
+	// try to establish TCP connection outside the tunnel (bound to the physical interface)
+	// expected result: connection is blocked by the firewall
+	dialer := net.Dialer{
+		Timeout:   _selfTestDialTimeout,
+		LocalAddr: &net.TCPAddr{IP: physicalIP}}
+
+	conn, err := dialer.Dial("tcp4", "1.1.1.1:443")
+	if err == nil {
+		conn.Close()
+		check.Details = "connection outside the tunnel was NOT blocked by the firewall"
+		return check
+	}
+
+	check.Passed = true
+	check.Details = "connections outside the tunnel are blocked"
+	return check
+}
@@ -0,0 +1,95 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"time"
+
+	"github.com/ivpn/desktop-app-daemon/service/firewall"
+)
+
+// Server switch ("change location").
+// A server switch is implemented as disconnect-then-connect. Within the switch window
+// the firewall handling of the disconnection is skipped: protection stays continuously
+// engaged and the post-disconnect cleanup of network artifacts is not performed
+// (the new connection reuses the interface; saves seconds on Windows).
+
+// timeout of the server-switch window: when a new connection request does not arrive
+// in time - the skipped firewall disconnection handling is performed
+const _serverSwitchTimeout = time.Minute
+
+// StartServerSwitch opens the server-switch window: the following disconnection
+// is treated as a part of a server switch (see the comment above)
+func (s *Service) StartServerSwitch() {
+	s._serverSwitchMutex.Lock()
+	defer s._serverSwitchMutex.Unlock()
+
+	s._isServerSwitch = true
+	if s._serverSwitchTimer != nil {
+		s._serverSwitchTimer.Stop()
+	}
+	s._serverSwitchTimer = time.AfterFunc(_serverSwitchTimeout, s.onServerSwitchTimeout)
+
+	log.Info("Server switch started")
+}
+
+// serverSwitchAcknowledge closes the server-switch window (new connection is starting).
+// Returns true when the window was open
+func (s *Service) serverSwitchAcknowledge() bool {
+	s._serverSwitchMutex.Lock()
+	defer s._serverSwitchMutex.Unlock()
+
+	if s._isServerSwitch == false {
+		return false
+	}
+
+	s._isServerSwitch = false
+	if s._serverSwitchTimer != nil {
+		s._serverSwitchTimer.Stop()
+		s._serverSwitchTimer = nil
+	}
+	return true
+}
+
+// isServerSwitch returns true when the server-switch window is open
+func (s *Service) isServerSwitch() bool {
+	s._serverSwitchMutex.Lock()
+	defer s._serverSwitchMutex.Unlock()
+	return s._isServerSwitch
+}
+
+// onServerSwitchTimeout performs the skipped firewall disconnection handling
+// when a new connection request did not arrive in time
+func (s *Service) onServerSwitchTimeout() {
+	if s.serverSwitchAcknowledge() == false {
+		return
+	}
+
+	log.Warning("Server switch was not completed in time")
+
+	if s.Connected() == false {
+		if err := firewall.ClientDisconnected(); err != nil {
+			log.Error("error on notifying FW about disconnected client:", err)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"net"
+	"strconv"
+
+	"github.com/ivpn/desktop-app-daemon/service/firewall"
+	"github.com/ivpn/desktop-app-daemon/stealth"
+)
+
+// Stealth mode: OpenVPN TCP traffic is wrapped into ordinary TLS to port 443 of an
+// obfuscation endpoint (see 'stealth' package)
+
+// stealthParams returns parameters of the stealth transport from preferences
+// (ok == false - stealth mode disabled or endpoint value is not acceptable)
+func (s *Service) stealthParams() (host string, port int, tlsServerName string, ok bool) {
+	prefs := s.Preferences()
+	if len(prefs.StealthEndpoint) == 0 {
+		return "", 0, "", false
+	}
+
+	host, portStr, err := net.SplitHostPort(prefs.StealthEndpoint)
+	if err != nil {
+		// port not defined: use default TLS port 443
+		host = prefs.StealthEndpoint
+		return host, 443, prefs.StealthTLSServerName, true
+	}
+	if port, err = strconv.Atoi(portStr); err != nil {
+		log.Warning("Stealth mode disabled: bad endpoint port value: ", err)
+		return "", 0, "", false
+	}
+
+	return host, port, prefs.StealthTLSServerName, true
+}
+
+// stealthStart starts new stealth transport instance for a connection (the previous instance is stopped)
+// Returns local port the VPN connection have to be routed through
+func (s *Service) stealthStart() (localPort int, err error) {
+	s.stealthStop()
+
+	host, port, tlsServerName, ok := s.stealthParams()
+	if ok == false {
+		return 0, nil
+	}
+
+	// allow direct connectivity to the obfuscation endpoint
+	if ips, err := net.LookupIP(host); err == nil {
+		if err := firewall.AddHostsToExceptions(ips, false); err != nil {
+			log.Warning("Failed to add stealth endpoint to firewall exceptions: ", err)
+		}
+	}
+
+	obj := stealth.CreateStealth(host, port, tlsServerName)
+	localPort, err = obj.Start()
+	if err != nil {
+		return 0, err
+	}
+
+	s._stealthMutex.Lock()
+	s._stealth = obj
+	s._stealthMutex.Unlock()
+	return localPort, nil
+}
+
+// stealthStop stops the stealth transport instance (if it is started)
+func (s *Service) stealthStop() {
+	s._stealthMutex.Lock()
+	obj := s._stealth
+	s._stealth = nil
+	s._stealthMutex.Unlock()
+
+	if obj != nil {
+		obj.Stop()
+	}
+}
@@ -0,0 +1,111 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/ivpn/desktop-app-daemon/service/firewall"
+	"github.com/ivpn/desktop-app-daemon/service/platform"
+	"github.com/ivpn/desktop-app-daemon/v2ray"
+)
+
+// V2Ray obfuscation transport
+// When enabled (preference 'v2ray_transport' is not empty), VPN traffic is wrapped
+// into VMess over TLS\WebSocket and forwarded to a designated obfuscation endpoint
+// (see 'v2ray' package for details). The V2Ray instance lifetime is bound to a connection:
+// it starts before each connection attempt and stops when connection closed.
+
+// v2rayParams returns V2Ray obfuscation parameters from preferences (false - when obfuscation disabled)
+func (s *Service) v2rayParams() (v2ray.ObfuscationParams, bool) {
+	prefs := s._preferences
+	if len(prefs.V2RayTransport) == 0 {
+		return v2ray.ObfuscationParams{}, false
+	}
+
+	params := v2ray.ObfuscationParams{
+		Transport:     prefs.V2RayTransport,
+		VMessID:       prefs.V2RayVMessID,
+		TLSServerName: prefs.V2RayTLSServerName}
+
+	host, portStr, err := net.SplitHostPort(prefs.V2RayEndpoint)
+	if err != nil {
+		log.Warning("V2Ray obfuscation disabled: bad endpoint value: ", err)
+		return v2ray.ObfuscationParams{}, false
+	}
+	params.EndpointHost = host
+	if params.EndpointPort, err = strconv.Atoi(portStr); err != nil {
+		log.Warning("V2Ray obfuscation disabled: bad endpoint port value: ", err)
+		return v2ray.ObfuscationParams{}, false
+	}
+
+	return params, true
+}
+
+// v2rayStart starts new V2Ray instance for a connection (the previous instance is stopped)
+// Returns local port the VPN connection have to be routed through
+func (s *Service) v2rayStart(params v2ray.ObfuscationParams, isTCP bool, vpnHost net.IP, vpnPort int) (localPort int, err error) {
+	s.v2rayStop()
+
+	// allow direct connectivity to the obfuscation endpoint
+	if ips, err := net.LookupIP(params.EndpointHost); err == nil {
+		if err := firewall.AddHostsToExceptions(ips, false); err != nil {
+			log.Warning("Failed to add V2Ray endpoint to firewall exceptions: ", err)
+		}
+	}
+
+	obj := v2ray.CreateV2Ray(platform.V2RayBinaryPath(), platform.V2RayConfigFile(), params)
+	localPort, err = obj.Start(isTCP, vpnHost, vpnPort)
+	if err != nil {
+		return 0, err
+	}
+
+	s._v2rayMutex.Lock()
+	s._v2ray = obj
+	s._v2rayMutex.Unlock()
+	return localPort, nil
+}
+
+// v2rayStop stops V2Ray instance (if it is running)
+func (s *Service) v2rayStop() {
+	s._v2rayMutex.Lock()
+	obj := s._v2ray
+	s._v2ray = nil
+	s._v2rayMutex.Unlock()
+
+	if obj != nil {
+		obj.Stop()
+	}
+}
+
+// validateV2RayTransport checks value of the 'v2ray_transport' preference
+func validateV2RayTransport(val string) error {
+	switch strings.ToLower(val) {
+	case "", v2ray.TransportTLS, v2ray.TransportWS:
+		return nil
+	}
+	return fmt.Errorf("unexpected V2Ray transport type '%s' (acceptable values: '%s', '%s' or empty string to disable obfuscation)", val, v2ray.TransportTLS, v2ray.TransportWS)
+}
@@ -0,0 +1,157 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/ivpn/desktop-app-daemon/ping"
+	"github.com/ivpn/desktop-app-daemon/service/firewall"
+)
+
+// Connection warm-up: optional pre-connect phase (preference 'connect_warmup')
+// Before the VPN process is started, the daemon checks reachability of the destination
+// server on the selected port and fails fast with a precise error
+// (e.g. "UDP 51820 blocked on this network") instead of waiting for a multi-minute
+// connection timeout. A failed warm-up goes through the same retry chain as a failed
+// connection attempt (port rotation, UDP-over-TCP fallback ...).
+
+const (
+	// timeout of the ICMP reachability probe
+	_warmupPingTimeout = time.Second * 3
+	// timeout of the TCP port reachability probe
+	_warmupDialTimeout = time.Second * 5
+	// count of UDP probe datagrams (and read timeout per probe)
+	_warmupUDPProbes      = 3
+	_warmupUDPReadTimeout = time.Millisecond * 300
+)
+
+// warmupSetTarget remembers port\protocol of the current connection attempt
+// (called from the VPN object creation; the destination IP is taken from the created object)
+func (s *Service) warmupSetTarget(port int, isTCP bool) {
+	s._warmupMutex.Lock()
+	defer s._warmupMutex.Unlock()
+	s._warmupPort = port
+	s._warmupIsTCP = isTCP
+}
+
+// connectWarmup checks reachability of the destination server on the selected port
+// Returns nil when the destination is reachable, the check is inconclusive or warm-up disabled
+func (s *Service) connectWarmup(destIP net.IP) error {
+	if s._preferences.IsConnectWarmup == false {
+		return nil
+	}
+	if destIP == nil || destIP.IsLoopback() {
+		return nil // a local obfuscation transport listener: nothing to probe
+	}
+
+	s._warmupMutex.Lock()
+	port := s._warmupPort
+	isTCP := s._warmupIsTCP
+	s._warmupMutex.Unlock()
+	if port <= 0 {
+		return nil
+	}
+
+	// allow direct connectivity to the destination (the kill-switch can be already enabled)
+	if err := firewall.AddHostsToExceptions([]net.IP{destIP}, false); err != nil {
+		log.Warning("Connection warm-up: failed to add server to firewall exceptions: ", err)
+	}
+
+	startTime := time.Now()
+	defer func() {
+		log.Info(fmt.Sprintf("Connection warm-up finished in %v", time.Since(startTime)))
+	}()
+
+	if isTCP {
+		return s.warmupProbeTCP(destIP, port)
+	}
+	return s.warmupProbeUDP(destIP, port)
+}
+
+// warmupProbeTCP verifies TCP port reachability directly (connection attempt)
+func (s *Service) warmupProbeTCP(destIP net.IP, port int) error {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(destIP.String(), strconv.Itoa(port)), _warmupDialTimeout)
+	if err == nil {
+		conn.Close()
+		return nil
+	}
+
+	if warmupProbeICMP(destIP) {
+		return fmt.Errorf("TCP %d blocked on this network (server %s responds to ICMP)", port, destIP)
+	}
+	return fmt.Errorf("VPN server %s is not reachable on this network", destIP)
+}
+
+// warmupProbeUDP - best-effort UDP port reachability check
+// No response is expected from a VPN server to an arbitrary datagram; detection is based on
+// ICMP 'destination unreachable' replies surfaced by the connected UDP socket.
+// Silently dropped probes are inconclusive: the connection proceeds (and can still time out).
+func (s *Service) warmupProbeUDP(destIP net.IP, port int) error {
+	conn, err := net.DialTimeout("udp", net.JoinHostPort(destIP.String(), strconv.Itoa(port)), _warmupDialTimeout)
+	if err != nil {
+		return fmt.Errorf("UDP %d blocked on this network: %w", port, err)
+	}
+	defer conn.Close()
+
+	buff := make([]byte, 64)
+	for i := 0; i < _warmupUDPProbes; i++ {
+		if _, err := conn.Write([]byte{0}); err != nil {
+			return fmt.Errorf("UDP %d blocked on this network: %w", port, err)
+		}
+
+		conn.SetReadDeadline(time.Now().Add(_warmupUDPReadTimeout))
+		if _, err := conn.Read(buff); err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				continue // no reply: inconclusive
+			}
+			// ICMP 'destination unreachable' received for the probe
+			return fmt.Errorf("UDP %d blocked on this network: %w", port, err)
+		}
+	}
+
+	// probes were sent but nothing confirms delivery: check at least the host reachability
+	if warmupProbeICMP(destIP) == false {
+		return fmt.Errorf("VPN server %s is not reachable on this network (no response to ICMP probe)", destIP)
+	}
+	return nil
+}
+
+// warmupProbeICMP returns true when the host responded to an ICMP echo request
+func warmupProbeICMP(destIP net.IP) bool {
+	pinger, err := ping.NewPinger(destIP.String())
+	if err != nil {
+		// do not fail the warm-up because of internal pinger problem
+		log.Error("Connection warm-up: pinger creation error: " + err.Error())
+		return true
+	}
+	pinger.SetPrivileged(true)
+	pinger.Count = 1
+	pinger.Timeout = _warmupPingTimeout
+	pinger.Run()
+
+	return pinger.Statistics().PacketsRecv > 0
+}
@@ -0,0 +1,107 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/ivpn/desktop-app-daemon/service/firewall"
+	"github.com/ivpn/desktop-app-daemon/vpn/wireguard"
+)
+
+// WireGuard roaming endpoint update.
+// When a server publishes a new endpoint IP (servers.json refresh) - the peer endpoint
+// of the active tunnel is updated in-place ('wg set') instead of tearing down and
+// reinstalling the tunnel (minimizes downtime: WG handles endpoint change seamlessly).
+
+// wgRoamingInit starts watching servers-list updates for endpoint changes of the connected server
+func (s *Service) wgRoamingInit() {
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Error("PANIC in WG roaming watcher: ", r)
+			}
+		}()
+
+		for {
+			<-s._serversUpdater.UpdateNotifierChannelInternal()
+			s.wgRoamingCheck()
+			// refreshed load data: check if a better server became available
+			// (see 'service_betterserver.go')
+			s.betterServerCheck()
+		}
+	}()
+}
+
+// wgRoamingCheck updates the peer endpoint of the active WG tunnel
+// when the connected server published a new endpoint IP
+func (s *Service) wgRoamingCheck() {
+	vpnObj := s._vpn
+	if vpnObj == nil || s.Connected() == false {
+		return
+	}
+
+	wgObj, ok := vpnObj.(*wireguard.WireGuard)
+	if ok == false || wgObj.IsPaused() {
+		return
+	}
+
+	curHostIP := wgObj.DestinationIP()
+	if curHostIP == nil || curHostIP.IsLoopback() {
+		return // an obfuscation transport is in use (local listener): nothing to update
+	}
+
+	servers, err := s._serversUpdater.GetServers()
+	if err != nil || servers == nil {
+		return
+	}
+
+	// the connected host is identified by its public key
+	hostPublicKey := wgObj.HostPublicKey()
+	for _, server := range servers.WireguardServers {
+		for _, host := range server.Hosts {
+			if host.PublicKey != hostPublicKey {
+				continue
+			}
+
+			newHostIP := net.ParseIP(host.Host)
+			if newHostIP == nil || newHostIP.Equal(curHostIP) {
+				return // endpoint not changed (or not parsable)
+			}
+
+			log.Info(fmt.Sprintf("Server '%s' published new endpoint (%s -> %s). Updating tunnel in-place...", server.Gateway, curHostIP, newHostIP))
+
+			// the new endpoint must be allowed by the firewall before switching to it
+			if err := firewall.AddHostsToExceptions([]net.IP{newHostIP}, false); err != nil {
+				log.Error("Failed to add new endpoint to firewall exceptions: ", err)
+				return
+			}
+
+			if err := wgObj.UpdateEndpoint(newHostIP, 0); err != nil {
+				log.Error("Failed to update WG peer endpoint: ", err)
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,101 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"net"
+
+	"github.com/ivpn/desktop-app-daemon/udp2tcp"
+)
+
+// WireGuard UDP-over-TCP fallback transport (see 'udp2tcp' package)
+// When enabled (preference 'wg_tcp_fallback') and the UDP connection attempts failed
+// (handshake timeouts - all UDP traffic can be blocked by a local network), the daemon
+// retries the connection with WireGuard datagrams encapsulated into a TCP connection
+// to the same server.
+
+// wgTcpFallbackInit resets the fallback state (called on start of each new connection request)
+// isAllowed == false - fallback is not applicable to the connection type (e.g. OpenVPN, custom WG config)
+func (s *Service) wgTcpFallbackInit(isAllowed bool) {
+	s._wgTcpMutex.Lock()
+	defer s._wgTcpMutex.Unlock()
+	s._wgTcpAllowed = isAllowed
+	s._wgTcpActive = false
+}
+
+// wgTcpFallbackNext switches the connection to the UDP-over-TCP transport
+// (returns false - when fallback disabled, not applicable to the connection or already in use)
+func (s *Service) wgTcpFallbackNext() (description string, ok bool) {
+	if s._preferences.IsWGTcpFallback == false {
+		return "", false
+	}
+	if _, ok := s.v2rayParams(); ok {
+		return "", false // an obfuscation transport is already in use
+	}
+
+	s._wgTcpMutex.Lock()
+	defer s._wgTcpMutex.Unlock()
+
+	if s._wgTcpAllowed == false || s._wgTcpActive {
+		return "", false
+	}
+
+	s._wgTcpActive = true
+	return "Retrying over TCP transport (UDP appears to be blocked)", true
+}
+
+// wgTcpFallbackActive returns true when the next connection attempt have to use the UDP-over-TCP transport
+func (s *Service) wgTcpFallbackActive() bool {
+	s._wgTcpMutex.Lock()
+	defer s._wgTcpMutex.Unlock()
+	return s._wgTcpActive
+}
+
+// wgTcpStart starts new UDP-over-TCP transport instance for a connection (the previous instance is stopped)
+// Returns local UDP port the WireGuard connection have to be routed through
+func (s *Service) wgTcpStart(vpnHost net.IP, vpnPort int) (localPort int, err error) {
+	s.wgTcpStop()
+
+	obj := udp2tcp.CreateUdp2Tcp(vpnHost, vpnPort)
+	localPort, err = obj.Start()
+	if err != nil {
+		return 0, err
+	}
+
+	s._wgTcpMutex.Lock()
+	s._wgTcp = obj
+	s._wgTcpMutex.Unlock()
+	return localPort, nil
+}
+
+// wgTcpStop stops the UDP-over-TCP transport instance (if it is started)
+func (s *Service) wgTcpStop() {
+	s._wgTcpMutex.Lock()
+	obj := s._wgTcp
+	s._wgTcp = nil
+	s._wgTcpMutex.Unlock()
+
+	if obj != nil {
+		obj.Stop()
+	}
+}
@@ -30,6 +30,7 @@ import (
 
 type wifiInfo struct {
 	ssid       string
+	bssid      string
 	isInsecure bool
 }
 
@@ -55,10 +56,12 @@ func (s *Service) onWiFiChanged(ssid string) {
 		}
 	}()
 
+	bssid := wifiNotifier.GetCurrentBSSID()
 	isInsecure := wifiNotifier.GetCurrentNetworkIsInsecure()
 
 	lastWiFiInfo = &wifiInfo{
 		ssid,
+		bssid,
 		isInsecure}
 
 	// do delay before processing wifi change
@@ -68,18 +71,34 @@ func (s *Service) onWiFiChanged(ssid string) {
 		timerDelayedNotify = nil
 	}
 	timerDelayedNotify = time.AfterFunc(delayBeforeWiFiChangeNotify, func() {
-		if lastWiFiInfo == nil || lastWiFiInfo.ssid != ssid || lastWiFiInfo.isInsecure != isInsecure {
+		if lastWiFiInfo == nil || lastWiFiInfo.ssid != ssid || lastWiFiInfo.bssid != bssid || lastWiFiInfo.isInsecure != isInsecure {
 			return // do nothing (new wifi info available)
 		}
 
 		// notify clients about WiFi change
-		s._evtReceiver.OnWiFiChanged(ssid, isInsecure)
+		s._evtReceiver.OnWiFiChanged(ssid, bssid, isInsecure)
+
+		// LAN access rules can depend on trusted\untrusted state of a current network
+		if s._preferences.IsFwAllowLANTrustedOnly {
+			if err := s.applyKillSwitchAllowLAN(); err != nil {
+				log.Error("Failed to update AllowLAN configuration: ", err)
+			}
+		}
+
+		// a new network can be behind a captive portal (see 'service_captiveportal.go')
+		go s.captivePortalCheck()
 	})
 }
 
+// isCurrentNetworkInsecure returns true when the currently connected WiFi network is known as insecure
+// (insecure networks are treated as 'not trusted'; see 'IsFwAllowLANTrustedOnly' preference)
+func (s *Service) isCurrentNetworkInsecure() bool {
+	return wifiNotifier.GetCurrentNetworkIsInsecure()
+}
+
 // GetWiFiCurrentState returns info about currently connected wifi
-func (s *Service) GetWiFiCurrentState() (ssid string, isInsecureNetwork bool) {
-	return wifiNotifier.GetCurrentSSID(), wifiNotifier.GetCurrentNetworkIsInsecure()
+func (s *Service) GetWiFiCurrentState() (ssid string, bssid string, isInsecureNetwork bool) {
+	return wifiNotifier.GetCurrentSSID(), wifiNotifier.GetCurrentBSSID(), wifiNotifier.GetCurrentNetworkIsInsecure()
 }
 
 // GetWiFiAvailableNetworks returns list of available WIFI networks
@@ -24,6 +24,13 @@ package service
 
 import (
 	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ivpn/desktop-app-daemon/oshelpers/windows/iphlpapi"
+	"github.com/ivpn/desktop-app-daemon/service/platform"
+	"github.com/ivpn/desktop-app-daemon/shell"
 )
 
 func (s *Service) implIsGoingToPingServers(hosts []net.IP) error {
@@ -31,3 +38,72 @@ func (s *Service) implIsGoingToPingServers(hosts []net.IP) error {
 	// firewall configured to allow all connectivity for service
 	return nil
 }
+
+func (s *Service) implDiagnosticNetworkInfo() string {
+	info := diagnosticExec("route", "print")
+	info += diagnosticExec("ipconfig", "/all")
+	return info
+}
+
+func (s *Service) implDiagnosticFirewallInfo() string {
+	return diagnosticExec("netsh", "advfirewall", "show", "allprofiles")
+}
+
+// implGetTunnelStatistics returns raw byte counters of a network interface (via 'GetIfEntry' WinAPI)
+// Note: MIB_IFROW counters are 32-bit (possible wrap-around on very long sessions)
+func (s *Service) implGetTunnelStatistics(tunnelInterface *net.Interface) (received int64, sent int64, err error) {
+	row := iphlpapi.APIMibIfRow{DwIndex: uint32(tunnelInterface.Index)}
+	if err := iphlpapi.APIGetIfEntry(&row); err != nil {
+		return 0, 0, err
+	}
+	return int64(row.DwInOctets), int64(row.DwOutOctets), nil
+}
+
+// index of the tunnel interface which metric was modified (0 - metric not modified)
+var tunnelInterfaceIndex int
+
+func (s *Service) implOnVpnConnected(tunnelInterface *net.Interface) {
+	// Set the lowest metric for the tunnel interface.
+	// Windows chooses the outgoing interface by route metric + interface metric:
+	// a secondary adapter with a low automatic metric can win over the tunnel
+	// and traffic starts escaping the VPN.
+	idx := strconv.Itoa(tunnelInterface.Index)
+	if err := shell.Exec(log, "netsh", "interface", "ipv4", "set", "interface", idx, "metric=1"); err != nil {
+		log.Error("failed to update metric of the tunnel interface: ", err)
+		return
+	}
+	// IPv6 binding can be absent on the tunnel interface - error is not critical
+	shell.Exec(log, "netsh", "interface", "ipv6", "set", "interface", idx, "metric=1")
+
+	tunnelInterfaceIndex = tunnelInterface.Index
+}
+
+func (s *Service) implOnVpnDisconnected() {
+	if tunnelInterfaceIndex == 0 {
+		return
+	}
+	idx := strconv.Itoa(tunnelInterfaceIndex)
+	tunnelInterfaceIndex = 0
+
+	// restore automatic metric of the tunnel interface
+	shell.Exec(log, "netsh", "interface", "ipv4", "set", "interface", idx, "metric=automatic")
+	shell.Exec(log, "netsh", "interface", "ipv6", "set", "interface", idx, "metric=automatic")
+}
+
+func (s *Service) implCleanupNetworkArtifacts() error {
+	// uninstall leftover WireGuard tunnel service from a crashed session (e.g. 'WireGuardTunnel$IVPN')
+	// (the wintun adapter is removed together with the service)
+	tunnelName := filepath.Base(platform.WGConfigFilePath())
+	tunnelName = strings.TrimSuffix(tunnelName, filepath.Ext(tunnelName))
+	serviceName := "WireGuardTunnel$" + tunnelName
+
+	if err := shell.Exec(log, "sc", "stop", serviceName); err != nil {
+		// non-zero exit code here normally means 'service is not running'
+		log.Debug("sc stop ", serviceName, ": ", err)
+	}
+	if err := shell.Exec(log, "sc", "delete", serviceName); err != nil {
+		// non-zero exit code here normally means 'service does not exist' (nothing to cleanup)
+		log.Debug("sc delete ", serviceName, ": ", err)
+	}
+	return nil
+}
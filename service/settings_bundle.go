@@ -0,0 +1,120 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ivpn/desktop-app-daemon/helpers"
+	"github.com/ivpn/desktop-app-daemon/logger"
+	"github.com/ivpn/desktop-app-daemon/service/preferences"
+)
+
+// settingsBundle - settings data for migrating configuration between machines
+// NOTE! Secrets (session info, VPN credentials, WG private key) are exported
+// only by explicit request ('isIncludeSecrets'); the bundle is always encrypted
+type settingsBundle struct {
+	BundleVersion int
+	Preferences   preferences.Preferences
+}
+
+const settingsBundleVersion = 1
+
+// minimum passphrase length required when secrets are included into the bundle
+// (an exported bundle can be an offline brute-force target)
+const _minSecretsPassphraseLen = 12
+
+// ExportSettings returns encrypted and integrity-protected bundle of daemon settings.
+// By default secrets are excluded; 'isIncludeSecrets' allows to export them too
+// (they stay protected by the passphrase; enables full migration to another machine)
+func (s *Service) ExportSettings(passphrase string, isIncludeSecrets bool) (bundleBase64 string, err error) {
+	if len(passphrase) == 0 {
+		return "", fmt.Errorf("passphrase not defined")
+	}
+	if isIncludeSecrets && len(passphrase) < _minSecretsPassphraseLen {
+		return "", fmt.Errorf("passphrase too short: at least %d characters required to export secrets", _minSecretsPassphraseLen)
+	}
+
+	prefs := s._preferences
+	if isIncludeSecrets == false {
+		// erase secrets (session info contains tokens, VPN credentials and WG private key)
+		prefs.Session = preferences.SessionStatus{}
+	}
+
+	bundle := settingsBundle{
+		BundleVersion: settingsBundleVersion,
+		Preferences:   prefs}
+
+	data, err := json.Marshal(bundle)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize settings bundle: %w", err)
+	}
+
+	encrypted, err := helpers.EncryptAESGCM(data, passphrase)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt settings bundle: %w", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(encrypted), nil
+}
+
+// ImportSettings applies settings from a bundle created by ExportSettings
+// (current session info is kept unchanged)
+func (s *Service) ImportSettings(bundleBase64 string, passphrase string) error {
+	if len(passphrase) == 0 {
+		return fmt.Errorf("passphrase not defined")
+	}
+
+	encrypted, err := base64.StdEncoding.DecodeString(bundleBase64)
+	if err != nil {
+		return fmt.Errorf("failed to decode settings bundle: %w", err)
+	}
+
+	data, err := helpers.DecryptAESGCM(encrypted, passphrase)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt settings bundle: %w", err)
+	}
+
+	var bundle settingsBundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return fmt.Errorf("failed to deserialize settings bundle: %w", err)
+	}
+	if bundle.BundleVersion != settingsBundleVersion {
+		return fmt.Errorf("unsupported settings bundle version (%d)", bundle.BundleVersion)
+	}
+
+	prefs := bundle.Preferences
+	if prefs.Session.IsLoggedIn() == false {
+		// bundle does not contain secrets - keep current session info
+		prefs.Session = s._preferences.Session
+	}
+
+	s.setPreferences(prefs)
+
+	// apply activated preferences
+	logger.Enable(prefs.IsLogging)
+
+	return nil
+}
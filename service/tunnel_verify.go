@@ -0,0 +1,76 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ivpn/desktop-app-daemon/ping"
+	"github.com/ivpn/desktop-app-daemon/vpn"
+)
+
+const (
+	// overall timeout of the in-tunnel connectivity verification
+	tunnelVerifyTimeout = time.Second * 15
+	// timeout for a single ping of the in-tunnel VPN gateway
+	tunnelVerifyPingTimeout = time.Second * 2
+)
+
+// verifyTunnelConnectivity confirms that traffic actually passes through the just-established
+// tunnel by pinging the VPN gateway inside the tunnel.
+// It is in use before reporting CONNECTED state to clients: the WireGuard interface comes up
+// even when the handshake with the server did not happen (e.g. UDP blocked by the network).
+// Returns nil when the gateway responded (or when there is no gateway info to verify).
+func (s *Service) verifyTunnelConnectivity(vpnObj vpn.Process) error {
+	gatewayIP := vpnObj.InTunnelGatewayIP()
+	if gatewayIP == nil {
+		return nil // no information about in-tunnel gateway (nothing to verify)
+	}
+
+	startTime := time.Now()
+	for {
+		pinger, err := ping.NewPinger(gatewayIP.String())
+		if err != nil {
+			// do not block the connection because of internal pinger problem
+			log.Error("Tunnel verification: pinger creation error: " + err.Error())
+			return nil
+		}
+		pinger.SetPrivileged(true)
+		pinger.Count = 1
+		pinger.Timeout = tunnelVerifyPingTimeout
+		pinger.Run()
+
+		if stat := pinger.Statistics(); stat.PacketsRecv > 0 {
+			log.Info(fmt.Sprintf("Tunnel verified: VPN gateway %s responded in %v", gatewayIP, time.Since(startTime)))
+			return nil
+		}
+
+		if s._requiredVpnState == Disconnect {
+			return nil // disconnection requested: verification makes no sense anymore
+		}
+		if time.Since(startTime) >= tunnelVerifyTimeout {
+			return fmt.Errorf("no response from in-tunnel VPN gateway %s within %v", gatewayIP, tunnelVerifyTimeout)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ivpn/desktop-app-daemon/ping"
+)
+
+const (
+	// interval between tunnel liveness checks
+	tunnelWatchdogInterval = time.Second * 30
+	// timeout for a single ping of the in-tunnel VPN gateway
+	tunnelWatchdogPingTimeout = time.Second * 3
+	// amount of consecutive failed checks before tunnel treated as dead
+	tunnelWatchdogMaxFails = 3
+)
+
+// checkTunnelAlive verifies the active tunnel by pinging the VPN gateway inside the tunnel.
+// When tunnel is dead (WireGuard: no handshake/response; OpenVPN: stalled session) - initiates reconnection.
+// The reconnection is performed by 'keepConnection(...)' loop; clients receive RECONNECTING state with reason.
+func (s *Service) checkTunnelAlive(failsCounter *int) {
+	vpnObj := s._vpn
+	if vpnObj == nil {
+		*failsCounter = 0
+		return
+	}
+	if vpnObj.IsPaused() {
+		*failsCounter = 0
+		return
+	}
+	// checking only fully established connections
+	if s._requiredVpnState != KeepConnection {
+		*failsCounter = 0
+		return
+	}
+
+	gatewayIP := vpnObj.InTunnelGatewayIP()
+	if gatewayIP == nil {
+		return // no information about in-tunnel gateway (nothing to check)
+	}
+
+	pinger, err := ping.NewPinger(gatewayIP.String())
+	if err != nil {
+		log.Error("Tunnel watchdog: pinger creation error: " + err.Error())
+		return
+	}
+	pinger.SetPrivileged(true)
+	pinger.Count = 1
+	pinger.Timeout = tunnelWatchdogPingTimeout
+	pinger.Run()
+
+	if stat := pinger.Statistics(); stat.PacketsRecv > 0 {
+		*failsCounter = 0
+		return // tunnel is alive
+	}
+
+	*failsCounter++
+	log.Warning(fmt.Sprintf("Tunnel watchdog: no response from VPN gateway %s (%d/%d)", gatewayIP, *failsCounter, tunnelWatchdogMaxFails))
+
+	if *failsCounter < tunnelWatchdogMaxFails {
+		return
+	}
+	*failsCounter = 0
+
+	// tunnel is dead - reconnecting
+	// (reconnection is performed by 'keepConnection(...)' loop according to '_requiredVpnState')
+	log.Info("Tunnel watchdog: tunnel is dead. Reconnecting...")
+	s._reconnectReason = "Reconnecting due to dead tunnel (no response from VPN gateway)"
+	s.reconnect()
+}
+
+// tunnelWatchdogReconnectReason returns (and erases) reason of watchdog-initiated reconnection
+func (s *Service) tunnelWatchdogReconnectReason() string {
+	reason := s._reconnectReason
+	s._reconnectReason = ""
+	if len(reason) == 0 {
+		return "Reconnecting due to disconnection"
+	}
+	return reason
+}
+
+// startTunnelWatchdog starts periodical tunnel liveness checks for the active connection
+// (stops when 'stopChannel' closed)
+func (s *Service) startTunnelWatchdog(stopChannel <-chan bool) {
+	log.Info("Tunnel watchdog started")
+	defer log.Info("Tunnel watchdog stopped")
+
+	failsCounter := 0
+	for {
+		select {
+		case <-time.After(tunnelWatchdogInterval):
+			s.checkTunnelAlive(&failsCounter)
+		case <-stopChannel: // triggered when the stopChannel is closed
+			return
+		}
+	}
+}
@@ -41,9 +41,19 @@ func init() {
 
 //HardExpirationIntervalDays = 40;
 
+// interval limits of re-try attempts when key update failed (e.g. offline, API blocked)
+// (interval is growing exponentially, starting from the minimum value)
+const (
+	updateRetryIntervalMin = time.Minute * 5
+	updateRetryIntervalMax = time.Hour
+)
+
 // IWgKeysChangeReceiver WG key update handler
 type IWgKeysChangeReceiver interface {
 	WireGuardSaveNewKeys(wgPublicKey string, wgPrivateKey string, wgLocalIP string)
+	// WireGuardSavePendingKeys - save (persistently) WG key pair which is not confirmed by the API yet (empty values - erase)
+	WireGuardSavePendingKeys(wgPublicKey string, wgPrivateKey string)
+	WireGuardGetPendingKeys() (wgPublicKey, wgPrivateKey string)
 	WireGuardGetKeys() (session, wgPublicKey, wgPrivateKey, wgLocalIP string, generatedTime time.Time, updateInterval time.Duration)
 	FirewallEnabled() (bool, error)
 	Connected() bool
@@ -101,13 +111,23 @@ func (m *KeysManager) StartKeysRotation() error {
 
 		needStop := false
 		isLastUpdateFailed := false
+		retryInterval := updateRetryIntervalMin
 
 		for needStop == false {
 			_, _, _, _, lastUpdate, interval = m.service.WireGuardGetKeys()
 			waitInterval := time.Until(lastUpdate.Add(interval))
 			if isLastUpdateFailed {
-				waitInterval = time.Hour
+				// previous update attempt failed (e.g. offline, API blocked)
+				// re-try with exponentially growing interval
+				waitInterval = retryInterval
 				lastUpdate = time.Now()
+
+				retryInterval *= 2
+				if retryInterval > updateRetryIntervalMax {
+					retryInterval = updateRetryIntervalMax
+				}
+			} else {
+				retryInterval = updateRetryIntervalMin
 			}
 
 			// update immediately, if it is a time
@@ -211,9 +231,18 @@ func (m *KeysManager) generateKeys(onlyUpdateIfNecessary bool) (retErr error) {
 
 	log.Info("Updating WG keys...")
 
-	pub, priv, err := wireguard.GenerateKeys(m.wgToolBinPath)
-	if err != nil {
-		return err
+	// If there is a pending key pair (generated by a previous failed update) - re-use it:
+	// the API could have accepted the key even when we did not receive the response
+	pub, priv := m.service.WireGuardGetPendingKeys()
+	if len(pub) == 0 || len(priv) == 0 {
+		var err error
+		pub, priv, err = wireguard.GenerateKeys(m.wgToolBinPath)
+		if err != nil {
+			return err
+		}
+		// persist the generated pair: the update will be re-tried with the same keys
+		// until the API confirms it (even after daemon restart)
+		m.service.WireGuardSavePendingKeys(pub, priv)
 	}
 
 	isVPNConnected, connectedVpnType := m.service.ConnectedType()
@@ -245,6 +274,9 @@ func (m *KeysManager) generateKeys(onlyUpdateIfNecessary bool) (retErr error) {
 
 	log.Info(fmt.Sprintf("WG keys updated (%s:%s) ", localIP.String(), pub))
 
+	// the new key pair is confirmed by the API - erase pending state
+	m.service.WireGuardSavePendingKeys("", "")
+
 	// notify service about new keys
 	m.service.WireGuardSaveNewKeys(pub, priv, localIP.String())
 
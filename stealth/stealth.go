@@ -0,0 +1,162 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+// Package stealth implements a TLS-wrapping obfuscation transport ('stealth' connection mode):
+// OpenVPN TCP traffic is encapsulated into ordinary TLS to port 443 of an obfuscation endpoint
+// (stunnel-like setup: the endpoint terminates TLS and forwards plain TCP to the OpenVPN server).
+// Implemented natively (crypto/tls); no child process required.
+package stealth
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ivpn/desktop-app-daemon/logger"
+)
+
+var log *logger.Logger
+
+func init() {
+	log = logger.NewLogger("stlth")
+}
+
+const _dialTimeout = time.Second * 15
+
+// Stealth - local TCP listener forwarding VPN traffic to the obfuscation endpoint over TLS
+type Stealth struct {
+	endpointHost string
+	endpointPort int
+	serverName   string // SNI of the TLS connection (empty - endpoint host)
+
+	mutex       sync.Mutex
+	listener    net.Listener
+	connections map[net.Conn]struct{}
+}
+
+// CreateStealth creates new stealth transport object
+func CreateStealth(endpointHost string, endpointPort int, tlsServerName string) *Stealth {
+	return &Stealth{
+		endpointHost: endpointHost,
+		endpointPort: endpointPort,
+		serverName:   tlsServerName,
+		connections:  map[net.Conn]struct{}{}}
+}
+
+// Start initializes the local listener (asynchronous; returns local port to connect VPN to)
+func (s *Stealth) Start() (localPort int, err error) {
+	if len(s.endpointHost) == 0 || s.endpointPort <= 0 {
+		return 0, fmt.Errorf("stealth obfuscation endpoint not defined")
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, fmt.Errorf("failed to start stealth listener: %w", err)
+	}
+
+	s.mutex.Lock()
+	s.listener = listener
+	s.mutex.Unlock()
+
+	localPort = listener.Addr().(*net.TCPAddr).Port
+	log.Info(fmt.Sprintf("Stealth transport started: 127.0.0.1:%d -> TLS %s:%d", localPort, s.endpointHost, s.endpointPort))
+
+	go s.acceptLoop(listener)
+
+	return localPort, nil
+}
+
+// Stop closes the local listener and all active connections
+func (s *Stealth) Stop() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.listener != nil {
+		log.Info("Stopping stealth transport...")
+		s.listener.Close()
+		s.listener = nil
+	}
+	for conn := range s.connections {
+		conn.Close()
+	}
+	s.connections = map[net.Conn]struct{}{}
+}
+
+func (s *Stealth) acceptLoop(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return // listener closed
+		}
+		go s.forward(conn)
+	}
+}
+
+func (s *Stealth) forward(local net.Conn) {
+	defer local.Close()
+
+	serverName := s.serverName
+	if len(serverName) == 0 {
+		serverName = s.endpointHost
+	}
+
+	dialer := &net.Dialer{Timeout: _dialTimeout}
+	remote, err := tls.DialWithDialer(dialer,
+		"tcp",
+		net.JoinHostPort(s.endpointHost, strconv.Itoa(s.endpointPort)),
+		&tls.Config{ServerName: serverName})
+	if err != nil {
+		log.Error("Failed to connect stealth endpoint: ", err)
+		return
+	}
+	defer remote.Close()
+
+	s.registerConnection(local, true)
+	s.registerConnection(remote, true)
+	defer s.registerConnection(local, false)
+	defer s.registerConnection(remote, false)
+
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(remote, local)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(local, remote)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
+func (s *Stealth) registerConnection(conn net.Conn, isAdd bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if isAdd {
+		s.connections[conn] = struct{}{}
+	} else {
+		delete(s.connections, conn)
+	}
+}
@@ -0,0 +1,185 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+// Package udp2tcp implements a UDP-over-TCP fallback transport for WireGuard
+// (udp2raw-style setup) for networks where all UDP traffic is blocked:
+// WireGuard datagrams received on a local UDP listener are framed with a
+// 2-byte length prefix and forwarded over a single TCP connection to the VPN server.
+// Implemented natively; no child process required.
+package udp2tcp
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ivpn/desktop-app-daemon/logger"
+)
+
+var log *logger.Logger
+
+func init() {
+	log = logger.NewLogger("u2tcp")
+}
+
+const (
+	_dialTimeout = time.Second * 15
+	// maximum size of a forwarded datagram
+	// (WireGuard packets never exceed the tunnel MTU, but the frame format allows up to 64KB)
+	_maxDatagramSize = 0xffff
+)
+
+// Udp2Tcp - local UDP listener forwarding WireGuard datagrams to the VPN server over TCP
+type Udp2Tcp struct {
+	endpointIP   net.IP
+	endpointPort int
+
+	mutex      sync.Mutex
+	udpConn    *net.UDPConn
+	tcpConn    net.Conn
+	clientAddr *net.UDPAddr // source address of the local WireGuard socket (known after first datagram)
+}
+
+// CreateUdp2Tcp creates new UDP-over-TCP transport object
+func CreateUdp2Tcp(endpointIP net.IP, endpointPort int) *Udp2Tcp {
+	return &Udp2Tcp{
+		endpointIP:   endpointIP,
+		endpointPort: endpointPort}
+}
+
+// Start initializes the transport (asynchronous; returns local UDP port to connect WireGuard to)
+func (u *Udp2Tcp) Start() (localPort int, err error) {
+	if u.endpointIP == nil || u.endpointPort <= 0 {
+		return 0, fmt.Errorf("UDP-over-TCP endpoint not defined")
+	}
+
+	udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		return 0, fmt.Errorf("failed to start UDP-over-TCP listener: %w", err)
+	}
+
+	tcpConn, err := net.DialTimeout("tcp",
+		net.JoinHostPort(u.endpointIP.String(), strconv.Itoa(u.endpointPort)),
+		_dialTimeout)
+	if err != nil {
+		udpConn.Close()
+		return 0, fmt.Errorf("failed to connect UDP-over-TCP endpoint: %w", err)
+	}
+	if tcp, ok := tcpConn.(*net.TCPConn); ok {
+		tcp.SetNoDelay(true) // handshake latency matters more than throughput
+	}
+
+	u.mutex.Lock()
+	u.udpConn = udpConn
+	u.tcpConn = tcpConn
+	u.mutex.Unlock()
+
+	localPort = udpConn.LocalAddr().(*net.UDPAddr).Port
+	log.Info(fmt.Sprintf("UDP-over-TCP transport started: 127.0.0.1:%d (UDP) -> %s:%d (TCP)", localPort, u.endpointIP, u.endpointPort))
+
+	go u.forwardUDPToTCP(udpConn, tcpConn)
+	go u.forwardTCPToUDP(udpConn, tcpConn)
+
+	return localPort, nil
+}
+
+// Stop closes the local listener and the TCP connection
+func (u *Udp2Tcp) Stop() {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	if u.udpConn != nil {
+		log.Info("Stopping UDP-over-TCP transport...")
+		u.udpConn.Close()
+		u.udpConn = nil
+	}
+	if u.tcpConn != nil {
+		u.tcpConn.Close()
+		u.tcpConn = nil
+	}
+}
+
+// forwardUDPToTCP reads WireGuard datagrams from the local UDP listener
+// and forwards them (framed with a 2-byte length prefix) into the TCP connection
+func (u *Udp2Tcp) forwardUDPToTCP(udpConn *net.UDPConn, tcpConn net.Conn) {
+	defer u.Stop()
+
+	buff := make([]byte, _maxDatagramSize+2)
+	for {
+		size, addr, err := udpConn.ReadFromUDP(buff[2:])
+		if err != nil {
+			return // listener closed
+		}
+		u.setClientAddr(addr)
+
+		binary.BigEndian.PutUint16(buff[:2], uint16(size))
+		if _, err := tcpConn.Write(buff[:size+2]); err != nil {
+			log.Error("Failed to forward datagram into TCP connection: ", err)
+			return
+		}
+	}
+}
+
+// forwardTCPToUDP reads length-prefixed frames from the TCP connection
+// and forwards the encapsulated datagrams back to the local WireGuard socket
+func (u *Udp2Tcp) forwardTCPToUDP(udpConn *net.UDPConn, tcpConn net.Conn) {
+	defer u.Stop()
+
+	reader := bufio.NewReader(tcpConn)
+	sizeBuff := make([]byte, 2)
+	buff := make([]byte, _maxDatagramSize)
+	for {
+		if _, err := io.ReadFull(reader, sizeBuff); err != nil {
+			return // connection closed
+		}
+		size := int(binary.BigEndian.Uint16(sizeBuff))
+		if _, err := io.ReadFull(reader, buff[:size]); err != nil {
+			return // connection closed
+		}
+
+		clientAddr := u.getClientAddr()
+		if clientAddr == nil {
+			continue // WireGuard did not send anything yet: nowhere to forward
+		}
+		if _, err := udpConn.WriteToUDP(buff[:size], clientAddr); err != nil {
+			log.Error("Failed to forward datagram to local WireGuard socket: ", err)
+			return
+		}
+	}
+}
+
+func (u *Udp2Tcp) setClientAddr(addr *net.UDPAddr) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.clientAddr = addr
+}
+
+func (u *Udp2Tcp) getClientAddr() *net.UDPAddr {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	return u.clientAddr
+}
@@ -0,0 +1,116 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package v2ray
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+)
+
+// ObfuscationParams - parameters of V2Ray obfuscation transport
+type ObfuscationParams struct {
+	// Transport - "tls" (VMess over TCP+TLS) or "ws" (VMess over WebSocket+TLS)
+	Transport string
+	// EndpointHost\EndpointPort - obfuscation endpoint the wrapped traffic is forwarded to
+	EndpointHost string
+	EndpointPort int
+	// VMessID - user ID (UUID) of the VMess protocol
+	VMessID string
+	// TLSServerName - SNI to use for the TLS connection (empty - use EndpointHost)
+	TLSServerName string
+}
+
+// IsValid checks obfuscation parameters
+func (p ObfuscationParams) IsValid() error {
+	if p.Transport != TransportTLS && p.Transport != TransportWS {
+		return fmt.Errorf("unexpected V2Ray transport type: '%s'", p.Transport)
+	}
+	if len(p.EndpointHost) == 0 || p.EndpointPort <= 0 || p.EndpointPort > 65535 {
+		return fmt.Errorf("V2Ray obfuscation endpoint not defined")
+	}
+	if len(p.VMessID) == 0 {
+		return fmt.Errorf("V2Ray VMess ID not defined")
+	}
+	return nil
+}
+
+// generateConfiguration returns configuration of the V2Ray process (JSON):
+// single inbound (local listener for VPN traffic) and single VMess outbound (obfuscation endpoint).
+//	- UDP VPN traffic (WireGuard, OpenVPN UDP) enters via 'dokodemo-door' inbound targeted to the VPN server
+//	- TCP VPN traffic (OpenVPN TCP) enters via local 'socks' inbound
+func generateConfiguration(localPort int, isTCP bool, vpnHost net.IP, vpnPort int, params ObfuscationParams) ([]byte, error) {
+	type dict = map[string]interface{}
+
+	var inbound dict
+	if isTCP {
+		inbound = dict{
+			"listen":   "127.0.0.1",
+			"port":     localPort,
+			"protocol": "socks",
+			"settings": dict{"udp": false}}
+	} else {
+		inbound = dict{
+			"listen":   "127.0.0.1",
+			"port":     localPort,
+			"protocol": "dokodemo-door",
+			"settings": dict{
+				"address": vpnHost.String(),
+				"port":    vpnPort,
+				"network": "udp"}}
+	}
+
+	serverName := params.TLSServerName
+	if len(serverName) == 0 {
+		serverName = params.EndpointHost
+	}
+
+	streamSettings := dict{
+		"security":    "tls",
+		"tlsSettings": dict{"serverName": serverName}}
+	if params.Transport == TransportWS {
+		streamSettings["network"] = "ws"
+		streamSettings["wsSettings"] = dict{"path": "/"}
+	} else {
+		streamSettings["network"] = "tcp"
+	}
+
+	outbound := dict{
+		"protocol": "vmess",
+		"settings": dict{
+			"vnext": []dict{{
+				"address": params.EndpointHost,
+				"port":    params.EndpointPort,
+				"users": []dict{{
+					"id":       params.VMessID,
+					"alterId":  0,
+					"security": "none"}}}}},
+		"streamSettings": streamSettings}
+
+	config := dict{
+		"log":       dict{"loglevel": "warning"},
+		"inbounds":  []dict{inbound},
+		"outbounds": []dict{outbound}}
+
+	return json.Marshal(config)
+}
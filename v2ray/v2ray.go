@@ -0,0 +1,233 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+// Package v2ray implements V2Ray-based obfuscation transport:
+// VPN traffic (WireGuard or OpenVPN) is wrapped into VMess over TLS\WebSocket
+// and forwarded to a designated obfuscation endpoint (useful behind DPI-heavy firewalls).
+// The V2Ray binary is running as a child process of the daemon and monitored by periodical health checks.
+package v2ray
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"os/exec"
+	"time"
+
+	"github.com/ivpn/desktop-app-daemon/logger"
+	"github.com/ivpn/desktop-app-daemon/service/platform/filerights"
+	"github.com/ivpn/desktop-app-daemon/shell"
+)
+
+var log *logger.Logger
+
+func init() {
+	log = logger.NewLogger("v2ray")
+	rand.Seed(time.Now().UnixNano())
+}
+
+// Supported transport types
+const (
+	TransportTLS = "tls"
+	TransportWS  = "ws"
+)
+
+const (
+	constDefLocalPort         = 1070
+	constMaxConnectionRetries = 7
+	constHealthCheckInterval  = time.Second * 30
+	constStartTimeout         = time.Second * 10
+)
+
+// V2Ray structure. Represents the V2Ray child process
+type V2Ray struct {
+	binaryPath     string
+	configFilePath string
+	params         ObfuscationParams
+	localPort      int
+	isTCP          bool
+	command        *exec.Cmd
+	stoppedChan    chan struct{}
+	exitError      error
+}
+
+// CreateV2Ray creates new V2Ray object
+func CreateV2Ray(binaryPath string, configFilePath string, params ObfuscationParams) *V2Ray {
+	return &V2Ray{binaryPath: binaryPath, configFilePath: configFilePath, params: params}
+}
+
+// Start - asynchronously start V2Ray process: local listener forwards VPN traffic
+// (destined to 'vpnHost:vpnPort') to the obfuscation endpoint
+func (v *V2Ray) Start(isTCP bool, vpnHost net.IP, vpnPort int) (localPort int, err error) {
+	if err := v.params.IsValid(); err != nil {
+		return 0, err
+	}
+
+	defer func() {
+		if err != nil {
+			log.Error("Error starting V2Ray: ", err)
+			v.Stop()
+		}
+	}()
+
+	// retry-loop (required local port may be already in use)
+	port := constDefLocalPort
+	for tryNo := 0; tryNo < constMaxConnectionRetries; tryNo++ {
+		if checkIsPortInUse(port) {
+			newPort := getRandPort()
+			log.Info(fmt.Sprintf("Local port %d already in use. Trying another port %d.", port, newPort))
+			port = newPort
+			continue
+		}
+
+		if err := v.start(port, isTCP, vpnHost, vpnPort); err != nil {
+			return 0, fmt.Errorf("failed to start V2Ray: %w", err)
+		}
+
+		v.localPort = port
+		v.isTCP = isTCP
+		go v.healthCheckLoop()
+		return port, nil
+	}
+
+	return 0, errors.New("V2Ray not started: no free local port found")
+}
+
+// Wait - wait for V2Ray process stop
+func (v *V2Ray) Wait() error {
+	stopped := v.stoppedChan
+	if stopped == nil {
+		return nil
+	}
+	<-stopped
+	return v.exitError
+}
+
+// Stop - stop V2Ray process
+func (v *V2Ray) Stop() {
+	cmd := v.command
+	if cmd == nil {
+		return
+	}
+
+	log.Info("Stopping V2Ray...")
+	if err := shell.Kill(cmd); err != nil {
+		log.Error(err)
+	}
+}
+
+func (v *V2Ray) start(localPort int, isTCP bool, vpnHost net.IP, vpnPort int) error {
+	config, err := generateConfiguration(localPort, isTCP, vpnHost, vpnPort, v.params)
+	if err != nil {
+		return fmt.Errorf("failed to generate V2Ray configuration: %w", err)
+	}
+
+	if err := ioutil.WriteFile(v.configFilePath, config, 0600); err != nil { // read\write only for privileged user
+		return fmt.Errorf("failed to save V2Ray configuration: %w", err)
+	}
+	// only for Windows: Golang is not able to change file permissins in Windows style
+	if err := filerights.WindowsChmod(v.configFilePath, 0600); err != nil {
+		return fmt.Errorf("failed to change V2Ray configuration file permissions: %w", err)
+	}
+
+	cmd := exec.Command(v.binaryPath, "-config", v.configFilePath)
+
+	outputParseFunc := func(text string, isError bool) {
+		if isError {
+			log.Error("[ERR] ", text)
+		} else {
+			log.Debug("[OUT] ", text)
+		}
+	}
+	if err := shell.StartConsoleReaders(cmd, outputParseFunc); err != nil {
+		return fmt.Errorf("failed to init V2Ray command: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start V2Ray process: %w", err)
+	}
+
+	v.command = cmd
+	v.stoppedChan = make(chan struct{})
+	go func() {
+		v.exitError = cmd.Wait()
+		log.Info("V2Ray stopped")
+		close(v.stoppedChan)
+	}()
+
+	// waiting until local listener is ready (ensure process successfully started)
+	started := time.Now()
+	for {
+		if shell.IsRunning(cmd) == false {
+			return errors.New("V2Ray process unexpectedly stopped")
+		}
+		// UDP inbound ('dokodemo-door') can not be checked by a connection - only TCP
+		if isTCP == false || checkIsPortInUse(localPort) {
+			break
+		}
+		if time.Since(started) > constStartTimeout {
+			return errors.New("V2Ray start timeout")
+		}
+		time.Sleep(time.Millisecond * 50)
+	}
+
+	log.Info(fmt.Sprintf("Started on local port %d (transport: %s)", localPort, v.params.Transport))
+	return nil
+}
+
+// healthCheckLoop periodically checks that the V2Ray process is operational.
+// When the check fails - the process is stopped: the VPN layer detects a dead tunnel and re-connects
+// (which starts a new V2Ray instance).
+func (v *V2Ray) healthCheckLoop() {
+	stopped := v.stoppedChan
+	for {
+		select {
+		case <-stopped:
+			return
+		case <-time.After(constHealthCheckInterval):
+		}
+
+		if shell.IsRunning(v.command) == false {
+			return
+		}
+		if v.isTCP && checkIsPortInUse(v.localPort) == false {
+			log.Error("Health check failed: V2Ray local listener is not responding. Stopping V2Ray process.")
+			v.Stop()
+			return
+		}
+	}
+}
+
+func getRandPort() int {
+	return constDefLocalPort + rand.Intn(3000)
+}
+
+func checkIsPortInUse(localPort int) bool {
+	conn, err := net.DialTimeout("tcp", fmt.Sprintf("127.0.0.1:%d", localPort), time.Second)
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+	return true
+}
@@ -49,6 +49,11 @@ type ConnectionParams struct {
 	proxyPort         int
 	proxyUsername     string
 	proxyPassword     string
+
+	// importedProfileText - content of an imported third-party '.ovpn' profile
+	// (when defined - configuration is generated from the profile instead of IVPN server parameters;
+	// see 'generateImportedProfileConfiguration()')
+	importedProfileText string
 }
 
 // SetCredentials update WG credentials
@@ -63,6 +68,45 @@ func (c *ConnectionParams) SetCredentials(username, password string) {
 	}
 }
 
+// SetImportedProfile sets content of an imported third-party '.ovpn' profile
+// (content must be already validated; see 'ValidateImportedProfile()')
+func (c *ConnectionParams) SetImportedProfile(profileText string) {
+	c.importedProfileText = profileText
+}
+
+// Port returns destination port and protocol of the connection
+func (c *ConnectionParams) Port() (port int, isTCP bool) {
+	return c.hostPort, c.tcp
+}
+
+// SetPort overrides destination port and protocol of the connection
+func (c *ConnectionParams) SetPort(port int, tcp bool) {
+	c.hostPort = port
+	c.tcp = tcp
+}
+
+// Host returns destination host IP of the connection
+func (c *ConnectionParams) Host() net.IP {
+	return c.hostIP
+}
+
+// SetHost overrides destination host IP of the connection
+// (e.g. to a local listener of an obfuscation transport)
+func (c *ConnectionParams) SetHost(hostIP net.IP) {
+	c.hostIP = hostIP
+}
+
+// SetLocalSocksProxy - route the connection via a local SOCKS proxy
+// (e.g. a local listener of an obfuscation transport; implies TCP connection to the proxy)
+func (c *ConnectionParams) SetLocalSocksProxy(port int) {
+	c.tcp = true
+	c.proxyType = "socks"
+	c.proxyAddress = net.IPv4(127, 0, 0, 1)
+	c.proxyPort = port
+	c.proxyUsername = ""
+	c.proxyPassword = ""
+}
+
 // CreateConnectionParams creates OpenVPN connection parameters object
 func CreateConnectionParams(
 	multihopExitSrvID string,
@@ -132,6 +176,13 @@ func (c *ConnectionParams) generateConfiguration(
 	extraParameters string,
 	isCanUseV24Params bool) (cfg []string, err error) {
 
+	// imported third-party profile: connection directives are taken from the profile,
+	// daemon adds only the management interface, logging and up\down scripts
+	// (the same state machine and firewall integration as for IVPN servers)
+	if len(c.importedProfileText) > 0 {
+		return c.generateImportedProfileConfiguration(miAddr, miPort, logFile)
+	}
+
 	if obfsproxyPort > 0 {
 		c.tcp = true
 		c.hostPort = platform.ObfsproxyHostPort()
@@ -154,6 +205,10 @@ func (c *ConnectionParams) generateConfiguration(
 
 	cfg = append(cfg, "management-query-passwords")
 
+	// do not exit on authentication error: re-query credentials over the management interface
+	// (allows a client to supply new credentials and resume the same connection attempt)
+	cfg = append(cfg, "auth-retry interact")
+
 	cfg = append(cfg, "management-signal")
 
 	// Handshake Window --the TLS - based key exchange must finalize within n seconds of handshake initiation by any peer(default = 60 seconds).
@@ -235,10 +290,18 @@ func (c *ConnectionParams) generateConfiguration(
 	}
 	cfg = append(cfg, fmt.Sprintf("ca \"%s\"", platform.OpenvpnCaKeyFile()))
 
-	if _, err := os.Stat(platform.OpenvpnTaKeyFile()); os.IsNotExist(err) {
-		return nil, errors.New("TLS auth key not found")
+	if tlsCryptV2Key := getTLSCryptV2Key(); len(tlsCryptV2Key) > 0 {
+		// per-client control channel protection (see 'tlscrypt.go'; requires OpenVPN 2.5+)
+		// the key is included inline: it is not saved into a separate file
+		cfg = append(cfg, "<tls-crypt-v2>")
+		cfg = append(cfg, strings.Split(tlsCryptV2Key, "\n")...)
+		cfg = append(cfg, "</tls-crypt-v2>")
+	} else {
+		if _, err := os.Stat(platform.OpenvpnTaKeyFile()); os.IsNotExist(err) {
+			return nil, errors.New("TLS auth key not found")
+		}
+		cfg = append(cfg, fmt.Sprintf("tls-auth \"%s\" 1", platform.OpenvpnTaKeyFile()))
 	}
-	cfg = append(cfg, fmt.Sprintf("tls-auth \"%s\" 1", platform.OpenvpnTaKeyFile()))
 
 	cfg = append(cfg, "cipher AES-256-CBC")
 	cfg = append(cfg, "remote-cert-tls server")
@@ -271,6 +334,9 @@ func (c *ConnectionParams) generateConfiguration(
 		}
 	}
 
+	// OS-specific configuration parameters (if defined)
+	cfg = append(cfg, getOSSpecificConfigParams(isCanUseV24Params)...)
+
 	cfg, err = addUserDefinedParameters(cfg, extraParameters)
 	if err != nil {
 		return nil, fmt.Errorf("failed to add user-defined parameters: %w", err)
@@ -279,6 +345,47 @@ func (c *ConnectionParams) generateConfiguration(
 	return cfg, nil
 }
 
+// generateImportedProfileConfiguration builds configuration for an imported third-party '.ovpn' profile:
+// daemon-controlled directives (management interface, credentials query, logging, up\down scripts)
+// followed by the validated profile content
+func (c *ConnectionParams) generateImportedProfileConfiguration(miAddr string, miPort int, logFile string) ([]string, error) {
+	cfg := make([]string, 0, 16)
+
+	cfg = append(cfg, fmt.Sprintf("management %s %d", miAddr, miPort))
+	cfg = append(cfg, "management-client")
+	cfg = append(cfg, "management-hold")
+	cfg = append(cfg, "management-signal")
+
+	// profile credentials (when defined) are provided over the management interface
+	if len(c.username) > 0 && len(c.password) > 0 {
+		cfg = append(cfg, "auth-user-pass")
+		cfg = append(cfg, "auth-nocache")
+		cfg = append(cfg, "management-query-passwords")
+	}
+
+	if len(logFile) > 0 && logger.IsEnabled() {
+		cfg = append(cfg, fmt.Sprintf(`log "%s"`, logFile))
+	}
+
+	if upCmd := platform.OpenvpnUpScript(); upCmd != "" {
+		cfg = append(cfg, "up \""+upCmd+"\"")
+	}
+	if downCmd := platform.OpenvpnDownScript(); downCmd != "" {
+		cfg = append(cfg, "down \""+downCmd+"\"")
+	}
+	cfg = append(cfg, "script-security 2")
+
+	// the profile content itself (must be already validated; see 'ValidateImportedProfile()')
+	if err := ValidateImportedProfile(c.importedProfileText); err != nil {
+		return nil, fmt.Errorf("imported profile validation error: %w", err)
+	}
+	for _, line := range strings.Split(c.importedProfileText, "\n") {
+		cfg = append(cfg, strings.TrimRight(line, "\r"))
+	}
+
+	return cfg, nil
+}
+
 // merge current parameters with user-defined parameters
 func addUserDefinedParameters(currParams []string, userParams string) ([]string, error) {
 	if len(userParams) <= 0 {
@@ -0,0 +1,123 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package openvpn
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Validation of user-defined OpenVPN configuration parameters.
+// Only directives from the whitelist below are accepted: the daemon runs with root privileges,
+// therefore directives which are able to execute external commands or change OpenVPN runtime behavior
+// (e.g. 'up', 'down', 'script-security', 'plugin', 'management', ...) must never come from a user.
+var allowedUserParameters = map[string]struct{}{
+	// connection\transport
+	"connect-retry":        {},
+	"connect-retry-max":    {},
+	"connect-timeout":      {},
+	"server-poll-timeout":  {},
+	"resolv-retry":         {},
+	"remote-random":        {},
+	"float":                {},
+	"nobind":               {},
+	"proto-force":          {},
+	"explicit-exit-notify": {},
+	// MTU tuning
+	"tun-mtu":       {},
+	"tun-mtu-extra": {},
+	"link-mtu":      {},
+	"mssfix":        {},
+	"fragment":      {},
+	"mtu-test":      {},
+	// socket buffers
+	"sndbuf":      {},
+	"rcvbuf":      {},
+	"tcp-nodelay": {},
+	"fast-io":     {},
+	// connection-check timing
+	"keepalive":    {},
+	"ping":         {},
+	"ping-restart": {},
+	"ping-exit":    {},
+	"inactive":     {},
+	"hand-window":  {},
+	// TLS\crypto
+	"cipher":                {},
+	"auth":                  {},
+	"auth-retry":            {},
+	"auth-nocache":          {},
+	"tls-version-min":       {},
+	"tls-version-max":       {},
+	"tls-cipher":            {},
+	"tls-ciphersuites":      {},
+	"tls-timeout":           {},
+	"reneg-sec":             {},
+	"reneg-bytes":           {},
+	"reneg-pkts":            {},
+	"ncp-ciphers":           {},
+	"ncp-disable":           {},
+	"data-ciphers":          {},
+	"data-ciphers-fallback": {},
+	"remote-cert-tls":       {},
+	// compression
+	"compress":          {},
+	"comp-lzo":          {},
+	"allow-compression": {},
+	// routing\DNS (pushed options)
+	"pull-filter":      {},
+	"route":            {},
+	"route-nopull":     {},
+	"route-metric":     {},
+	"redirect-gateway": {},
+	"dhcp-option":      {},
+	"topology":         {},
+	"persist-tun":      {},
+	"persist-key":      {},
+	// logging
+	"verb":                 {},
+	"mute":                 {},
+	"mute-replay-warnings": {},
+	// Windows-specific
+	"block-outside-dns": {},
+	"windows-driver":    {},
+	"dev-node":          {},
+}
+
+// ValidateUserParameters checks user-defined OpenVPN configuration parameters against the whitelist
+// of allowed directives (returns error describing the first directive which is not allowed)
+func ValidateUserParameters(userParams string) error {
+	for _, line := range strings.Split(userParams, "\n") {
+		directive := getParamFromConfigLine(line)
+		if len(directive) == 0 {
+			continue // empty line or comment
+		}
+		// user can define directives in a command-line style (e.g. '--tun-mtu 1500')
+		directive = strings.TrimLeft(directive, "-")
+
+		if _, ok := allowedUserParameters[directive]; ok == false {
+			return fmt.Errorf("OpenVPN directive '%s' is not allowed as user-defined parameter", directive)
+		}
+	}
+	return nil
+}
@@ -60,8 +60,26 @@ type ManagementInterface struct {
 
 	pushReplyCmds []string
 	pushReplyDNS  net.IP
+
+	// traffic counters of current connection (updated by BYTECOUNT events)
+	statsMutex    sync.Mutex
+	bytesReceived uint64
+	bytesSent     uint64
+
+	// credentials state ('auth-retry interact' re-prompt flow)
+	credsMutex       sync.Mutex
+	isCredsRejected  bool // last credentials were rejected by the server
+	isCredsRequested bool // OpenVPN is waiting for new credentials now
+	authRetryTimer   *time.Timer
 }
 
+// interval of the BYTECOUNT traffic counters notifications, seconds
+const _byteCountNotifyIntervalSec = 5
+
+// timeout of waiting for new credentials after the server rejected current ones
+// (when a client does not supply new credentials in time - the connection is closed)
+const _authRetryTimeout = time.Minute * 2
+
 // StartManagementInterface - starts TCP interface to communicate with IVPN application (server to listen incoming connections)
 func StartManagementInterface(miSecret string, username string, password string, stateChan chan<- vpn.StateInfo) (mi *ManagementInterface, err error) {
 	ret := &ManagementInterface{
@@ -148,6 +166,14 @@ func (i *ManagementInterface) GetRouteAddCommands() []string {
 	return i.routeAddCmds
 }
 
+// GetTransferredBytes returns traffic counters of current connection
+// (values are updated every '_byteCountNotifyIntervalSec' seconds)
+func (i *ManagementInterface) GetTransferredBytes() (bytesReceived uint64, bytesSent uint64) {
+	i.statsMutex.Lock()
+	defer i.statsMutex.Unlock()
+	return i.bytesReceived, i.bytesSent
+}
+
 // start - starts TCP interface to communicate with IVPN application (server to listen incoming connections)
 func (i *ManagementInterface) start() error {
 	if i.isDisconnectRequested {
@@ -203,34 +229,42 @@ func (i *ManagementInterface) start() error {
 	return nil
 }
 
-// miCommunication - communication with openVPN process (OpenVPN Management Interface).
-// Processing requests from client and sending response
-func (i *ManagementInterface) miCommunication() {
-	// Example: "/sbin/route" - for macOS, "/sbin/ip route" - for Linux, "C:\\Windows\\System32\\ROUTE.EXE" - for Windows
-	routeCommand := platform.RouteCommand()
-
-	mesRegexp := regexp.MustCompile("^>([a-zA-Z0-9-]+):(.*)")
-	mesNeedPassRegexp := regexp.MustCompile("Need '(.+)' username/password")
+// 'route add ...' commands detection RegExp
+// Windows (OpenVPN 2.4):	>LOG:1612484260,,C:\WINDOWS\system32\route.exe ADD 123.200.11.22 MASK 255.255.255.255 192.168.1.1
+// macOS   (OpenVPN 2.4):	>LOG:1612517083,,/sbin/route add -net 123.200.11.22 192.168.1.1 255.255.255.255\r
+// Linux   (OpenVPN 2.4):	>LOG:1612516859,,/sbin/ip route add 123.200.11.22/32 via 192.168.1.1\r
+var (
+	mesLogRouteAddCmdRegexp     *regexp.Regexp
+	mesLogRouteAddCmdRegexpOnce sync.Once
 
-	// 'route add ...' commands detection RegExp
-	// Windows (OpenVPN 2.4):	>LOG:1612484260,,C:\WINDOWS\system32\route.exe ADD 123.200.11.22 MASK 255.255.255.255 192.168.1.1
-	// macOS   (OpenVPN 2.4):	>LOG:1612517083,,/sbin/route add -net 123.200.11.22 192.168.1.1 255.255.255.255\r
-	// Linux   (OpenVPN 2.4):	>LOG:1612516859,,/sbin/ip route add 123.200.11.22/32 via 192.168.1.1\r
-	mesLogRouteAddCmdRegexp := regexp.MustCompile(
-		"(?i)" + // i modifier: insensitive. Case insensitive match (ignores case of [a-zA-Z])
-			"^" + // beginning of the line (it is important for security reason)
-			regexp.QuoteMeta(routeCommand) + "[ ]+" + // platform-specific route command
-			"ADD[ \t]+" + // 'add' instruction
-			"(-net[ \t]+)?" + // '-net' instruction for macOS
-			"(([0-9]{1,3}[.]){3,3}[0-9]{1,3})(/[0-9]{1,2})?[ \t]+" + // IPv4 address
-			"((MASK|via)[ \t]+)?" + // instructions 'MASK' for Windows or 'via' for Linux
-			"([0-9]{1,3}[.]){3,3}[0-9]{1,3}([ \t]+" + // IPv4 address
-			"([0-9]{1,3}[.]){3,3}[0-9]{1,3})?") // IPv4 address
+	mesLogPushReplyCmdRegexp = regexp.MustCompile(".*PUSH.*'PUSH_REPLY[ ,]*(.*)'")
 
-	mesLogPushReplyCmdRegexp := regexp.MustCompile(".*PUSH.*'PUSH_REPLY[ ,]*(.*)'")
+	mesLogRouteAddCmdRegexpOvpn45 = regexp.MustCompile(".*net_route_v4_add:[ \t]+(([0-9]{1,3}[.]){3,3}[0-9]{1,3}(\\/[0-9]+)?[ \t]+.*[ \t]+([0-9]{1,3}[.]){3,3}[0-9]{1,3}).*")
+)
 
-	mesLogRouteAddCmdRegexpOvpn45 := regexp.MustCompile(".*net_route_v4_add:[ \t]+(([0-9]{1,3}[.]){3,3}[0-9]{1,3}(\\/[0-9]+)?[ \t]+.*[ \t]+([0-9]{1,3}[.]){3,3}[0-9]{1,3}).*")
+func getRouteAddCmdRegexp() *regexp.Regexp {
+	mesLogRouteAddCmdRegexpOnce.Do(func() {
+		// Example: "/sbin/route" - for macOS, "/sbin/ip route" - for Linux, "C:\\Windows\\System32\\ROUTE.EXE" - for Windows
+		routeCommand := platform.RouteCommand()
+
+		mesLogRouteAddCmdRegexp = regexp.MustCompile(
+			"(?i)" + // i modifier: insensitive. Case insensitive match (ignores case of [a-zA-Z])
+				"^" + // beginning of the line (it is important for security reason)
+				regexp.QuoteMeta(routeCommand) + "[ ]+" + // platform-specific route command
+				"ADD[ \t]+" + // 'add' instruction
+				"(-net[ \t]+)?" + // '-net' instruction for macOS
+				"(([0-9]{1,3}[.]){3,3}[0-9]{1,3})(/[0-9]{1,2})?[ \t]+" + // IPv4 address
+				"((MASK|via)[ \t]+)?" + // instructions 'MASK' for Windows or 'via' for Linux
+				"([0-9]{1,3}[.]){3,3}[0-9]{1,3}([ \t]+" + // IPv4 address
+				"([0-9]{1,3}[.]){3,3}[0-9]{1,3})?") // IPv4 address
+	})
+	return mesLogRouteAddCmdRegexp
+}
 
+// miCommunication - communication with openVPN process (OpenVPN Management Interface).
+// Each received real-time message is converted into a typed event (see 'parseMiEvent()')
+// and processed by a corresponding handler
+func (i *ManagementInterface) miCommunication() {
 	if i.miConn == nil {
 		i.log.Panic("INTERNAL ERROR: OpenVPN MI connection is null!")
 	}
@@ -273,151 +307,226 @@ func (i *ManagementInterface) miCommunication() {
 			continue
 		}
 
-		i.log.Info("[<-]: ", message)
+		event := parseMiEvent(message)
 
-		columns := mesRegexp.FindStringSubmatch(message)
-		if len(columns) <= 2 {
-			continue
+		if _, isByteCount := event.(miEventByteCount); isByteCount == false {
+			// BYTECOUNT notifications are too frequent for logging
+			i.log.Info("[<-]: ", message)
 		}
 
-		msgSource := columns[1]
-		msgText := columns[2]
-
-		switch msgSource {
-		case "LOG":
-			// detect for routing change commands
-			cols := strings.Split(msgText, ",")
-			if len(cols) == 3 {
-				if len(routeCommand) > 0 {
-					cmdStr := strings.ToLower(cols[2])
-
-					submaches := mesLogRouteAddCmdRegexp.FindStringSubmatch(cmdStr)
-					if len(submaches) >= 1 {
-						i.addRouteAddCommand(submaches[0])
-					} else {
-						// OpenVPN >= 4.5:
-						// Routing log format was changed since OpenVPN 4.5
-						// LOG:1607410951,,net_route_v4_add: 193.203.48.54/32 via 192.168.1.1 dev [NULL] table 0 metric -1
-						submaches := mesLogRouteAddCmdRegexpOvpn45.FindStringSubmatch(cmdStr)
-						if len(submaches) >= 2 {
-							i.addRouteAddCommand(fmt.Sprint(routeCommand, " add ", submaches[1]))
-						}
-					}
-				}
-			} else {
-				// LOG:1586341059,,PUSH: Received control message: 'PUSH_REPLY,redirect-gateway def1,explicit-exit-notify 3,comp-lzo no,route-gateway 10.34.44.1,topology subnet,ping 10,ping-restart 60,dhcp-option DNS 10.34.44.1,ifconfig 10.34.44.19 255.255.252.0,peer-id 17,cipher AES-256-GCM'
-				cols := mesLogPushReplyCmdRegexp.FindStringSubmatch(msgText)
-				if len(cols) == 2 {
-					i.onPushReplyCommands(strings.Split(cols[1], ","))
-				}
-			}
+		switch ev := event.(type) {
+		case miEventLog:
+			i.onLogEvent(ev)
 
-			break
+		case miEventHold:
+			i.sendResponse("state on", "log on",
+				fmt.Sprintf("bytecount %d", _byteCountNotifyIntervalSec),
+				"hold off", "hold release")
 
-		case "INFO":
-			break
+		case miEventPassword:
+			i.onPasswordEvent(ev)
 
-		case "HOLD":
-			i.sendResponse("state on", "log on", "hold off", "hold release")
+		case miEventState:
+			i.onStateEvent(ev)
+
+		case miEventByteCount:
+			i.statsMutex.Lock()
+			i.bytesReceived = ev.BytesReceived
+			i.bytesSent = ev.BytesSent
+			i.statsMutex.Unlock()
+
+		case miEventInfo:
 			break
+		}
+	}
+}
 
-		case "PASSWORD":
-			if strings.HasPrefix(msgText, "Verification Failed: 'Auth'") {
-				// Authentication error is handled by state: >STATE:1563526742,EXITING,auth-failure,,,,,
-				break
-			}
+// onLogEvent processes a LOG event: detection of routing change commands and pushed server parameters
+func (i *ManagementInterface) onLogEvent(ev miEventLog) {
+	// Example: "/sbin/route" - for macOS, "/sbin/ip route" - for Linux, "C:\\Windows\\System32\\ROUTE.EXE" - for Windows
+	routeCommand := platform.RouteCommand()
 
-			if mesNeedPassRegexp.Match([]byte(msgText)) == false {
-				continue
-			}
+	if len(routeCommand) > 0 {
+		cmdStr := strings.ToLower(ev.Message)
 
-			i.sendResp(false, fmt.Sprintf("username \"Auth\" %s", i.username))
-
-			// Some passwords for tests in case of implementation change:
-			//
-			// #;0$%:k'j?~?:f3%2,O4x<
-			// #;0///$%\\\:k\\'j?\~?://f3%2,/O4x<
-			// ;0///$%\\\:k\\'j?\~?://f3%2,/O4x<#456!@#$%^&*()_+}{P||:?><~~
-			// ";0///$%\\\:k\\'j?\~?://""f3%2""",/O4x<#456!@#$%^&*()_+}{P||:?><~~
-			// lkhgd#;0$%:k'j?~?:f3%2,"O4x<
-			escapedPass := strings.ReplaceAll(i.password, "\\", "\\\\")
-			escapedPass = strings.ReplaceAll(escapedPass, "\"", "\\\"")
-			i.sendResp(false, fmt.Sprintf("password \"Auth\" %s", escapedPass))
-			break
+		submaches := getRouteAddCmdRegexp().FindStringSubmatch(cmdStr)
+		if len(submaches) >= 1 {
+			i.addRouteAddCommand(submaches[0])
+			return
+		}
 
-		case "STATE":
-			// The output format consists of 4 comma-separated parameters:
-			//  (a) the integer unix date/time,
-			//  (b) the state name,
-			//  (c) optional descriptive string (used mostly on RECONNECTING
-			//      and EXITING to show the reason for the disconnect),
-			//  (d) optional TUN/TAP local IP address (shown for ASSIGN_IP
-			//      and CONNECTED), and
-			//  (e) optional address of remote server (OpenVPN 2.1 or higher).
-			params := strings.Split(msgText, ",")
-			if len(params) < 2 {
-				i.log.Error("STATE format error.")
-				continue
-			}
-			stateStr := params[1]
+		// OpenVPN >= 4.5:
+		// Routing log format was changed since OpenVPN 4.5
+		// LOG:1607410951,,net_route_v4_add: 193.203.48.54/32 via 192.168.1.1 dev [NULL] table 0 metric -1
+		submaches = mesLogRouteAddCmdRegexpOvpn45.FindStringSubmatch(cmdStr)
+		if len(submaches) >= 2 {
+			i.addRouteAddCommand(fmt.Sprint(routeCommand, " add ", submaches[1]))
+			return
+		}
+	}
 
-			state, err := vpn.ParseState(stateStr)
-			if err != nil {
-				i.log.Error("Unable to parse VPN state:", err.Error())
-			} else {
-				i.log.Info("State changed:", state)
-
-				var clientIP net.IP
-				var serverIP net.IP
-				var isAuthError bool
-				var additionalInfo string
-
-				// If state is Connected - save local and server IP addresses
-				if state == vpn.CONNECTED {
-					if len(params) > 3 {
-						clientIP = net.ParseIP(strings.TrimSpace(params[3]))
-					}
-					if len(params) > 4 {
-						serverIP = net.ParseIP(strings.TrimSpace(params[4]))
-					}
-
-				} else if state == vpn.EXITING {
-					//>STATE:1563526742,EXITING,auth-failure,,,,,
-					if strings.Contains(msgText, "auth-failure") { //if (params[2] == "auth-failure")
-						isAuthError = true
-					}
-				} else if state == vpn.RECONNECTING {
-					if len(params[2]) >= 3 {
-						additionalInfo = params[2]
-					}
-				}
+	// LOG:1586341059,,PUSH: Received control message: 'PUSH_REPLY,redirect-gateway def1,explicit-exit-notify 3,comp-lzo no,route-gateway 10.34.44.1,topology subnet,ping 10,ping-restart 60,dhcp-option DNS 10.34.44.1,ifconfig 10.34.44.19 255.255.252.0,peer-id 17,cipher AES-256-GCM'
+	cols := mesLogPushReplyCmdRegexp.FindStringSubmatch(ev.Message)
+	if len(cols) == 2 {
+		i.onPushReplyCommands(strings.Split(cols[1], ","))
+	}
+}
 
-				// erase old routing commands
-				if state == vpn.RECONNECTING {
-					i.eraseRouteAddCommands()
-				}
+// onPasswordEvent processes a PASSWORD event: sending credentials when requested
+func (i *ManagementInterface) onPasswordEvent(ev miEventPassword) {
+	if ev.IsVerificationFailed {
+		// 'auth-retry interact' is in use: OpenVPN does not exit on an authentication error,
+		// it re-queries credentials by a next PASSWORD event
+		i.credsMutex.Lock()
+		i.isCredsRejected = true
+		i.credsMutex.Unlock()
+		return
+	}
 
-				// save current state info
-				state := vpn.StateInfo{
-					State:               state,
-					Description:         msgText,
-					ClientIP:            clientIP,
-					ServerIP:            serverIP,
-					IsAuthError:         isAuthError,
-					StateAdditionalInfo: additionalInfo,
-					IsCanPause:          len(i.GetRouteAddCommands()) > 0}
-
-				select {
-				case i.stateChan <- state: // notify: state was changed
-				default:
-					i.log.Debug("State channel is full. Waiting...")
-					i.stateChan <- state
-				}
-			}
+	if ev.NeedName != "Auth" {
+		return
+	}
 
-			break
+	i.credsMutex.Lock()
+	if i.isCredsRejected {
+		// current credentials were rejected by the server:
+		// not sending them again; waiting for new credentials from a client (see 'SetCredentials()')
+		i.isCredsRequested = true
+		if i.authRetryTimer == nil {
+			i.authRetryTimer = time.AfterFunc(_authRetryTimeout, i.onAuthRetryTimeout)
 		}
+		i.credsMutex.Unlock()
+
+		i.log.Info("Credentials rejected by server. Waiting for new credentials...")
+		i.notifyState(vpn.StateInfo{
+			State:               vpn.AUTH,
+			IsAuthError:         true,
+			StateAdditionalInfo: "credentials-required"})
+		return
+	}
+	i.credsMutex.Unlock()
 
+	i.sendCredentials()
+}
+
+// sendCredentials sends current credentials to OpenVPN
+func (i *ManagementInterface) sendCredentials() error {
+	i.credsMutex.Lock()
+	username := i.username
+	password := i.password
+	i.credsMutex.Unlock()
+
+	if err := i.sendResp(false, fmt.Sprintf("username \"Auth\" %s", username)); err != nil {
+		return err
+	}
+
+	// Some passwords for tests in case of implementation change:
+	//
+	// #;0$%:k'j?~?:f3%2,O4x<
+	// #;0///$%\\\:k\\'j?\~?://f3%2,/O4x<
+	// ;0///$%\\\:k\\'j?\~?://f3%2,/O4x<#456!@#$%^&*()_+}{P||:?><~~
+	// ";0///$%\\\:k\\'j?\~?://""f3%2""",/O4x<#456!@#$%^&*()_+}{P||:?><~~
+	// lkhgd#;0$%:k'j?~?:f3%2,"O4x<
+	escapedPass := strings.ReplaceAll(password, "\\", "\\\\")
+	escapedPass = strings.ReplaceAll(escapedPass, "\"", "\\\"")
+	return i.sendResp(false, fmt.Sprintf("password \"Auth\" %s", escapedPass))
+}
+
+// SetCredentials updates credentials of current connection.
+// When OpenVPN is waiting for new credentials (previous ones were rejected by the server) -
+// the new credentials are sent immediately and the connection attempt resumes
+func (i *ManagementInterface) SetCredentials(username string, password string) error {
+	i.credsMutex.Lock()
+	i.username = username
+	i.password = password
+	i.isCredsRejected = false
+	isPending := i.isCredsRequested
+	i.isCredsRequested = false
+	if i.authRetryTimer != nil {
+		i.authRetryTimer.Stop()
+		i.authRetryTimer = nil
+	}
+	i.credsMutex.Unlock()
+
+	if isPending {
+		i.log.Info("New credentials received. Resuming connection attempt...")
+		return i.sendCredentials()
+	}
+	return nil
+}
+
+// onAuthRetryTimeout closes the connection when new credentials were not supplied in time
+func (i *ManagementInterface) onAuthRetryTimeout() {
+	i.credsMutex.Lock()
+	isPending := i.isCredsRequested
+	i.authRetryTimer = nil
+	i.credsMutex.Unlock()
+
+	if isPending == false {
+		return
+	}
+
+	i.log.Info("New credentials were not provided in time. Disconnecting...")
+	i.notifyState(vpn.StateInfo{
+		State:       vpn.EXITING,
+		Description: "auth-failure (new credentials were not provided)",
+		IsAuthError: true})
+	i.SendDisconnect()
+}
+
+// onStateEvent processes a STATE event: notifying daemon about VPN state change
+func (i *ManagementInterface) onStateEvent(ev miEventState) {
+	state, err := vpn.ParseState(ev.StateName)
+	if err != nil {
+		i.log.Error("Unable to parse VPN state:", err.Error())
+		return
+	}
+
+	i.log.Info("State changed:", state)
+
+	var clientIP net.IP
+	var serverIP net.IP
+	var isAuthError bool
+	var additionalInfo string
+
+	// If state is Connected - save local and server IP addresses
+	if state == vpn.CONNECTED {
+		clientIP = ev.LocalIP
+		serverIP = ev.RemoteIP
+	} else if state == vpn.EXITING {
+		isAuthError = ev.IsAuthFailure()
+	} else if state == vpn.RECONNECTING {
+		if len(ev.Description) >= 3 {
+			additionalInfo = ev.Description
+		}
+	}
+
+	// erase old routing commands
+	if state == vpn.RECONNECTING {
+		i.eraseRouteAddCommands()
+	}
+
+	// save current state info
+	stateInf := vpn.StateInfo{
+		State:               state,
+		Description:         ev.Raw,
+		Time:                ev.UnixTime,
+		ClientIP:            clientIP,
+		ServerIP:            serverIP,
+		IsAuthError:         isAuthError,
+		StateAdditionalInfo: additionalInfo,
+		IsCanPause:          len(i.GetRouteAddCommands()) > 0}
+
+	i.notifyState(stateInf)
+}
+
+// notifyState notifies daemon about VPN state change
+func (i *ManagementInterface) notifyState(stateInf vpn.StateInfo) {
+	select {
+	case i.stateChan <- stateInf: // notify: state was changed
+	default:
+		i.log.Debug("State channel is full. Waiting...")
+		i.stateChan <- stateInf
 	}
 }
 func (i *ManagementInterface) onPushReplyCommands(cmds []string) {
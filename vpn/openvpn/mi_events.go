@@ -0,0 +1,199 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package openvpn
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Parser of real-time messages of the OpenVPN management interface.
+// Each message line ('>SOURCE:text') is converted into a typed event
+// (see https://openvpn.net/community-resources/management-interface/ for the messages format)
+
+// miEvent - typed real-time message of the management interface
+// (one of: miEventLog, miEventState, miEventHold, miEventPassword, miEventByteCount, miEventInfo)
+type miEvent interface{}
+
+// miEventLog - '>LOG:' real-time output of the OpenVPN log
+type miEventLog struct {
+	UnixTime int64
+	Flags    string // combination of "I" (informational), "F" (fatal), "N" (non-fatal), "W" (warning), "D" (debug)
+	Message  string
+}
+
+// miEventState - '>STATE:' OpenVPN state transition
+type miEventState struct {
+	Raw         string // original message text (as received)
+	UnixTime    int64
+	StateName   string
+	Description string // disconnection reason for RECONNECTING\EXITING states
+	LocalIP     net.IP // TUN\TAP local IP (defined for ASSIGN_IP and CONNECTED states)
+	RemoteIP    net.IP // address of the remote server (OpenVPN 2.1+)
+}
+
+// IsAuthFailure returns true for state transitions caused by an authentication error
+// (e.g. '>STATE:1563526742,EXITING,auth-failure,,,,,')
+func (e miEventState) IsAuthFailure() bool {
+	return strings.Contains(e.Description, "auth-failure")
+}
+
+// miEventHold - '>HOLD:' OpenVPN is in a hold state and waiting for 'hold release'
+type miEventHold struct {
+	Info string
+}
+
+// miEventPassword - '>PASSWORD:' OpenVPN requests credentials or reports a verification failure
+type miEventPassword struct {
+	Raw string
+	// NeedName - name of the requested credentials (e.g. "Auth"); empty when nothing requested
+	NeedName string
+	// IsVerificationFailed - credentials were rejected
+	// (the authentication error itself is reported by a STATE event too)
+	IsVerificationFailed bool
+}
+
+// miEventByteCount - '>BYTECOUNT:' traffic counters of current connection
+// (periodic notifications; enabled by the 'bytecount' command)
+type miEventByteCount struct {
+	BytesReceived uint64 // received from the server
+	BytesSent     uint64 // sent to the server
+}
+
+// miEventInfo - '>INFO:' informational message
+type miEventInfo struct {
+	Message string
+}
+
+var (
+	miEventRegexp        = regexp.MustCompile("^>([a-zA-Z0-9-]+):(.*)")
+	miPasswordNeedRegexp = regexp.MustCompile("Need '(.+)' username/password")
+)
+
+// parseMiEvent converts one line received from the management interface into a typed event.
+// Returns nil for lines which are not real-time messages (e.g. command responses)
+// or for message types which are not processed
+func parseMiEvent(line string) miEvent {
+	line = strings.TrimRight(line, "\r\n")
+
+	cols := miEventRegexp.FindStringSubmatch(line)
+	if len(cols) <= 2 {
+		return nil
+	}
+	source := cols[1]
+	text := cols[2]
+
+	switch source {
+	case "LOG":
+		return parseMiEventLog(text)
+	case "STATE":
+		return parseMiEventState(text)
+	case "HOLD":
+		return miEventHold{Info: text}
+	case "PASSWORD":
+		return parseMiEventPassword(text)
+	case "BYTECOUNT":
+		return parseMiEventByteCount(text)
+	case "INFO":
+		return miEventInfo{Message: text}
+	}
+	return nil
+}
+
+// parseMiEventLog parses a '>LOG:' message text: '{unix-time},{flags},{message}'
+func parseMiEventLog(text string) miEvent {
+	ret := miEventLog{}
+
+	// the message itself can contain commas: split only first two fields
+	cols := strings.SplitN(text, ",", 3)
+	if len(cols) != 3 {
+		ret.Message = text
+		return ret
+	}
+
+	ret.UnixTime, _ = strconv.ParseInt(cols[0], 10, 64)
+	ret.Flags = cols[1]
+	ret.Message = cols[2]
+	return ret
+}
+
+// parseMiEventState parses a '>STATE:' message text.
+// The format consists of comma-separated parameters:
+//
+//	(a) the integer unix date/time,
+//	(b) the state name,
+//	(c) optional descriptive string (used mostly on RECONNECTING
+//	    and EXITING to show the reason for the disconnect),
+//	(d) optional TUN/TAP local IP address (shown for ASSIGN_IP
+//	    and CONNECTED), and
+//	(e) optional address of remote server (OpenVPN 2.1 or higher).
+func parseMiEventState(text string) miEvent {
+	params := strings.Split(text, ",")
+	if len(params) < 2 {
+		return miEventInfo{Message: fmt.Sprint("STATE format error: ", text)}
+	}
+
+	ret := miEventState{Raw: text, StateName: strings.TrimSpace(params[1])}
+	ret.UnixTime, _ = strconv.ParseInt(params[0], 10, 64)
+	if len(params) > 2 {
+		ret.Description = strings.TrimSpace(params[2])
+	}
+	if len(params) > 3 {
+		ret.LocalIP = net.ParseIP(strings.TrimSpace(params[3]))
+	}
+	if len(params) > 4 {
+		ret.RemoteIP = net.ParseIP(strings.TrimSpace(params[4]))
+	}
+	return ret
+}
+
+// parseMiEventPassword parses a '>PASSWORD:' message text
+func parseMiEventPassword(text string) miEvent {
+	ret := miEventPassword{Raw: text}
+
+	if strings.HasPrefix(text, "Verification Failed:") {
+		ret.IsVerificationFailed = true
+		return ret
+	}
+
+	if cols := miPasswordNeedRegexp.FindStringSubmatch(text); len(cols) == 2 {
+		ret.NeedName = cols[1]
+	}
+	return ret
+}
+
+// parseMiEventByteCount parses a '>BYTECOUNT:' message text: '{bytes-in},{bytes-out}'
+func parseMiEventByteCount(text string) miEvent {
+	cols := strings.Split(text, ",")
+	if len(cols) != 2 {
+		return miEventInfo{Message: fmt.Sprint("BYTECOUNT format error: ", text)}
+	}
+
+	ret := miEventByteCount{}
+	ret.BytesReceived, _ = strconv.ParseUint(strings.TrimSpace(cols[0]), 10, 64)
+	ret.BytesSent, _ = strconv.ParseUint(strings.TrimSpace(cols[1]), 10, 64)
+	return ret
+}
@@ -119,7 +119,9 @@ func NewOpenVpnObject(
 	extraParameters string,
 	connectionParams ConnectionParams) (*OpenVPN, error) {
 
-	if len(connectionParams.username) == 0 || len(connectionParams.password) == 0 {
+	// imported third-party profiles can use certificate-only authentication (credentials optional)
+	if len(connectionParams.importedProfileText) == 0 &&
+		(len(connectionParams.username) == 0 || len(connectionParams.password) == 0) {
 		return nil, fmt.Errorf("OpenVPN user credentials not defined")
 	}
 
@@ -143,13 +145,24 @@ func (o *OpenVPN) DestinationIP() net.IP {
 	return o.connectParams.hostIP
 }
 
+// InTunnelGatewayIP - IP address of the VPN gateway inside the tunnel (DNS pushed by OpenVPN server)
+// Can be nil (e.g. before PUSH_REPLY received)
+// In use by tunnel watchdog to verify that the tunnel is alive
+func (o *OpenVPN) InTunnelGatewayIP() net.IP {
+	mi := o.managementInterface
+	if mi != nil && mi.isConnected && o.state != vpn.DISCONNECTED && o.state != vpn.EXITING {
+		return mi.pushReplyDNS
+	}
+	return nil
+}
+
 // Type just returns VPN type
 func (o *OpenVPN) Type() vpn.Type { return vpn.OpenVPN }
 
 // Init performs basic initializations before connection
 // It is useful, for example:
-//	- for WireGuard(Windows) - to ensure that WG service is fully uninstalled
-//	- for OpenVPN(Linux) - to ensure that OpenVPN has correct version
+//   - for WireGuard(Windows) - to ensure that WG service is fully uninstalled
+//   - for OpenVPN(Linux) - to ensure that OpenVPN has correct version
 func (o *OpenVPN) Init() error {
 	return o.implInit()
 }
@@ -410,6 +423,20 @@ func (o *OpenVPN) doDisconnect() error {
 	return mi.SendDisconnect()
 }
 
+// SetCredentials updates credentials of the active connection attempt.
+// Applicable when the server rejected current credentials ('auth-failure'):
+// OpenVPN waits for new credentials and resumes the same connection attempt
+func (o *OpenVPN) SetCredentials(username string, password string) error {
+	// multihop configuration can extend the username (see 'ConnectionParams.SetCredentials()')
+	o.connectParams.SetCredentials(username, password)
+
+	mi := o.managementInterface
+	if mi == nil {
+		return errors.New("OpenVPN MI is not started")
+	}
+	return mi.SetCredentials(o.connectParams.username, o.connectParams.password)
+}
+
 // Pause doing required operation for Pause (temporary restoring default DNS)
 func (o *OpenVPN) Pause() error {
 	o.isPaused = true
@@ -419,10 +446,10 @@ func (o *OpenVPN) Pause() error {
 		return errors.New("OpenVPN MI is nil")
 	}
 
+	// remove routes installed by the OpenVPN process (if any)
+	// (on Linux routes are not installed by OpenVPN; routing is performed by policy routing rules
+	// which are removed by 'implOnPause()')
 	routeAddCommands := mi.GetRouteAddCommands()
-	if len(routeAddCommands) == 0 {
-		return errors.New("OpenVPN: no route-add commands detected")
-	}
 
 	var retErr error
 	for _, cmd := range routeAddCommands {
@@ -457,15 +484,30 @@ func (o *OpenVPN) Resume() error {
 		o.isPaused = false
 	}()
 
+	if err := o.reapplyRoutes(); err != nil {
+		log.Error(err)
+	}
+
+	// OS-specific operation (if required)
+	retErr := o.implOnResume()
+	if retErr != nil {
+		log.ErrorTrace(retErr)
+	}
+
+	return retErr
+}
+
+// reapplyRoutes re-executes all 'route add' commands which were detected during connection establishing
+// (e.g. to restore VPN routes erased by DHCP renewal or another VPN software)
+func (o *OpenVPN) reapplyRoutes() error {
 	mi := o.managementInterface
 	if mi == nil {
 		return errors.New("OpenVPN MI is nil")
 	}
 
+	// nothing to do when no route-add commands were detected
+	// (e.g. on Linux routes are not installed by OpenVPN; routing is performed by policy routing rules)
 	routeAddCommands := mi.GetRouteAddCommands()
-	if len(routeAddCommands) == 0 {
-		return errors.New("OpenVPN: no route-add commands detected")
-	}
 
 	var retErr error
 	for _, cmd := range routeAddCommands {
@@ -483,12 +525,6 @@ func (o *OpenVPN) Resume() error {
 		}
 	}
 
-	// OS-specific operation (if required)
-	retErr = o.implOnResume()
-	if retErr != nil {
-		log.ErrorTrace(retErr)
-	}
-
 	return retErr
 }
 
@@ -507,7 +543,7 @@ func (o *OpenVPN) ResetManualDNS() error {
 	return o.implOnResetManualDNS()
 }
 
+// OnRoutingChanged - called when there were some routing changes but the tunnel interface is still the default route
 func (o *OpenVPN) OnRoutingChanged() error {
-	// Nothing to do for OpenVPN
-	return nil
+	return o.implOnRoutingChanged()
 }
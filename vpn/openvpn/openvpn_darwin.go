@@ -35,6 +35,12 @@ type platformSpecificProperties struct {
 func (o *OpenVPN) implInit() error             { return nil }
 func (o *OpenVPN) implIsCanUseParamsV24() bool { return true }
 
+// getOSSpecificConfigParams returns OpenVPN configuration parameters specific for macOS
+func getOSSpecificConfigParams(isCanUseParamsV24 bool) []string {
+	// no specific parameters for macOS implementation
+	return nil
+}
+
 func (o *OpenVPN) implOnConnected() error {
 	// not in use in macOS implementation
 	return nil
@@ -53,6 +59,16 @@ func (o *OpenVPN) implOnResume() error {
 	return dns.Resume(nil)
 }
 
+func (o *OpenVPN) implOnRoutingChanged() error {
+	// Routing configuration was changed outside of the daemon (e.g. DHCP renewal
+	// or another VPN software) but the tunnel is still the default route.
+	// Reinstall the routes received from the OpenVPN server to avoid traffic leaks.
+	if o.isPaused || o.isDisconnectRequested {
+		return nil
+	}
+	return o.reapplyRoutes()
+}
+
 func (o *OpenVPN) implOnSetManualDNS(addr net.IP) error {
 	return dns.SetManual(addr, nil)
 }
@@ -23,22 +23,54 @@
 package openvpn
 
 import (
+	"errors"
 	"fmt"
 	"net"
+	"strings"
 
+	"github.com/ivpn/desktop-app-daemon/netinfo"
 	"github.com/ivpn/desktop-app-daemon/service/dns"
 	"github.com/ivpn/desktop-app-daemon/service/platform/filerights"
+	"github.com/ivpn/desktop-app-daemon/shell"
 	"github.com/ivpn/desktop-app-daemon/vpn"
 )
 
+// fwmark for the OpenVPN tunnel socket and ID of the dedicated VPN routing table.
+// The daemon directs traffic into the tunnel by policy routing rules (like 'wg-quick')
+// instead of replacing the default route in the main routing table.
+// Must not overlap with table IDs used by other software (e.g. 51820 used by 'wg-quick').
+const _policyRoutingTable = "62144"
+
 type platformSpecificProperties struct {
 	// no specific properties for Linux implementation
 	isCanUseParamsV24 bool
 }
 
+// getOSSpecificConfigParams returns OpenVPN configuration parameters specific for Linux
+func getOSSpecificConfigParams(isCanUseParamsV24 bool) []string {
+	cfg := make([]string, 0, 2)
+
+	// mark packets of the OpenVPN tunnel socket by the fwmark
+	// (marked traffic keeps using the main routing table; everything else is
+	// routed into the tunnel by the dedicated VPN routing table; see 'setupPolicyRouting()')
+	cfg = append(cfg, "mark "+_policyRoutingTable)
+
+	if isCanUseParamsV24 {
+		// do not allow the server to modify the default route in the main routing table
+		// (routing into the tunnel is performed by policy routing rules)
+		cfg = append(cfg, "pull-filter ignore \"redirect-gateway\"")
+	}
+
+	return cfg
+}
+
 func (o *OpenVPN) implInit() error {
 	o.psProps.isCanUseParamsV24 = true
 
+	// remove policy routing rules which could stay after a not-correctly-stopped connection
+	// (e.g. when the daemon process was terminated)
+	o.removePolicyRouting()
+
 	if err := filerights.CheckFileAccessRightsExecutable(o.binaryPath); err != nil {
 		return fmt.Errorf("error checking OpenVPN binary file: %w", err)
 	}
@@ -66,23 +98,98 @@ func (o *OpenVPN) implIsCanUseParamsV24() bool {
 }
 
 func (o *OpenVPN) implOnConnected() error {
-	// TODO: not implemented
-	return nil
+	return o.setupPolicyRouting()
 }
 
 func (o *OpenVPN) implOnDisconnected() error {
-	// TODO: not implemented
+	o.removePolicyRouting()
 	return nil
 }
 
 func (o *OpenVPN) implOnPause() error {
+	// stop routing traffic into the tunnel
+	o.removePolicyRouting()
 	return dns.Pause()
 }
 
 func (o *OpenVPN) implOnResume() error {
+	if err := o.setupPolicyRouting(); err != nil {
+		log.Error(err)
+	}
 	return dns.Resume(o.getDefaultDNS())
 }
 
+func (o *OpenVPN) implOnRoutingChanged() error {
+	// The routing configuration was modified by somebody else (e.g. DHCP renewal or another VPN software)
+	// but the tunnel interface is still the default route.
+	// Reassert the policy routing rules to be sure traffic is not going around the tunnel.
+	if o.isPaused || o.isDisconnectRequested {
+		return nil
+	}
+	return o.setupPolicyRouting()
+}
+
+// setupPolicyRouting directs all traffic into the tunnel by policy routing rules
+// (fwmark + dedicated routing table + 'suppress_prefixlength' rule, like 'wg-quick')
+// instead of replacing the default route in the main routing table:
+//
+//	ip -4 route replace default dev tun0 table 62144
+//	ip -4 rule add not fwmark 62144 table 62144
+//	ip -4 rule add table main suppress_prefixlength 0
+//
+// The OpenVPN tunnel socket is marked by the fwmark (see 'mark' configuration parameter),
+// therefore encrypted traffic to the VPN server keeps using the main routing table.
+// The function is safe to call multiple times (rules are not duplicated).
+func (o *OpenVPN) setupPolicyRouting() error {
+	if o.clientIP == nil {
+		return errors.New("unable to configure policy routing: tunnel local IP not defined")
+	}
+	tunInterface, err := netinfo.InterfaceByIPAddr(o.clientIP)
+	if err != nil {
+		return fmt.Errorf("unable to configure policy routing: %w", err)
+	}
+
+	if err := shell.Exec(log, "ip", "-4", "route", "replace", "default", "dev", tunInterface.Name, "table", _policyRoutingTable); err != nil {
+		return fmt.Errorf("failed to add route to the VPN routing table: %w", err)
+	}
+
+	rules, _, _, err := shell.ExecAndGetOutput(log, 1024*64, "", "ip", "-4", "rule")
+	if err != nil {
+		return fmt.Errorf("failed to check policy routing rules: %w", err)
+	}
+	// NOTE: keep the order of the rules:
+	// the 'suppress_prefixlength' rule must be evaluated before the fwmark rule
+	// (rules added later have higher priority)
+	if strings.Contains(rules, "lookup "+_policyRoutingTable) == false {
+		if err := shell.Exec(log, "ip", "-4", "rule", "add", "not", "fwmark", _policyRoutingTable, "table", _policyRoutingTable); err != nil {
+			return fmt.Errorf("failed to add policy routing rule: %w", err)
+		}
+	}
+	if strings.Contains(rules, "suppress_prefixlength 0") == false {
+		if err := shell.Exec(log, "ip", "-4", "rule", "add", "table", "main", "suppress_prefixlength", "0"); err != nil {
+			return fmt.Errorf("failed to add policy routing rule: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// removePolicyRouting removes the policy routing rules and erases the dedicated VPN routing table
+func (o *OpenVPN) removePolicyRouting() {
+	rules, _, _, err := shell.ExecAndGetOutput(log, 1024*64, "", "ip", "-4", "rule")
+	if err != nil {
+		log.Error(fmt.Errorf("failed to check policy routing rules: %w", err))
+		return
+	}
+	if strings.Contains(rules, "lookup "+_policyRoutingTable) == false {
+		return // nothing to remove
+	}
+
+	shell.Exec(log, "ip", "-4", "rule", "del", "not", "fwmark", _policyRoutingTable, "table", _policyRoutingTable)
+	shell.Exec(log, "ip", "-4", "rule", "del", "table", "main", "suppress_prefixlength", "0")
+	shell.Exec(log, "ip", "-4", "route", "flush", "table", _policyRoutingTable)
+}
+
 func (o *OpenVPN) implOnSetManualDNS(addr net.IP) error {
 	return dns.SetManual(addr, nil)
 }
@@ -36,6 +36,12 @@ type platformSpecificProperties struct {
 func (o *OpenVPN) implInit() error             { return nil }
 func (o *OpenVPN) implIsCanUseParamsV24() bool { return true }
 
+// getOSSpecificConfigParams returns OpenVPN configuration parameters specific for Windows
+func getOSSpecificConfigParams(isCanUseParamsV24 bool) []string {
+	// no specific parameters for Windows implementation
+	return nil
+}
+
 func (o *OpenVPN) implOnConnected() error {
 	// on Windows it is not possible to change network interface properties (over WMI) until it not enabled
 	// apply DNS value when VPN connected (TAP interface enabled)
@@ -61,6 +67,11 @@ func (o *OpenVPN) implOnResume() error {
 	return nil
 }
 
+func (o *OpenVPN) implOnRoutingChanged() error {
+	// not in use in Windows implementation
+	return nil
+}
+
 func (o *OpenVPN) implOnSetManualDNS(addr net.IP) error {
 	o.psProps.manualDNS = addr
 
@@ -0,0 +1,156 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package openvpn
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Validation of imported third-party '.ovpn' profiles.
+// Same safety rules as for user-defined extra parameters (the daemon runs with root privileges:
+// directives which are able to execute external commands must never come from a user) plus:
+//	- connection directives of a full client profile are acceptable ('remote', 'proto', 'dev', ...)
+//	- certificates\keys are acceptable only as inline blocks ('<ca>...</ca>'):
+//	  file-path arguments would make the daemon read arbitrary files with root privileges
+//	- credentials are provided by the daemon over the management interface:
+//	  'auth-user-pass' with a file argument is not acceptable
+
+// directives of a full client profile which are allowed in addition to 'allowedUserParameters'
+var allowedImportedProfileParameters = map[string]struct{}{
+	"client":                    {},
+	"tls-client":                {},
+	"pull":                      {},
+	"remote":                    {},
+	"port":                      {},
+	"rport":                     {},
+	"lport":                     {},
+	"proto":                     {},
+	"dev":                       {},
+	"dev-type":                  {},
+	"key-direction":             {},
+	"verify-x509-name":          {},
+	"tls-crypt-v2-force-cookie": {},
+}
+
+// directives which are allowed only without a file-path argument
+// (value is provided inline or over the management interface)
+var importedProfileParametersNoArgs = map[string]struct{}{
+	"auth-user-pass": {},
+}
+
+// inline blocks of a client profile which are acceptable (e.g. '<ca> ... </ca>')
+var allowedImportedProfileInlineBlocks = map[string]struct{}{
+	"ca":           {},
+	"cert":         {},
+	"key":          {},
+	"tls-auth":     {},
+	"tls-crypt":    {},
+	"tls-crypt-v2": {},
+	"dh":           {},
+}
+
+// GetImportedProfileRemoteHosts returns host arguments of all 'remote' directives of a profile
+// (used, for example, to annotate imported profiles with country info from the local GeoIP dataset)
+func GetImportedProfileRemoteHosts(profileText string) []string {
+	var hosts []string
+	inlineBlock := ""
+
+	for _, line := range strings.Split(profileText, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		// skip content of inline blocks (e.g. '<ca> ... </ca>')
+		if len(inlineBlock) > 0 {
+			if trimmed == "</"+inlineBlock+">" {
+				inlineBlock = ""
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "<") && strings.HasSuffix(trimmed, ">") && strings.HasPrefix(trimmed, "</") == false {
+			inlineBlock = strings.Trim(trimmed, "<>")
+			continue
+		}
+
+		if strings.TrimLeft(getParamFromConfigLine(line), "-") != "remote" {
+			continue
+		}
+		// syntax: 'remote <host> [port] [proto]'
+		if fields := strings.Fields(trimmed); len(fields) >= 2 {
+			hosts = append(hosts, fields[1])
+		}
+	}
+	return hosts
+}
+
+// ValidateImportedProfile checks content of a third-party '.ovpn' profile against
+// the whitelist of allowed directives (returns error describing the first violation)
+func ValidateImportedProfile(profileText string) error {
+	inlineBlock := ""
+
+	for _, line := range strings.Split(profileText, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		// content of an inline block is opaque (not OpenVPN directives)
+		if len(inlineBlock) > 0 {
+			if trimmed == "</"+inlineBlock+">" {
+				inlineBlock = ""
+			}
+			continue
+		}
+
+		// beginning of an inline block (e.g. '<ca>')
+		if strings.HasPrefix(trimmed, "<") && strings.HasSuffix(trimmed, ">") && strings.HasPrefix(trimmed, "</") == false {
+			blockName := strings.Trim(trimmed, "<>")
+			if _, ok := allowedImportedProfileInlineBlocks[blockName]; ok == false {
+				return fmt.Errorf("inline block '<%s>' is not allowed in an imported profile", blockName)
+			}
+			inlineBlock = blockName
+			continue
+		}
+
+		directive := getParamFromConfigLine(line)
+		if len(directive) == 0 {
+			continue // empty line or comment
+		}
+		directive = strings.TrimLeft(directive, "-")
+
+		if _, ok := importedProfileParametersNoArgs[directive]; ok {
+			if len(strings.Fields(trimmed)) > 1 {
+				return fmt.Errorf("OpenVPN directive '%s' with a file argument is not allowed in an imported profile", directive)
+			}
+			continue
+		}
+
+		_, isProfileParam := allowedImportedProfileParameters[directive]
+		_, isUserParam := allowedUserParameters[directive]
+		if isProfileParam == false && isUserParam == false {
+			return fmt.Errorf("OpenVPN directive '%s' is not allowed in an imported profile", directive)
+		}
+	}
+
+	if len(inlineBlock) > 0 {
+		return fmt.Errorf("inline block '<%s>' is not closed", inlineBlock)
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package openvpn
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ivpn/desktop-app-daemon/service/secrets"
+)
+
+// Per-client tls-crypt-v2 key management.
+// A tls-crypt-v2 key (obtained from the API or provisioned locally by an administrator)
+// is kept in the protected secrets storage and included inline into generated
+// OpenVPN configurations. When no key is provisioned - the static 'tls-auth' key is used.
+// NOTE: tls-crypt-v2 requires OpenVPN 2.5+
+
+// key of the tls-crypt-v2 client key in the protected secrets storage
+const _secretKeyTLSCryptV2 = "ovpn_tls_crypt_v2_key"
+
+const (
+	_tlsCryptV2KeyHeader = "-----BEGIN OpenVPN tls-crypt-v2 client key-----"
+	_tlsCryptV2KeyFooter = "-----END OpenVPN tls-crypt-v2 client key-----"
+)
+
+// SetTLSCryptV2Key stores a per-client tls-crypt-v2 key into the protected secrets storage
+// (empty value erases the key: generated configurations fall back to the static 'tls-auth' key)
+func SetTLSCryptV2Key(keyPem string) error {
+	keyPem = strings.TrimSpace(strings.ReplaceAll(keyPem, "\r\n", "\n"))
+
+	if len(keyPem) == 0 {
+		return secrets.Erase(_secretKeyTLSCryptV2)
+	}
+
+	if strings.HasPrefix(keyPem, _tlsCryptV2KeyHeader) == false ||
+		strings.HasSuffix(keyPem, _tlsCryptV2KeyFooter) == false {
+		return fmt.Errorf("unexpected tls-crypt-v2 key format")
+	}
+
+	return secrets.Store(_secretKeyTLSCryptV2, keyPem)
+}
+
+// getTLSCryptV2Key returns the provisioned tls-crypt-v2 client key
+// (empty string - no key provisioned)
+func getTLSCryptV2Key() string {
+	key, err := secrets.Retrieve(_secretKeyTLSCryptV2)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(key)
+}
@@ -188,6 +188,11 @@ type Process interface {
 	// This information if required, for example, to allow this address in firewall
 	DestinationIP() net.IP
 
+	// InTunnelGatewayIP - IP address of the VPN gateway inside the tunnel
+	// (can be nil when this information is not available; e.g. OpenVPN before PUSH_REPLY received)
+	// In use by tunnel watchdog to verify that the tunnel is alive
+	InTunnelGatewayIP() net.IP
+
 	OnRoutingChanged() error
 }
 
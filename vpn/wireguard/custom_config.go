@@ -0,0 +1,152 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package wireguard
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/ivpn/desktop-app-daemon/helpers"
+)
+
+// CustomConfig - connection parameters parsed from a user-provided (non-IVPN) WireGuard
+// configuration file (wg-quick format). Allows to connect to self-hosted WG servers
+// using the same daemon machinery as for IVPN servers.
+type CustomConfig struct {
+	ClientPrivateKey string
+	ClientLocalIP    net.IP
+	// DNS - in-tunnel DNS server defined in the config (nil - not defined)
+	DNS net.IP
+
+	PeerPublicKey string
+	PeerEndpoint  net.IP
+	PeerPort      int
+}
+
+// ParseCustomConfig parses content of a user-provided WireGuard configuration file (wg-quick format)
+// Notes:
+//   - 'AllowedIPs' value is ignored: daemon always routes the entire traffic into the tunnel
+//     (required by the kill switch implementation)
+//   - 'PresharedKey' is not supported
+func ParseCustomConfig(cfgText string) (*CustomConfig, error) {
+	cfg := &CustomConfig{}
+	section := ""
+
+	for _, line := range strings.Split(cfgText, "\n") {
+		// strip comments
+		if pos := strings.IndexAny(line, "#;"); pos >= 0 {
+			line = line[:pos]
+		}
+		line = strings.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.ToLower(strings.Trim(line, "[]"))
+			continue
+		}
+
+		cols := strings.SplitN(line, "=", 2)
+		if len(cols) != 2 {
+			return nil, fmt.Errorf("failed to parse WireGuard configuration: unexpected line '%s'", line)
+		}
+		key := strings.ToLower(strings.TrimSpace(cols[0]))
+		value := strings.TrimSpace(cols[1])
+
+		switch section {
+		case "interface":
+			switch key {
+			case "privatekey":
+				if helpers.ValidateBase64(value) == false {
+					return nil, fmt.Errorf("failed to parse WireGuard configuration: 'PrivateKey' is not a base64 string")
+				}
+				cfg.ClientPrivateKey = value
+			case "address":
+				// only the first address is used (the tunnel interface IPv4 address)
+				addr := strings.Split(value, ",")[0]
+				ip := net.ParseIP(strings.Split(strings.TrimSpace(addr), "/")[0])
+				if ip == nil {
+					return nil, fmt.Errorf("failed to parse WireGuard configuration: bad 'Address' value '%s'", value)
+				}
+				cfg.ClientLocalIP = ip
+			case "dns":
+				// only the first DNS server is used
+				dnsIP := net.ParseIP(strings.TrimSpace(strings.Split(value, ",")[0]))
+				if dnsIP == nil {
+					return nil, fmt.Errorf("failed to parse WireGuard configuration: bad 'DNS' value '%s'", value)
+				}
+				cfg.DNS = dnsIP
+			}
+
+		case "peer":
+			switch key {
+			case "publickey":
+				if helpers.ValidateBase64(value) == false {
+					return nil, fmt.Errorf("failed to parse WireGuard configuration: 'PublicKey' is not a base64 string")
+				}
+				cfg.PeerPublicKey = value
+			case "presharedkey":
+				return nil, fmt.Errorf("failed to parse WireGuard configuration: 'PresharedKey' is not supported")
+			case "endpoint":
+				host, portStr, err := net.SplitHostPort(value)
+				if err != nil {
+					return nil, fmt.Errorf("failed to parse WireGuard configuration: bad 'Endpoint' value: %w", err)
+				}
+				port, err := strconv.Atoi(portStr)
+				if err != nil || port <= 0 || port > 65535 {
+					return nil, fmt.Errorf("failed to parse WireGuard configuration: bad 'Endpoint' port '%s'", portStr)
+				}
+				ip := net.ParseIP(host)
+				if ip == nil {
+					// endpoint can be defined as a hostname
+					ips, err := net.LookupIP(host)
+					if err != nil || len(ips) == 0 {
+						return nil, fmt.Errorf("failed to resolve WireGuard peer endpoint '%s'", host)
+					}
+					ip = ips[0]
+				}
+				cfg.PeerEndpoint = ip
+				cfg.PeerPort = port
+			}
+		}
+	}
+
+	// validate mandatory parameters
+	if len(cfg.ClientPrivateKey) == 0 {
+		return nil, fmt.Errorf("bad WireGuard configuration: 'PrivateKey' not defined")
+	}
+	if cfg.ClientLocalIP == nil {
+		return nil, fmt.Errorf("bad WireGuard configuration: 'Address' not defined")
+	}
+	if len(cfg.PeerPublicKey) == 0 {
+		return nil, fmt.Errorf("bad WireGuard configuration: 'PublicKey' not defined")
+	}
+	if cfg.PeerEndpoint == nil {
+		return nil, fmt.Errorf("bad WireGuard configuration: 'Endpoint' not defined")
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,143 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app-daemon
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2020 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package wireguard
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Reader of the WireGuard-windows ringlogger file ('log.bin').
+// The tunnel service logs failure reasons (bad configuration, adapter creation failure ...)
+// only into its own ring log; without reading it the daemon can report nothing more
+// detailed than "service stopped".
+//
+// File format (see 'ringlogger' package of WireGuard-windows sources):
+//	header: magic (uint32) + index of the next line to write (uint32)
+//	2048 lines: time of the line in nanoseconds (int64; 0 - line not in use) + line text (512 bytes)
+
+const (
+	_ringloggerMagic    = 0xbadbabe
+	_ringloggerMaxLines = 2048
+	_ringloggerLineLen  = 512
+)
+
+type ringloggerHeader struct {
+	Magic     uint32
+	NextIndex uint32
+}
+
+type ringloggerLine struct {
+	TimeNs int64
+	Text   [_ringloggerLineLen]byte
+}
+
+// getServiceLogFile returns location of the ringlogger file of the tunnel service
+// (empty string - file not found)
+func (wg *WireGuard) getServiceLogFile() string {
+	candidates := []string{filepath.Join(filepath.Dir(wg.binaryPath), "Data", "log.bin")}
+	if programFiles := os.Getenv("ProgramFiles"); len(programFiles) > 0 {
+		candidates = append(candidates, filepath.Join(programFiles, "WireGuard", "Data", "log.bin"))
+	}
+
+	for _, file := range candidates {
+		if _, err := os.Stat(file); err == nil {
+			return file
+		}
+	}
+	return ""
+}
+
+// readServiceLogExcerpt returns last 'maxLines' lines of the tunnel service log
+func (wg *WireGuard) readServiceLogExcerpt(maxLines int) (string, error) {
+	logFile := wg.getServiceLogFile()
+	if len(logFile) == 0 {
+		return "", fmt.Errorf("tunnel service log file not found")
+	}
+
+	data, err := ioutil.ReadFile(logFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read tunnel service log: %w", err)
+	}
+
+	reader := bytes.NewReader(data)
+	var header ringloggerHeader
+	if err := binary.Read(reader, binary.LittleEndian, &header); err != nil {
+		return "", fmt.Errorf("failed to parse tunnel service log: %w", err)
+	}
+	if header.Magic != _ringloggerMagic {
+		return "", fmt.Errorf("unexpected format of the tunnel service log")
+	}
+
+	lines := make([]ringloggerLine, 0, _ringloggerMaxLines)
+	for {
+		var line ringloggerLine
+		if err := binary.Read(reader, binary.LittleEndian, &line); err != nil {
+			break // end of file
+		}
+		lines = append(lines, line)
+	}
+
+	// lines are stored in a ring: the oldest line is at the next-to-write index
+	ret := make([]string, 0, maxLines)
+	for i := 0; i < len(lines); i++ {
+		line := lines[(int(header.NextIndex)+i)%len(lines)]
+		if line.TimeNs == 0 {
+			continue // line not in use
+		}
+
+		text := line.Text[:]
+		if end := bytes.IndexByte(text, 0); end >= 0 {
+			text = text[:end]
+		}
+
+		ret = append(ret, fmt.Sprintf("%s: %s",
+			time.Unix(0, line.TimeNs).Format("2006-01-02 15:04:05.000"),
+			strings.TrimSpace(string(text))))
+		if len(ret) > maxLines {
+			ret = ret[1:] // keep only last 'maxLines' lines
+		}
+	}
+
+	if len(ret) == 0 {
+		return "", fmt.Errorf("tunnel service log is empty")
+	}
+	return strings.Join(ret, "\n"), nil
+}
+
+// getServiceLogExcerpt - best-effort variant of 'readServiceLogExcerpt()'
+// (empty string when the log is not readable)
+func (wg *WireGuard) getServiceLogExcerpt() string {
+	excerpt, err := wg.readServiceLogExcerpt(20)
+	if err != nil {
+		log.Warning("unable to read tunnel service log: ", err)
+		return ""
+	}
+	return excerpt
+}
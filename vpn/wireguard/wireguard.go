@@ -32,6 +32,7 @@ import (
 	"github.com/ivpn/desktop-app-daemon/helpers"
 	"github.com/ivpn/desktop-app-daemon/logger"
 	"github.com/ivpn/desktop-app-daemon/netinfo"
+	"github.com/ivpn/desktop-app-daemon/shell"
 	"github.com/ivpn/desktop-app-daemon/vpn"
 )
 
@@ -57,6 +58,27 @@ func (cp *ConnectionParams) SetCredentials(privateKey string, localIP net.IP) {
 	cp.clientLocalIP = localIP
 }
 
+// Port returns destination port of the connection
+func (cp *ConnectionParams) Port() int {
+	return cp.hostPort
+}
+
+// SetPort overrides destination port of the connection
+func (cp *ConnectionParams) SetPort(port int) {
+	cp.hostPort = port
+}
+
+// Host returns destination host IP of the connection
+func (cp *ConnectionParams) Host() net.IP {
+	return cp.hostIP
+}
+
+// SetHost overrides destination host IP of the connection
+// (e.g. to a local listener of an obfuscation transport)
+func (cp *ConnectionParams) SetHost(hostIP net.IP) {
+	cp.hostIP = hostIP
+}
+
 // CreateConnectionParams initializing connection parameters object
 func CreateConnectionParams(
 	hostPort int,
@@ -102,13 +124,19 @@ func (wg *WireGuard) DestinationIP() net.IP {
 	return wg.connectParams.hostIP
 }
 
+// InTunnelGatewayIP - IP address of the VPN gateway inside the tunnel
+// In use by tunnel watchdog to verify that the tunnel is alive
+func (wg *WireGuard) InTunnelGatewayIP() net.IP {
+	return wg.connectParams.hostLocalIP
+}
+
 // Type just returns VPN type
 func (wg *WireGuard) Type() vpn.Type { return vpn.WireGuard }
 
 // Init performs basic initializations before connection
 // It is useful, for example:
-//	- for WireGuard(Windows) - to ensure that WG service is fully uninstalled
-//	- for OpenVPN(Linux) - to ensure that OpenVPN has correct version
+//   - for WireGuard(Windows) - to ensure that WG service is fully uninstalled
+//   - for OpenVPN(Linux) - to ensure that OpenVPN has correct version
 func (wg *WireGuard) Init() error {
 	return wg.init()
 }
@@ -234,3 +262,40 @@ func (wg *WireGuard) notifyConnectedStat(stateChan chan<- vpn.StateInfo) {
 func (wg *WireGuard) OnRoutingChanged() error {
 	return wg.onRoutingChanged()
 }
+
+// HostPublicKey returns public key of the connected WG server
+func (wg *WireGuard) HostPublicKey() string {
+	return wg.connectParams.hostPublicKey
+}
+
+// UpdateEndpoint updates the peer endpoint of the active tunnel in-place ('wg set')
+// without tearing down and reinstalling the tunnel.
+// Applicable when the server published a new endpoint IP\port (servers.json refresh)
+// or after an obfuscation proxy failover (hostPort==0 - keep current port)
+func (wg *WireGuard) UpdateEndpoint(hostIP net.IP, hostPort int) error {
+	if hostIP == nil {
+		return fmt.Errorf("new endpoint not defined")
+	}
+
+	wg.connectParams.hostIP = hostIP
+	if hostPort > 0 {
+		wg.connectParams.hostPort = hostPort
+	}
+
+	return wg.updateEndpoint()
+}
+
+// setPeerEndpoint applies the endpoint from current connection parameters
+// to the active tunnel interface: 'wg set <interface> peer <key> endpoint <ip:port>'
+func (wg *WireGuard) setPeerEndpoint(interfaceName string) error {
+	// prevent user-defined data injection: ensure that nothing except the base64 public key will be passed to the tool
+	if !helpers.ValidateBase64(wg.connectParams.hostPublicKey) {
+		return fmt.Errorf("WG public key is not base64 string")
+	}
+
+	endpoint := wg.connectParams.hostIP.String() + ":" + strconv.Itoa(wg.connectParams.hostPort)
+	log.Info("Updating WireGuard peer endpoint: ", endpoint)
+
+	return shell.Exec(log, wg.toolBinaryPath, "set", interfaceName,
+		"peer", wg.connectParams.hostPublicKey, "endpoint", endpoint)
+}
@@ -42,7 +42,7 @@ import (
 	"github.com/ivpn/desktop-app-daemon/vpn"
 )
 
-//TODO: BE CAREFUL! Constant string! (can be changed after WireGuard update)
+// TODO: BE CAREFUL! Constant string! (can be changed after WireGuard update)
 const (
 	strTriggerSuccessInit      string = "UAPI listener started"
 	strTriggerAddrAlreadyInUse string = "Address already in use"
@@ -56,6 +56,7 @@ type internalVariables struct {
 	command       *exec.Cmd
 	isGoingToStop bool
 	defGateway    net.IP
+	utunName      string // name of the tunnel interface in use
 
 	isPaused      bool
 	omResumedChan chan struct{} // channel for 'On Resume' events
@@ -136,6 +137,7 @@ func (wg *WireGuard) internalConnect(stateChan chan<- vpn.StateInfo) error {
 	}
 
 	log.Info("Starting WireGuard in interface ", utunName)
+	wg.internals.utunName = utunName
 	// LOG_LEVEL=verbose
 	wg.internals.command = exec.Command(wg.binaryPath, "-f", utunName)
 	wg.internals.command.Env = os.Environ()
@@ -258,6 +260,14 @@ func (wg *WireGuard) isPaused() bool {
 	return wg.internals.isPaused
 }
 
+// updateEndpoint updates the peer endpoint of the active tunnel in-place
+func (wg *WireGuard) updateEndpoint() error {
+	if wg.internals.command == nil || len(wg.internals.utunName) == 0 || wg.isPaused() {
+		return fmt.Errorf("WireGuard is not connected")
+	}
+	return wg.setPeerEndpoint(wg.internals.utunName)
+}
+
 func (wg *WireGuard) pause() error {
 	wg.internals.isPaused = true
 	return wg.internalDisconnect()
@@ -473,3 +483,9 @@ func (wg *WireGuard) getOSSpecificConfigParams() (interfaceCfg []string, peerCfg
 
 	return interfaceCfg, peerCfg
 }
+
+// SetServiceWaitTimeouts - limit wait timeouts of the tunnel service setup
+// Applicable only for Windows implementation
+func SetServiceWaitTimeouts(installTimeout time.Duration, startTimeout time.Duration) {
+	// nothing to do for macOS
+}
@@ -29,6 +29,7 @@ import (
 	"os/exec"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -246,7 +247,85 @@ func (wg *WireGuard) getOSSpecificConfigParams() (interfaceCfg []string, peerCfg
 	return interfaceCfg, peerCfg
 }
 
+// updateEndpoint updates the peer endpoint of the active tunnel in-place
+func (wg *WireGuard) updateEndpoint() error {
+	if wg.internals.isRunning == false || wg.isPaused() {
+		return fmt.Errorf("WireGuard is not connected")
+	}
+
+	wgInterfaceName := filepath.Base(wg.configFilePath)
+	wgInterfaceName = strings.TrimSuffix(wgInterfaceName, path.Ext(wgInterfaceName))
+	return wg.setPeerEndpoint(wgInterfaceName)
+}
+
 func (wg *WireGuard) onRoutingChanged() error {
-	// do nothing for Linux
+	// On Linux all traffic is directed into the tunnel by the policy routing rules
+	// installed by the 'wg-quick' tool (everything which is not marked by the WG fwmark
+	// is looked up in a separate routing table).
+	// A DHCP renewal or another VPN software can erase these rules. In this case
+	// traffic silently starts going around the tunnel.
+	// Here we check the rules and reinstall them when necessary.
+	if wg.internals.isRunning == false || wg.isPaused() {
+		return nil
+	}
+
+	wgInterfaceName := filepath.Base(wg.configFilePath)
+	wgInterfaceName = strings.TrimSuffix(wgInterfaceName, path.Ext(wgInterfaceName))
+	if _, err := net.InterfaceByName(wgInterfaceName); err != nil {
+		// no tunnel interface - nothing to reassert (reconnection will be initiated by the service)
+		return nil
+	}
+
+	// fwmark of the current connection ('wg-quick' uses the same value as ID of the routing table)
+	outText, _, _, err := shell.ExecAndGetOutput(log, 64, "", wg.toolBinaryPath, "show", wgInterfaceName, "fwmark")
+	if err != nil {
+		return fmt.Errorf("failed to obtain WG fwmark: %w", err)
+	}
+	fwmarkStr := strings.TrimSpace(outText)
+	if len(fwmarkStr) == 0 || fwmarkStr == "off" {
+		return nil // fwmark not in use - no policy routing rules to reassert
+	}
+	fwmark, err := strconv.ParseUint(strings.TrimPrefix(fwmarkStr, "0x"), 16, 32)
+	if err != nil {
+		return fmt.Errorf("failed to parse WG fwmark '%s': %w", fwmarkStr, err)
+	}
+	tableID := strconv.FormatUint(fwmark, 10)
+
+	rules, _, _, err := shell.ExecAndGetOutput(log, 1024*64, "", "ip", "-4", "rule")
+	if err != nil {
+		return fmt.Errorf("failed to check policy routing rules: %w", err)
+	}
+
+	isLookupRuleOk := strings.Contains(rules, "lookup "+tableID)
+	isSuppressRuleOk := strings.Contains(rules, "suppress_prefixlength 0")
+	if isLookupRuleOk && isSuppressRuleOk {
+		return nil // rules are in place
+	}
+
+	log.Warning("WireGuard policy routing rules were erased. Reinstalling...")
+
+	// example commands:	ip -4 route replace default dev wgivpn table 51820
+	//						ip -4 rule add not fwmark 51820 table 51820
+	//						ip -4 rule add table main suppress_prefixlength 0
+	if err := shell.Exec(log, "ip", "-4", "route", "replace", "default", "dev", wgInterfaceName, "table", tableID); err != nil {
+		return fmt.Errorf("failed to reinstall WG route: %w", err)
+	}
+	if isLookupRuleOk == false {
+		if err := shell.Exec(log, "ip", "-4", "rule", "add", "not", "fwmark", tableID, "table", tableID); err != nil {
+			return fmt.Errorf("failed to reinstall WG routing rule: %w", err)
+		}
+	}
+	if isSuppressRuleOk == false {
+		if err := shell.Exec(log, "ip", "-4", "rule", "add", "table", "main", "suppress_prefixlength", "0"); err != nil {
+			return fmt.Errorf("failed to reinstall WG routing rule: %w", err)
+		}
+	}
+
 	return nil
 }
+
+// SetServiceWaitTimeouts - limit wait timeouts of the tunnel service setup
+// Applicable only for Windows implementation
+func SetServiceWaitTimeouts(installTimeout time.Duration, startTimeout time.Duration) {
+	// nothing to do for Linux
+}
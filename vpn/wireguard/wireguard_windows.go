@@ -30,6 +30,7 @@ import (
 	"strings"
 	"sync"
 	"time"
+	"unsafe"
 
 	"github.com/ivpn/desktop-app-daemon/service/dns"
 	"github.com/ivpn/desktop-app-daemon/shell"
@@ -49,25 +50,142 @@ var (
 type operation int
 
 const (
-	pause  operation = iota
-	resume operation = iota
+	pause   operation = iota
+	resume  operation = iota
+	restart operation = iota // restart connection with new connection parameters (e.g. new ManualDNS value)
 )
 
-// internalVariables of wireguard implementation for macOS
+// internalVariables of wireguard implementation for Windows
+//
+// Connection control (pause\resume\disconnect\restart) is synchronized:
+// control requests are serialized into a command channel which is consumed only by the
+// connection loop (single owner goroutine of the connection state); boolean state flags
+// are protected by the mutex (they are read from other goroutines)
 type internalVariables struct {
-	manualDNS             net.IP
-	isRestartRequired     bool           // if true - connection will be restarted
-	pauseRequireChan      chan operation // control connection pause\resume or disconnect from paused state
+	manualDNS net.IP
+
+	mutex                 sync.Mutex     // protects fields below
+	commandChan           chan operation // control commands of the connection loop (nil - loop is not running)
 	isDisconnectRequested bool
 	isPaused              bool
+
+	restartsHistory     []time.Time // flap detection: moments of tunnel service (re)starts
+	lastServiceExitCode uint32      // exit code of the tunnel service at the moment of last stop
+}
+
+func (v *internalVariables) setDisconnectRequested() {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	v.isDisconnectRequested = true
+}
+
+func (v *internalVariables) getDisconnectRequested() bool {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	return v.isDisconnectRequested
+}
+
+func (v *internalVariables) setPaused(paused bool) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	v.isPaused = paused
+}
+
+func (v *internalVariables) getPaused() bool {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	return v.isPaused
+}
+
+// initCommandChan initializes the command channel of the connection loop
+func (v *internalVariables) initCommandChan() chan operation {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	v.commandChan = make(chan operation, 16)
+	return v.commandChan
+}
+
+func (v *internalVariables) closeCommandChan() {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	v.commandChan = nil
+}
+
+// sendCommand passes a control command to the connection loop
+// (does nothing when the connection loop is not running)
+func (v *internalVariables) sendCommand(o operation) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	if v.commandChan == nil {
+		return
+	}
+	select {
+	case v.commandChan <- o:
+	default:
+		log.Warning("connection control command skipped (commands queue is full)")
+	}
 }
 
 const (
 	// such significant delays required to support ultimate slow PC
-	_waitServiceInstallTimeout = time.Minute * 3
-	_waitServiceStartTimeout   = time.Minute * 5
+	_defWaitServiceInstallTimeout = time.Minute * 3
+	_defWaitServiceStartTimeout   = time.Minute * 5
 )
 
+var (
+	// effective wait timeouts of the tunnel service setup
+	// (defaults can be limited by user preferences; see 'SetServiceWaitTimeouts()')
+	_waitServiceInstallTimeout = _defWaitServiceInstallTimeout
+	_waitServiceStartTimeout   = _defWaitServiceStartTimeout
+)
+
+// SetServiceWaitTimeouts overrides maximum wait timeouts of the tunnel service install\start phases
+// (zero or negative value - restore the built-in default)
+func SetServiceWaitTimeouts(installTimeout time.Duration, startTimeout time.Duration) {
+	if installTimeout <= 0 {
+		installTimeout = _defWaitServiceInstallTimeout
+	}
+	if startTimeout <= 0 {
+		startTimeout = _defWaitServiceStartTimeout
+	}
+	_waitServiceInstallTimeout = installTimeout
+	_waitServiceStartTimeout = startTimeout
+}
+
+// staged progress of the tunnel service setup (streamed to clients with elapsed time)
+const (
+	_stageInstallingService  = "INSTALLING_SERVICE"
+	_stageStartingService    = "STARTING_SERVICE"
+	_stageConfiguringAdapter = "CONFIGURING_ADAPTER"
+
+	// interval of progress notifications of a long-running setup stage
+	_stageNotifyInterval = time.Second * 5
+)
+
+const (
+	// flap detection: stop retrying when the tunnel service had to be (re)started too frequently
+	_flapDetectionMaxRestarts = 5
+	_flapDetectionInterval    = time.Minute * 5
+)
+
+// TunnelServiceFlappingError - the WireGuard tunnel service was restarting too frequently (flap detection)
+// Restart attempts are stopped to avoid an endless install\uninstall loop of the service
+type TunnelServiceFlappingError struct {
+	RestartsCount   int
+	Interval        time.Duration
+	ServiceExitCode uint32 // exit code of the tunnel service at the moment of last stop
+	ServiceLog      string // excerpt of the tunnel service log (empty - log not readable)
+}
+
+func (e *TunnelServiceFlappingError) Error() string {
+	ret := fmt.Sprintf("WireGuard tunnel service restarted %d times in %v; giving up (service exit code at last stop: %d)",
+		e.RestartsCount, e.Interval, e.ServiceExitCode)
+	if len(e.ServiceLog) > 0 {
+		ret += "; service log:\n" + e.ServiceLog
+	}
+	return ret
+}
+
 func (wg *WireGuard) init() error {
 	// uninstall WG service (if installed)
 
@@ -84,12 +202,12 @@ func (wg *WireGuard) init() error {
 
 // connect - SYNCHRONOUSLY execute openvpn process (wait until it finished)
 func (wg *WireGuard) connect(stateChan chan<- vpn.StateInfo) error {
-	if wg.internals.isDisconnectRequested {
+	if wg.internals.getDisconnectRequested() {
 		return fmt.Errorf("disconnection already requested for this object. To make a new connection, please, initialize new one")
 	}
 
 	defer func() {
-		wg.internals.pauseRequireChan = nil
+		wg.internals.closeCommandChan()
 		// do not forget to remove manual DNS configuration (if necessary)
 		if err := dns.DeleteManual(nil); err != nil {
 			log.Error(err)
@@ -117,14 +235,14 @@ func (wg *WireGuard) connect(stateChan chan<- vpn.StateInfo) error {
 
 	// CONNECTED
 
-	if wg.internals.isDisconnectRequested {
+	if wg.internals.getDisconnectRequested() {
 		// there is chance that disconnection request come during WG was establishing connection
 		// in this case - perform disconnection
 		log.Info("Disconnection was requested")
 		return wg.uninstallService()
 	}
 
-	wg.internals.pauseRequireChan = make(chan operation, 1)
+	commandChan := wg.internals.initCommandChan()
 
 	// this method is synchronous. Waiting until service stop
 	// (periodically checking of service status)
@@ -138,17 +256,22 @@ func (wg *WireGuard) connect(stateChan chan<- vpn.StateInfo) error {
 		}
 
 		if stat == svc.Stopped {
+			if exitCode, e := wg.getServiceExitCode(m); e == nil {
+				wg.internals.lastServiceExitCode = exitCode
+				log.Info(fmt.Sprintf("Tunnel service stopped (service exit code: %d)", exitCode))
+			}
 			break
 		}
 
-		// PAUSE\RESUME
+		// control commands (the connection loop is the only consumer of the command channel)
 		select {
-		case toDoOperation := <-wg.internals.pauseRequireChan:
-			if toDoOperation == pause {
-				wg.internals.isPaused = true
+		case toDoOperation := <-commandChan:
+			switch toDoOperation {
+			case pause:
+				wg.internals.setPaused(true)
 				defer func() {
 					// do not forget to mark connection as resumed
-					wg.internals.isPaused = false
+					wg.internals.setPaused(false)
 				}()
 
 				log.Info("Pausing...")
@@ -161,53 +284,44 @@ func (wg *WireGuard) connect(stateChan chan<- vpn.StateInfo) error {
 				log.Info("Paused")
 
 				// waiting to resume or stop request
-				for {
-					toDoOperation = <-wg.internals.pauseRequireChan
-					if toDoOperation != pause { // ignore consequent 'pause' requests
-						break
-					}
+				// (ignore other commands in paused state: consequent 'pause', 'restart')
+				for toDoOperation != resume {
+					toDoOperation = <-commandChan
 				}
 
-				if wg.internals.isDisconnectRequested {
+				if wg.internals.getDisconnectRequested() {
 					break
 				}
 
-				if toDoOperation == resume {
-					log.Info("Resuming...")
+				log.Info("Resuming...")
 
-					if err := wg.installService(stateChan); err != nil {
-						log.Error("failed to resume connection (new connetion error):", err.Error())
-						return err
-					}
-
-					// reconnected successfully
-					wg.internals.isPaused = false
-					log.Info("Resumed")
-					break
+				if err := wg.installService(stateChan); err != nil {
+					log.Error("failed to resume connection (new connetion error):", err.Error())
+					return err
 				}
-			}
-		default:
-			// no pause required
-		}
 
-		// Check is reconnection required
-		// It can happen when configuration parameters were changed (e.g. ManualDNS value)
-		if wg.internals.isRestartRequired {
-			wg.internals.isRestartRequired = false
+				// reconnected successfully
+				wg.internals.setPaused(false)
+				log.Info("Resumed")
 
-			stateChan <- vpn.NewStateInfo(vpn.RECONNECTING, "Reconnecting with new connection parameters")
+			case restart:
+				// reconnection required: configuration parameters were changed (e.g. ManualDNS value)
+				stateChan <- vpn.NewStateInfo(vpn.RECONNECTING, "Reconnecting with new connection parameters")
 
-			log.Info("Restarting...")
-			if err := wg.uninstallService(); err != nil {
-				log.Error("failed to restart connection (disconnetion error):", err.Error())
-			} else {
-				if err := wg.installService(stateChan); err != nil {
-					log.Error("failed to restart connection (new connetion error):", err.Error())
+				log.Info("Restarting...")
+				if err := wg.uninstallService(); err != nil {
+					log.Error("failed to restart connection (disconnetion error):", err.Error())
 				} else {
-					// reconnected successfully
-					log.Info("Connection restarted")
+					if err := wg.installService(stateChan); err != nil {
+						log.Error("failed to restart connection (new connetion error):", err.Error())
+					} else {
+						// reconnected successfully
+						log.Info("Connection restarted")
+					}
 				}
 			}
+		default:
+			// no control commands
 		}
 	}
 
@@ -215,7 +329,7 @@ func (wg *WireGuard) connect(stateChan chan<- vpn.StateInfo) error {
 }
 
 func (wg *WireGuard) disconnect() error {
-	wg.internals.isDisconnectRequested = true
+	wg.internals.setDisconnectRequested()
 	return wg.disconnectInternal()
 }
 
@@ -228,7 +342,20 @@ func (wg *WireGuard) disconnectInternal() error {
 }
 
 func (wg *WireGuard) isPaused() bool {
-	return wg.internals.isPaused
+	return wg.internals.getPaused()
+}
+
+// updateEndpoint updates the peer endpoint of the active tunnel in-place
+// ('wg.exe' communicates with the tunnel service over the UAPI named pipe)
+func (wg *WireGuard) updateEndpoint() error {
+	wg.internals.mutex.Lock()
+	isRunning := wg.internals.commandChan != nil
+	wg.internals.mutex.Unlock()
+
+	if isRunning == false || wg.isPaused() {
+		return fmt.Errorf("WireGuard is not connected")
+	}
+	return wg.setPeerEndpoint(wg.getTunnelName())
 }
 
 func (wg *WireGuard) pause() error {
@@ -242,10 +369,7 @@ func (wg *WireGuard) resume() error {
 }
 
 func (wg *WireGuard) requireOperation(o operation) error {
-	ch := wg.internals.pauseRequireChan
-	if ch != nil {
-		ch <- o
-	}
+	wg.internals.sendCommand(o)
 	return nil
 }
 
@@ -262,7 +386,7 @@ func (wg *WireGuard) setManualDNS(addr net.IP) error {
 
 	log.Info("Connection will be restarted due to DNS server IP configuration change...")
 	// request a restart with new connection parameters
-	wg.internals.isRestartRequired = true
+	wg.internals.sendCommand(restart)
 
 	return nil
 }
@@ -280,7 +404,7 @@ func (wg *WireGuard) resetManualDNS() error {
 
 	log.Info("Connection will be restarted due to DNS server IP configuration change...")
 	// request a restart with new connection parameters
-	wg.internals.isRestartRequired = true
+	wg.internals.sendCommand(restart)
 
 	return nil
 }
@@ -329,6 +453,30 @@ func (wg *WireGuard) getServiceStatus(m *mgr.Mgr) (bool, svc.State, error) {
 	return true, stat.State, nil
 }
 
+// getServiceExitCode returns exit code of the tunnel service (applicable for a stopped service)
+func (wg *WireGuard) getServiceExitCode(m *mgr.Mgr) (uint32, error) {
+	service, err := m.OpenService(wg.getServiceName())
+	if err != nil {
+		return 0, err
+	}
+	defer service.Close()
+
+	var status windows.SERVICE_STATUS_PROCESS
+	var bytesNeeded uint32
+	if err := windows.QueryServiceStatusEx(service.Handle,
+		windows.SC_STATUS_PROCESS_INFO,
+		(*byte)(unsafe.Pointer(&status)),
+		uint32(unsafe.Sizeof(status)),
+		&bytesNeeded); err != nil {
+		return 0, err
+	}
+
+	if status.Win32ExitCode == uint32(windows.ERROR_SERVICE_SPECIFIC_ERROR) {
+		return status.ServiceSpecificExitCode, nil
+	}
+	return status.Win32ExitCode, nil
+}
+
 func (wg *WireGuard) isServiceRunning() (bool, error) {
 	// connect to service maneger
 	m, err := mgr.Connect()
@@ -378,6 +526,24 @@ func (wg *WireGuard) installService(stateChan chan<- vpn.StateInfo) error {
 		_globalInitMutex.Unlock()
 	}()
 
+	// flap detection: forget service (re)starts older than the detection interval
+	now := time.Now()
+	restarts := wg.internals.restartsHistory[:0]
+	for _, t := range wg.internals.restartsHistory {
+		if now.Sub(t) < _flapDetectionInterval {
+			restarts = append(restarts, t)
+		}
+	}
+	if len(restarts) >= _flapDetectionMaxRestarts {
+		wg.internals.restartsHistory = restarts
+		return &TunnelServiceFlappingError{
+			RestartsCount:   len(restarts),
+			Interval:        _flapDetectionInterval,
+			ServiceExitCode: wg.internals.lastServiceExitCode,
+			ServiceLog:      wg.getServiceLogExcerpt()}
+	}
+	wg.internals.restartsHistory = append(restarts, now)
+
 	log.Info("Connecting...")
 
 	// generate configuration
@@ -389,6 +555,7 @@ func (wg *WireGuard) installService(stateChan chan<- vpn.StateInfo) error {
 
 	// start service
 	log.Info("Installing service...")
+	notifyStage(stateChan, _stageInstallingService, 0)
 	err = shell.Exec(nil, wg.binaryPath, "/installtunnelservice", wg.configFilePath)
 	if err != nil {
 		return fmt.Errorf("failed to install WireGuard service: %w", err)
@@ -404,7 +571,14 @@ func (wg *WireGuard) installService(stateChan chan<- vpn.StateInfo) error {
 	// waiting for until service installed
 	log.Info("Waiting for service install...")
 	serviceName := wg.getServiceName()
-	for started := time.Now(); time.Since(started) < _waitServiceInstallTimeout; time.Sleep(time.Millisecond * 10) {
+	installStarted := time.Now()
+	lastStageNotify := installStarted
+	for ; time.Since(installStarted) < _waitServiceInstallTimeout; time.Sleep(time.Millisecond * 10) {
+		// do not wait for the whole timeout when disconnection requested
+		if wg.internals.getDisconnectRequested() {
+			return fmt.Errorf("service install canceled (disconnection requested)")
+		}
+
 		service, err := m.OpenService(serviceName)
 		if err == nil {
 			log.Info("Service installed")
@@ -412,18 +586,38 @@ func (wg *WireGuard) installService(stateChan chan<- vpn.StateInfo) error {
 			isInstalled = true
 			break
 		}
+
+		// early failure: SCM errors which will not recover by waiting
+		if err == windows.ERROR_ACCESS_DENIED || err == windows.ERROR_SERVICE_MARKED_FOR_DELETE || err == windows.ERROR_SERVICE_DISABLED {
+			return fmt.Errorf("service install failed: %w", err)
+		}
+
+		// stream stage progress to clients (the wait can take minutes on a slow PC)
+		if time.Since(lastStageNotify) >= _stageNotifyInterval {
+			lastStageNotify = time.Now()
+			notifyStage(stateChan, _stageInstallingService, time.Since(installStarted))
+		}
 	}
 
 	// service install timeout
 	if isInstalled == false {
-		return fmt.Errorf("service not installed (timeout)")
+		return fmt.Errorf("service not installed (timeout %v)", _waitServiceInstallTimeout)
 	}
 
 	// wait for service starting
 	log.Info("Waiting for service start...")
-	for started := time.Now(); time.Since(started) < _waitServiceStartTimeout; time.Sleep(time.Millisecond * 10) {
+	notifyStage(stateChan, _stageStartingService, 0)
+	startStarted := time.Now()
+	lastStageNotify = startStarted
+	for ; time.Since(startStarted) < _waitServiceStartTimeout; time.Sleep(time.Millisecond * 10) {
+		// do not wait for the whole timeout when disconnection requested
+		if wg.internals.getDisconnectRequested() {
+			return fmt.Errorf("service start canceled (disconnection requested)")
+		}
+
 		_, stat, err := wg.getServiceStatus(m)
 		if err != nil {
+			// early failure: the just-installed service disappeared (e.g. marked for delete by the SCM)
 			return fmt.Errorf("service start error: %w", err)
 		}
 
@@ -432,14 +626,33 @@ func (wg *WireGuard) installService(stateChan chan<- vpn.StateInfo) error {
 			isStarted = true
 			break
 		} else if stat == svc.Stopped {
-			return fmt.Errorf("WireGuard service stopped")
+			// early failure: no sense to wait for the rest of the timeout
+			exitCode, e := wg.getServiceExitCode(m)
+			if e != nil {
+				return fmt.Errorf("WireGuard service stopped")
+			}
+			wg.internals.lastServiceExitCode = exitCode
+
+			// include failure reason from the tunnel service log (bad config, adapter creation failure ...)
+			if excerpt := wg.getServiceLogExcerpt(); len(excerpt) > 0 {
+				return fmt.Errorf("WireGuard service stopped (service exit code: %d); service log:\n%s", exitCode, excerpt)
+			}
+			return fmt.Errorf("WireGuard service stopped (service exit code: %d)", exitCode)
+		}
+
+		// stream stage progress to clients (the wait can take minutes on a slow PC)
+		if time.Since(lastStageNotify) >= _stageNotifyInterval {
+			lastStageNotify = time.Now()
+			notifyStage(stateChan, _stageStartingService, time.Since(startStarted))
 		}
 	}
 
 	if isStarted == false {
-		return fmt.Errorf("service not started (timeout)")
+		return fmt.Errorf("service not started (timeout %v)", _waitServiceStartTimeout)
 	}
 
+	notifyStage(stateChan, _stageConfiguringAdapter, 0)
+
 	// WireGuard interface is configured to correct DNS.
 	// But we must to be sure if non-ivpn interfaces are configured to our DNS
 	// (it needed ONLY if DNS IP located in local network)
@@ -546,3 +759,13 @@ func (wg *WireGuard) onRoutingChanged() error {
 	// do nothing for Windows
 	return nil
 }
+
+// notifyStage streams the current stage of the tunnel service setup to clients
+// (consequent notifications of a same stage include the elapsed time)
+func notifyStage(stateChan chan<- vpn.StateInfo, stage string, elapsed time.Duration) {
+	info := stage
+	if elapsed > 0 {
+		info = fmt.Sprintf("%s (elapsed %v)", stage, elapsed.Round(time.Second))
+	}
+	stateChan <- vpn.NewStateInfo(vpn.CONNECTING, info)
+}
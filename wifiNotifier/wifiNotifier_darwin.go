@@ -1,3 +1,4 @@
+//go:build darwin && !nowifi
 // +build darwin,!nowifi
 
 package wifiNotifier
@@ -44,6 +45,15 @@ static inline char * getCurrentSSID(void) {
 	return nsstring2cstring(ssid);
 }
 
+static inline char * getCurrentBSSID(void) {
+	CWInterface * WiFiInterface = getCWInterface();
+	if (WiFiInterface == nil) return nsstring2cstring(NOT_CONNECTED);
+
+	// note: can be empty when the application has no location permission (macOS 10.15+)
+	NSString *bssid = [WiFiInterface bssid] ? [WiFiInterface bssid] : NOT_CONNECTED;
+	return nsstring2cstring(bssid);
+}
+
 static inline int getCurrentNetworkSecurity() {
 	CWInterface * WiFiInterface = getCWInterface();
 	if (WiFiInterface == nil) return 0xFFFFFFFF;
@@ -141,6 +151,15 @@ func GetCurrentSSID() string {
 	return goSsid
 }
 
+// GetCurrentBSSID returns BSSID (MAC address of the access point) of the current WiFi network
+// (empty string - not connected to WiFi)
+func GetCurrentBSSID() string {
+	bssid := C.getCurrentBSSID()
+	goBssid := C.GoString(bssid)
+	C.free(unsafe.Pointer(bssid))
+	return goBssid
+}
+
 // GetCurrentNetworkIsInsecure returns current security mode
 func GetCurrentNetworkIsInsecure() bool {
 	const (
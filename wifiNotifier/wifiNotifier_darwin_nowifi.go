@@ -1,3 +1,4 @@
+//go:build darwin && nowifi
 // +build darwin,nowifi
 
 package wifiNotifier
@@ -14,6 +15,11 @@ func GetCurrentSSID() string {
 	return ""
 }
 
+// GetCurrentBSSID returns BSSID of the current WiFi network
+func GetCurrentBSSID() string {
+	return ""
+}
+
 // GetCurrentNetworkIsInsecure returns current security mode
 func GetCurrentNetworkIsInsecure() bool {
 	return false
@@ -1,3 +1,4 @@
+//go:build linux
 // +build linux
 
 package wifiNotifier
@@ -208,6 +209,29 @@ static inline char* get_essid (char *iface)
    return strndup (essid, 32); // normally, the IW_ESSID_MAX_SIZE is 32 bytes (the coping with potential security flaws within the driver)
 }
 
+static inline char* get_bssid (char *iface)
+{
+   int           fd;
+   struct iwreq  w;
+   if (!iface) return NULL;
+
+   fd = socket(AF_INET, SOCK_DGRAM, 0);
+
+   strncpy (w.ifr_ifrn.ifrn_name, iface, IFNAMSIZ);
+   memset (&w.u, 0, sizeof(w.u));
+
+   int isOK = ioctl (fd, SIOCGIWAP, &w);
+   close (fd);
+
+   if (isOK != 0) return NULL;
+
+   const unsigned char* mac = (const unsigned char*)w.u.ap_addr.sa_data;
+   char* ret = (char*)malloc(18);
+   snprintf(ret, 18, "%02x:%02x:%02x:%02x:%02x:%02x",
+       mac[0], mac[1], mac[2], mac[3], mac[4], mac[5]);
+   return ret;
+}
+
 static inline char * getCurrentWifiInfo(int* retIsInsecure) {
     char* retSSID = NULL;
 
@@ -242,6 +266,34 @@ static inline char * getCurrentSSID(void) {
     return getCurrentWifiInfo(NULL);
 }
 
+static inline char * getCurrentBSSID(void) {
+    char* retBSSID = NULL;
+
+    // get all available network interfaces
+    struct ifaddrs *addrs,*tmp_addrs;
+    getifaddrs(&addrs);
+    tmp_addrs = addrs;
+    while (tmp_addrs)
+    {
+        if (tmp_addrs->ifa_addr && tmp_addrs->ifa_addr->sa_family == AF_PACKET)
+        {
+            char* ssid = get_essid (tmp_addrs->ifa_name);
+            if (ssid!=NULL)
+            {
+                free(ssid);
+                retBSSID = get_bssid (tmp_addrs->ifa_name);
+                // do not forget to free 'retBSSID' from memory!
+                break;
+            }
+        }
+
+        tmp_addrs = tmp_addrs->ifa_next;
+    }
+    freeifaddrs(addrs);
+
+    return retBSSID;
+}
+
 static inline int getCurrentNetworkIsInsecure() {
     int retIsInecure = 0xFFFFFFFF;
     char* ssid = getCurrentWifiInfo(&retIsInecure);
@@ -292,6 +344,18 @@ func GetCurrentSSID() string {
 	return goSsid
 }
 
+// GetCurrentBSSID returns BSSID (MAC address of the access point) of the current WiFi network
+// (empty string - not connected to WiFi)
+func GetCurrentBSSID() string {
+	bssid := C.getCurrentBSSID()
+	if bssid == nil {
+		return ""
+	}
+	goBssid := C.GoString(bssid)
+	C.free(unsafe.Pointer(bssid))
+	return goBssid
+}
+
 // GetCurrentNetworkIsInsecure returns current security mode
 func GetCurrentNetworkIsInsecure() bool {
 	// TODO: implement GetCurrentNetworkIsInsecure functionality for Linux
@@ -318,7 +382,7 @@ func SetWifiNotifier(cb func(string)) error {
 		for {
 			msgs, err := l.ReadMsgs()
 			if err != nil {
-				fmt.Println("Could not read netlink messages: %s", err)
+				fmt.Printf("Could not read netlink messages: %s\n", err)
 			}
 			for _, m := range msgs {
 				if netlink.IsNewAddr(&m) || netlink.IsDelAddr(&m) {
@@ -1,3 +1,4 @@
+//go:build windows
 // +build windows
 
 package wifiNotifier
@@ -12,6 +13,11 @@ func GetCurrentSSID() string {
 	return ""
 }
 
+// GetCurrentBSSID returns BSSID of the current WiFi network
+func GetCurrentBSSID() string {
+	return ""
+}
+
 // GetCurrentNetworkIsInsecure returns current security mode
 func GetCurrentNetworkIsInsecure() bool {
 	return false
@@ -1,3 +1,4 @@
+//go:build windows
 // +build windows
 
 package wifiNotifier
@@ -131,6 +132,66 @@ static inline char * getCurrentSSID(void) {
 	return ssid;
 }
 
+static inline char * getCurrentBSSID(void) {
+
+	openHandle();
+
+	char *bssid = malloc(64);
+	memset(bssid,0,64);
+
+	DWORD dwResult = 0;
+    unsigned int i;
+
+    PWLAN_INTERFACE_INFO_LIST pIfList = NULL;
+    PWLAN_INTERFACE_INFO pIfInfo = NULL;
+
+    PWLAN_CONNECTION_ATTRIBUTES pConnectInfo = NULL;
+    DWORD connectInfoSize = sizeof(WLAN_CONNECTION_ATTRIBUTES);
+	WLAN_OPCODE_VALUE_TYPE opCode = wlan_opcode_value_type_invalid;
+
+    dwResult = WlanEnumInterfaces(hClient, NULL, &pIfList);
+    if (dwResult != ERROR_SUCCESS) {
+        wprintf(L"WlanEnumInterfaces failed with error: %u\n", dwResult);
+    } else {
+
+        for (i = 0; i < (int) pIfList->dwNumberOfItems; i++) {
+			pIfInfo = (WLAN_INTERFACE_INFO *) & pIfList->InterfaceInfo[i];
+
+            if (pIfInfo->isState == wlan_interface_state_connected) {
+                dwResult = WlanQueryInterface(hClient,
+                                              &pIfInfo->InterfaceGuid,
+                                              wlan_intf_opcode_current_connection,
+                                              NULL,
+                                              &connectInfoSize,
+                                              (PVOID *) &pConnectInfo,
+                                              &opCode);
+
+                if (dwResult != ERROR_SUCCESS) {
+                    wprintf(L"WlanQueryInterface failed with error: %u\n", dwResult);
+                } else {
+					const unsigned char* mac = (const unsigned char*)pConnectInfo->wlanAssociationAttributes.dot11Bssid;
+					sprintf_s(bssid, 64, "%02x:%02x:%02x:%02x:%02x:%02x",
+						mac[0], mac[1], mac[2], mac[3], mac[4], mac[5]);
+					break;
+                }
+            }
+        }
+
+	}
+
+    if (pConnectInfo != NULL) {
+        WlanFreeMemory(pConnectInfo);
+        pConnectInfo = NULL;
+    }
+
+    if (pIfList != NULL) {
+        WlanFreeMemory(pIfList);
+        pIfList = NULL;
+    }
+
+	return bssid;
+}
+
 static inline int getCurrentNetworkSecurity() {
 	openHandle();
 
@@ -340,6 +401,15 @@ func GetCurrentSSID() string {
 	return goSsid
 }
 
+// GetCurrentBSSID returns BSSID (MAC address of the access point) of the current WiFi network
+// (empty string - not connected to WiFi)
+func GetCurrentBSSID() string {
+	bssid := C.getCurrentBSSID()
+	goBssid := C.GoString(bssid)
+	C.free(unsafe.Pointer(bssid))
+	return goBssid
+}
+
 // GetCurrentNetworkIsInsecure returns current security mode
 func GetCurrentNetworkIsInsecure() bool {
 	const (